@@ -8,6 +8,9 @@ import (
 
 	"github.com/muaviaUsmani/bananas/internal/config"
 	"github.com/muaviaUsmani/bananas/internal/logger"
+	"github.com/muaviaUsmani/bananas/internal/progress"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/muaviaUsmani/bananas/internal/scheduler"
 )
 
 func main() {
@@ -56,6 +59,27 @@ func main() {
 		fmt.Fprintf(w, "Bananas API Server")
 	})
 
+	// Stream job progress as Server-Sent Events
+	redisClient, err := redisconn.NewClient(cfg.RedisURL, nil)
+	if err != nil {
+		apiLog.Error("Failed to parse Redis URL", "error", err)
+		os.Exit(1)
+	}
+	progressStore := progress.NewRedisStore(redisClient, cfg.ProgressTTL)
+	if err := progressStore.SetNamespace(cfg.Namespace); err != nil {
+		apiLog.Error("Invalid namespace", "error", err)
+		os.Exit(1)
+	}
+	mainMux.Handle("GET /jobs/{id}/progress", progress.NewSSEHandler(progressStore))
+
+	// Manage cron schedules stored in Redis; schedulers pick up changes on their own
+	scheduleStore := scheduler.NewStore(redisClient)
+	if err := scheduleStore.SetNamespace(cfg.Namespace); err != nil {
+		apiLog.Error("Invalid namespace", "error", err)
+		os.Exit(1)
+	}
+	scheduler.NewAPIHandler(scheduleStore).Register(mainMux)
+
 	addr := ":" + cfg.APIPort
 	apiLog.Info("API server listening", "address", addr)
 
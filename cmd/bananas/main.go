@@ -0,0 +1,173 @@
+// Command bananas manages a Bananas deployment from the command line
+//
+// Usage:
+//
+//	bananas schedules list
+//	bananas schedules get <id>
+//	bananas schedules set -id <id> -cron <expr> -job <name> [flags]
+//	bananas schedules delete <id>
+//
+// REDIS_URL and BANANAS_NAMESPACE select the deployment, like the services.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/scheduler"
+	"github.com/muaviaUsmani/bananas/pkg/client"
+)
+
+const usage = `Usage:
+  bananas schedules list
+  bananas schedules get <id>
+  bananas schedules set -id <id> -cron <expr> -job <name> [flags]
+  bananas schedules delete <id>
+
+Run "bananas schedules set -h" for the schedule flags.
+`
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "schedules" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := runSchedules(os.Args[2], os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// connect creates a client for the deployment selected by the environment
+func connect() (*client.Client, error) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379"
+	}
+	ns := os.Getenv("BANANAS_NAMESPACE")
+	if ns == "" {
+		ns = namespace.Default
+	}
+
+	c, err := client.NewClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.SetNamespace(ns); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// runSchedules runs a schedules subcommand
+func runSchedules(command string, args []string) error {
+	switch command {
+	case "list", "get", "set", "delete":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var schedule *scheduler.Schedule
+	if command == "set" {
+		var err error
+		if schedule, err = parseSchedule(args); err != nil {
+			return err
+		}
+	} else if command != "list" && len(args) != 1 {
+		return fmt.Errorf("%s takes a schedule ID", command)
+	}
+
+	c, err := connect()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	switch command {
+	case "list":
+		schedules, err := c.ListSchedules()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCRON\tTIMEZONE\tJOB\tENABLED")
+		for _, s := range schedules {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", s.ID, s.Cron, s.Timezone, s.Job, s.Enabled)
+		}
+		return w.Flush()
+	case "get":
+		s, err := c.GetSchedule(args[0])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case "set":
+		if err := c.SaveSchedule(schedule); err != nil {
+			return err
+		}
+		fmt.Printf("Schedule %s saved\n", schedule.ID)
+	case "delete":
+		if err := c.DeleteSchedule(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Schedule %s deleted\n", args[0])
+	}
+	return nil
+}
+
+// parseSchedule builds a schedule from the flags of "schedules set"
+func parseSchedule(args []string) (*scheduler.Schedule, error) {
+	fs := flag.NewFlagSet("schedules set", flag.ExitOnError)
+	id := fs.String("id", "", "schedule ID (required)")
+	cron := fs.String("cron", "", "5-field cron expression (required)")
+	jobName := fs.String("job", "", "job name (required)")
+	payload := fs.String("payload", "", "job payload (JSON)")
+	priority := fs.String("priority", string(job.PriorityNormal), "job priority: high, normal or low")
+	timezone := fs.String("timezone", "UTC", "IANA timezone the cron expression is evaluated in")
+	description := fs.String("description", "", "description of the enqueued jobs")
+	disabled := fs.Bool("disabled", false, "save the schedule without running it")
+	misfire := fs.String("misfire", "", "misfire policy: fire_once, skip or fire_all")
+	maxCatchUp := fs.Int("max-catch-up", 0, "most missed runs fire_all enqueues")
+	window := fs.Duration("catch-up-window", 0, "how far back missed runs are caught up (0: no limit)")
+	startAt := fs.String("start-at", "", "first run at or after this time (RFC3339)")
+	fs.Parse(args)
+
+	if *payload != "" && !json.Valid([]byte(*payload)) {
+		return nil, fmt.Errorf("payload must be valid JSON")
+	}
+
+	schedule := &scheduler.Schedule{
+		ID:            *id,
+		Cron:          *cron,
+		Job:           *jobName,
+		Priority:      job.JobPriority(*priority),
+		Timezone:      *timezone,
+		Enabled:       !*disabled,
+		Description:   *description,
+		Misfire:       scheduler.MisfirePolicy(*misfire),
+		MaxCatchUp:    *maxCatchUp,
+		CatchUpWindow: *window,
+	}
+	if *payload != "" {
+		schedule.Payload = []byte(*payload)
+	}
+	if *startAt != "" {
+		t, err := time.Parse(time.RFC3339, *startAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time: %w", err)
+		}
+		schedule.StartAt = t
+	}
+	return schedule, nil
+}
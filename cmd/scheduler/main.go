@@ -13,26 +13,25 @@ import (
 	"github.com/muaviaUsmani/bananas/internal/config"
 	"github.com/muaviaUsmani/bananas/internal/logger"
 	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
 	"github.com/muaviaUsmani/bananas/internal/scheduler"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
 	"github.com/redis/go-redis/v9"
 )
 
 // createRedisClient creates a Redis client from the Redis URL
-func createRedisClient(redisURL string) (*redis.Client, error) {
-	opts, err := redis.ParseURL(redisURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
-	}
-	return redis.NewClient(opts), nil
+func createRedisClient(redisURL string) (redis.UniversalClient, error) {
+	return redisconn.NewClient(redisURL, nil)
 }
 
 // connectWithRetry attempts to connect to Redis with exponential backoff
-func connectWithRetry(redisURL string, maxRetries int, log logger.Logger) (*queue.RedisQueue, error) {
-	var redisQueue *queue.RedisQueue
+func connectWithRetry(redisURL, backend string, maxRetries int, log logger.Logger) (queue.RedisBroker, error) {
+	var redisQueue queue.RedisBroker
 	var err error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		redisQueue, err = queue.NewRedisQueue(redisURL)
+		redisQueue, err = queue.NewRedisBroker(redisURL, backend)
 		if err == nil {
 			return redisQueue, nil
 		}
@@ -79,6 +78,7 @@ func main() {
 
 	schedulerLog.Info("Scheduler starting",
 		"redis_url", cfg.RedisURL,
+		"namespace", cfg.Namespace,
 		"max_retries", cfg.MaxRetries)
 
 	// Start pprof server on separate port for profiling
@@ -94,14 +94,53 @@ func main() {
 	}()
 
 	// Connect to Redis queue with retry logic
-	redisQueue, err := connectWithRetry(cfg.RedisURL, 5, schedulerLog)
+	redisQueue, err := connectWithRetry(cfg.RedisURL, cfg.QueueBackend, 5, schedulerLog)
 	if err != nil {
 		schedulerLog.Error("Failed to connect to Redis", "error", err)
 		os.Exit(1)
 	}
 	defer redisQueue.Close()
 
-	schedulerLog.Info("Successfully connected to Redis")
+	schedulerLog.Info("Successfully connected to Redis", "queue_backend", cfg.QueueBackend)
+
+	// Keep every key in this deployment's namespace; cron enqueues count against its quota
+	if err := redisQueue.SetNamespace(cfg.Namespace); err != nil {
+		schedulerLog.Error("Invalid namespace", "error", err)
+		os.Exit(1)
+	}
+	redisQueue.SetQuota(queue.Quota{
+		MaxQueuedJobs: int64(cfg.QuotaMaxQueuedJobs),
+		EnqueueRate:   int64(cfg.QuotaEnqueueRate),
+		RateWindow:    cfg.QuotaRateWindow,
+	})
+
+	// Payloads of cron-scheduled jobs are encrypted like any other enqueued job
+	if cfg.EncryptionEnabled {
+		keys, err := serialization.NewKeyProvider(cfg.EncryptionKeyProvider, cfg.EncryptionKeysFile)
+		if err != nil {
+			schedulerLog.Error("Failed to load encryption keys", "error", err)
+			os.Exit(1)
+		}
+		redisQueue.SetEncryptor(serialization.NewEncryptor(keys))
+		schedulerLog.Info("Payload encryption enabled", "key_provider", cfg.EncryptionKeyProvider)
+	}
+
+	// Offloaded payloads of expired jobs are cleaned up by the scheduler
+	blobStore, err := storage.NewBlobStore(cfg.BlobStore)
+	if err != nil {
+		schedulerLog.Error("Failed to create blob store", "error", err)
+		os.Exit(1)
+	}
+	if blobStore != nil {
+		redisQueue.SetBlobStore(blobStore, cfg.BlobOffloadThreshold)
+		schedulerLog.Info("Blob cleanup enabled", "blob_store", cfg.BlobStore.Type)
+	}
+
+	// Enqueues of cron jobs and retries that dead-letter are published like any other transition
+	if cfg.EventStreamEnabled {
+		redisQueue.EnableEventStream(int64(cfg.EventStreamMaxLen))
+		schedulerLog.Info("Event stream enabled", "max_len", cfg.EventStreamMaxLen)
+	}
 
 	// Create Redis client for cron scheduler
 	redisClient, err := createRedisClient(cfg.RedisURL)
@@ -132,7 +171,26 @@ func main() {
 		// 	Description: "Generate daily report",
 		// })
 
+		// Schedules stored in Redis (via the API, CLI or client) are loaded and
+		// reloaded whenever they change
+		store := scheduler.NewStore(redisClient)
+		if err := store.SetNamespace(cfg.Namespace); err != nil {
+			schedulerLog.Error("Invalid namespace", "error", err)
+			os.Exit(1)
+		}
+		loaded, err := registry.Load(ctx, store)
+		if err != nil {
+			// Watch retries the load
+			schedulerLog.Error("Failed to load stored schedules", "error", err)
+			loaded = -1
+		}
+		go registry.Watch(ctx, store, cfg.CronSchedulerInterval, loaded)
+
 		cronScheduler = scheduler.NewCronScheduler(registry, redisQueue, redisClient, cfg.CronSchedulerInterval)
+		if err := cronScheduler.SetNamespace(cfg.Namespace); err != nil {
+			schedulerLog.Error("Invalid namespace", "error", err)
+			os.Exit(1)
+		}
 		schedulerLog.Info("Cron scheduler initialized",
 			"interval", cfg.CronSchedulerInterval,
 			"schedules", registry.Count())
@@ -166,6 +224,20 @@ func main() {
 					schedulerLog.Info("Moved scheduled jobs to ready queues", "count", count)
 				}
 
+				// Retry jobs whose worker stopped sending heartbeats
+				reaped, err := redisQueue.ReapExpiredLeases(ctx)
+				if err != nil {
+					schedulerLog.Error("Error reaping expired leases", "error", err)
+				}
+				if reaped > 0 {
+					schedulerLog.Warn("Reaped jobs with expired leases", "count", reaped)
+				}
+
+				// Delete offloaded payloads whose jobs have expired
+				if _, err := redisQueue.CleanupExpiredBlobs(ctx); err != nil {
+					schedulerLog.Error("Error cleaning up expired blobs", "error", err)
+				}
+
 			case <-ctx.Done():
 				schedulerLog.Info("Scheduler stopping")
 				return
@@ -11,12 +11,18 @@ import (
 	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/config"
+	"github.com/muaviaUsmani/bananas/internal/fleet"
+	"github.com/muaviaUsmani/bananas/internal/job"
 	"github.com/muaviaUsmani/bananas/internal/logger"
 	"github.com/muaviaUsmani/bananas/internal/metrics"
+	"github.com/muaviaUsmani/bananas/internal/progress"
 	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
 	"github.com/muaviaUsmani/bananas/internal/result"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	"github.com/muaviaUsmani/bananas/internal/webhook"
 	"github.com/muaviaUsmani/bananas/internal/worker"
-	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -54,6 +60,7 @@ func main() {
 		"priorities", len(workerCfg.Priorities),
 		"job_types", len(workerCfg.JobTypes),
 		"job_timeout", cfg.JobTimeout,
+		"namespace", cfg.Namespace,
 		"redis_url", cfg.RedisURL)
 
 	// Log detailed worker configuration
@@ -71,24 +78,82 @@ func main() {
 		}
 	}()
 
-	// Connect to Redis queue
-	redisQueue, err := queue.NewRedisQueue(cfg.RedisURL)
+	// Connect to Redis queue (list or streams, see QUEUE_BACKEND)
+	redisQueue, err := queue.NewRedisBroker(cfg.RedisURL, cfg.QueueBackend)
 	if err != nil {
 		workerLog.Error("Failed to connect to Redis", "error", err)
 		os.Exit(1)
 	}
 	defer redisQueue.Close()
+	workerLog.Info("Queue backend selected", "backend", cfg.QueueBackend)
+
+	// Routing keys need a queue with routed queues; the streams backend has none
+	if _, routed := redisQueue.(worker.RoutedQueueReader); !routed && len(workerCfg.RoutingKeys) > 0 {
+		workerLog.Error("Queue backend does not support routing keys", "backend", cfg.QueueBackend)
+		os.Exit(1)
+	}
+
+	// Keep every key in this deployment's namespace
+	if err := redisQueue.SetNamespace(cfg.Namespace); err != nil {
+		workerLog.Error("Invalid namespace", "error", err)
+		os.Exit(1)
+	}
+
+	// Running jobs are leased to this worker and kept alive with heartbeats;
+	// the scheduler retries jobs whose lease expires (e.g. after a crash)
+	redisQueue.SetLeaseDuration(cfg.JobLeaseDuration)
+
+	// Enable payload encryption at rest if configured
+	var encryptor *serialization.Encryptor
+	if cfg.EncryptionEnabled {
+		keys, err := serialization.NewKeyProvider(cfg.EncryptionKeyProvider, cfg.EncryptionKeysFile)
+		if err != nil {
+			workerLog.Error("Failed to load encryption keys", "error", err)
+			os.Exit(1)
+		}
+		encryptor = serialization.NewEncryptor(keys)
+		redisQueue.SetEncryptor(encryptor)
+		job.DefaultEncryptor = encryptor
+		workerLog.Info("Payload encryption enabled", "key_provider", cfg.EncryptionKeyProvider)
+	}
+
+	// Enable payload offloading if a blob store is configured
+	blobStore, err := storage.NewBlobStore(cfg.BlobStore)
+	if err != nil {
+		workerLog.Error("Failed to create blob store", "error", err)
+		os.Exit(1)
+	}
+	if blobStore != nil {
+		redisQueue.SetBlobStore(blobStore, cfg.BlobOffloadThreshold)
+		job.DefaultBlobStore = blobStore
+		workerLog.Info("Payload offloading enabled",
+			"blob_store", cfg.BlobStore.Type,
+			"threshold_bytes", cfg.BlobOffloadThreshold)
+	}
+
+	// Publish job state transitions to the Redis event stream if enabled
+	if cfg.EventStreamEnabled {
+		redisQueue.EnableEventStream(int64(cfg.EventStreamMaxLen))
+		workerLog.Info("Event stream enabled", "max_len", cfg.EventStreamMaxLen)
+	}
 
 	// Create result backend if enabled
 	var resultBackend result.Backend
 	if cfg.ResultBackendEnabled {
-		opts, err := redis.ParseURL(cfg.RedisURL)
+		redisClient, err := redisconn.NewClient(cfg.RedisURL, nil)
 		if err != nil {
 			workerLog.Error("Failed to parse Redis URL for result backend", "error", err)
 			os.Exit(1)
 		}
-		redisClient := redis.NewClient(opts)
-		resultBackend = result.NewRedisBackend(redisClient, cfg.ResultBackendTTLSuccess, cfg.ResultBackendTTLFailure)
+		redisBackend := result.NewRedisBackend(redisClient, cfg.ResultBackendTTLSuccess, cfg.ResultBackendTTLFailure)
+		if err := redisBackend.SetNamespace(cfg.Namespace); err != nil {
+			workerLog.Error("Invalid namespace", "error", err)
+			os.Exit(1)
+		}
+		if encryptor != nil {
+			redisBackend.SetEncryptor(encryptor)
+		}
+		resultBackend = redisBackend
 		workerLog.Info("Result backend enabled",
 			"success_ttl", cfg.ResultBackendTTLSuccess,
 			"failure_ttl", cfg.ResultBackendTTLFailure)
@@ -99,10 +164,13 @@ func main() {
 
 	// TODO: Replace example handlers with your actual job handlers
 	// Register example handlers for demonstration
-	registry.Register("count_items", worker.HandleCountItems)
+	worker.RegisterTyped(registry, "count_items", worker.CountItems)
 	registry.Register("send_email", worker.HandleSendEmail)
 	registry.Register("process_data", worker.HandleProcessData)
 
+	// Built-in handler for "webhook" jobs (tasks.WebhookTask payloads)
+	worker.RegisterWebhookHandler(registry, worker.WebhookHandlerOptions{})
+
 	workerLog.Info("Registered job handlers", "count", registry.Count())
 
 	// Create executor with queue integration
@@ -113,8 +181,84 @@ func main() {
 		executor.SetResultBackend(resultBackend)
 	}
 
+	// Start events go to the same hooks and event stream as the queue's transitions
+	executor.SetHooks(redisQueue.Hooks())
+
+	// Let handlers report progress (worker.ReportProgress)
+	progressClient, err := redisconn.NewClient(cfg.RedisURL, nil)
+	if err != nil {
+		workerLog.Error("Failed to parse Redis URL for progress reporting", "error", err)
+		os.Exit(1)
+	}
+	progressStore := progress.NewRedisStore(progressClient, cfg.ProgressTTL)
+	if err := progressStore.SetNamespace(cfg.Namespace); err != nil {
+		workerLog.Error("Invalid namespace", "error", err)
+		os.Exit(1)
+	}
+	executor.SetProgressReporter(progressStore)
+
+	// Deliver results to job callback URLs if enabled
+	var dispatcher *webhook.Dispatcher
+	if cfg.WebhooksEnabled {
+		webhookClient, err := redisconn.NewClient(cfg.RedisURL, nil)
+		if err != nil {
+			workerLog.Error("Failed to parse Redis URL for webhooks", "error", err)
+			os.Exit(1)
+		}
+		dispatcher = webhook.NewDispatcher(webhookClient, webhook.Options{
+			MaxAttempts: cfg.WebhookMaxAttempts,
+			Timeout:     cfg.WebhookTimeout,
+			Namespace:   cfg.Namespace,
+			Encryptor:   encryptor,
+		})
+		executor.SetWebhookNotifier(dispatcher)
+		workerLog.Info("Webhooks enabled",
+			"max_attempts", cfg.WebhookMaxAttempts,
+			"timeout", cfg.WebhookTimeout)
+	}
+
 	// Create worker pool with new configuration system
 	pool := worker.NewPoolWithConfig(executor, redisQueue, workerCfg, cfg.JobTimeout)
+	pool.SetShutdownGracePeriod(cfg.ShutdownGracePeriod)
+
+	// Report this worker's state to the worker registry (bananas:workers)
+	registryClient, err := redisconn.NewClient(cfg.RedisURL, nil)
+	if err != nil {
+		workerLog.Error("Failed to parse Redis URL for worker registry", "error", err)
+		os.Exit(1)
+	}
+	hostname, _ := os.Hostname()
+	workerRegistry := fleet.NewRegistry(registryClient, time.Minute)
+	if err := workerRegistry.SetNamespace(cfg.Namespace); err != nil {
+		workerLog.Error("Invalid namespace", "error", err)
+		os.Exit(1)
+	}
+	var replicaGroup string
+	if workerCfg.Autoscale != nil {
+		replicaGroup = workerCfg.Autoscale.ReplicaGroup
+	}
+	member := workerRegistry.Member(fleet.Worker{
+		ID:          fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		Hostname:    hostname,
+		PID:         os.Getpid(),
+		Mode:        string(workerCfg.Mode),
+		Group:       replicaGroup,
+		Concurrency: workerCfg.Concurrency,
+	})
+	pool.SetStateReporter(member)
+
+	// Adapt concurrency to load, and recommend a replica count to the orchestrator
+	var autoscaler *worker.Autoscaler
+	if workerCfg.Autoscale != nil {
+		autoscaler = worker.NewAutoscaler(pool, workerCfg.Autoscale)
+		if replicaGroup != "" {
+			autoscaler.SetReplicaPublisher(workerRegistry.ReplicaGroup(replicaGroup))
+		}
+		workerLog.Info("Autoscaling enabled",
+			"min_concurrency", workerCfg.Autoscale.MinConcurrency,
+			"max_concurrency", workerCfg.Autoscale.MaxConcurrency,
+			"replica_group", replicaGroup)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -127,6 +271,15 @@ func main() {
 	// Start worker pool
 	pool.Start(ctx)
 
+	// Start webhook delivery
+	if dispatcher != nil {
+		go dispatcher.Run(ctx)
+	}
+
+	if autoscaler != nil {
+		go autoscaler.Run(ctx)
+	}
+
 	// Start periodic metrics logging
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
@@ -143,6 +296,8 @@ func main() {
 					"jobs_completed", m.TotalJobsCompleted,
 					"jobs_failed", m.TotalJobsFailed,
 					"avg_duration_ms", m.AvgJobDuration.Milliseconds(),
+					"avg_wait_ms", m.AvgWaitTime.Milliseconds(),
+					"workers", pool.Concurrency(),
 					"worker_utilization", fmt.Sprintf("%.1f%%", m.WorkerUtilization),
 					"error_rate", fmt.Sprintf("%.2f%%", m.ErrorRate),
 					"uptime", m.Uptime.String(),
@@ -155,12 +310,13 @@ func main() {
 	sig := <-sigChan
 	workerLog.Info("Received shutdown signal, initiating graceful shutdown", "signal", sig)
 
-	// Cancel context to stop workers
-	cancel()
-
-	// Stop the pool (waits for all workers to finish)
+	// Stop dequeuing and let running jobs finish; jobs still running when the
+	// grace period ends are requeued without counting an attempt
 	pool.Stop()
 
+	// Cancel context to stop background tasks
+	cancel()
+
 	workerLog.Info("Worker shut down successfully")
 }
 
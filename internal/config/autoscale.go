@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AutoscaleConfig holds the settings for adaptive worker concurrency (see worker.Autoscaler)
+type AutoscaleConfig struct {
+	// MinConcurrency and MaxConcurrency bound the number of worker goroutines
+	// WorkerConfig.Concurrency is the starting point, clamped into this range
+	MinConcurrency int
+	MaxConcurrency int
+
+	// Interval is how often the autoscaler evaluates its signals
+	// Default: 10 seconds
+	Interval time.Duration
+
+	// ScaleUpCooldown and ScaleDownCooldown are the minimum time between a scaling
+	// step and the next scale-up or scale-down, so bursts don't cause flapping
+	// Defaults: 30 seconds and 2 minutes
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+
+	// TargetUtilization is the busy-worker percentage (0-100) above which the pool grows
+	// The pool shrinks once utilization drops below half of it with an empty queue
+	// Default: 75
+	TargetUtilization float64
+
+	// TargetWait is the average queue wait above which the pool grows (0 disables)
+	// Default: 5 seconds
+	TargetWait time.Duration
+
+	// BacklogPerWorker is the queue depth per worker goroutine above which the pool grows
+	// Default: 10
+	BacklogPerWorker int
+
+	// MaxCPUPercent and MaxMemoryBytes are the process resource ceiling: above
+	// either, the pool stops growing and sheds workers (0 disables)
+	// Defaults: 85% CPU, no memory ceiling
+	MaxCPUPercent  float64
+	MaxMemoryBytes uint64
+
+	// ReplicaGroup names the worker deployment this process belongs to; the
+	// desired replica count is published to bananas:autoscale:<group>:desired_replicas
+	// Empty disables publishing
+	ReplicaGroup string
+}
+
+// DefaultAutoscaleConfig returns autoscaling settings with the defaults filled in
+func DefaultAutoscaleConfig(minConcurrency, maxConcurrency int) *AutoscaleConfig {
+	return &AutoscaleConfig{
+		MinConcurrency:    minConcurrency,
+		MaxConcurrency:    maxConcurrency,
+		Interval:          10 * time.Second,
+		ScaleUpCooldown:   30 * time.Second,
+		ScaleDownCooldown: 2 * time.Minute,
+		TargetUtilization: 75,
+		TargetWait:        5 * time.Second,
+		BacklogPerWorker:  10,
+		MaxCPUPercent:     85,
+	}
+}
+
+// loadAutoscaleConfig loads autoscaling settings from environment variables
+// Returns nil unless WORKER_AUTOSCALE is enabled.
+func loadAutoscaleConfig(concurrency int) *AutoscaleConfig {
+	if !getEnvAsBool("WORKER_AUTOSCALE", false) {
+		return nil
+	}
+
+	defaults := DefaultAutoscaleConfig(1, concurrency)
+	return &AutoscaleConfig{
+		MinConcurrency:    getEnvAsInt("WORKER_MIN_CONCURRENCY", defaults.MinConcurrency),
+		MaxConcurrency:    getEnvAsInt("WORKER_MAX_CONCURRENCY", defaults.MaxConcurrency),
+		Interval:          getEnvAsDuration("AUTOSCALE_INTERVAL", defaults.Interval),
+		ScaleUpCooldown:   getEnvAsDuration("AUTOSCALE_SCALE_UP_COOLDOWN", defaults.ScaleUpCooldown),
+		ScaleDownCooldown: getEnvAsDuration("AUTOSCALE_SCALE_DOWN_COOLDOWN", defaults.ScaleDownCooldown),
+		TargetUtilization: getEnvAsFloat("AUTOSCALE_TARGET_UTILIZATION", defaults.TargetUtilization),
+		TargetWait:        getEnvAsDuration("AUTOSCALE_TARGET_WAIT", defaults.TargetWait),
+		BacklogPerWorker:  getEnvAsInt("AUTOSCALE_BACKLOG_PER_WORKER", defaults.BacklogPerWorker),
+		MaxCPUPercent:     getEnvAsFloat("AUTOSCALE_MAX_CPU_PERCENT", defaults.MaxCPUPercent),
+		MaxMemoryBytes:    uint64(getEnvAsInt("AUTOSCALE_MAX_MEMORY_MB", 0)) << 20,
+		ReplicaGroup:      getEnv("AUTOSCALE_REPLICA_GROUP", ""),
+	}
+}
+
+// Validate checks if the autoscaling configuration is valid
+func (c *AutoscaleConfig) Validate() error {
+	if c.MinConcurrency < 1 {
+		return fmt.Errorf("autoscale minimum concurrency must be at least 1 (got %d)", c.MinConcurrency)
+	}
+	if c.MaxConcurrency < c.MinConcurrency {
+		return fmt.Errorf("autoscale maximum concurrency (%d) must be at least the minimum (%d)", c.MaxConcurrency, c.MinConcurrency)
+	}
+	if c.MaxConcurrency > 1000 {
+		return fmt.Errorf("autoscale maximum concurrency too high: %d (maximum 1000)", c.MaxConcurrency)
+	}
+	if c.Interval < time.Second {
+		return fmt.Errorf("autoscale interval too short: %v (minimum 1s)", c.Interval)
+	}
+	if c.ScaleUpCooldown < 0 || c.ScaleDownCooldown < 0 {
+		return fmt.Errorf("autoscale cooldowns cannot be negative")
+	}
+	if c.TargetUtilization <= 0 || c.TargetUtilization > 100 {
+		return fmt.Errorf("autoscale target utilization must be between 0 and 100 (got %v)", c.TargetUtilization)
+	}
+	if c.BacklogPerWorker < 1 {
+		return fmt.Errorf("autoscale backlog per worker must be at least 1 (got %d)", c.BacklogPerWorker)
+	}
+	if c.MaxCPUPercent < 0 || c.MaxCPUPercent > 100 {
+		return fmt.Errorf("autoscale CPU ceiling must be between 0 and 100 (got %v)", c.MaxCPUPercent)
+	}
+	return nil
+}
+
+// Clamp returns n limited to the autoscaling concurrency range
+func (c *AutoscaleConfig) Clamp(n int) int {
+	if n < c.MinConcurrency {
+		return c.MinConcurrency
+	}
+	if n > c.MaxConcurrency {
+		return c.MaxConcurrency
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLoadWorkerConfig_AutoscaleDisabledByDefault(t *testing.T) {
+	os.Clearenv()
+
+	cfg, err := LoadWorkerConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Autoscale != nil {
+		t.Errorf("Expected autoscaling to be disabled, got %+v", cfg.Autoscale)
+	}
+}
+
+func TestLoadWorkerConfig_Autoscale(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("WORKER_CONCURRENCY", "50")
+	os.Setenv("WORKER_AUTOSCALE", "true")
+	os.Setenv("WORKER_MIN_CONCURRENCY", "2")
+	os.Setenv("WORKER_MAX_CONCURRENCY", "20")
+	os.Setenv("AUTOSCALE_SCALE_DOWN_COOLDOWN", "5m")
+	os.Setenv("AUTOSCALE_MAX_MEMORY_MB", "512")
+	os.Setenv("AUTOSCALE_REPLICA_GROUP", "emails")
+
+	cfg, err := LoadWorkerConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	a := cfg.Autoscale
+	if a == nil {
+		t.Fatal("Expected autoscaling to be enabled")
+	}
+	if a.MinConcurrency != 2 || a.MaxConcurrency != 20 {
+		t.Errorf("Expected range 2-20, got %d-%d", a.MinConcurrency, a.MaxConcurrency)
+	}
+	if cfg.Concurrency != 20 {
+		t.Errorf("Expected starting concurrency clamped to 20, got %d", cfg.Concurrency)
+	}
+	if a.ScaleDownCooldown != 5*time.Minute {
+		t.Errorf("Expected scale-down cooldown 5m, got %v", a.ScaleDownCooldown)
+	}
+	if a.ScaleUpCooldown != 30*time.Second {
+		t.Errorf("Expected default scale-up cooldown 30s, got %v", a.ScaleUpCooldown)
+	}
+	if a.MaxMemoryBytes != 512<<20 {
+		t.Errorf("Expected memory ceiling 512MB, got %d", a.MaxMemoryBytes)
+	}
+	if a.ReplicaGroup != "emails" {
+		t.Errorf("Expected replica group emails, got %q", a.ReplicaGroup)
+	}
+}
+
+func TestAutoscaleConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *AutoscaleConfig)
+	}{
+		{"zero minimum", func(c *AutoscaleConfig) { c.MinConcurrency = 0 }},
+		{"maximum below minimum", func(c *AutoscaleConfig) { c.MaxConcurrency = 1 }},
+		{"interval too short", func(c *AutoscaleConfig) { c.Interval = 100 * time.Millisecond }},
+		{"negative cooldown", func(c *AutoscaleConfig) { c.ScaleUpCooldown = -time.Second }},
+		{"utilization out of range", func(c *AutoscaleConfig) { c.TargetUtilization = 150 }},
+		{"zero backlog per worker", func(c *AutoscaleConfig) { c.BacklogPerWorker = 0 }},
+		{"CPU ceiling out of range", func(c *AutoscaleConfig) { c.MaxCPUPercent = -1 }},
+	}
+
+	if err := DefaultAutoscaleConfig(2, 10).Validate(); err != nil {
+		t.Fatalf("Expected defaults to be valid, got %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultAutoscaleConfig(2, 10)
+			tt.modify(c)
+			if err := c.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestAutoscaleConfig_Clamp(t *testing.T) {
+	c := DefaultAutoscaleConfig(2, 10)
+
+	if got := c.Clamp(1); got != 2 {
+		t.Errorf("Expected 2, got %d", got)
+	}
+	if got := c.Clamp(5); got != 5 {
+		t.Errorf("Expected 5, got %d", got)
+	}
+	if got := c.Clamp(50); got != 10 {
+		t.Errorf("Expected 10, got %d", got)
+	}
+}
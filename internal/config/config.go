@@ -8,12 +8,24 @@ import (
 	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/logger"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/storage"
 )
 
 // Config holds all configuration for the Bananas application
 type Config struct {
 	// RedisURL is the connection URL for Redis
 	RedisURL string
+	// QueueBackend selects the Redis queue implementation: "list" (default) or "streams"
+	QueueBackend string
+	// Namespace prefixes every Redis key (<namespace>:*) so several products can share one Redis
+	Namespace string
+	// QuotaMaxQueuedJobs caps the namespace's unfinished jobs, enforced at enqueue (0 disables)
+	QuotaMaxQueuedJobs int
+	// QuotaEnqueueRate caps how many jobs the namespace can enqueue per QuotaRateWindow (0 disables)
+	QuotaEnqueueRate int
+	// QuotaRateWindow is the window QuotaEnqueueRate is counted over
+	QuotaRateWindow time.Duration
 	// APIPort is the port the API server listens on
 	APIPort string
 	// WorkerConcurrency is the number of concurrent jobs a worker can process
@@ -32,6 +44,32 @@ type Config struct {
 	ResultBackendTTLSuccess time.Duration
 	// ResultBackendTTLFailure is the TTL for failed job results
 	ResultBackendTTLFailure time.Duration
+	// BlobStore configures where large payloads are offloaded (disabled when Type is empty)
+	BlobStore storage.Options
+	// BlobOffloadThreshold is the payload size in bytes above which payloads are offloaded
+	BlobOffloadThreshold int
+	// EncryptionEnabled enables AES-GCM encryption of payloads and results at rest
+	EncryptionEnabled bool
+	// EncryptionKeyProvider selects where encryption keys come from (env or file)
+	EncryptionKeyProvider string
+	// EncryptionKeysFile is the JSON key file used by the file key provider
+	EncryptionKeysFile string
+	// EventStreamEnabled appends job state transitions to the <prefix>events Redis stream
+	EventStreamEnabled bool
+	// EventStreamMaxLen caps the event stream length (approximate trimming)
+	EventStreamMaxLen int
+	// WebhooksEnabled delivers results of jobs with a callback URL from this worker
+	WebhooksEnabled bool
+	// WebhookMaxAttempts is how many times a webhook is tried before it is dead-lettered
+	WebhookMaxAttempts int
+	// WebhookTimeout is the per-request timeout for webhook deliveries
+	WebhookTimeout time.Duration
+	// ProgressTTL is how long a job's latest progress update is kept
+	ProgressTTL time.Duration
+	// JobLeaseDuration is how long a running job stays leased without a heartbeat before it is reaped
+	JobLeaseDuration time.Duration
+	// ShutdownGracePeriod is how long a stopping worker lets running jobs finish before requeueing them
+	ShutdownGracePeriod time.Duration
 	// Logging configuration
 	Logging *logger.Config
 }
@@ -40,6 +78,11 @@ type Config struct {
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
 		RedisURL:                getEnv("REDIS_URL", "redis://localhost:6379"),
+		QueueBackend:            getEnv("QUEUE_BACKEND", "list"),
+		Namespace:               getEnv("BANANAS_NAMESPACE", namespace.Default),
+		QuotaMaxQueuedJobs:      getEnvAsInt("QUOTA_MAX_QUEUED_JOBS", 0),
+		QuotaEnqueueRate:        getEnvAsInt("QUOTA_ENQUEUE_RATE", 0),
+		QuotaRateWindow:         getEnvAsDuration("QUOTA_RATE_WINDOW", 1*time.Second),
 		APIPort:                 getEnv("API_PORT", "8080"),
 		WorkerConcurrency:       getEnvAsInt("WORKER_CONCURRENCY", 5),
 		JobTimeout:              getEnvAsDuration("JOB_TIMEOUT", 5*time.Minute),
@@ -49,6 +92,19 @@ func LoadConfig() (*Config, error) {
 		ResultBackendEnabled:    getEnvAsBool("RESULT_BACKEND_ENABLED", true),
 		ResultBackendTTLSuccess: getEnvAsDuration("RESULT_BACKEND_TTL_SUCCESS", 1*time.Hour),
 		ResultBackendTTLFailure: getEnvAsDuration("RESULT_BACKEND_TTL_FAILURE", 24*time.Hour),
+		BlobStore:               loadBlobStoreOptions(),
+		BlobOffloadThreshold:    getEnvAsInt("BLOB_OFFLOAD_THRESHOLD", 1<<20),
+		EncryptionEnabled:       getEnvAsBool("ENCRYPTION_ENABLED", false),
+		EncryptionKeyProvider:   getEnv("ENCRYPTION_KEY_PROVIDER", "env"),
+		EncryptionKeysFile:      getEnv("ENCRYPTION_KEYS_FILE", ""),
+		EventStreamEnabled:      getEnvAsBool("EVENT_STREAM_ENABLED", false),
+		EventStreamMaxLen:       getEnvAsInt("EVENT_STREAM_MAX_LEN", 100000),
+		WebhooksEnabled:         getEnvAsBool("WEBHOOKS_ENABLED", false),
+		WebhookMaxAttempts:      getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeout:          getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		ProgressTTL:             getEnvAsDuration("PROGRESS_TTL", 24*time.Hour),
+		JobLeaseDuration:        getEnvAsDuration("JOB_LEASE_DURATION", 1*time.Minute),
+		ShutdownGracePeriod:     getEnvAsDuration("SHUTDOWN_GRACE_PERIOD", 30*time.Second),
 		Logging:                 loadLoggingConfig(),
 	}
 
@@ -56,6 +112,12 @@ func LoadConfig() (*Config, error) {
 	if cfg.RedisURL == "" {
 		return nil, fmt.Errorf("REDIS_URL cannot be empty")
 	}
+	if cfg.QueueBackend != "list" && cfg.QueueBackend != "streams" {
+		return nil, fmt.Errorf("QUEUE_BACKEND must be list or streams, got %q", cfg.QueueBackend)
+	}
+	if err := namespace.Validate(cfg.Namespace); err != nil {
+		return nil, fmt.Errorf("invalid BANANAS_NAMESPACE: %w", err)
+	}
 	if cfg.APIPort == "" {
 		return nil, fmt.Errorf("API_PORT cannot be empty")
 	}
@@ -65,6 +127,15 @@ func LoadConfig() (*Config, error) {
 	if cfg.MaxRetries < 0 {
 		return nil, fmt.Errorf("MAX_RETRIES cannot be negative")
 	}
+	if cfg.QuotaMaxQueuedJobs < 0 || cfg.QuotaEnqueueRate < 0 {
+		return nil, fmt.Errorf("QUOTA_MAX_QUEUED_JOBS and QUOTA_ENQUEUE_RATE cannot be negative")
+	}
+	if cfg.QuotaRateWindow <= 0 {
+		return nil, fmt.Errorf("QUOTA_RATE_WINDOW must be positive")
+	}
+	if cfg.BlobOffloadThreshold < 1 {
+		return nil, fmt.Errorf("BLOB_OFFLOAD_THRESHOLD must be at least 1")
+	}
 
 	// Validate logging config
 	if err := cfg.Logging.Validate(); err != nil {
@@ -108,6 +179,19 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return value
 }
 
+// getEnvAsFloat retrieves an environment variable as a float or returns a default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // getEnvAsBool retrieves an environment variable as a boolean or returns a default value
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
@@ -140,6 +224,22 @@ func getEnvAsStringSlice(key string, defaultValue []string) []string {
 	return result
 }
 
+// loadBlobStoreOptions loads payload offloading configuration from environment variables
+func loadBlobStoreOptions() storage.Options {
+	return storage.Options{
+		Type: getEnv("BLOB_STORE", storage.TypeNone),
+		Path: getEnv("BLOB_STORE_PATH", "/var/lib/bananas/blobs"),
+		S3: storage.S3Config{
+			Endpoint:        getEnv("BLOB_S3_ENDPOINT", ""),
+			Bucket:          getEnv("BLOB_S3_BUCKET", ""),
+			Region:          getEnv("BLOB_S3_REGION", "us-east-1"),
+			AccessKeyID:     getEnv("BLOB_S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("BLOB_S3_SECRET_ACCESS_KEY", ""),
+			Prefix:          getEnv("BLOB_S3_PREFIX", ""),
+		},
+	}
+}
+
 // loadLoggingConfig loads logging configuration from environment variables
 func loadLoggingConfig() *logger.Config {
 	cfg := logger.DefaultConfig()
@@ -58,6 +58,12 @@ type WorkerConfig struct {
 	// Example: ["send_email", "generate_report"]
 	JobTypes []string
 
+	// RoutingKeys specifies which routing keys this worker should handle, in order
+	// Empty slice means the default route only (the shared priority queues)
+	// Not applicable in job-specialized mode, which dequeues by job name
+	// Example: ["gpu", "default"]
+	RoutingKeys []string
+
 	// SchedulerInterval is how often to check for scheduled jobs
 	// Default: 1 second
 	SchedulerInterval time.Duration
@@ -65,6 +71,10 @@ type WorkerConfig struct {
 	// EnableScheduler determines whether to run the scheduler loop
 	// True for all modes except when you have a dedicated scheduler-only worker
 	EnableScheduler bool
+
+	// Autoscale enables adaptive concurrency between a minimum and maximum
+	// nil means Concurrency is fixed (WORKER_AUTOSCALE=true to enable)
+	Autoscale *AutoscaleConfig
 }
 
 // LoadWorkerConfig loads worker configuration from environment variables
@@ -74,6 +84,7 @@ func LoadWorkerConfig() (*WorkerConfig, error) {
 		Concurrency:       getEnvAsInt("WORKER_CONCURRENCY", 10),
 		Priorities:        parsePriorities(getEnv("WORKER_PRIORITIES", "")),
 		JobTypes:          parseJobTypes(getEnv("WORKER_JOB_TYPES", "")),
+		RoutingKeys:       getEnvAsStringSlice("WORKER_ROUTING_KEYS", nil),
 		SchedulerInterval: getEnvAsDuration("SCHEDULER_INTERVAL", 1*time.Second),
 		EnableScheduler:   getEnvAsBool("ENABLE_SCHEDULER", true),
 	}
@@ -81,6 +92,14 @@ func LoadWorkerConfig() (*WorkerConfig, error) {
 	// Apply mode-specific defaults
 	cfg.applyModeDefaults()
 
+	// Concurrency is the starting point when autoscaling (not in scheduler-only mode)
+	if cfg.Mode != WorkerModeSchedulerOnly {
+		cfg.Autoscale = loadAutoscaleConfig(cfg.Concurrency)
+		if cfg.Autoscale != nil {
+			cfg.Concurrency = cfg.Autoscale.Clamp(cfg.Concurrency)
+		}
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -139,6 +158,7 @@ func (c *WorkerConfig) applyModeDefaults() {
 		c.Concurrency = 0
 		c.Priorities = nil
 		c.JobTypes = nil
+		c.RoutingKeys = nil
 		c.EnableScheduler = true
 	}
 }
@@ -205,6 +225,28 @@ func (c *WorkerConfig) Validate() error {
 		}
 	}
 
+	// Validate routing keys
+	if len(c.RoutingKeys) > 0 {
+		if c.Mode == WorkerModeJobSpecialized {
+			return fmt.Errorf("job-specialized mode cannot be combined with routing keys")
+		}
+		for _, rk := range c.RoutingKeys {
+			if err := job.ValidateRoutingKey(rk); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate autoscaling
+	if c.Autoscale != nil {
+		if c.Mode == WorkerModeSchedulerOnly {
+			return fmt.Errorf("scheduler-only mode cannot autoscale")
+		}
+		if err := c.Autoscale.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Validate scheduler interval
 	if c.EnableScheduler {
 		if c.SchedulerInterval < 100*time.Millisecond {
@@ -219,7 +261,7 @@ func (c *WorkerConfig) Validate() error {
 }
 
 // ShouldProcessJob checks if this worker should process a given job
-// based on its configuration (priorities, job types)
+// based on its configuration (priorities, job types, routing keys)
 func (c *WorkerConfig) ShouldProcessJob(j *job.Job) bool {
 	// Check priority filter
 	if len(c.Priorities) > 0 {
@@ -249,6 +291,24 @@ func (c *WorkerConfig) ShouldProcessJob(j *job.Job) bool {
 		}
 	}
 
+	// Check routing key filter
+	if len(c.RoutingKeys) > 0 {
+		routingKey := j.RoutingKey
+		if routingKey == "" {
+			routingKey = job.DefaultRoutingKey
+		}
+		routingKeyMatch := false
+		for _, rk := range c.RoutingKeys {
+			if routingKey == rk {
+				routingKeyMatch = true
+				break
+			}
+		}
+		if !routingKeyMatch {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -272,14 +332,24 @@ func (c *WorkerConfig) String() string {
 		}
 	}
 
+	routingKeys := job.DefaultRoutingKey
+	if len(c.RoutingKeys) > 0 {
+		routingKeys = strings.Join(c.RoutingKeys, ",")
+	}
+
+	concurrency := fmt.Sprintf("%d", c.Concurrency)
+	if c.Autoscale != nil {
+		concurrency = fmt.Sprintf("%d (autoscale %d-%d)", c.Concurrency, c.Autoscale.MinConcurrency, c.Autoscale.MaxConcurrency)
+	}
+
 	scheduler := "disabled"
 	if c.EnableScheduler {
 		scheduler = fmt.Sprintf("enabled (interval: %v)", c.SchedulerInterval)
 	}
 
 	return fmt.Sprintf(
-		"WorkerConfig{mode=%s, concurrency=%d, priorities=%s, jobTypes=%s, scheduler=%s}",
-		c.Mode, c.Concurrency, priorities, jobTypes, scheduler,
+		"WorkerConfig{mode=%s, concurrency=%s, priorities=%s, jobTypes=%s, routingKeys=%s, scheduler=%s}",
+		c.Mode, concurrency, priorities, jobTypes, routingKeys, scheduler,
 	)
 }
 
@@ -302,6 +302,66 @@ func TestShouldProcessJob_BothFilters(t *testing.T) {
 	}
 }
 
+func TestShouldProcessJob_RoutingKeyFilter(t *testing.T) {
+	cfg := &WorkerConfig{
+		Mode:        WorkerModeDefault,
+		Concurrency: 10,
+		Priorities:  allPriorities(),
+		RoutingKeys: []string{"gpu", "default"},
+	}
+
+	gpuJob := &job.Job{Priority: job.PriorityNormal, Name: "train", RoutingKey: "gpu"}
+	legacyJob := &job.Job{Priority: job.PriorityNormal, Name: "send_email"} // No routing key
+	emailJob := &job.Job{Priority: job.PriorityNormal, Name: "send_email", RoutingKey: "email"}
+
+	if !cfg.ShouldProcessJob(gpuJob) {
+		t.Error("Expected to process gpu job")
+	}
+	if !cfg.ShouldProcessJob(legacyJob) {
+		t.Error("Expected job without routing key to use the default route")
+	}
+	if cfg.ShouldProcessJob(emailJob) {
+		t.Error("Expected NOT to process email job")
+	}
+}
+
+func TestLoadWorkerConfig_RoutingKeys(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("WORKER_ROUTING_KEYS", "gpu, default")
+
+	cfg, err := LoadWorkerConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.RoutingKeys) != 2 || cfg.RoutingKeys[0] != "gpu" || cfg.RoutingKeys[1] != "default" {
+		t.Errorf("Expected routing keys [gpu default], got %v", cfg.RoutingKeys)
+	}
+}
+
+func TestValidate_RoutingKeys(t *testing.T) {
+	cfg := &WorkerConfig{
+		Mode:        WorkerModeDefault,
+		Concurrency: 10,
+		Priorities:  allPriorities(),
+		RoutingKeys: []string{"gpu:fast"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for invalid routing key")
+	}
+
+	cfg = &WorkerConfig{
+		Mode:        WorkerModeJobSpecialized,
+		Concurrency: 10,
+		Priorities:  allPriorities(),
+		JobTypes:    []string{"send_email"},
+		RoutingKeys: []string{"gpu"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for routing keys in job-specialized mode")
+	}
+}
+
 func TestParsePriorities(t *testing.T) {
 	tests := []struct {
 		input    string
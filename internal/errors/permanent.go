@@ -0,0 +1,35 @@
+package errors
+
+import "errors"
+
+// PermanentError marks a job failure that retrying cannot fix (e.g. a payload that
+// cannot be decoded). Jobs failing with a permanent error go straight to the dead
+// letter queue.
+type PermanentError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (p *PermanentError) Error() string {
+	return p.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (p *PermanentError) Unwrap() error {
+	return p.Err
+}
+
+// Permanent wraps err as a permanent error
+// Returns nil if err is nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent returns true if err or any error it wraps is a permanent error
+func IsPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,42 @@
+package errors
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryAfterError marks a job failure that should be retried no sooner than Delay,
+// e.g. when a remote service responds 429 with a Retry-After header. It replaces the
+// queue's exponential backoff for that attempt; the job's retry limit still applies.
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+// Error implements the error interface
+func (r *RetryAfterError) Error() string {
+	return r.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (r *RetryAfterError) Unwrap() error {
+	return r.Err
+}
+
+// RetryAfter wraps err so the job is retried after delay
+// Returns nil if err is nil
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryAfterError{Err: err, Delay: delay}
+}
+
+// RetryDelay returns the delay requested by a RetryAfterError in err's chain
+func RetryDelay(err error) (time.Duration, bool) {
+	var r *RetryAfterError
+	if errors.As(err, &r) {
+		return r.Delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+// Package events publishes job lifecycle transitions to in-process hooks and
+// external sinks such as a Redis Streams event log.
+package events
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// Type identifies a job lifecycle transition
+type Type string
+
+const (
+	// TypeEnqueue is emitted when a job is added to a queue
+	TypeEnqueue Type = "enqueue"
+	// TypeStart is emitted when a worker starts executing a job
+	TypeStart Type = "start"
+	// TypeSuccess is emitted when a job completes successfully
+	TypeSuccess Type = "success"
+	// TypeFailure is emitted when a job fails and is scheduled for retry
+	TypeFailure Type = "failure"
+	// TypeDead is emitted when a job is moved to the dead letter queue
+	TypeDead Type = "dead"
+	// TypeExpired is emitted when a job is discarded because its deadline passed before it started
+	TypeExpired Type = "expired"
+	// TypeRequeue is emitted when a worker returns a job to its queue without counting an attempt
+	TypeRequeue Type = "requeue"
+	// TypeRelease is emitted when a waiting job's dependencies finished and it moves to its queue
+	TypeRelease Type = "release"
+	// TypeCancel is emitted when a job is cancelled before it ran
+	TypeCancel Type = "cancel"
+)
+
+// Event describes a single job state transition
+type Event struct {
+	Type      Type            `json:"type"`
+	JobID     string          `json:"job_id"`
+	JobName   string          `json:"job_name"`
+	Priority  job.JobPriority `json:"priority"`
+	Status    job.JobStatus   `json:"status"`
+	Attempts  int             `json:"attempts"`
+	Error     string          `json:"error,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// NewEvent creates an event for a job's current state
+func NewEvent(t Type, j *job.Job) Event {
+	return Event{
+		Type:      t,
+		JobID:     j.ID,
+		JobName:   j.Name,
+		Priority:  j.Priority,
+		Status:    j.Status,
+		Attempts:  j.Attempts,
+		Error:     j.Error,
+		Timestamp: time.Now(),
+	}
+}
+
+// Hook is an in-process callback for job lifecycle events
+// Hooks run synchronously on the goroutine performing the transition, so they should be fast
+type Hook func(ctx context.Context, e Event)
+
+// Sink receives every event, e.g. to publish it to an external event log
+type Sink interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Registry dispatches lifecycle events to hooks and sinks
+// A nil *Registry is valid and discards all events.
+type Registry struct {
+	mu    sync.RWMutex
+	hooks map[Type][]Hook
+	sinks []Sink
+}
+
+// NewRegistry creates an empty hook registry
+func NewRegistry() *Registry {
+	return &Registry{
+		hooks: make(map[Type][]Hook),
+	}
+}
+
+// On registers a hook for an event type
+func (r *Registry) On(t Type, hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[t] = append(r.hooks[t], hook)
+}
+
+// OnEnqueue registers a hook called when a job is enqueued
+func (r *Registry) OnEnqueue(hook Hook) { r.On(TypeEnqueue, hook) }
+
+// OnStart registers a hook called when a worker starts a job
+func (r *Registry) OnStart(hook Hook) { r.On(TypeStart, hook) }
+
+// OnSuccess registers a hook called when a job completes
+func (r *Registry) OnSuccess(hook Hook) { r.On(TypeSuccess, hook) }
+
+// OnFailure registers a hook called when a job fails and will be retried
+func (r *Registry) OnFailure(hook Hook) { r.On(TypeFailure, hook) }
+
+// OnDead registers a hook called when a job is moved to the dead letter queue
+func (r *Registry) OnDead(hook Hook) { r.On(TypeDead, hook) }
+
+// OnExpired registers a hook called when a job expires before it starts
+func (r *Registry) OnExpired(hook Hook) { r.On(TypeExpired, hook) }
+
+// OnRequeue registers a hook called when a job is requeued without counting an attempt
+func (r *Registry) OnRequeue(hook Hook) { r.On(TypeRequeue, hook) }
+
+// OnRelease registers a hook called when a waiting job's dependencies finished
+func (r *Registry) OnRelease(hook Hook) { r.On(TypeRelease, hook) }
+
+// OnCancel registers a hook called when a job is cancelled
+func (r *Registry) OnCancel(hook Hook) { r.On(TypeCancel, hook) }
+
+// AddSink registers a sink that receives every event
+func (r *Registry) AddSink(sink Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks = append(r.sinks, sink)
+}
+
+// Emit dispatches an event for a job to the hooks for its type and to all sinks
+// Emitting is best-effort: hook panics and sink errors are logged, never returned,
+// so they can't affect the transition that triggered them.
+func (r *Registry) Emit(ctx context.Context, t Type, j *job.Job) {
+	if r == nil {
+		return
+	}
+
+	r.mu.RLock()
+	hooks := r.hooks[t]
+	sinks := r.sinks
+	r.mu.RUnlock()
+
+	if len(hooks) == 0 && len(sinks) == 0 {
+		return
+	}
+
+	e := NewEvent(t, j)
+	for _, hook := range hooks {
+		runHook(ctx, hook, e)
+	}
+	for _, sink := range sinks {
+		if err := sink.Publish(ctx, e); err != nil {
+			log.Printf("Failed to publish %s event for job %s: %v", e.Type, e.JobID, err)
+		}
+	}
+}
+
+// runHook calls a hook, recovering from panics
+func runHook(ctx context.Context, hook Hook, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Hook for %s event of job %s panicked: %v\n%s", e.Type, e.JobID, r, debug.Stack())
+		}
+	}()
+	hook(ctx, e)
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+type recordingSink struct {
+	events []Event
+	err    error
+}
+
+func (s *recordingSink) Publish(ctx context.Context, e Event) error {
+	s.events = append(s.events, e)
+	return s.err
+}
+
+func TestRegistry_EmitCallsHooksForType(t *testing.T) {
+	r := NewRegistry()
+
+	var dead, success []Event
+	r.OnDead(func(ctx context.Context, e Event) { dead = append(dead, e) })
+	r.OnSuccess(func(ctx context.Context, e Event) { success = append(success, e) })
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityHigh)
+	j.Attempts = 3
+	j.Error = "boom"
+	r.Emit(context.Background(), TypeDead, j)
+
+	if len(success) != 0 {
+		t.Errorf("expected no success hooks called, got %d", len(success))
+	}
+	if len(dead) != 1 {
+		t.Fatalf("expected 1 dead event, got %d", len(dead))
+	}
+
+	e := dead[0]
+	if e.Type != TypeDead || e.JobID != j.ID || e.JobName != "test_job" || e.Priority != job.PriorityHigh {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.Attempts != 3 || e.Error != "boom" {
+		t.Errorf("expected attempts and error to be copied, got %+v", e)
+	}
+	if e.Timestamp.IsZero() {
+		t.Error("expected timestamp to be set")
+	}
+}
+
+func TestRegistry_EmitPublishesToSinks(t *testing.T) {
+	r := NewRegistry()
+	failing := &recordingSink{err: errors.New("unavailable")}
+	ok := &recordingSink{}
+	r.AddSink(failing)
+	r.AddSink(ok)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	r.Emit(context.Background(), TypeEnqueue, j)
+	r.Emit(context.Background(), TypeStart, j)
+
+	// A failing sink must not stop delivery to the others
+	if len(ok.events) != 2 {
+		t.Fatalf("expected 2 events in sink, got %d", len(ok.events))
+	}
+	if ok.events[0].Type != TypeEnqueue || ok.events[1].Type != TypeStart {
+		t.Errorf("unexpected event types: %s, %s", ok.events[0].Type, ok.events[1].Type)
+	}
+}
+
+func TestRegistry_HookPanicIsRecovered(t *testing.T) {
+	r := NewRegistry()
+
+	called := false
+	r.OnStart(func(ctx context.Context, e Event) { panic("hook bug") })
+	r.OnStart(func(ctx context.Context, e Event) { called = true })
+
+	r.Emit(context.Background(), TypeStart, job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+
+	if !called {
+		t.Error("expected hooks after a panicking hook to still run")
+	}
+}
+
+func TestRegistry_NilIsNoOp(t *testing.T) {
+	var r *Registry
+	r.Emit(context.Background(), TypeStart, job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+}
@@ -0,0 +1,198 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultStreamMaxLen caps the event stream length (trimmed approximately)
+const DefaultStreamMaxLen = 100000
+
+// RedisStream is a Sink that appends events to a Redis stream
+//
+// Other services consume the stream with consumer groups (see StreamConsumer or
+// XREADGROUP). Each entry has the fields type, job_id, job_name, priority,
+// status, attempts, error and timestamp (RFC 3339).
+type RedisStream struct {
+	client redis.Cmdable
+	key    string
+	maxLen int64
+}
+
+// NewRedisStream creates a stream sink; maxLen <= 0 disables trimming
+func NewRedisStream(client redis.Cmdable, key string, maxLen int64) *RedisStream {
+	return &RedisStream{
+		client: client,
+		key:    key,
+		maxLen: maxLen,
+	}
+}
+
+// Publish appends the event to the stream
+func (s *RedisStream) Publish(ctx context.Context, e Event) error {
+	args := &redis.XAddArgs{
+		Stream: s.key,
+		Values: map[string]interface{}{
+			"type":      string(e.Type),
+			"job_id":    e.JobID,
+			"job_name":  e.JobName,
+			"priority":  string(e.Priority),
+			"status":    string(e.Status),
+			"attempts":  e.Attempts,
+			"error":     e.Error,
+			"timestamp": e.Timestamp.UTC().Format(time.RFC3339Nano),
+		},
+	}
+	if s.maxLen > 0 {
+		args.MaxLen = s.maxLen
+		args.Approx = true
+	}
+
+	if err := s.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("failed to append event to stream: %w", err)
+	}
+	return nil
+}
+
+// StreamConsumer reads events from a Redis stream as a member of a consumer group
+type StreamConsumer struct {
+	client   redis.Cmdable
+	key      string
+	group    string
+	consumer string
+	// Block is how long each read waits for new events (default 1s)
+	Block time.Duration
+	// Count is the maximum number of events read at once (default 10)
+	Count int64
+}
+
+// NewStreamConsumer creates a consumer; consumer names must be unique within a group
+func NewStreamConsumer(client redis.Cmdable, key, group, consumer string) *StreamConsumer {
+	return &StreamConsumer{
+		client:   client,
+		key:      key,
+		group:    group,
+		consumer: consumer,
+		Block:    time.Second,
+		Count:    10,
+	}
+}
+
+// EnsureGroup creates the consumer group (and the stream) if it doesn't exist
+// A new group starts with events appended after its creation
+func (c *StreamConsumer) EnsureGroup(ctx context.Context) error {
+	err := c.client.XGroupCreateMkStream(ctx, c.key, c.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", c.group, err)
+	}
+	return nil
+}
+
+// Consume calls handler for each event until ctx is cancelled
+//
+// Events are acknowledged when handler returns nil. Events the handler failed on
+// stay pending and are redelivered to this consumer the next time Consume starts.
+func (c *StreamConsumer) Consume(ctx context.Context, handler func(ctx context.Context, e Event) error) error {
+	if err := c.EnsureGroup(ctx); err != nil {
+		return err
+	}
+
+	// Redeliver our pending events first ("0"), then read new ones (">")
+	id := "0"
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.key, id},
+			Count:    c.Count,
+			Block:    c.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read from stream %s: %w", c.key, err)
+		}
+
+		delivered := 0
+		lastID := id
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				delivered++
+				lastID = msg.ID
+				e, err := parseStreamEvent(msg.Values)
+				if err != nil {
+					// Redelivering can't fix a malformed entry - acknowledge and skip it
+					log.Printf("Skipping event %s in stream %s: %v", msg.ID, c.key, err)
+				} else if err := handler(ctx, e); err != nil {
+					continue // Left pending for redelivery
+				}
+				// Acknowledge even if ctx was cancelled while the handler ran
+				if err := c.client.XAck(context.WithoutCancel(ctx), c.key, c.group, msg.ID).Err(); err != nil {
+					return fmt.Errorf("failed to acknowledge event %s: %w", msg.ID, err)
+				}
+			}
+		}
+
+		// Walk the pending backlog past the entries just handled; once it is
+		// drained, switch to new events
+		if id != ">" {
+			id = lastID
+			if delivered == 0 {
+				id = ">"
+			}
+		}
+	}
+}
+
+// parseStreamEvent converts stream entry fields back into an Event
+func parseStreamEvent(values map[string]interface{}) (Event, error) {
+	field := func(name string) string {
+		s, _ := values[name].(string)
+		return s
+	}
+
+	e := Event{
+		Type:     Type(field("type")),
+		JobID:    field("job_id"),
+		JobName:  field("job_name"),
+		Priority: job.JobPriority(field("priority")),
+		Status:   job.JobStatus(field("status")),
+		Error:    field("error"),
+	}
+	if e.Type == "" || e.JobID == "" {
+		return e, fmt.Errorf("malformed event: missing type or job_id")
+	}
+
+	if attempts := field("attempts"); attempts != "" {
+		n, err := strconv.Atoi(attempts)
+		if err != nil {
+			return e, fmt.Errorf("malformed event attempts %q: %w", attempts, err)
+		}
+		e.Attempts = n
+	}
+
+	if ts := field("timestamp"); ts != "" {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return e, fmt.Errorf("malformed event timestamp %q: %w", ts, err)
+		}
+		e.Timestamp = t
+	}
+	return e, nil
+}
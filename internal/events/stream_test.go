@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// testStreamKey is the event stream key of a RedisQueue with the default namespace
+// (see RedisQueue.EventStreamKey)
+const testStreamKey = "bananas:events"
+
+func setupTestStream(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, mr
+}
+
+func TestRedisStream_Publish(t *testing.T) {
+	client, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	stream := NewRedisStream(client, testStreamKey, 100)
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityLow)
+	j.Error = "boom"
+
+	if err := stream.Publish(ctx, NewEvent(TypeFailure, j)); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	entries, err := client.XRange(ctx, testStreamKey, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	e, err := parseStreamEvent(entries[0].Values)
+	if err != nil {
+		t.Fatalf("failed to parse entry: %v", err)
+	}
+	if e.Type != TypeFailure || e.JobID != j.ID || e.Priority != job.PriorityLow || e.Error != "boom" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestStreamConsumer_ConsumeAndRedeliver(t *testing.T) {
+	client, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	consumer := NewStreamConsumer(client, testStreamKey, "billing", "billing-1")
+	consumer.Block = 10 * time.Millisecond
+	if err := consumer.EnsureGroup(ctx); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	// Creating the group again is not an error
+	if err := consumer.EnsureGroup(ctx); err != nil {
+		t.Fatalf("expected EnsureGroup to be idempotent, got %v", err)
+	}
+
+	stream := NewRedisStream(client, testStreamKey, 0)
+	first := job.NewJob("first", []byte(`{}`), job.PriorityNormal)
+	second := job.NewJob("second", []byte(`{}`), job.PriorityNormal)
+	stream.Publish(ctx, NewEvent(TypeSuccess, first))
+	stream.Publish(ctx, NewEvent(TypeDead, second))
+
+	// First run: fail on the dead event so it stays pending
+	runCtx, cancel := context.WithCancel(ctx)
+	var seen []string
+	consumer.Consume(runCtx, func(ctx context.Context, e Event) error {
+		seen = append(seen, e.JobName)
+		if len(seen) == 2 {
+			defer cancel()
+		}
+		if e.Type == TypeDead {
+			return errors.New("pager unavailable")
+		}
+		return nil
+	})
+	if len(seen) != 2 {
+		t.Fatalf("expected 2 events on first run, got %v", seen)
+	}
+
+	pending, err := client.XPending(ctx, testStreamKey, "billing").Result()
+	if err != nil {
+		t.Fatalf("failed to read pending: %v", err)
+	}
+	if pending.Count != 1 {
+		t.Fatalf("expected 1 pending event, got %d", pending.Count)
+	}
+
+	// Second run: the failed event is redelivered
+	runCtx, cancel = context.WithCancel(ctx)
+	var redelivered []string
+	consumer.Consume(runCtx, func(ctx context.Context, e Event) error {
+		redelivered = append(redelivered, e.JobName)
+		cancel()
+		return nil
+	})
+	if len(redelivered) != 1 || redelivered[0] != "second" {
+		t.Errorf("expected failed event to be redelivered, got %v", redelivered)
+	}
+
+	pending, _ = client.XPending(ctx, testStreamKey, "billing").Result()
+	if pending.Count != 0 {
+		t.Errorf("expected no pending events after redelivery, got %d", pending.Count)
+	}
+}
+
+func TestParseStreamEvent_Malformed(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"job_id": "1"},
+		{"type": "start"},
+		{"type": "start", "job_id": "1", "attempts": "x"},
+		{"type": "start", "job_id": "1", "timestamp": "yesterday"},
+	}
+
+	for _, values := range cases {
+		if _, err := parseStreamEvent(values); err == nil {
+			t.Errorf("expected error parsing %v", values)
+		}
+	}
+}
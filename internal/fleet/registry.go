@@ -0,0 +1,184 @@
+// Package fleet tracks the worker processes serving a queue and their lifecycle state.
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/redis/go-redis/v9"
+)
+
+// State is a worker's lifecycle state
+type State string
+
+const (
+	// StateRunning workers are dequeuing and running jobs
+	StateRunning State = "running"
+	// StateDraining workers have stopped dequeuing and are finishing in-flight jobs
+	StateDraining State = "draining"
+	// StateStopped workers have shut down (kept until their entry expires)
+	StateStopped State = "stopped"
+)
+
+// Worker describes a worker process in the registry
+type Worker struct {
+	ID          string    `json:"id"`
+	Hostname    string    `json:"hostname,omitempty"`
+	PID         int       `json:"pid,omitempty"`
+	Mode        string    `json:"mode,omitempty"`
+	Group       string    `json:"group,omitempty"`
+	Concurrency int       `json:"concurrency"`
+	State       State     `json:"state"`
+	ActiveJobs  int       `json:"active_jobs"`
+	StartedAt   time.Time `json:"started_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Registry stores worker entries in Redis (bananas:workers:<id>, indexed by the
+// bananas:workers set, in the default namespace). Entries expire ttl after the last report, so crashed
+// workers drop out on their own.
+type Registry struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	prefix string
+}
+
+// NewRegistry creates a worker registry
+func NewRegistry(client redis.UniversalClient, ttl time.Duration) *Registry {
+	return &Registry{
+		client: client,
+		ttl:    ttl,
+		prefix: redisconn.KeyPrefix(client, namespace.Default),
+	}
+}
+
+// SetNamespace keeps the registry in a namespace's keyspace, so it only lists that namespace's workers
+func (r *Registry) SetNamespace(ns string) error {
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	r.prefix = redisconn.KeyPrefix(r.client, ns)
+	return nil
+}
+
+// indexKey returns the set holding the IDs of registered workers
+func (r *Registry) indexKey() string {
+	return r.prefix + "workers"
+}
+
+// key returns the Redis key holding a worker's entry
+func (r *Registry) key(workerID string) string {
+	return r.prefix + "workers:" + workerID
+}
+
+// Report stores a worker's entry and refreshes its TTL
+func (r *Registry) Report(ctx context.Context, w Worker) error {
+	if w.UpdatedAt.IsZero() {
+		w.UpdatedAt = time.Now()
+	}
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("failed to marshal worker: %w", err)
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.Set(ctx, r.key(w.ID), data, r.ttl)
+	pipe.SAdd(ctx, r.indexKey(), w.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to report worker: %w", err)
+	}
+	return nil
+}
+
+// Get returns a worker's entry, or nil if it isn't registered
+func (r *Registry) Get(ctx context.Context, workerID string) (*Worker, error) {
+	data, err := r.client.Get(ctx, r.key(workerID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worker: %w", err)
+	}
+
+	var w Worker
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal worker: %w", err)
+	}
+	return &w, nil
+}
+
+// List returns all registered workers, ordered by ID
+// Expired entries are removed from the index as they are found.
+func (r *Registry) List(ctx context.Context) ([]Worker, error) {
+	ids, err := r.client.SMembers(ctx, r.indexKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.key(id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workers: %w", err)
+	}
+
+	workers := make([]Worker, 0, len(ids))
+	var expired []interface{}
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		var w Worker
+		if err := json.Unmarshal([]byte(s), &w); err != nil {
+			continue
+		}
+		workers = append(workers, w)
+	}
+	if len(expired) > 0 {
+		r.client.SRem(ctx, r.indexKey(), expired...)
+	}
+
+	sort.Slice(workers, func(i, j int) bool { return workers[i].ID < workers[j].ID })
+	return workers, nil
+}
+
+// Member returns a reporter for one worker process, for worker.Pool.SetStateReporter
+func (r *Registry) Member(w Worker) *Member {
+	if w.StartedAt.IsZero() {
+		w.StartedAt = time.Now()
+	}
+	return &Member{registry: r, worker: w}
+}
+
+// Member reports the state of a single worker process
+type Member struct {
+	registry *Registry
+	worker   Worker
+}
+
+// ID returns the worker's ID
+func (m *Member) ID() string {
+	return m.worker.ID
+}
+
+// ReportState records the worker's current state and number of running jobs
+func (m *Member) ReportState(ctx context.Context, state State, activeJobs int) error {
+	w := m.worker
+	w.State = state
+	w.ActiveJobs = activeJobs
+	w.UpdatedAt = time.Now()
+	return m.registry.Report(ctx, w)
+}
@@ -0,0 +1,73 @@
+package fleet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupRegistry(t *testing.T) (*Registry, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRegistry(client, time.Minute), mr
+}
+
+func TestMember_ReportState(t *testing.T) {
+	registry, mr := setupRegistry(t)
+	ctx := context.Background()
+
+	member := registry.Member(Worker{ID: "host-1", Hostname: "host", Concurrency: 4})
+	if err := member.ReportState(ctx, StateRunning, 2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	w, err := registry.Get(ctx, "host-1")
+	if err != nil || w == nil {
+		t.Fatalf("expected worker entry, got %v (%v)", w, err)
+	}
+	if w.State != StateRunning || w.ActiveJobs != 2 || w.Concurrency != 4 || w.StartedAt.IsZero() {
+		t.Errorf("unexpected worker entry: %+v", w)
+	}
+	if ttl := mr.TTL(registry.key("host-1")); ttl != time.Minute {
+		t.Errorf("expected entry TTL 1m, got %v", ttl)
+	}
+
+	member.ReportState(ctx, StateDraining, 1)
+	w, _ = registry.Get(ctx, "host-1")
+	if w.State != StateDraining || w.ActiveJobs != 1 {
+		t.Errorf("expected draining state with 1 job, got %+v", w)
+	}
+}
+
+func TestRegistry_ListDropsExpiredWorkers(t *testing.T) {
+	registry, mr := setupRegistry(t)
+	ctx := context.Background()
+
+	registry.Report(ctx, Worker{ID: "b", State: StateRunning})
+	registry.Report(ctx, Worker{ID: "a", State: StateStopped})
+	mr.Del(registry.key("b")) // as if its TTL ran out
+
+	workers, err := registry.List(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(workers) != 1 || workers[0].ID != "a" || workers[0].State != StateStopped {
+		t.Errorf("expected only worker a, got %+v", workers)
+	}
+	if ok, _ := mr.SIsMember(registry.indexKey(), "b"); ok {
+		t.Error("expected expired worker to be removed from the index")
+	}
+}
+
+func TestRegistry_GetUnknown(t *testing.T) {
+	registry, _ := setupRegistry(t)
+
+	w, err := registry.Get(context.Background(), "missing")
+	if err != nil || w != nil {
+		t.Errorf("expected nil worker, got %v (%v)", w, err)
+	}
+}
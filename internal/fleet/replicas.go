@@ -0,0 +1,80 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// desiredReplicasKey returns the key holding a worker group's desired replica count
+func (r *Registry) desiredReplicasKey(group string) string {
+	return r.prefix + "autoscale:" + group + ":desired_replicas"
+}
+
+// Replicas returns the number of running workers in a group
+func (r *Registry) Replicas(ctx context.Context, group string) (int, error) {
+	workers, err := r.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	running := 0
+	for _, w := range workers {
+		if w.Group == group && w.State == StateRunning {
+			running++
+		}
+	}
+	return running, nil
+}
+
+// PublishDesiredReplicas stores a group's desired replica count for external orchestrators
+//
+// The value is a plain integer at bananas:autoscale:<group>:desired_replicas (e.g. for a
+// KEDA Redis scaler or a custom controller). It expires with the registry TTL, so it
+// disappears once no worker in the group reports.
+func (r *Registry) PublishDesiredReplicas(ctx context.Context, group string, replicas int) error {
+	if err := r.client.Set(ctx, r.desiredReplicasKey(group), replicas, r.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to publish desired replicas: %w", err)
+	}
+	return nil
+}
+
+// DesiredReplicas returns a group's desired replica count, or 0 if none is published
+func (r *Registry) DesiredReplicas(ctx context.Context, group string) (int, error) {
+	value, err := r.client.Get(ctx, r.desiredReplicasKey(group)).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get desired replicas: %w", err)
+	}
+
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid desired replicas %q: %w", value, err)
+	}
+	return replicas, nil
+}
+
+// ReplicaGroup returns the registry view of one worker group, for worker.Autoscaler.SetReplicaPublisher
+func (r *Registry) ReplicaGroup(group string) *ReplicaGroup {
+	return &ReplicaGroup{registry: r, group: group}
+}
+
+// ReplicaGroup counts and publishes replicas for a single worker group
+type ReplicaGroup struct {
+	registry *Registry
+	group    string
+}
+
+// Replicas returns the number of running workers in the group
+func (g *ReplicaGroup) Replicas(ctx context.Context) (int, error) {
+	return g.registry.Replicas(ctx, g.group)
+}
+
+// PublishDesiredReplicas stores the group's desired replica count
+func (g *ReplicaGroup) PublishDesiredReplicas(ctx context.Context, replicas int) error {
+	return g.registry.PublishDesiredReplicas(ctx, g.group, replicas)
+}
@@ -0,0 +1,50 @@
+package fleet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Replicas(t *testing.T) {
+	registry, _ := setupRegistry(t)
+	ctx := context.Background()
+
+	registry.Report(ctx, Worker{ID: "a", Group: "emails", State: StateRunning})
+	registry.Report(ctx, Worker{ID: "b", Group: "emails", State: StateRunning})
+	registry.Report(ctx, Worker{ID: "c", Group: "emails", State: StateDraining})
+	registry.Report(ctx, Worker{ID: "d", Group: "reports", State: StateRunning})
+
+	replicas, err := registry.ReplicaGroup("emails").Replicas(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if replicas != 2 {
+		t.Errorf("expected 2 running replicas, got %d", replicas)
+	}
+}
+
+func TestRegistry_PublishDesiredReplicas(t *testing.T) {
+	registry, mr := setupRegistry(t)
+	ctx := context.Background()
+
+	if n, err := registry.DesiredReplicas(ctx, "emails"); err != nil || n != 0 {
+		t.Fatalf("expected 0 before publishing, got %d (%v)", n, err)
+	}
+
+	if err := registry.ReplicaGroup("emails").PublishDesiredReplicas(ctx, 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Orchestrators read a plain integer
+	value, err := mr.Get("bananas:autoscale:emails:desired_replicas")
+	if err != nil || value != "3" {
+		t.Errorf("expected stored value 3, got %q (%v)", value, err)
+	}
+	if ttl := mr.TTL("bananas:autoscale:emails:desired_replicas"); ttl != time.Minute {
+		t.Errorf("expected TTL 1m, got %v", ttl)
+	}
+	if n, _ := registry.DesiredReplicas(ctx, "emails"); n != 3 {
+		t.Errorf("expected 3 desired replicas, got %d", n)
+	}
+}
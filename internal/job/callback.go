@@ -0,0 +1,39 @@
+package job
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SetCallback sets the URL that receives the job's result when it finishes
+// secretRef optionally names the signing secret (see webhook.SecretProvider)
+func (j *Job) SetCallback(callbackURL, secretRef string) error {
+	if err := ValidateCallbackURL(callbackURL); err != nil {
+		return err
+	}
+	j.CallbackURL = callbackURL
+	j.CallbackSecretRef = secretRef
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// HasCallback returns true if the job has a callback URL
+func (j *Job) HasCallback() bool {
+	return j.CallbackURL != ""
+}
+
+// ValidateCallbackURL checks that a callback URL is an absolute http(s) URL
+func ValidateCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid callback URL %q: scheme must be http or https", callbackURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid callback URL %q: missing host", callbackURL)
+	}
+	return nil
+}
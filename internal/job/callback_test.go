@@ -0,0 +1,32 @@
+package job
+
+import "testing"
+
+func TestSetCallback(t *testing.T) {
+	j := NewJob("test_job", []byte(`{}`), PriorityNormal)
+	if j.HasCallback() {
+		t.Fatal("expected new job to have no callback")
+	}
+
+	if err := j.SetCallback("https://partner.example.com/hook", "partner-a"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !j.HasCallback() {
+		t.Error("expected job to have a callback")
+	}
+	if j.CallbackURL != "https://partner.example.com/hook" || j.CallbackSecretRef != "partner-a" {
+		t.Errorf("unexpected callback fields: %q %q", j.CallbackURL, j.CallbackSecretRef)
+	}
+}
+
+func TestSetCallback_RejectsInvalidURLs(t *testing.T) {
+	for _, callbackURL := range []string{"", "partner.example.com/hook", "ftp://partner.example.com", "https://", "http://[::1"} {
+		j := NewJob("test_job", []byte(`{}`), PriorityNormal)
+		if err := j.SetCallback(callbackURL, ""); err == nil {
+			t.Errorf("expected error for callback URL %q", callbackURL)
+		}
+		if j.HasCallback() {
+			t.Errorf("expected callback to stay unset for %q", callbackURL)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package job
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetTimeout limits how long each attempt of the job may run
+// It takes precedence over the handler's default timeout and the worker's JOB_TIMEOUT.
+func (j *Job) SetTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("invalid job timeout %v: must not be negative", timeout)
+	}
+	j.Timeout = timeout
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetDeadline sets the latest time the job may start
+// A job still waiting (or waiting for a retry) at its deadline expires instead of running.
+// The deadline does not limit a job that has already started; use SetTimeout for that.
+func (j *Job) SetDeadline(deadline time.Time) {
+	j.Deadline = &deadline
+	j.UpdatedAt = time.Now()
+}
+
+// IsExpired returns true if the job has a deadline that has passed at now
+func (j *Job) IsExpired(now time.Time) bool {
+	return j.Deadline != nil && now.After(*j.Deadline)
+}
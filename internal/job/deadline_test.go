@@ -0,0 +1,60 @@
+package job
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSetTimeout(t *testing.T) {
+	j := NewJob("test_job", []byte(`{}`), PriorityNormal)
+
+	if err := j.SetTimeout(2 * time.Hour); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if j.Timeout != 2*time.Hour {
+		t.Errorf("expected timeout 2h, got %v", j.Timeout)
+	}
+	if err := j.SetTimeout(-time.Second); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Now()
+	j := NewJob("test_job", []byte(`{}`), PriorityNormal)
+
+	if j.IsExpired(now) {
+		t.Error("expected job without deadline not to expire")
+	}
+
+	j.SetDeadline(now.Add(time.Minute))
+	if j.IsExpired(now) {
+		t.Error("expected job before its deadline not to be expired")
+	}
+	if !j.IsExpired(now.Add(2 * time.Minute)) {
+		t.Error("expected job after its deadline to be expired")
+	}
+}
+
+func TestTimeoutAndDeadline_JSONRoundTrip(t *testing.T) {
+	j := NewJob("test_job", []byte(`{}`), PriorityNormal)
+	j.SetTimeout(90 * time.Second)
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	j.SetDeadline(deadline)
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded Job
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if decoded.Timeout != 90*time.Second {
+		t.Errorf("expected timeout 90s, got %v", decoded.Timeout)
+	}
+	if decoded.Deadline == nil || !decoded.Deadline.Equal(deadline) {
+		t.Errorf("expected deadline %v, got %v", deadline, decoded.Deadline)
+	}
+}
@@ -0,0 +1,115 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DependencyFailurePolicy decides what happens to a waiting job when a job it depends
+// on fails permanently, expires or is cancelled
+type DependencyFailurePolicy string
+
+const (
+	// CancelOnParentFailure cancels the job; its own dependents then follow their policies
+	// This is the default.
+	CancelOnParentFailure DependencyFailurePolicy = "cancel"
+	// ContinueOnParentFailure runs the job once every parent has finished, successfully or not
+	ContinueOnParentFailure DependencyFailurePolicy = "continue"
+)
+
+// ErrDependencyCycle is returned for DAG submissions whose dependencies form a cycle
+var ErrDependencyCycle = errors.New("job dependencies form a cycle")
+
+// ErrUnknownDependency is returned for jobs that depend on a job outside their DAG submission
+var ErrUnknownDependency = errors.New("job depends on a job outside the submission")
+
+// After makes the job wait until all parents have finished
+// The job and its parents must be submitted together (see client.SubmitDAG).
+func (j *Job) After(parents ...*Job) {
+	for _, p := range parents {
+		j.DependsOn = append(j.DependsOn, p.ID)
+	}
+	j.UpdatedAt = time.Now()
+}
+
+// SetOnParentFailure sets what happens to the job when a parent fails
+func (j *Job) SetOnParentFailure(policy DependencyFailurePolicy) error {
+	switch policy {
+	case CancelOnParentFailure, ContinueOnParentFailure:
+	default:
+		return fmt.Errorf("invalid dependency failure policy %q: must be %q or %q",
+			policy, CancelOnParentFailure, ContinueOnParentFailure)
+	}
+	j.OnParentFailure = policy
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// CancelsOnParentFailure returns true if the job is cancelled when a parent fails
+func (j *Job) CancelsOnParentFailure() bool {
+	return j.OnParentFailure != ContinueOnParentFailure
+}
+
+// HasDependencies returns true if the job waits for other jobs
+func (j *Job) HasDependencies() bool {
+	return len(j.DependsOn) > 0
+}
+
+// LinkDAG checks that jobs submitted together form a DAG and records on each job
+// the IDs of the jobs that depend on it (Dependents)
+//
+// Every DependsOn ID must be another job of the submission; duplicate IDs,
+// self-dependencies and cycles are rejected.
+func LinkDAG(jobs []*Job) error {
+	byID := make(map[string]*Job, len(jobs))
+	for _, j := range jobs {
+		if _, dup := byID[j.ID]; dup {
+			return fmt.Errorf("duplicate job ID %s in DAG", j.ID)
+		}
+		byID[j.ID] = j
+		j.Dependents = nil
+	}
+
+	// Count each job's unfinished parents and find the jobs that can run first
+	pending := make(map[string]int, len(jobs))
+	for _, j := range jobs {
+		seen := make(map[string]bool, len(j.DependsOn))
+		for _, parentID := range j.DependsOn {
+			parent, ok := byID[parentID]
+			if !ok {
+				return fmt.Errorf("%w: job %s depends on %s", ErrUnknownDependency, j.ID, parentID)
+			}
+			if seen[parentID] {
+				continue
+			}
+			seen[parentID] = true
+			parent.Dependents = append(parent.Dependents, j.ID)
+			pending[j.ID]++
+		}
+	}
+
+	// Kahn's algorithm: jobs never reached from the roots are on a cycle
+	order := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		if pending[j.ID] == 0 {
+			order = append(order, j.ID)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		for _, childID := range byID[order[i]].Dependents {
+			pending[childID]--
+			if pending[childID] == 0 {
+				order = append(order, childID)
+			}
+		}
+	}
+	if len(order) < len(jobs) {
+		for _, j := range jobs {
+			if pending[j.ID] > 0 {
+				return fmt.Errorf("%w: job %s", ErrDependencyCycle, j.ID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package job
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLinkDAG_RecordsDependents(t *testing.T) {
+	a := NewJob("import", nil, PriorityNormal)
+	b := NewJob("import", nil, PriorityNormal)
+	report := NewJob("report", nil, PriorityNormal)
+	report.After(a, b)
+	notify := NewJob("notify", nil, PriorityNormal)
+	notify.After(report, report)
+
+	if err := LinkDAG([]*Job{notify, report, a, b}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(a.Dependents, []string{report.ID}) || !reflect.DeepEqual(b.Dependents, []string{report.ID}) {
+		t.Errorf("expected imports to list the report as dependent, got %v and %v", a.Dependents, b.Dependents)
+	}
+	if !reflect.DeepEqual(report.Dependents, []string{notify.ID}) {
+		t.Errorf("expected a repeated parent to be linked once, got %v", report.Dependents)
+	}
+	if len(notify.Dependents) != 0 {
+		t.Errorf("expected no dependents for the last job, got %v", notify.Dependents)
+	}
+}
+
+func TestLinkDAG_RejectsInvalidGraphs(t *testing.T) {
+	cycle := func() []*Job {
+		a := NewJob("a", nil, PriorityNormal)
+		b := NewJob("b", nil, PriorityNormal)
+		c := NewJob("c", nil, PriorityNormal)
+		b.After(a)
+		c.After(b)
+		a.After(c)
+		return []*Job{a, b, c, NewJob("d", nil, PriorityNormal)}
+	}
+	self := func() []*Job {
+		a := NewJob("a", nil, PriorityNormal)
+		a.After(a)
+		return []*Job{a}
+	}
+	unknown := func() []*Job {
+		a := NewJob("a", nil, PriorityNormal)
+		a.After(NewJob("elsewhere", nil, PriorityNormal))
+		return []*Job{a}
+	}
+
+	tests := []struct {
+		name string
+		jobs []*Job
+		want error
+	}{
+		{"cycle", cycle(), ErrDependencyCycle},
+		{"self dependency", self(), ErrDependencyCycle},
+		{"unknown parent", unknown(), ErrUnknownDependency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := LinkDAG(tt.jobs); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	a := NewJob("a", nil, PriorityNormal)
+	if err := LinkDAG([]*Job{a, a}); err == nil {
+		t.Error("expected error for duplicate job IDs")
+	}
+}
+
+func TestSetOnParentFailure(t *testing.T) {
+	j := NewJob("report", nil, PriorityNormal)
+	if !j.CancelsOnParentFailure() {
+		t.Error("expected jobs to be cancelled on parent failure by default")
+	}
+	if err := j.SetOnParentFailure(ContinueOnParentFailure); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if j.CancelsOnParentFailure() {
+		t.Error("expected continue policy not to cancel")
+	}
+	if err := j.SetOnParentFailure("retry"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
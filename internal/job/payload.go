@@ -1,10 +1,14 @@
 package job
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -12,6 +16,14 @@ var (
 	// DefaultSerializer is the global serializer instance
 	// Set to protobuf by default for better performance
 	DefaultSerializer = serialization.NewProtobufSerializer()
+
+	// DefaultBlobStore resolves offloaded payload references (claim-checks)
+	// Nil by default - processes that enable payload offloading must set it
+	DefaultBlobStore storage.BlobStore
+
+	// DefaultEncryptor encrypts payloads set with SetPayload and decrypts them on unmarshal
+	// Nil by default - payloads are stored in plain text
+	DefaultEncryptor *serialization.Encryptor
 )
 
 // NewJobWithProto creates a new job with a protobuf payload
@@ -52,20 +64,91 @@ func (j *Job) IsJSONPayload() bool {
 	return DefaultSerializer.IsJSON(j.Payload)
 }
 
+// HasPayloadRef returns true if the payload was offloaded to a blob store
+func (j *Job) HasPayloadRef() bool {
+	return j.PayloadRef != ""
+}
+
+// payloadLoaded returns true if the payload bytes are present in the job
+// An offloaded payload round-trips through JSON as the literal null
+func (j *Job) payloadLoaded() bool {
+	return len(j.Payload) > 0 && !bytes.Equal(j.Payload, []byte("null"))
+}
+
+// ResolvePayload fetches an offloaded payload from DefaultBlobStore
+// It is a no-op if the job has no payload reference or the payload is already loaded
+func (j *Job) ResolvePayload(ctx context.Context) error {
+	if !j.HasPayloadRef() || j.payloadLoaded() {
+		return nil
+	}
+
+	if DefaultBlobStore == nil {
+		return fmt.Errorf("payload for job %s was offloaded to %s but no blob store is configured", j.ID, j.PayloadRef)
+	}
+
+	data, err := DefaultBlobStore.Get(ctx, j.PayloadRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve offloaded payload: %w", err)
+	}
+
+	j.Payload = data
+	return nil
+}
+
+// IsEncryptedPayload returns true if the job's payload is encrypted at rest
+func (j *Job) IsEncryptedPayload() bool {
+	return serialization.IsEncrypted(j.Payload)
+}
+
+// PlainPayload returns the job's payload bytes, resolving offloaded payloads (with
+// ctx) and decrypting encrypted ones. j.Payload itself is left encrypted so it is
+// never written back to Redis in plain text.
+func (j *Job) PlainPayload(ctx context.Context) ([]byte, error) {
+	if err := j.ResolvePayload(ctx); err != nil {
+		return nil, err
+	}
+
+	if !j.IsEncryptedPayload() {
+		return j.Payload, nil
+	}
+
+	if DefaultEncryptor == nil {
+		return nil, fmt.Errorf("payload for job %s is encrypted but no encryptor is configured", j.ID)
+	}
+
+	return DefaultEncryptor.Decrypt(j.Payload)
+}
+
 // UnmarshalPayload deserializes the job's payload into the provided type
 // The format is automatically detected (JSON or protobuf)
+// Offloaded payloads are resolved from DefaultBlobStore on first use and
+// encrypted payloads are decrypted with DefaultEncryptor. Use PlainPayload to
+// resolve them with the handler's context.
 func (j *Job) UnmarshalPayload(v interface{}) error {
-	return DefaultSerializer.Unmarshal(j.Payload, v)
+	payload, err := j.PlainPayload(context.Background())
+	if err != nil {
+		return err
+	}
+	return DefaultSerializer.Unmarshal(payload, v)
 }
 
 // UnmarshalPayloadProto deserializes the job's payload into a protobuf message
 func (j *Job) UnmarshalPayloadProto(msg proto.Message) error {
-	return DefaultSerializer.Unmarshal(j.Payload, msg)
+	payload, err := j.PlainPayload(context.Background())
+	if err != nil {
+		return err
+	}
+	return DefaultSerializer.Unmarshal(payload, msg)
 }
 
 // UnmarshalPayloadJSON deserializes the job's payload into a Go value (legacy)
 func (j *Job) UnmarshalPayloadJSON(v interface{}) error {
-	format, payload, err := DefaultSerializer.DetectFormat(j.Payload)
+	plain, err := j.PlainPayload(context.Background())
+	if err != nil {
+		return err
+	}
+
+	format, payload, err := DefaultSerializer.DetectFormat(plain)
 	if err != nil {
 		return err
 	}
@@ -79,6 +162,7 @@ func (j *Job) UnmarshalPayloadJSON(v interface{}) error {
 
 // SetPayload sets the job's payload with automatic serialization
 // Detects if the value is a proto.Message and serializes accordingly
+// The payload is encrypted when DefaultEncryptor is configured
 func (j *Job) SetPayload(v interface{}) error {
 	var data []byte
 	var err error
@@ -96,6 +180,40 @@ func (j *Job) SetPayload(v interface{}) error {
 		return err
 	}
 
+	if DefaultEncryptor != nil {
+		data, err = DefaultEncryptor.Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt payload: %w", err)
+		}
+	}
+
 	j.Payload = data
+	j.PayloadRef = "" // New inline payload supersedes any offloaded one
 	return nil
 }
+
+// EncodeValue serializes a payload or result value
+// Protobuf messages are serialized with DefaultSerializer (format-prefixed),
+// everything else as plain JSON
+func EncodeValue(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok {
+		return DefaultSerializer.Marshal(msg)
+	}
+	return json.Marshal(v)
+}
+
+// DecodeValue deserializes data produced by EncodeValue (or any JSON) into dest
+// dest must be a pointer or a proto.Message
+func DecodeValue(data []byte, dest interface{}) error {
+	if !json.Valid(data) {
+		// Format-prefixed payload (see serialization.Serializer)
+		return DefaultSerializer.Unmarshal(data, dest)
+	}
+
+	if msg, ok := dest.(proto.Message); ok {
+		// protojson accepts both the proto field names and their JSON (camelCase) names
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+	}
+	return json.Unmarshal(data, dest)
+}
+
@@ -0,0 +1,198 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
+)
+
+func TestResolvePayload_FromBlobStore(t *testing.T) {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	original := DefaultBlobStore
+	DefaultBlobStore = store
+	defer func() { DefaultBlobStore = original }()
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "payloads/job-1", []byte(`{"name":"offloaded"}`)); err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+
+	// Simulate a job read back from Redis after offloading
+	var j Job
+	if err := json.Unmarshal([]byte(`{"id":"job-1","payload":null,"payload_ref":"payloads/job-1"}`), &j); err != nil {
+		t.Fatalf("failed to unmarshal job: %v", err)
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+	if err := j.UnmarshalPayload(&payload); err != nil {
+		t.Fatalf("expected payload to resolve, got %v", err)
+	}
+	if payload.Name != "offloaded" {
+		t.Errorf("expected name 'offloaded', got %q", payload.Name)
+	}
+	if string(j.Payload) != `{"name":"offloaded"}` {
+		t.Errorf("expected resolved payload to be cached on the job, got %s", j.Payload)
+	}
+}
+
+func TestResolvePayload_NoStoreConfigured(t *testing.T) {
+	original := DefaultBlobStore
+	DefaultBlobStore = nil
+	defer func() { DefaultBlobStore = original }()
+
+	j := &Job{ID: "job-1", PayloadRef: "payloads/job-1"}
+	err := j.UnmarshalPayload(&map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), "no blob store is configured") {
+		t.Errorf("expected missing blob store error, got %v", err)
+	}
+}
+
+func TestResolvePayload_InlinePayloadUntouched(t *testing.T) {
+	j := NewJob("test_job", []byte(`{"a":1}`), PriorityNormal)
+	if err := j.ResolvePayload(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(j.Payload) != `{"a":1}` {
+		t.Errorf("expected inline payload to be unchanged, got %s", j.Payload)
+	}
+}
+
+func TestSetPayload_ClearsPayloadRef(t *testing.T) {
+	j := &Job{ID: "job-1", PayloadRef: "payloads/job-1"}
+	if err := j.SetPayload(map[string]int{"a": 1}); err != nil {
+		t.Fatalf("SetPayload failed: %v", err)
+	}
+	if j.HasPayloadRef() {
+		t.Error("expected SetPayload to clear the payload reference")
+	}
+}
+
+func TestSetPayload_EncryptsWithDefaultEncryptor(t *testing.T) {
+	keys, err := serialization.NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	original := DefaultEncryptor
+	DefaultEncryptor = serialization.NewEncryptor(keys)
+	defer func() { DefaultEncryptor = original }()
+
+	j := NewJob("test_job", nil, PriorityNormal)
+	if err := j.SetPayload(map[string]string{"card": "4111"}); err != nil {
+		t.Fatalf("SetPayload failed: %v", err)
+	}
+	if !j.IsEncryptedPayload() {
+		t.Fatal("expected payload to be encrypted")
+	}
+	if bytes.Contains(j.Payload, []byte("4111")) {
+		t.Error("encrypted payload contains plaintext")
+	}
+
+	var got map[string]string
+	if err := j.UnmarshalPayload(&got); err != nil {
+		t.Fatalf("UnmarshalPayload failed: %v", err)
+	}
+	if got["card"] != "4111" {
+		t.Errorf("unexpected payload: %v", got)
+	}
+
+	// Without an encryptor the payload cannot be read
+	DefaultEncryptor = nil
+	if err := j.UnmarshalPayload(&got); err == nil || !strings.Contains(err.Error(), "no encryptor") {
+		t.Errorf("expected missing encryptor error, got %v", err)
+	}
+}
+
+func TestEncodeDecodeValue_JSON(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	data, err := EncodeValue(payload{Name: "test", Count: 3})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("expected plain JSON, got %q", data)
+	}
+
+	var got payload
+	if err := DecodeValue(data, &got); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if got.Name != "test" || got.Count != 3 {
+		t.Errorf("unexpected decoded value: %+v", got)
+	}
+}
+
+func TestEncodeDecodeValue_Protobuf(t *testing.T) {
+	data, err := EncodeValue(&tasks.EmailTask{To: "user@example.com", BodyText: "hi"})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if data[0] != byte(serialization.FormatProtobuf) {
+		t.Fatalf("expected protobuf format byte, got 0x%02X", data[0])
+	}
+
+	var got tasks.EmailTask
+	if err := DecodeValue(data, &got); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if got.To != "user@example.com" || got.BodyText != "hi" {
+		t.Errorf("unexpected decoded value: %v", &got)
+	}
+}
+
+func TestDecodeValue_JSONIntoProtobuf(t *testing.T) {
+	var got tasks.EmailTask
+	if err := DecodeValue([]byte(`{"to": "user@example.com", "body_text": "hi"}`), &got); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if got.To != "user@example.com" || got.BodyText != "hi" {
+		t.Errorf("unexpected decoded value: %v", &got)
+	}
+}
+
+
+// contextStore records the context an offloaded payload is fetched with
+type contextStore struct {
+	storage.BlobStore
+	ctx context.Context
+}
+
+func (s *contextStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.ctx = ctx
+	return []byte(`{"ok":true}`), nil
+}
+
+func TestPlainPayload_ResolvesWithContext(t *testing.T) {
+	store := &contextStore{}
+	original := DefaultBlobStore
+	DefaultBlobStore = store
+	defer func() { DefaultBlobStore = original }()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "handler")
+	j := &Job{ID: "job-1", PayloadRef: "payloads/job-1"}
+	payload, err := j.PlainPayload(ctx)
+	if err != nil {
+		t.Fatalf("PlainPayload failed: %v", err)
+	}
+	if string(payload) != `{"ok":true}` {
+		t.Errorf("unexpected payload %s", payload)
+	}
+	if store.ctx == nil || store.ctx.Value(key{}) != "handler" {
+		t.Error("expected the payload to be fetched with the caller's context")
+	}
+}
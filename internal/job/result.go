@@ -10,7 +10,7 @@ type JobResult struct {
 	// JobID is the unique identifier of the job
 	JobID string `json:"job_id"`
 
-	// Status is the final status of the job (completed or failed)
+	// Status is the final status of the job (completed, failed or expired)
 	Status JobStatus `json:"status"`
 
 	// Result contains the job's return value (only for successful jobs)
@@ -27,17 +27,50 @@ type JobResult struct {
 	Duration time.Duration `json:"duration"`
 }
 
+// jobResultJSON has the same fields as JobResult without its JSON methods
+type jobResultJSON JobResult
+
+// binaryResultJSON carries results that are not valid JSON (protobuf or encrypted
+// results) base64-encoded in "result_binary", since "result" must be raw JSON
+type binaryResultJSON struct {
+	*jobResultJSON
+	Result       json.RawMessage `json:"result,omitempty"`
+	ResultBinary []byte          `json:"result_binary,omitempty"`
+}
+
+// MarshalJSON encodes the result, storing binary results base64-encoded
+func (r JobResult) MarshalJSON() ([]byte, error) {
+	if len(r.Result) == 0 || json.Valid(r.Result) {
+		return json.Marshal((*jobResultJSON)(&r))
+	}
+	return json.Marshal(binaryResultJSON{jobResultJSON: (*jobResultJSON)(&r), ResultBinary: r.Result})
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON
+func (r *JobResult) UnmarshalJSON(data []byte) error {
+	aux := binaryResultJSON{jobResultJSON: (*jobResultJSON)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Result = aux.Result
+	if len(aux.ResultBinary) > 0 {
+		r.Result = aux.ResultBinary
+	}
+	return nil
+}
+
 // IsSuccess returns true if the job completed successfully
 func (r *JobResult) IsSuccess() bool {
 	return r.Status == StatusCompleted
 }
 
-// IsFailed returns true if the job failed
+// IsFailed returns true if the job failed or expired before it could run
 func (r *JobResult) IsFailed() bool {
-	return r.Status == StatusFailed
+	return r.Status == StatusFailed || r.Status == StatusExpired
 }
 
 // UnmarshalResult unmarshals the result data into the provided destination
+// JSON and protobuf results (see EncodeValue) are supported
 // Returns an error if the job failed or if unmarshaling fails
 func (r *JobResult) UnmarshalResult(dest interface{}) error {
 	if r.IsFailed() {
@@ -48,7 +81,7 @@ func (r *JobResult) UnmarshalResult(dest interface{}) error {
 		return nil // No result data
 	}
 
-	return json.Unmarshal(r.Result, dest)
+	return DecodeValue(r.Result, dest)
 }
 
 // ResultError represents an error when retrieving or processing a result
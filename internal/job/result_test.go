@@ -1,9 +1,12 @@
 package job
 
 import (
+	"bytes"
 	"encoding/json"
 	"testing"
 	"time"
+
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
 )
 
 func TestJobResult_IsSuccess(t *testing.T) {
@@ -35,6 +38,7 @@ func TestJobResult_IsFailed(t *testing.T) {
 		want   bool
 	}{
 		{"Failed", StatusFailed, true},
+		{"Expired", StatusExpired, true},
 		{"Completed", StatusCompleted, false},
 		{"Pending", StatusPending, false},
 	}
@@ -156,3 +160,32 @@ func TestJobResult_JSON(t *testing.T) {
 		t.Errorf("Result = %v, want %v", string(r2.Result), string(r.Result))
 	}
 }
+
+func TestJobResult_JSONBinaryResult(t *testing.T) {
+	data, err := EncodeValue(&tasks.EmailTask{To: "user@example.com", Subject: "hi"})
+	if err != nil {
+		t.Fatalf("EncodeValue() error = %v", err)
+	}
+	r := &JobResult{JobID: "job123", Status: StatusCompleted, Result: data}
+
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var r2 JobResult
+	if err := json.Unmarshal(encoded, &r2); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !bytes.Equal(r2.Result, data) {
+		t.Errorf("Result = %v, want %v", r2.Result, data)
+	}
+
+	var task tasks.EmailTask
+	if err := r2.UnmarshalResult(&task); err != nil {
+		t.Fatalf("UnmarshalResult() error = %v", err)
+	}
+	if task.To != "user@example.com" || task.Subject != "hi" {
+		t.Errorf("unexpected result: %+v", &task)
+	}
+}
@@ -0,0 +1,41 @@
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultRoutingKey is the routing key of jobs that don't set one
+const DefaultRoutingKey = "default"
+
+// maxRoutingKeyLength is the longest routing key accepted
+const maxRoutingKeyLength = 64
+
+// routingKeyPattern allows letters, digits, underscores and hyphens
+var routingKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// SetRoutingKey routes the job to workers configured with the given routing key
+func (j *Job) SetRoutingKey(routingKey string) error {
+	if err := ValidateRoutingKey(routingKey); err != nil {
+		return err
+	}
+	j.RoutingKey = routingKey
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// ValidateRoutingKey checks that a routing key is non-empty, at most 64 characters
+// and contains only letters, digits, underscores and hyphens
+func ValidateRoutingKey(routingKey string) error {
+	if routingKey == "" {
+		return fmt.Errorf("routing key cannot be empty")
+	}
+	if len(routingKey) > maxRoutingKeyLength {
+		return fmt.Errorf("routing key too long: %d characters (maximum %d)", len(routingKey), maxRoutingKeyLength)
+	}
+	if !routingKeyPattern.MatchString(routingKey) {
+		return fmt.Errorf("invalid routing key format: %q (use letters, digits, underscores and hyphens)", routingKey)
+	}
+	return nil
+}
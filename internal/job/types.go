@@ -21,6 +21,12 @@ const (
 	StatusFailed JobStatus = "failed"
 	// StatusScheduled indicates the job is scheduled for future execution
 	StatusScheduled JobStatus = "scheduled"
+	// StatusExpired indicates the job's deadline passed before it started, so it was discarded
+	StatusExpired JobStatus = "expired"
+	// StatusCancelled indicates the job was cancelled before a worker started it
+	StatusCancelled JobStatus = "cancelled"
+	// StatusWaiting indicates the job is waiting for the jobs it depends on to finish
+	StatusWaiting JobStatus = "waiting"
 )
 
 // JobPriority represents the priority level of a job
@@ -45,6 +51,9 @@ type Job struct {
 	Description string `json:"description,omitempty"`
 	// Payload contains the job-specific data in JSON format
 	Payload json.RawMessage `json:"payload"`
+	// PayloadRef is the blob store key holding the payload when it was offloaded
+	// (claim-check). Payload is empty until the reference is resolved.
+	PayloadRef string `json:"payload_ref,omitempty"`
 	// Status is the current status of the job
 	Status JobStatus `json:"status"`
 	// Priority determines the processing order
@@ -61,6 +70,23 @@ type Job struct {
 	MaxRetries int `json:"max_retries"`
 	// Error contains the error message if the job failed
 	Error string `json:"error,omitempty"`
+	// RoutingKey selects the workers that process the job (see SetRoutingKey)
+	RoutingKey string `json:"routing_key,omitempty"`
+	// Timeout limits how long a single attempt may run (0 uses the handler's or worker's default)
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Deadline is the latest time the job may start; jobs dequeued after it expire without running
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// CallbackURL receives an HTTP POST of the JobResult when the job completes or fails permanently
+	CallbackURL string `json:"callback_url,omitempty"`
+	// CallbackSecretRef names the secret used to HMAC-sign callback requests
+	// It is a reference resolved by the webhook dispatcher, never the secret itself
+	CallbackSecretRef string `json:"callback_secret_ref,omitempty"`
+	// DependsOn lists the IDs of the jobs that must finish before this one runs (see After)
+	DependsOn []string `json:"depends_on,omitempty"`
+	// OnParentFailure decides what happens to the job when one it depends on fails
+	OnParentFailure DependencyFailurePolicy `json:"on_parent_failure,omitempty"`
+	// Dependents lists the IDs of the jobs that depend on this one; set when the DAG is submitted
+	Dependents []string `json:"dependents,omitempty"`
 }
 
 // NewJob creates a new job with the specified name, payload, priority, and optional description.
@@ -91,9 +117,42 @@ func NewJob(name string, payload []byte, priority JobPriority, description ...st
 		Attempts:    0,
 		MaxRetries:  3, // Default, can be overridden
 		Error:       "",
+		RoutingKey:  DefaultRoutingKey,
 	}
 }
 
+// jobJSON has the same fields as Job without its JSON methods
+type jobJSON Job
+
+// binaryPayloadJSON carries payloads that are not valid JSON (protobuf or encrypted
+// payloads) base64-encoded in "payload_binary", since "payload" must be raw JSON
+type binaryPayloadJSON struct {
+	*jobJSON
+	Payload       json.RawMessage `json:"payload"`
+	PayloadBinary []byte          `json:"payload_binary,omitempty"`
+}
+
+// MarshalJSON encodes the job, storing binary payloads base64-encoded
+func (j Job) MarshalJSON() ([]byte, error) {
+	if len(j.Payload) == 0 || json.Valid(j.Payload) {
+		return json.Marshal((*jobJSON)(&j))
+	}
+	return json.Marshal(binaryPayloadJSON{jobJSON: (*jobJSON)(&j), PayloadBinary: j.Payload})
+}
+
+// UnmarshalJSON decodes a job encoded by MarshalJSON
+func (j *Job) UnmarshalJSON(data []byte) error {
+	aux := binaryPayloadJSON{jobJSON: (*jobJSON)(j)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	j.Payload = aux.Payload
+	if len(aux.PayloadBinary) > 0 {
+		j.Payload = aux.PayloadBinary
+	}
+	return nil
+}
+
 // UpdateStatus updates the job's status and UpdatedAt timestamp
 func (j *Job) UpdateStatus(status JobStatus) {
 	j.Status = status
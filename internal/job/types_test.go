@@ -160,6 +160,27 @@ func TestJob_JSONMarshaling(t *testing.T) {
 	}
 }
 
+func TestJob_JSONMarshaling_BinaryPayload(t *testing.T) {
+	payload := []byte{0x01, 0x0a, 0x03, 'f', 'o', 'o', 0xff}
+	original := NewJob("binary_job", payload, PriorityNormal)
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("failed to marshal job with binary payload: %v", err)
+	}
+
+	var decoded Job
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal job: %v", err)
+	}
+	if string(decoded.Payload) != string(payload) {
+		t.Errorf("expected payload %v, got %v", payload, []byte(decoded.Payload))
+	}
+	if decoded.Name != original.Name {
+		t.Errorf("expected name %s, got %s", original.Name, decoded.Name)
+	}
+}
+
 func TestJob_TimestampsSet(t *testing.T) {
 	before := time.Now()
 	j := NewJob("test_job", []byte("{}"), PriorityNormal)
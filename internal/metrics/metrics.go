@@ -27,11 +27,14 @@ type Collector struct {
 	jobsByPriority    map[job.JobPriority]int64
 	queueDepths       map[job.JobPriority]int64
 	totalDuration     time.Duration
+	totalWait         time.Duration
+	waitCount         int64
 	startTime         time.Time
 	activeWorkers     int64
 	totalWorkers      int64
 	errorCount        int64
 	operationCount    int64
+	tenants           map[string]*TenantMetrics
 }
 
 // Metrics represents a snapshot of current system metrics
@@ -43,9 +46,22 @@ type Metrics struct {
 	JobsByPriority     map[job.JobPriority]int64  `json:"jobs_by_priority"`
 	QueueDepths        map[job.JobPriority]int64  `json:"queue_depths"`
 	AvgJobDuration     time.Duration              `json:"avg_job_duration"`
+	AvgWaitTime        time.Duration              `json:"avg_wait_time"`
+	TotalWaitTime      time.Duration              `json:"total_wait_time"`
+	WaitSamples        int64                      `json:"wait_samples"`
 	WorkerUtilization  float64                    `json:"worker_utilization"`
 	ErrorRate          float64                    `json:"error_rate"`
 	Uptime             time.Duration              `json:"uptime"`
+	Tenants            map[string]TenantMetrics   `json:"tenants"`
+}
+
+// TenantMetrics are the job counters of one namespace
+type TenantMetrics struct {
+	Enqueued    int64 `json:"enqueued"`
+	Rejected    int64 `json:"rejected"`
+	Completed   int64 `json:"completed"`
+	Failed      int64 `json:"failed"`
+	Outstanding int64 `json:"outstanding"`
 }
 
 // Default returns the global metrics collector instance
@@ -63,6 +79,7 @@ func NewCollector() *Collector {
 		jobsByPriority: make(map[job.JobPriority]int64),
 		queueDepths:    make(map[job.JobPriority]int64),
 		startTime:      time.Now(),
+		tenants:        make(map[string]*TenantMetrics),
 	}
 }
 
@@ -101,6 +118,18 @@ func (c *Collector) RecordJobFailed(priority job.JobPriority, duration time.Dura
 	c.errorCount++
 }
 
+// RecordJobWait records how long a job waited in its queue before a worker started it
+func (c *Collector) RecordJobWait(wait time.Duration) {
+	if wait < 0 {
+		wait = 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.totalWait += wait
+	c.waitCount++
+}
+
 // RecordQueueDepth updates the current queue depth for a priority
 func (c *Collector) RecordQueueDepth(priority job.JobPriority, depth int64) {
 	c.mu.Lock()
@@ -116,6 +145,52 @@ func (c *Collector) RecordWorkerActivity(active, total int64) {
 	c.totalWorkers = total
 }
 
+// tenant returns a namespace's counters, creating them on first use
+// Callers must hold c.mu.
+func (c *Collector) tenant(namespace string) *TenantMetrics {
+	t, ok := c.tenants[namespace]
+	if !ok {
+		t = &TenantMetrics{}
+		c.tenants[namespace] = t
+	}
+	return t
+}
+
+// RecordTenantEnqueued records a job enqueued in a namespace
+func (c *Collector) RecordTenantEnqueued(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant(namespace).Enqueued++
+}
+
+// RecordTenantRejected records an enqueue refused by a namespace's quota
+func (c *Collector) RecordTenantRejected(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant(namespace).Rejected++
+}
+
+// RecordTenantCompleted records a job completed in a namespace
+func (c *Collector) RecordTenantCompleted(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant(namespace).Completed++
+}
+
+// RecordTenantFailed records a job of a namespace moved to the dead letter queue
+func (c *Collector) RecordTenantFailed(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant(namespace).Failed++
+}
+
+// RecordTenantOutstanding updates how many unfinished jobs a namespace holds
+func (c *Collector) RecordTenantOutstanding(namespace string, outstanding int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant(namespace).Outstanding = outstanding
+}
+
 // GetMetrics returns a snapshot of current metrics
 func (c *Collector) GetMetrics() Metrics {
 	c.mu.RLock()
@@ -137,12 +212,23 @@ func (c *Collector) GetMetrics() Metrics {
 		queueDepths[k] = v
 	}
 
+	tenants := make(map[string]TenantMetrics, len(c.tenants))
+	for k, v := range c.tenants {
+		tenants[k] = *v
+	}
+
 	// Calculate average duration
 	var avgDuration time.Duration
 	if c.operationCount > 0 {
 		avgDuration = c.totalDuration / time.Duration(c.operationCount)
 	}
 
+	// Calculate average queue wait
+	var avgWait time.Duration
+	if c.waitCount > 0 {
+		avgWait = c.totalWait / time.Duration(c.waitCount)
+	}
+
 	// Calculate worker utilization
 	var utilization float64
 	if c.totalWorkers > 0 {
@@ -164,9 +250,13 @@ func (c *Collector) GetMetrics() Metrics {
 		JobsByPriority:     jobsByPriority,
 		QueueDepths:        queueDepths,
 		AvgJobDuration:     avgDuration,
+		AvgWaitTime:        avgWait,
+		TotalWaitTime:      c.totalWait,
+		WaitSamples:        c.waitCount,
 		WorkerUtilization:  utilization,
 		ErrorRate:          errorRate,
 		Uptime:             time.Since(c.startTime),
+		Tenants:            tenants,
 	}
 }
 
@@ -182,11 +272,14 @@ func (c *Collector) Reset() {
 	c.jobsByPriority = make(map[job.JobPriority]int64)
 	c.queueDepths = make(map[job.JobPriority]int64)
 	c.totalDuration = 0
+	c.totalWait = 0
+	c.waitCount = 0
 	c.startTime = time.Now()
 	c.activeWorkers = 0
 	c.totalWorkers = 0
 	c.errorCount = 0
 	c.operationCount = 0
+	c.tenants = make(map[string]*TenantMetrics)
 }
 
 // GetMetrics returns metrics from the global collector
@@ -155,6 +155,50 @@ func TestRecordQueueDepth(t *testing.T) {
 	}
 }
 
+func TestRecordJobWait(t *testing.T) {
+	c := NewCollector()
+
+	c.RecordJobWait(100 * time.Millisecond)
+	c.RecordJobWait(300 * time.Millisecond)
+	c.RecordJobWait(-time.Second) // Clock skew counts as no wait
+
+	metrics := c.GetMetrics()
+	if metrics.WaitSamples != 3 {
+		t.Errorf("Expected WaitSamples = 3, got %d", metrics.WaitSamples)
+	}
+	if metrics.TotalWaitTime != 400*time.Millisecond {
+		t.Errorf("Expected TotalWaitTime = 400ms, got %v", metrics.TotalWaitTime)
+	}
+	if metrics.AvgWaitTime != 400*time.Millisecond/3 {
+		t.Errorf("Expected AvgWaitTime = %v, got %v", 400*time.Millisecond/3, metrics.AvgWaitTime)
+	}
+}
+
+func TestRecordTenantMetrics(t *testing.T) {
+	c := NewCollector()
+
+	c.RecordTenantEnqueued("acme")
+	c.RecordTenantEnqueued("acme")
+	c.RecordTenantRejected("acme")
+	c.RecordTenantCompleted("acme")
+	c.RecordTenantFailed("globex")
+	c.RecordTenantOutstanding("acme", 7)
+
+	metrics := c.GetMetrics()
+	acme := metrics.Tenants["acme"]
+	if acme.Enqueued != 2 || acme.Rejected != 1 || acme.Completed != 1 || acme.Outstanding != 7 {
+		t.Errorf("Unexpected acme metrics: %+v", acme)
+	}
+	if metrics.Tenants["globex"].Failed != 1 {
+		t.Errorf("Expected globex Failed = 1, got %d", metrics.Tenants["globex"].Failed)
+	}
+
+	c.Reset()
+	if len(c.GetMetrics().Tenants) != 0 {
+		t.Error("Expected Reset to clear tenant metrics")
+	}
+}
+
 func TestRecordWorkerActivity(t *testing.T) {
 	c := NewCollector()
 
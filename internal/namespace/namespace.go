@@ -0,0 +1,50 @@
+package namespace
+
+import "fmt"
+
+// Default is the namespace used when none is configured
+// Its keys are the historical bananas:* keys, so existing deployments keep their data.
+const Default = "bananas"
+
+// maxLength bounds namespace names so keys stay readable
+const maxLength = 64
+
+// Prefix returns the Redis key prefix for a namespace, e.g. "acme:" for "acme"
+// An empty namespace selects the default namespace.
+func Prefix(ns string) string {
+	if ns == "" {
+		ns = Default
+	}
+	return ns + ":"
+}
+
+// Validate checks that a namespace can be used as a key prefix
+// Names may contain letters, digits, '-', '_' and '.', and must not contain ':'
+// so that one namespace's keys can never match another's.
+func Validate(ns string) error {
+	if ns == "" {
+		return fmt.Errorf("namespace cannot be empty")
+	}
+	if len(ns) > maxLength {
+		return fmt.Errorf("namespace %q is longer than %d characters", ns, maxLength)
+	}
+	for _, r := range ns {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return fmt.Errorf("namespace %q contains invalid character %q", ns, r)
+		}
+	}
+	return nil
+}
+
+// HashTaggedPrefix returns the Redis Cluster key prefix for a namespace, e.g. "{acme}:"
+// The braces make Cluster hash only the namespace name, so every key of the namespace
+// lands in the same slot. An empty namespace selects the default namespace.
+func HashTaggedPrefix(ns string) string {
+	if ns == "" {
+		ns = Default
+	}
+	return "{" + ns + "}:"
+}
@@ -0,0 +1,37 @@
+package namespace
+
+import "testing"
+
+func TestPrefix(t *testing.T) {
+	if got := Prefix(""); got != "bananas:" {
+		t.Errorf("expected default prefix 'bananas:', got %q", got)
+	}
+	if got := Prefix("acme"); got != "acme:" {
+		t.Errorf("expected prefix 'acme:', got %q", got)
+	}
+}
+
+func TestHashTaggedPrefix(t *testing.T) {
+	if got := HashTaggedPrefix(""); got != "{bananas}:" {
+		t.Errorf("expected default prefix '{bananas}:', got %q", got)
+	}
+	if got := HashTaggedPrefix("acme"); got != "{acme}:" {
+		t.Errorf("expected prefix '{acme}:', got %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"bananas", "acme", "team-a", "prod_eu.1"}
+	for _, ns := range valid {
+		if err := Validate(ns); err != nil {
+			t.Errorf("expected %q to be valid, got %v", ns, err)
+		}
+	}
+
+	invalid := []string{"", "acme:prod", "has space", "ünïcode", string(make([]byte, 65))}
+	for _, ns := range invalid {
+		if err := Validate(ns); err == nil {
+			t.Errorf("expected %q to be invalid", ns)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package progress stores and streams progress updates reported by running jobs.
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/redis/go-redis/v9"
+)
+
+// Update is a progress report for a job
+type Update struct {
+	JobID   string                 `json:"job_id"`
+	Percent float64                `json:"percent"`
+	Message string                 `json:"message,omitempty"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+	// Done is set on the last update, published when the job finishes
+	Done bool `json:"done,omitempty"`
+	// Status is the job's final status on the Done update
+	Status    job.JobStatus `json:"status,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// RedisStore persists the latest update for each job and publishes every update
+// on a per-job channel (bananas:progress:notify:<job id> in the default namespace), like the result
+// backend's notify channel
+type RedisStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	prefix string
+}
+
+// NewRedisStore creates a progress store; updates expire ttl after the last report
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{
+		client: client,
+		ttl:    ttl,
+		prefix: redisconn.KeyPrefix(client, namespace.Default),
+	}
+}
+
+// SetNamespace stores progress in a namespace's keyspace
+func (s *RedisStore) SetNamespace(ns string) error {
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	s.prefix = redisconn.KeyPrefix(s.client, ns)
+	return nil
+}
+
+// key returns the Redis key holding a job's latest update
+func (s *RedisStore) key(jobID string) string {
+	return s.prefix + "progress:" + jobID
+}
+
+// channel returns the pub/sub channel for a job's updates
+func (s *RedisStore) channel(jobID string) string {
+	return s.prefix + "progress:notify:" + jobID
+}
+
+// Report stores an update and publishes it to watchers
+func (s *RedisStore) Report(ctx context.Context, u Update) error {
+	if u.Percent < 0 || u.Percent > 100 {
+		return fmt.Errorf("progress percent must be between 0 and 100, got %v", u.Percent)
+	}
+	if u.Timestamp.IsZero() {
+		u.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+
+	// Use pipeline for atomicity: SET + PUBLISH
+	pipe := s.client.Pipeline()
+	pipe.Set(ctx, s.key(u.JobID), data, s.ttl)
+	pipe.Publish(ctx, s.channel(u.JobID), data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to report progress: %w", err)
+	}
+	return nil
+}
+
+// Get returns a job's latest update, or nil if it hasn't reported any
+func (s *RedisStore) Get(ctx context.Context, jobID string) (*Update, error) {
+	data, err := s.client.Get(ctx, s.key(jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get progress: %w", err)
+	}
+
+	var u Update
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal progress: %w", err)
+	}
+	return &u, nil
+}
+
+// Watch streams a job's updates, starting with the latest stored one
+//
+// The channel is closed after the Done update or when ctx is cancelled. Jobs that
+// never report progress publish no Done update, so watchers should bound ctx.
+func (s *RedisStore) Watch(ctx context.Context, jobID string) (<-chan Update, error) {
+	// Subscribe before reading the latest update so nothing is missed in between
+	pubsub := s.client.Subscribe(ctx, s.channel(jobID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to progress: %w", err)
+	}
+
+	latest, err := s.Get(ctx, jobID)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	updates := make(chan Update)
+	go func() {
+		defer close(updates)
+		defer pubsub.Close()
+
+		var last time.Time
+		send := func(u Update) bool {
+			// Skip updates already sent (the latest may also arrive via pub/sub)
+			if !u.Timestamp.After(last) && !last.IsZero() {
+				return true
+			}
+			last = u.Timestamp
+			select {
+			case updates <- u:
+				return !u.Done
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if latest != nil && !send(*latest) {
+			return
+		}
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var u Update
+				if err := json.Unmarshal([]byte(msg.Payload), &u); err != nil {
+					continue
+				}
+				if !send(u) {
+					return
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
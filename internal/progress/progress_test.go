@@ -0,0 +1,147 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupTestStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, time.Hour), mr
+}
+
+// receive reads the next update or fails the test after a timeout
+func receive(t *testing.T, updates <-chan Update) Update {
+	t.Helper()
+	select {
+	case u, ok := <-updates:
+		if !ok {
+			t.Fatal("channel closed unexpectedly")
+		}
+		return u
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for progress update")
+	}
+	return Update{}
+}
+
+func TestRedisStore_ReportAndGet(t *testing.T) {
+	store, mr := setupTestStore(t)
+	ctx := context.Background()
+
+	if u, err := store.Get(ctx, "job-1"); err != nil || u != nil {
+		t.Fatalf("expected no progress, got %v (%v)", u, err)
+	}
+
+	err := store.Report(ctx, Update{JobID: "job-1", Percent: 40, Message: "rows", Meta: map[string]interface{}{"rows": 400}})
+	if err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+
+	u, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	if u.Percent != 40 || u.Message != "rows" || u.Meta["rows"] != float64(400) || u.Timestamp.IsZero() {
+		t.Errorf("unexpected update: %+v", u)
+	}
+	if ttl := mr.TTL("bananas:progress:job-1"); ttl != time.Hour {
+		t.Errorf("expected TTL 1h, got %v", ttl)
+	}
+}
+
+func TestRedisStore_RejectsInvalidPercent(t *testing.T) {
+	store, _ := setupTestStore(t)
+
+	for _, pct := range []float64{-1, 100.5} {
+		if err := store.Report(context.Background(), Update{JobID: "job-1", Percent: pct}); err == nil {
+			t.Errorf("expected error for percent %v", pct)
+		}
+	}
+}
+
+func TestRedisStore_Watch(t *testing.T) {
+	store, _ := setupTestStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store.Report(ctx, Update{JobID: "job-1", Percent: 10})
+
+	updates, err := store.Watch(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+
+	// The latest stored update comes first
+	if u := receive(t, updates); u.Percent != 10 {
+		t.Errorf("expected 10%%, got %v", u.Percent)
+	}
+
+	store.Report(ctx, Update{JobID: "job-1", Percent: 50, Message: "halfway"})
+	if u := receive(t, updates); u.Percent != 50 || u.Message != "halfway" {
+		t.Errorf("unexpected update: %+v", u)
+	}
+
+	// Other jobs' updates aren't delivered
+	store.Report(ctx, Update{JobID: "job-2", Percent: 99})
+
+	store.Report(ctx, Update{JobID: "job-1", Percent: 100, Done: true, Status: job.StatusCompleted})
+	if u := receive(t, updates); !u.Done || u.Status != job.StatusCompleted {
+		t.Errorf("expected done update, got %+v", u)
+	}
+
+	// The channel closes after the done update
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("expected channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("timed out waiting for channel to close")
+	}
+}
+
+func TestRedisStore_WatchFinishedJob(t *testing.T) {
+	store, _ := setupTestStore(t)
+	ctx := context.Background()
+
+	store.Report(ctx, Update{JobID: "job-1", Percent: 30, Done: true, Status: job.StatusFailed})
+
+	updates, err := store.Watch(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	if u := receive(t, updates); !u.Done {
+		t.Errorf("expected done update, got %+v", u)
+	}
+	if _, ok := <-updates; ok {
+		t.Error("expected channel to be closed")
+	}
+}
+
+func TestRedisStore_WatchStopsOnCancel(t *testing.T) {
+	store, _ := setupTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	updates, err := store.Watch(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to watch: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("expected no updates after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("timed out waiting for channel to close")
+	}
+}
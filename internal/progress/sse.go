@@ -0,0 +1,79 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseKeepAlive is how often a comment is sent to keep idle connections open
+const sseKeepAlive = 15 * time.Second
+
+// SSEHandler streams a job's progress as Server-Sent Events
+//
+// Mount it on a pattern with an {id} wildcard, e.g. "GET /jobs/{id}/progress".
+// Each update is sent as a "progress" event with the Update as JSON data; the
+// last update of a finished job is sent as a "done" event, then the stream ends.
+type SSEHandler struct {
+	store *RedisStore
+}
+
+// NewSSEHandler creates an SSE handler backed by a progress store
+func NewSSEHandler(store *RedisStore) *SSEHandler {
+	return &SSEHandler{store: store}
+}
+
+// ServeHTTP implements http.Handler
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if jobID == "" {
+		http.Error(w, "missing job id", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates, err := h.store.Watch(r.Context(), jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(u)
+			if err != nil {
+				continue
+			}
+			event := "progress"
+			if u.Done {
+				event = "done"
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package progress
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func TestSSEHandler_StreamsUpdates(t *testing.T) {
+	store, _ := setupTestStore(t)
+	ctx := context.Background()
+	store.Report(ctx, Update{JobID: "job-1", Percent: 25, Message: "started"})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /jobs/{id}/progress", NewSSEHandler(store))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jobs/job-1/progress")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %s", ct)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		store.Report(ctx, Update{JobID: "job-1", Percent: 100, Done: true, Status: job.StatusCompleted})
+	}()
+
+	// The stream ends after the done event
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) != 4 {
+		t.Fatalf("expected 2 events, got %q", lines)
+	}
+	if lines[0] != "event: progress" || !strings.Contains(lines[1], `"message":"started"`) {
+		t.Errorf("unexpected first event: %q", lines[:2])
+	}
+	if lines[2] != "event: done" || !strings.Contains(lines[3], `"status":"completed"`) {
+		t.Errorf("unexpected last event: %q", lines[2:])
+	}
+}
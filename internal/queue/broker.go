@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// ErrJobNotFound is returned when a job ID is unknown to the broker (or its data expired)
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrNotCancellable is returned by Cancel for jobs that are running or already finished
+var ErrNotCancellable = errors.New("job cannot be cancelled")
+
+// Producer is the part of a Broker that submits jobs
+type Producer interface {
+	// Enqueue makes a job ready for workers
+	Enqueue(ctx context.Context, j *job.Job) error
+	// Schedule makes a job ready for workers at the given time (see MoveScheduledToReady)
+	Schedule(ctx context.Context, j *job.Job, at time.Time) error
+}
+
+// Consumer is the part of a Broker that workers use to take and settle jobs
+type Consumer interface {
+	// Dequeue takes the next ready job from the highest priority non-empty queue,
+	// or returns nil if none became ready while it waited
+	Dequeue(ctx context.Context, priorities []job.JobPriority) (*job.Job, error)
+	// Complete acknowledges a dequeued job as successfully finished
+	Complete(ctx context.Context, jobID string) error
+	// Fail records a failed attempt and schedules a retry with exponential backoff,
+	// or moves the job to the dead letter queue once its retries are exhausted
+	Fail(ctx context.Context, j *job.Job, errMsg string) error
+	// FailAfter is like Fail, but a retry is scheduled after delay
+	FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error
+	// DeadLetter moves a job straight to the dead letter queue without retrying
+	DeadLetter(ctx context.Context, j *job.Job, errMsg string) error
+	// Expire discards a dequeued job whose deadline passed before it started
+	Expire(ctx context.Context, j *job.Job, reason string) error
+	// Requeue returns a dequeued job to the front of its queue without counting an attempt
+	Requeue(ctx context.Context, j *job.Job) error
+}
+
+// Broker stores jobs and hands them to workers
+//
+// RedisQueue is the production implementation. Clients depend on Producer, workers
+// on Consumer and schedulers on the maintenance methods, so any Broker can back
+// them. Every implementation must pass the conformance suite in package queuetest.
+type Broker interface {
+	Producer
+	Consumer
+
+	// MoveScheduledToReady moves scheduled jobs and retries whose time has come to
+	// their ready queues, returning how many were moved
+	MoveScheduledToReady(ctx context.Context) (int, error)
+	// GetJob returns a job by ID, or an error wrapping ErrJobNotFound
+	GetJob(ctx context.Context, jobID string) (*job.Job, error)
+	// Cancel removes a job that is still waiting or scheduled and marks it cancelled
+	// Running and finished jobs return an error wrapping ErrNotCancellable.
+	Cancel(ctx context.Context, jobID string) error
+	// Stats returns the number of jobs in each state
+	Stats(ctx context.Context) (Stats, error)
+	// Close releases the broker's resources
+	Close() error
+}
+
+// Stats counts a broker's jobs by state
+type Stats struct {
+	// Ready is the number of jobs waiting in each shared priority queue
+	// Jobs waiting on routing keys or dedicated job name queues are not included.
+	Ready map[job.JobPriority]int64 `json:"ready"`
+	// Scheduled is the number of jobs waiting for their scheduled time or a retry
+	Scheduled int64 `json:"scheduled"`
+	// Waiting is the number of jobs waiting for the jobs they depend on
+	Waiting int64 `json:"waiting"`
+	// Processing is the number of jobs dequeued by workers and not yet settled
+	Processing int64 `json:"processing"`
+	// Dead is the number of jobs in the dead letter queue
+	Dead int64 `json:"dead"`
+}
+
+// TotalReady returns the number of ready jobs across priorities
+func (s Stats) TotalReady() int64 {
+	var total int64
+	for _, n := range s.Ready {
+		total += n
+	}
+	return total
+}
+
+// RedisQueue is the Redis implementation of Broker
+var _ Broker = (*RedisQueue)(nil)
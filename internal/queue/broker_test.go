@@ -0,0 +1,113 @@
+package queue_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/queue/queuetest"
+)
+
+func TestRedisQueue_Conformance(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		mr := miniredis.RunT(t)
+		q, err := queue.NewRedisQueue("redis://" + mr.Addr())
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		// RedisQueue schedules on the wall clock
+		return q, nil
+	})
+}
+
+func TestStreamQueue_Conformance(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		mr := miniredis.RunT(t)
+		q, err := queue.NewStreamQueue("redis://" + mr.Addr())
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		q.SetDequeueTimeout(100 * time.Millisecond)
+		return q, nil
+	})
+}
+
+func TestRedisQueue_ClusterConformance(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		// miniredis answers CLUSTER SLOTS as a single node owning every slot
+		mr := miniredis.RunT(t)
+		q, err := queue.NewRedisQueue("redis+cluster://" + mr.Addr())
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		return q, nil
+	})
+}
+
+func TestStreamQueue_ClusterConformance(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		mr := miniredis.RunT(t)
+		q, err := queue.NewStreamQueue("redis+cluster://" + mr.Addr())
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		q.SetDequeueTimeout(100 * time.Millisecond)
+		return q, nil
+	})
+}
+
+// TestRedisQueue_LocalClusterConformance runs the suite against a real Redis Cluster,
+// which (unlike miniredis) rejects cross-slot commands
+func TestRedisQueue_LocalClusterConformance(t *testing.T) {
+	url := os.Getenv("BANANAS_TEST_REDIS_CLUSTER_URL")
+	if url == "" {
+		t.Skip("BANANAS_TEST_REDIS_CLUSTER_URL not set")
+	}
+
+	run := 0
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		q, err := queue.NewRedisQueue(url)
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		// A fresh namespace per test keeps runs apart without flushing the cluster
+		run++
+		if err := q.SetNamespace(fmt.Sprintf("conformance-%d-%d", time.Now().UnixNano(), run)); err != nil {
+			t.Fatalf("failed to set namespace: %v", err)
+		}
+		return q, nil
+	})
+}
+
+func TestMemoryBroker_Conformance(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		clock := queue.NewFakeClock(time.Now())
+		b := queue.NewMemoryBrokerWithClock(clock)
+		b.SetDequeueTimeout(100 * time.Millisecond)
+		return b, clock.Advance
+	})
+}
+
+func TestPostgresQueue_Conformance(t *testing.T) {
+	url := os.Getenv("BANANAS_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("BANANAS_TEST_POSTGRES_URL not set")
+	}
+
+	queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+		q, err := queue.NewPostgresQueue(url)
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		if _, err := q.Pool().Exec(context.Background(), "TRUNCATE bananas_jobs"); err != nil {
+			t.Fatalf("failed to empty jobs table: %v", err)
+		}
+		q.SetDequeueTimeout(200 * time.Millisecond)
+		return q, nil
+	})
+}
@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// checkpointKey returns the Redis key holding a job's latest checkpoint
+func (q *RedisQueue) checkpointKey(jobID string) string {
+	return q.jobKey(jobID) + ":checkpoint"
+}
+
+// SaveCheckpoint stores a running job's checkpoint, replacing the previous one
+//
+// Checkpoints survive retries (including reaped leases), so the next attempt can
+// resume where the last one stopped. They are deleted when the job completes or
+// expires, and expire with the job data in the dead letter queue. Checkpoints are
+// encrypted like payloads when an encryptor is set.
+func (q *RedisQueue) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	if q.encryptor != nil {
+		encrypted, err := q.encryptor.Encrypt(data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt checkpoint: %w", err)
+		}
+		data = encrypted
+	}
+
+	if err := q.client.Set(ctx, q.checkpointKey(jobID), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint returns a job's latest checkpoint, or nil if it has none
+func (q *RedisQueue) LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error) {
+	data, err := q.client.Get(ctx, q.checkpointKey(jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	if q.encryptor != nil {
+		data, err = q.encryptor.Decrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt checkpoint: %w", err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func TestCheckpoint_SurvivesRetry(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	dequeued, _ := queue.Dequeue(ctx, allPriorities)
+
+	if data, err := queue.LoadCheckpoint(ctx, j.ID); err != nil || data != nil {
+		t.Fatalf("expected no checkpoint, got %q (%v)", data, err)
+	}
+
+	if err := queue.SaveCheckpoint(ctx, j.ID, []byte(`{"offset":10}`)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := queue.SaveCheckpoint(ctx, j.ID, []byte(`{"offset":20}`)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	queue.Fail(ctx, dequeued, "boom")
+
+	data, err := queue.LoadCheckpoint(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(data) != `{"offset":20}` {
+		t.Errorf("expected latest checkpoint after retry, got %q", data)
+	}
+}
+
+func TestCheckpoint_DeletedOnComplete(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+
+	queue.SaveCheckpoint(ctx, j.ID, []byte(`{"offset":10}`))
+	if err := queue.Complete(ctx, j.ID); err != nil {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+
+	if mr.Exists(queue.checkpointKey(j.ID)) {
+		t.Error("expected checkpoint to be deleted")
+	}
+}
+
+func TestCheckpoint_ExpiresWithDeadLetteredJob(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	dequeued, _ := queue.Dequeue(ctx, allPriorities)
+
+	queue.SaveCheckpoint(ctx, j.ID, []byte(`{"offset":10}`))
+	queue.DeadLetter(ctx, dequeued, "bad input")
+
+	if ttl := mr.TTL(queue.checkpointKey(j.ID)); ttl != queue.failedJobTTL {
+		t.Errorf("expected checkpoint TTL %v, got %v", queue.failedJobTTL, ttl)
+	}
+}
+
+func TestCheckpoint_Encrypted(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	queue.SetEncryptor(newTestEncryptor(t))
+
+	ctx := context.Background()
+	checkpoint := []byte(`{"last_email":"jane@example.com"}`)
+	if err := queue.SaveCheckpoint(ctx, "job-1", checkpoint); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	raw, _ := mr.Get(queue.checkpointKey("job-1"))
+	if bytes.Contains([]byte(raw), []byte("jane@example.com")) {
+		t.Error("expected checkpoint to be encrypted at rest")
+	}
+
+	data, err := queue.LoadCheckpoint(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(data, checkpoint) {
+		t.Errorf("expected decrypted checkpoint, got %q", data)
+	}
+}
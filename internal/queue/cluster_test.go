@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// assertHashTagged checks that every key in Redis belongs to the namespace's hash tag,
+// i.e. hashes to the same Cluster slot
+func assertHashTagged(t *testing.T, mr *miniredis.Miniredis, prefix string) {
+	t.Helper()
+	keys := mr.Keys()
+	if len(keys) == 0 {
+		t.Fatal("expected keys in Redis")
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			t.Errorf("expected key %q to start with %q", key, prefix)
+		}
+	}
+}
+
+func TestNewRedisQueue_ClusterUsesHashTaggedKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q, err := NewRedisQueue("redis+cluster://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.Close()
+	ctx := context.Background()
+
+	if q.keyPrefix != "{bananas}:" {
+		t.Errorf("expected keyPrefix '{bananas}:', got %q", q.keyPrefix)
+	}
+
+	// Exercise the multi-key paths: enqueue, dequeue, retry, schedule, dead letter
+	if err := q.DedicateJobNames(ctx, "report"); err != nil {
+		t.Fatalf("failed to dedicate job names: %v", err)
+	}
+	q.SetQuota(Quota{MaxQueuedJobs: 10})
+	q.EnableEventStream(100)
+	for _, name := range []string{"a", "report"} {
+		if err := q.Enqueue(ctx, job.NewJob(name, []byte(`{}`), job.PriorityNormal)); err != nil {
+			t.Fatalf("failed to enqueue %s: %v", name, err)
+		}
+	}
+	j, err := q.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	if err != nil || j == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+	if err := q.Fail(ctx, j, "boom"); err != nil {
+		t.Fatalf("failed to fail job: %v", err)
+	}
+	if _, err := q.MoveScheduledToReady(ctx); err != nil {
+		t.Fatalf("failed to move scheduled jobs: %v", err)
+	}
+	if err := q.Enqueue(ctx, job.NewJob("b", []byte(`{}`), job.PriorityHigh)); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	j, err = q.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+	if err != nil || j == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+	if err := q.DeadLetter(ctx, j, "gave up"); err != nil {
+		t.Fatalf("failed to dead letter job: %v", err)
+	}
+
+	assertHashTagged(t, mr, "{bananas}:")
+}
+
+func TestSetNamespace_ClusterUsesHashTaggedKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q, err := NewRedisQueue("redis+cluster://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.Close()
+
+	if err := q.SetNamespace("acme"); err != nil {
+		t.Fatalf("failed to set namespace: %v", err)
+	}
+	if err := q.Enqueue(context.Background(), job.NewJob("a", []byte(`{}`), job.PriorityHigh)); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	assertHashTagged(t, mr, "{acme}:")
+}
+
+func TestNewStreamQueue_ClusterUsesHashTaggedKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q, err := NewStreamQueue("redis+cluster://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.Close()
+	q.SetDequeueTimeout(50 * time.Millisecond)
+	ctx := context.Background()
+
+	if err := q.Enqueue(ctx, job.NewJob("a", []byte(`{}`), job.PriorityLow)); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	j, err := q.Dequeue(ctx, allPriorities)
+	if err != nil || j == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+	if err := q.Complete(ctx, j.ID); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	assertHashTagged(t, mr, "{bananas}:")
+}
@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// dependenciesKey returns the Redis key holding the parents a waiting job still waits for
+func (q *RedisQueue) dependenciesKey(jobID string) string {
+	return q.jobKey(jobID) + ":deps"
+}
+
+// EnqueueDAG submits jobs that depend on each other (see job.Job.After)
+//
+// Jobs without dependencies are enqueued like Enqueue. The others are stored with
+// status waiting and moved to their ready queue once all their parents have
+// finished, by the Complete (or dead letter, Expire or Cancel) of the last one.
+// Cycles and dependencies on jobs outside the submission are rejected before
+// anything is stored. If enqueueing fails part way, the waiting jobs are cancelled;
+// jobs without dependencies that were already enqueued still run.
+// Waiting jobs count against the MaxQueuedJobs quota once they are released.
+func (q *RedisQueue) EnqueueDAG(ctx context.Context, jobs []*job.Job) error {
+	if err := job.LinkDAG(jobs); err != nil {
+		return err
+	}
+
+	// Dependents are stored before their parents are enqueued, so no parent can
+	// finish before the jobs waiting for it exist
+	var waiting []*job.Job
+	for _, j := range jobs {
+		if !j.HasDependencies() {
+			continue
+		}
+		if err := q.storeWaiting(ctx, j); err != nil {
+			q.abandonDAG(ctx, waiting)
+			return err
+		}
+		waiting = append(waiting, j)
+	}
+
+	for _, j := range jobs {
+		if j.HasDependencies() {
+			continue
+		}
+		if err := q.Enqueue(ctx, j); err != nil {
+			q.abandonDAG(ctx, waiting)
+			return err
+		}
+	}
+	return nil
+}
+
+// storeWaiting stores a new job that waits for the jobs it depends on
+func (q *RedisQueue) storeWaiting(ctx context.Context, j *job.Job) error {
+	if err := q.encryptPayload(j); err != nil {
+		return err
+	}
+	if err := q.offloadPayload(ctx, j); err != nil {
+		return err
+	}
+
+	j.UpdateStatus(job.StatusWaiting)
+	jobData, err := json.Marshal(j)
+	if err != nil {
+		q.releasePayload(ctx, j)
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	parents := make([]interface{}, len(j.DependsOn))
+	for i, parentID := range j.DependsOn {
+		parents[i] = parentID
+	}
+
+	pipe := q.client.TxPipeline()
+	pipe.Set(ctx, q.jobKey(j.ID), jobData, 0)
+	pipe.SAdd(ctx, q.dependenciesKey(j.ID), parents...)
+	pipe.SAdd(ctx, q.waitingKey, j.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		q.releasePayload(ctx, j)
+		return fmt.Errorf("failed to store waiting job: %w", err)
+	}
+
+	log.Printf("Job %s waiting for %d jobs", j.ID, len(j.DependsOn))
+	q.hooks.Emit(ctx, events.TypeEnqueue, j)
+	return nil
+}
+
+// abandonDAG cancels the waiting jobs of a DAG submission that failed (best-effort)
+func (q *RedisQueue) abandonDAG(ctx context.Context, waiting []*job.Job) {
+	for _, j := range waiting {
+		if err := q.Cancel(ctx, j.ID); err != nil {
+			log.Printf("Failed to cancel waiting job %s of failed DAG submission: %v", j.ID, err)
+		}
+	}
+}
+
+// settleDependents releases or cancels the jobs waiting for a parent that finished
+//
+// A dependent is released once its last parent has finished. If the parent didn't
+// succeed, dependents with the cancel policy are cancelled instead, which in turn
+// settles their own dependents. Errors are logged: the parent's transition has
+// already happened.
+func (q *RedisQueue) settleDependents(ctx context.Context, parent *job.Job, succeeded bool) {
+	for _, childID := range parent.Dependents {
+		child, err := q.GetJob(ctx, childID)
+		if err != nil {
+			log.Printf("Failed to load job %s depending on %s: %v", childID, parent.ID, err)
+			continue
+		}
+		if child.Status != job.StatusWaiting {
+			continue
+		}
+
+		if !succeeded && child.CancelsOnParentFailure() {
+			err = q.cancelDependent(ctx, child, parent.ID)
+		} else {
+			err = q.releaseDependent(ctx, child, parent.ID)
+		}
+		if err != nil {
+			log.Printf("Failed to settle job %s depending on %s: %v", childID, parent.ID, err)
+		}
+	}
+}
+
+// releaseDependent resolves a waiting job's dependency on parentID, moving the job to
+// its ready queue if that was the last one
+func (q *RedisQueue) releaseDependent(ctx context.Context, j *job.Job, parentID string) error {
+	j.UpdateStatus(job.StatusPending)
+	jobData, err := q.marshalJob(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	dedicated, err := q.isDedicated(ctx, j.Name)
+	if err != nil {
+		return err
+	}
+	queueKey := q.readyQueueKey(j, dedicated)
+
+	keys := []string{q.dependenciesKey(j.ID), q.waitingKey, q.jobKey(j.ID), queueKey, q.outstandingKey}
+	released, err := releaseScript.Run(ctx, q.client, keys, j.ID, parentID, jobData).Int()
+	if err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	if released == 1 {
+		log.Printf("Released job %s to %s after its dependencies finished", j.ID, queueKey)
+		q.hooks.Emit(ctx, events.TypeRelease, j)
+	}
+	return nil
+}
+
+// cancelDependent cancels a waiting job because parentID didn't succeed, then settles
+// the jobs depending on it
+func (q *RedisQueue) cancelDependent(ctx context.Context, j *job.Job, parentID string) error {
+	j.UpdateStatus(job.StatusCancelled)
+	j.Error = fmt.Sprintf("dependency %s did not complete", parentID)
+	jobData, err := q.marshalJob(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	keys := []string{q.waitingKey, q.jobKey(j.ID), q.dependenciesKey(j.ID)}
+	cancelled, err := cancelWaitingScript.Run(ctx, q.client, keys, j.ID, jobData, q.completedJobTTL.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if cancelled == 0 {
+		return nil
+	}
+
+	// The payload will never be needed
+	q.releasePayload(ctx, j)
+
+	log.Printf("Cancelled job %s: %s", j.ID, j.Error)
+	q.hooks.Emit(ctx, events.TypeCancel, j)
+	q.settleDependents(ctx, j, false)
+	return nil
+}
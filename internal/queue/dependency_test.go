@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// diamond returns two imports and a report that runs after both
+func diamond() (a, b, report *job.Job) {
+	a = job.NewJob("import", []byte(`{"file":"a"}`), job.PriorityNormal)
+	b = job.NewJob("import", []byte(`{"file":"b"}`), job.PriorityNormal)
+	report = job.NewJob("report", []byte(`{}`), job.PriorityNormal)
+	report.After(a, b)
+	return a, b, report
+}
+
+// jobStatus returns a job's stored status
+func jobStatus(t *testing.T, q *RedisQueue, jobID string) job.JobStatus {
+	t.Helper()
+	j, err := q.GetJob(context.Background(), jobID)
+	if err != nil {
+		t.Fatalf("failed to get job %s: %v", jobID, err)
+	}
+	return j.Status
+}
+
+func TestEnqueueDAG_ReleasesAfterAllParentsComplete(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	a, b, report := diamond()
+	if err := queue.EnqueueDAG(ctx, []*job.Job{report, a, b}); err != nil {
+		t.Fatalf("failed to enqueue DAG: %v", err)
+	}
+	if status := jobStatus(t, queue, report.ID); status != job.StatusWaiting {
+		t.Fatalf("expected report to be waiting, got %s", status)
+	}
+	if stats, _ := queue.Stats(ctx); stats.Waiting != 1 || stats.Ready[job.PriorityNormal] != 2 {
+		t.Fatalf("expected 1 waiting and 2 ready jobs, got %+v", stats)
+	}
+
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	if status := jobStatus(t, queue, report.ID); status != job.StatusWaiting {
+		t.Fatalf("expected report to wait for the second import, got %s", status)
+	}
+
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	if status := jobStatus(t, queue, report.ID); status != job.StatusPending {
+		t.Fatalf("expected report to be released, got %s", status)
+	}
+	if ready, _ := mr.List(queue.queueKey(job.PriorityNormal)); len(ready) != 1 || ready[0] != report.ID {
+		t.Errorf("expected report in its ready queue, got %v", ready)
+	}
+	if mr.Exists(queue.dependenciesKey(report.ID)) {
+		t.Error("expected the dependency set to be deleted")
+	}
+	if stats, _ := queue.Stats(ctx); stats.Waiting != 0 {
+		t.Errorf("expected no waiting jobs, got %d", stats.Waiting)
+	}
+}
+
+func TestEnqueueDAG_ParentFailureCancelsDownstream(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	a, b, report := diamond()
+	notify := job.NewJob("notify", []byte(`{}`), job.PriorityNormal)
+	notify.After(report)
+	if err := queue.EnqueueDAG(ctx, []*job.Job{a, b, report, notify}); err != nil {
+		t.Fatalf("failed to enqueue DAG: %v", err)
+	}
+
+	queue.DeadLetter(ctx, dequeueNormal(t, queue), "bad file")
+
+	for _, j := range []*job.Job{report, notify} {
+		stored, _ := queue.GetJob(ctx, j.ID)
+		if stored.Status != job.StatusCancelled {
+			t.Errorf("expected %s to be cancelled, got %s", j.Name, stored.Status)
+		}
+		if stored.Error == "" {
+			t.Errorf("expected %s to record why it was cancelled", j.Name)
+		}
+	}
+
+	// The other import still runs, and completing it releases nothing
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	if ready, _ := mr.List(queue.queueKey(job.PriorityNormal)); len(ready) != 0 {
+		t.Errorf("expected no released jobs, got %v", ready)
+	}
+}
+
+func TestEnqueueDAG_ContinueOnParentFailure(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	a, b, report := diamond()
+	report.SetOnParentFailure(job.ContinueOnParentFailure)
+	if err := queue.EnqueueDAG(ctx, []*job.Job{a, b, report}); err != nil {
+		t.Fatalf("failed to enqueue DAG: %v", err)
+	}
+
+	queue.DeadLetter(ctx, dequeueNormal(t, queue), "bad file")
+	if status := jobStatus(t, queue, report.ID); status != job.StatusWaiting {
+		t.Fatalf("expected report to keep waiting, got %s", status)
+	}
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	if status := jobStatus(t, queue, report.ID); status != job.StatusPending {
+		t.Errorf("expected report to be released, got %s", status)
+	}
+}
+
+func TestEnqueueDAG_RejectsCycle(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	a := job.NewJob("a", []byte(`{}`), job.PriorityNormal)
+	b := job.NewJob("b", []byte(`{}`), job.PriorityNormal)
+	root := job.NewJob("root", []byte(`{}`), job.PriorityNormal)
+	a.After(b)
+	b.After(a)
+
+	if err := queue.EnqueueDAG(ctx, []*job.Job{root, a, b}); !errors.Is(err, job.ErrDependencyCycle) {
+		t.Fatalf("expected ErrDependencyCycle, got %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("expected nothing stored, got %v", keys)
+	}
+}
+
+func TestCancel_WaitingJob(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	a, b, report := diamond()
+	notify := job.NewJob("notify", []byte(`{}`), job.PriorityNormal)
+	notify.After(report)
+	queue.EnqueueDAG(ctx, []*job.Job{a, b, report, notify})
+
+	if err := queue.Cancel(ctx, report.ID); err != nil {
+		t.Fatalf("failed to cancel waiting job: %v", err)
+	}
+	if status := jobStatus(t, queue, notify.ID); status != job.StatusCancelled {
+		t.Errorf("expected the cancellation to propagate, got %s", status)
+	}
+
+	// Parents completing later don't resurrect it
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	if status := jobStatus(t, queue, report.ID); status != job.StatusCancelled {
+		t.Errorf("expected report to stay cancelled, got %s", status)
+	}
+}
+
+func TestEnqueueDAG_ConcurrentParentsReleaseOnce(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		a, b, report := diamond()
+		queue.EnqueueDAG(ctx, []*job.Job{a, b, report})
+		first, second := dequeueNormal(t, queue), dequeueNormal(t, queue)
+
+		var wg sync.WaitGroup
+		for _, j := range []*job.Job{first, second} {
+			wg.Add(1)
+			go func(q *RedisQueue, id string) {
+				defer wg.Done()
+				q.Complete(ctx, id)
+			}(newQueueOn(t, mr), j.ID)
+		}
+		wg.Wait()
+
+		ready, _ := mr.List(queue.queueKey(job.PriorityNormal))
+		if len(ready) != 1 || ready[0] != report.ID {
+			t.Fatalf("expected report released exactly once, got %v", ready)
+		}
+		queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	}
+}
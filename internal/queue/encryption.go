@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+)
+
+// SetEncryptor enables encryption of job payloads at rest
+// Payloads are encrypted on Enqueue (before any blob offloading), so neither Redis
+// nor the blob store ever holds them in plain text. Passing nil disables encryption.
+func (q *RedisQueue) SetEncryptor(enc *serialization.Encryptor) {
+	q.encryptor = enc
+}
+
+// encryptPayload encrypts a job's inline payload if encryption is enabled
+// Payloads that are already encrypted (e.g., via job.SetPayload) are left as-is
+func (q *RedisQueue) encryptPayload(j *job.Job) error {
+	if q.encryptor == nil || len(j.Payload) == 0 || j.IsEncryptedPayload() {
+		return nil
+	}
+
+	encrypted, err := q.encryptor.Encrypt(j.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+
+	j.Payload = encrypted
+	return nil
+}
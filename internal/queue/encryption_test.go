@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+)
+
+func newTestEncryptor(t *testing.T) *serialization.Encryptor {
+	t.Helper()
+	keys, err := serialization.NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	return serialization.NewEncryptor(keys)
+}
+
+func TestEnqueue_EncryptsPayload(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	enc := newTestEncryptor(t)
+	queue.SetEncryptor(enc)
+
+	original := job.DefaultEncryptor
+	job.DefaultEncryptor = enc
+	defer func() { job.DefaultEncryptor = original }()
+
+	ctx := context.Background()
+	j := job.NewJob("pii_job", []byte(`{"email":"jane@example.com"}`), job.PriorityNormal)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	raw, err := mr.Get(queue.jobKey(j.ID))
+	if err != nil {
+		t.Fatalf("failed to read raw job data: %v", err)
+	}
+	if bytes.Contains([]byte(raw), []byte("jane@example.com")) {
+		t.Error("payload stored in plain text")
+	}
+
+	dequeued, err := queue.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	if err != nil || dequeued == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+	if !dequeued.IsEncryptedPayload() {
+		t.Error("expected dequeued payload to still be encrypted")
+	}
+
+	var payload map[string]string
+	if err := dequeued.UnmarshalPayload(&payload); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+	if payload["email"] != "jane@example.com" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+
+	// Retries write the payload back still encrypted
+	if err := queue.Fail(ctx, dequeued, "retry"); err != nil {
+		t.Fatalf("failed to fail job: %v", err)
+	}
+	raw, _ = mr.Get(queue.jobKey(j.ID))
+	if bytes.Contains([]byte(raw), []byte("jane@example.com")) {
+		t.Error("payload written back in plain text after Fail")
+	}
+}
+
+func TestEnqueue_EncryptsBeforeOffload(t *testing.T) {
+	queue, store := setupOffloadQueue(t, 8)
+	queue.SetEncryptor(newTestEncryptor(t))
+
+	ctx := context.Background()
+	j := job.NewJob("pii_job", []byte(`{"email":"jane@example.com"}`), job.PriorityNormal)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	blob, err := store.Get(ctx, payloadBlobKey(j.ID))
+	if err != nil {
+		t.Fatalf("expected payload to be offloaded: %v", err)
+	}
+	if !serialization.IsEncrypted(blob) {
+		t.Error("expected offloaded blob to be encrypted")
+	}
+}
@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"github.com/muaviaUsmani/bananas/internal/events"
+)
+
+// Hooks returns the registry that receives this queue's job lifecycle events
+// (enqueue, success, failure, dead, expired, requeue, release and cancel). Register in-process callbacks on it, or
+// share it with the Executor so start events go to the same hooks and sinks.
+func (q *RedisQueue) Hooks() *events.Registry {
+	return q.hooks
+}
+
+// SetHooks replaces the lifecycle hook registry
+func (q *RedisQueue) SetHooks(hooks *events.Registry) {
+	q.hooks = hooks
+}
+
+// EnableEventStream appends every job state transition to the Redis stream
+// <prefix>events, trimmed to roughly maxLen entries (<= 0 disables trimming).
+// Other services consume it with consumer groups (see events.StreamConsumer).
+func (q *RedisQueue) EnableEventStream(maxLen int64) {
+	q.hooks.AddSink(events.NewRedisStream(q.client, q.EventStreamKey(), maxLen))
+}
+
+// EventStreamKey returns the Redis key of the job event stream, including the
+// queue's key prefix (see SetNamespace)
+func (q *RedisQueue) EventStreamKey() string {
+	return q.keyPrefix + "events"
+}
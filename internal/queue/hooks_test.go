@@ -0,0 +1,150 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func TestHooks_QueueTransitions(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	var got []events.Type
+	record := func(ctx context.Context, e events.Event) { got = append(got, e.Type) }
+	hooks := queue.Hooks()
+	hooks.OnEnqueue(record)
+	hooks.OnSuccess(record)
+	hooks.OnFailure(record)
+	hooks.OnDead(record)
+
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+
+	// Enqueue -> success
+	ok := job.NewJob("ok_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, ok)
+	queue.Dequeue(ctx, priorities)
+	if err := queue.Complete(ctx, ok.ID); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	// Enqueue -> failure (retry) -> dead
+	bad := job.NewJob("bad_job", []byte(`{}`), job.PriorityNormal)
+	bad.MaxRetries = 2
+	queue.Enqueue(ctx, bad)
+	dequeued, _ := queue.Dequeue(ctx, priorities)
+	queue.Fail(ctx, dequeued, "first")
+	// Make the retry due now and run it, so the second attempt fails too
+	mr.ZAdd(queue.getScheduledSetKey(), 0, bad.ID)
+	queue.MoveScheduledToReady(ctx)
+	dequeued, _ = queue.Dequeue(ctx, priorities)
+	queue.Fail(ctx, dequeued, "second")
+
+	expected := []events.Type{
+		events.TypeEnqueue, events.TypeSuccess,
+		events.TypeEnqueue, events.TypeFailure, events.TypeDead,
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("event %d: expected %s, got %s", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestEnableEventStream(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.EnableEventStream(1000)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityHigh)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+	queue.DeadLetter(ctx, j, "invalid payload")
+
+	entries, err := queue.client.XRange(ctx, queue.EventStreamKey(), "-", "+").Result()
+	if err != nil {
+		t.Fatalf("failed to read event stream: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 stream entries, got %d", len(entries))
+	}
+	if entries[0].Values["type"] != "enqueue" || entries[1].Values["type"] != "dead" {
+		t.Errorf("unexpected event types: %v, %v", entries[0].Values["type"], entries[1].Values["type"])
+	}
+	if entries[1].Values["job_id"] != j.ID || entries[1].Values["error"] != "invalid payload" {
+		t.Errorf("unexpected dead event: %v", entries[1].Values)
+	}
+}
+
+func TestHooks_RequeueReleaseAndCancel(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	var got []events.Type
+	record := func(ctx context.Context, e events.Event) { got = append(got, e.Type) }
+	hooks := queue.Hooks()
+	hooks.OnRequeue(record)
+	hooks.OnRelease(record)
+	hooks.OnCancel(record)
+
+	// The first import is requeued, then completed, releasing nothing yet
+	a, b, report := diamond()
+	notify := job.NewJob("notify", []byte(`{}`), job.PriorityNormal)
+	notify.After(report)
+	if err := queue.EnqueueDAG(ctx, []*job.Job{a, b, report, notify}); err != nil {
+		t.Fatalf("failed to enqueue DAG: %v", err)
+	}
+	if err := queue.Requeue(ctx, dequeueNormal(t, queue)); err != nil {
+		t.Fatalf("failed to requeue: %v", err)
+	}
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+	queue.Complete(ctx, dequeueNormal(t, queue).ID)
+
+	// The released report is cancelled, which cancels notify
+	if err := queue.Cancel(ctx, report.ID); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+
+	expected := []events.Type{events.TypeRequeue, events.TypeRelease, events.TypeCancel, events.TypeCancel}
+	if len(got) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("event %d: expected %s, got %s", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestEnableEventStream_Namespaced(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	if err := queue.SetNamespace("acme"); err != nil {
+		t.Fatalf("failed to set namespace: %v", err)
+	}
+	queue.EnableEventStream(1000)
+	queue.Enqueue(ctx, job.NewJob("test_job", []byte(`{}`), job.PriorityHigh))
+
+	key := queue.EventStreamKey()
+	if key == "bananas:events" || !strings.HasSuffix(key, "events") {
+		t.Fatalf("expected a namespaced event stream key, got %q", key)
+	}
+	if !mr.Exists(key) {
+		t.Errorf("expected events in %s, got keys %v", key, mr.Keys())
+	}
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultIdempotencyTTL is how long a completed idempotency key is remembered by default
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// recordCompletionScript records the job that completed an idempotency key unless one already did
+// KEYS: completion record
+// ARGV: job ID, TTL (ms)
+// Returns the ID of the job the key is recorded for.
+var recordCompletionScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner then
+	return owner
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return ARGV[1]
+`)
+
+// SetIdempotencyTTL sets how long completed idempotency keys are remembered
+// Redeliveries and duplicate jobs arriving later run again. Keep it at least as
+// long as the result backend's success TTL so duplicates can return the stored
+// result. Non-positive values reset it to DefaultIdempotencyTTL.
+func (q *RedisQueue) SetIdempotencyTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	q.idempotencyTTL = ttl
+}
+
+// idempotencyKey returns the Redis key holding an idempotency key's completion record
+func (q *RedisQueue) idempotencyKey(key string) string {
+	return q.keyPrefix + "idempotency:" + key
+}
+
+// RecordCompletion records that jobID completed the work identified by key
+//
+// The record is set atomically and only once: if another job already completed
+// the key, its ID is returned and the record is left unchanged. Executors record
+// running jobs with CompleteIdempotent instead, which also acknowledges the job.
+func (q *RedisQueue) RecordCompletion(ctx context.Context, key, jobID string) (string, error) {
+	owner, err := recordCompletionScript.Run(ctx, q.client, []string{q.idempotencyKey(key)},
+		jobID, q.idempotencyTTL.Milliseconds()).Text()
+	if err != nil {
+		return "", fmt.Errorf("failed to record completion of idempotency key %s: %w", key, err)
+	}
+	return owner, nil
+}
+
+// CompletedBy returns the ID of the job that completed key, or "" if none has
+func (q *RedisQueue) CompletedBy(ctx context.Context, key string) (string, error) {
+	owner, err := q.client.Get(ctx, q.idempotencyKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up idempotency key %s: %w", key, err)
+	}
+	return owner, nil
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func TestRecordCompletion_FirstJobWins(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	if owner, _ := queue.CompletedBy(ctx, "charge:p-1"); owner != "" {
+		t.Fatalf("expected no completion yet, got %q", owner)
+	}
+	if owner, err := queue.RecordCompletion(ctx, "charge:p-1", "job-1"); err != nil || owner != "job-1" {
+		t.Fatalf("expected job-1 to record the key, got %q (%v)", owner, err)
+	}
+	if owner, _ := queue.RecordCompletion(ctx, "charge:p-1", "job-2"); owner != "job-1" {
+		t.Errorf("expected the first record to stand, got %q", owner)
+	}
+	if owner, _ := queue.CompletedBy(ctx, "charge:p-1"); owner != "job-1" {
+		t.Errorf("expected job-1, got %q", owner)
+	}
+
+	if ttl := mr.TTL(queue.idempotencyKey("charge:p-1")); ttl != DefaultIdempotencyTTL {
+		t.Errorf("expected TTL %v, got %v", DefaultIdempotencyTTL, ttl)
+	}
+}
+
+func TestRecordCompletion_Concurrent(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	owners := make([]string, 10)
+	var wg sync.WaitGroup
+	for i := range owners {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q := newQueueOn(t, mr)
+			owners[i], _ = q.RecordCompletion(ctx, "charge:p-1", string(rune('a'+i)))
+		}(i)
+	}
+	wg.Wait()
+
+	for _, owner := range owners {
+		if owner != owners[0] || owner == "" {
+			t.Fatalf("expected every caller to see the same owner, got %v", owners)
+		}
+	}
+}
+
+func TestCompleteIdempotent_RecordsAndCompletes(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("charge", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+
+	if err := queue.CompleteIdempotent(ctx, j.ID, "charge:p-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if owner, _ := queue.CompletedBy(ctx, "charge:p-1"); owner != j.ID {
+		t.Errorf("expected %s to own the key, got %q", j.ID, owner)
+	}
+	if ttl := mr.TTL(queue.idempotencyKey("charge:p-1")); ttl != DefaultIdempotencyTTL {
+		t.Errorf("expected TTL %v, got %v", DefaultIdempotencyTTL, ttl)
+	}
+	if stored, _ := queue.GetJob(ctx, j.ID); stored.Status != job.StatusCompleted {
+		t.Errorf("expected job to be completed, got %s", stored.Status)
+	}
+}
+
+func TestCompleteIdempotent_NotRunningRecordsNothing(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("charge", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+
+	if err := queue.CompleteIdempotent(ctx, j.ID, "charge:p-1"); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if owner, _ := queue.CompletedBy(ctx, "charge:p-1"); owner != "" {
+		t.Errorf("expected no completion record, got %q", owner)
+	}
+}
+
+func TestSetIdempotencyTTL(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	queue.SetIdempotencyTTL(time.Hour)
+	queue.RecordCompletion(ctx, "charge:p-1", "job-1")
+	if ttl := mr.TTL(queue.idempotencyKey("charge:p-1")); ttl != time.Hour {
+		t.Errorf("expected TTL 1h, got %v", ttl)
+	}
+
+	mr.FastForward(time.Hour)
+	if owner, _ := queue.CompletedBy(ctx, "charge:p-1"); owner != "" {
+		t.Errorf("expected the record to expire, got %q", owner)
+	}
+}
+
+func TestMemoryBroker_CompletionRecordExpires(t *testing.T) {
+	ctx := context.Background()
+	b, clock := newTestMemoryBroker()
+	b.SetIdempotencyTTL(time.Hour)
+
+	b.RecordCompletion(ctx, "charge:p-1", "job-1")
+	if owner, _ := b.RecordCompletion(ctx, "charge:p-1", "job-2"); owner != "job-1" {
+		t.Errorf("expected the first record to stand, got %q", owner)
+	}
+
+	clock.Advance(time.Hour)
+	if owner, _ := b.CompletedBy(ctx, "charge:p-1"); owner != "" {
+		t.Errorf("expected the record to expire, got %q", owner)
+	}
+}
+
+func TestMemoryBroker_CompleteIdempotent(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestMemoryBroker()
+
+	j := job.NewJob("charge", []byte(`{}`), job.PriorityNormal)
+	b.Enqueue(ctx, j)
+	if err := b.CompleteIdempotent(ctx, j.ID, "charge:p-1"); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost before the job runs, got %v", err)
+	}
+	if owner, _ := b.CompletedBy(ctx, "charge:p-1"); owner != "" {
+		t.Fatalf("expected no completion record, got %q", owner)
+	}
+
+	b.Dequeue(ctx, allPriorities)
+	if err := b.CompleteIdempotent(ctx, j.ID, "charge:p-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if owner, _ := b.CompletedBy(ctx, "charge:p-1"); owner != j.ID {
+		t.Errorf("expected %s to own the key, got %q", j.ID, owner)
+	}
+}
@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultLeaseDuration is how long a dequeued job stays leased without a heartbeat
+const DefaultLeaseDuration = time.Minute
+
+// ErrLeaseLost is returned by Heartbeat when the job is no longer leased, e.g.
+// because it finished or its lease expired and it was reaped. Every broker's
+// Complete, Fail, FailAfter, DeadLetter and Expire return it (wrapped) for jobs
+// that are no longer running, so a late worker can't finish a job twice.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// SetLeaseDuration sets how long a dequeued job is leased to its worker
+//
+// Workers extend the lease with Heartbeat while the job runs. Jobs whose lease
+// expires (the worker crashed or was partitioned) are returned to the retry path
+// by ReapExpiredLeases. Non-positive values reset it to DefaultLeaseDuration.
+func (q *RedisQueue) SetLeaseDuration(d time.Duration) {
+	if d <= 0 {
+		d = DefaultLeaseDuration
+	}
+	q.leaseDuration = d
+}
+
+// LeaseDuration returns how long a dequeued job is leased without a heartbeat
+func (q *RedisQueue) LeaseDuration() time.Duration {
+	return q.leaseDuration
+}
+
+// leaseExpiry returns the lease score for a lease taken or renewed now
+func (q *RedisQueue) leaseExpiry() float64 {
+	return float64(time.Now().Add(q.leaseDuration).UnixMilli())
+}
+
+// leaseOrphansScript leases processing jobs that have no lease
+// A worker that crashed between dequeuing a job and leasing it leaves the job in the
+// processing queue without one; the lease given here lets ReapExpiredLeases retry
+// it once it expires, while a live worker's heartbeats keep extending it.
+// KEYS: processing queue, leases
+// ARGV: lease expiry (unix ms)
+// Returns the number of jobs leased.
+var leaseOrphansScript = redis.NewScript(`
+local leased = 0
+for _, id in ipairs(redis.call("LRANGE", KEYS[1], 0, -1)) do
+	if not redis.call("ZSCORE", KEYS[2], id) then
+		redis.call("ZADD", KEYS[2], ARGV[1], id)
+		leased = leased + 1
+	end
+end
+return leased
+`)
+
+// acquireLease leases a job that was just moved to the processing queue
+func (q *RedisQueue) acquireLease(ctx context.Context, jobID string) error {
+	return q.client.ZAdd(ctx, q.leasesKey, redis.Z{
+		Score:  q.leaseExpiry(),
+		Member: jobID,
+	}).Err()
+}
+
+// Heartbeat extends a running job's lease by the lease duration
+// Returns ErrLeaseLost if the job is no longer leased.
+func (q *RedisQueue) Heartbeat(ctx context.Context, jobID string) error {
+	// XX only renews an existing lease, so a reaped job isn't leased again
+	changed, err := q.client.ZAddArgs(ctx, q.leasesKey, redis.ZAddArgs{
+		XX:      true,
+		Ch:      true,
+		Members: []redis.Z{{Score: q.leaseExpiry(), Member: jobID}},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if changed == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReapExpiredLeases returns jobs whose lease expired to the retry path
+//
+// A job whose worker stopped heartbeating is failed like any other attempt (see Fail):
+// it is retried with backoff, or moved to the dead letter queue once it has used all
+// its attempts. Its checkpoint is kept, so the retry can resume from it. Processing
+// jobs without a lease (their worker crashed before leasing them) are given one,
+// and are reaped by a later call once it expires.
+//
+// Like MoveScheduledToReady, this should be called periodically by the scheduler process.
+// Returns the number of jobs reaped.
+func (q *RedisQueue) ReapExpiredLeases(ctx context.Context) (int, error) {
+	now := time.Now().UnixMilli()
+	jobIDs, err := q.client.ZRangeByScore(ctx, q.leasesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now, 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired leases: %w", err)
+	}
+
+	reaped := 0
+	for _, jobID := range jobIDs {
+		j, err := q.GetJob(ctx, jobID)
+		if err != nil {
+			// Nothing left to retry - just drop the orphaned lease and processing entry
+			log.Printf("Failed to load job %s with expired lease: %v", jobID, err)
+			pipe := q.client.Pipeline()
+			pipe.ZRem(ctx, q.leasesKey, jobID)
+			pipe.LRem(ctx, q.processingQueueKey(), 1, jobID)
+			pipe.Exec(ctx)
+			continue
+		}
+
+		// The fail script removes the lease while claiming the job, and only if it is
+		// still expired, so concurrent schedulers (or a late heartbeat) can't fail it twice
+		err = q.fail(ctx, j, "lease expired: worker stopped sending heartbeats", 0, q.expiredLeaseClaim(jobID, now))
+		if errors.Is(err, ErrLeaseLost) {
+			// The worker finished the job or renewed its lease after all
+			continue
+		}
+		if err != nil {
+			return reaped, err
+		}
+		log.Printf("Reaped job %s after its lease expired", jobID)
+		reaped++
+	}
+
+	leased, err := leaseOrphansScript.Run(ctx, q.client, []string{q.processingQueueKey(), q.leasesKey}, q.leaseExpiry()).Int()
+	if err != nil {
+		return reaped, fmt.Errorf("failed to lease orphaned jobs: %w", err)
+	}
+	if leased > 0 {
+		log.Printf("Leased %d processing jobs that had no lease", leased)
+	}
+
+	return reaped, nil
+}
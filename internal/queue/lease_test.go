@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+var allPriorities = []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+
+func TestDequeue_LeasesJob(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+
+	before := time.Now()
+	if _, err := queue.Dequeue(ctx, allPriorities); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	score, err := queue.client.ZScore(ctx, queue.leasesKey, j.ID).Result()
+	if err != nil {
+		t.Fatalf("expected job to be leased: %v", err)
+	}
+	expiry := time.UnixMilli(int64(score))
+	if expiry.Before(before.Add(DefaultLeaseDuration)) {
+		t.Errorf("expected lease to expire after %v, got %v", DefaultLeaseDuration, expiry.Sub(before))
+	}
+}
+
+func TestHeartbeat_ExtendsLease(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetLeaseDuration(time.Second)
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+
+	first, _ := queue.client.ZScore(ctx, queue.leasesKey, j.ID).Result()
+	time.Sleep(5 * time.Millisecond)
+	if err := queue.Heartbeat(ctx, j.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, _ := queue.client.ZScore(ctx, queue.leasesKey, j.ID).Result()
+	if second <= first {
+		t.Errorf("expected heartbeat to extend the lease (%v -> %v)", first, second)
+	}
+}
+
+func TestHeartbeat_LeaseLostAfterComplete(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+
+	if err := queue.Complete(ctx, j.ID); err != nil {
+		t.Fatalf("failed to complete job: %v", err)
+	}
+	if n, _ := queue.client.ZCard(ctx, queue.leasesKey).Result(); n != 0 {
+		t.Errorf("expected lease to be released, got %d leases", n)
+	}
+	if err := queue.Heartbeat(ctx, j.ID); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost, got %v", err)
+	}
+	if n, _ := queue.client.ZCard(ctx, queue.leasesKey).Result(); n != 0 {
+		t.Error("expected heartbeat not to lease a finished job again")
+	}
+}
+
+func TestReapExpiredLeases_RetriesJob(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetLeaseDuration(time.Millisecond)
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+	time.Sleep(5 * time.Millisecond)
+
+	reaped, err := queue.ReapExpiredLeases(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reaped != 1 {
+		t.Fatalf("expected 1 reaped job, got %d", reaped)
+	}
+
+	if n, _ := queue.client.LLen(ctx, queue.processingQueueKey()).Result(); n != 0 {
+		t.Errorf("expected processing queue empty, got length %d", n)
+	}
+	if n, _ := queue.client.ZCard(ctx, queue.getScheduledSetKey()).Result(); n != 1 {
+		t.Errorf("expected job scheduled for retry, got %d scheduled", n)
+	}
+	retried, _ := queue.GetJob(ctx, j.ID)
+	if retried.Attempts != 1 || retried.Status != job.StatusPending {
+		t.Errorf("expected pending job with 1 attempt, got %s with %d", retried.Status, retried.Attempts)
+	}
+
+	// Already reaped
+	if reaped, _ := queue.ReapExpiredLeases(ctx); reaped != 0 {
+		t.Errorf("expected nothing left to reap, got %d", reaped)
+	}
+}
+
+func TestReapExpiredLeases_SkipsLiveLeases(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+
+	reaped, err := queue.ReapExpiredLeases(ctx)
+	if err != nil || reaped != 0 {
+		t.Fatalf("expected no reaped jobs, got %d (%v)", reaped, err)
+	}
+	if n, _ := queue.client.LLen(ctx, queue.processingQueueKey()).Result(); n != 1 {
+		t.Errorf("expected job to stay in processing, got length %d", n)
+	}
+}
+
+func TestReapExpiredLeases_DeadLettersLastAttempt(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetLeaseDuration(time.Millisecond)
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 1
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := queue.ReapExpiredLeases(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n, _ := queue.DeadLetterQueueLength(ctx); n != 1 {
+		t.Errorf("expected job in dead letter queue, got length %d", n)
+	}
+}
+
+func TestReapExpiredLeases_SkipsRenewedLease(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetLeaseDuration(time.Millisecond)
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+	time.Sleep(5 * time.Millisecond)
+
+	// A reaper that saw the lease expired before the worker's heartbeat renewed it
+	deadline := time.Now().UnixMilli()
+	queue.SetLeaseDuration(time.Minute)
+	if err := queue.Heartbeat(ctx, j.ID); err != nil {
+		t.Fatalf("failed to renew lease: %v", err)
+	}
+	err := queue.fail(ctx, j, "lease expired", 0, queue.expiredLeaseClaim(j.ID, deadline))
+	if !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if err := queue.Heartbeat(ctx, j.ID); err != nil {
+		t.Errorf("expected the job to stay leased, got %v", err)
+	}
+	if n, _ := queue.client.LLen(ctx, queue.processingQueueKey()).Result(); n != 1 {
+		t.Errorf("expected job to stay in processing, got length %d", n)
+	}
+}
+
+func TestReapExpiredLeases_ReapsJobsWithoutLease(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetLeaseDuration(time.Millisecond)
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.Dequeue(ctx, allPriorities)
+
+	// The worker crashed between dequeuing the job and leasing it
+	queue.client.ZRem(ctx, queue.leasesKey, j.ID)
+
+	if reaped, err := queue.ReapExpiredLeases(ctx); err != nil || reaped != 0 {
+		t.Fatalf("expected the job to get a grace period, got %d reaped (%v)", reaped, err)
+	}
+	if _, err := queue.client.ZScore(ctx, queue.leasesKey, j.ID).Result(); err != nil {
+		t.Fatalf("expected the orphaned job to be leased: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if reaped, err := queue.ReapExpiredLeases(ctx); err != nil || reaped != 1 {
+		t.Fatalf("expected 1 reaped job after the grace period, got %d (%v)", reaped, err)
+	}
+	if retried, _ := queue.GetJob(ctx, j.ID); retried.Attempts != 1 || retried.Status != job.StatusPending {
+		t.Errorf("expected pending job with 1 attempt, got %s with %d", retried.Status, retried.Attempts)
+	}
+}
@@ -0,0 +1,707 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+)
+
+// DefaultMemoryDequeueTimeout is how long MemoryBroker.Dequeue waits for a job by default
+const DefaultMemoryDequeueTimeout = time.Second
+
+// memoryEntry is a stored job and when its data is discarded
+type memoryEntry struct {
+	job *job.Job
+	// expiresAt is zero while the job is unfinished
+	expiresAt time.Time
+}
+
+// memoryCompletion is the job that completed an idempotency key and when the record is discarded
+type memoryCompletion struct {
+	jobID     string
+	expiresAt time.Time
+}
+
+// MemoryBroker is an in-process Broker for tests and single-process deployments
+//
+// It has the same priority, routing, scheduling, retry backoff and dead letter
+// semantics as RedisQueue, and is safe for concurrent producers and workers.
+// Scheduled jobs and retries follow its Clock, so tests can use a FakeClock and
+// call MoveScheduledToReady instead of sleeping. Jobs are lost when the process
+// exits, and leases, quotas and payload offload are not supported.
+type MemoryBroker struct {
+	mu    sync.Mutex
+	clock Clock
+	jobs  map[string]*memoryEntry
+	// ready holds the job IDs of each ready queue, oldest first; queues are named like RedisQueue's keys
+	ready      map[string][]string
+	scheduled  map[string]time.Time
+	processing map[string]bool
+	dead       []string
+	// Job names with their own queues and when that lapses (see DedicateJobNames)
+	dedicated   map[string]time.Time
+	checkpoints map[string][]byte
+	// Completed idempotency keys (see RecordCompletion)
+	completions    map[string]memoryCompletion
+	idempotencyTTL time.Duration
+	// signal is closed and replaced whenever a job becomes ready, waking blocked Dequeue calls
+	signal chan struct{}
+	closed bool
+	// How long Dequeue waits for a job before returning nil
+	dequeueTimeout time.Duration
+	// TTL configuration for finished job data, on the broker's clock
+	completedJobTTL time.Duration
+	failedJobTTL    time.Duration
+	hooks           *events.Registry
+}
+
+// MemoryBroker implements Broker
+var _ Broker = (*MemoryBroker)(nil)
+
+// NewMemoryBroker creates an empty in-memory broker on the wall clock
+func NewMemoryBroker() *MemoryBroker {
+	return NewMemoryBrokerWithClock(systemClock{})
+}
+
+// NewMemoryBrokerWithClock creates an empty in-memory broker whose scheduled jobs,
+// retries and job TTLs follow clock
+func NewMemoryBrokerWithClock(clock Clock) *MemoryBroker {
+	return &MemoryBroker{
+		clock:           clock,
+		jobs:            make(map[string]*memoryEntry),
+		ready:           make(map[string][]string),
+		scheduled:       make(map[string]time.Time),
+		processing:      make(map[string]bool),
+		dedicated:       make(map[string]time.Time),
+		checkpoints:     make(map[string][]byte),
+		completions:     make(map[string]memoryCompletion),
+		idempotencyTTL:  DefaultIdempotencyTTL,
+		signal:          make(chan struct{}),
+		dequeueTimeout:  DefaultMemoryDequeueTimeout,
+		completedJobTTL: 24 * time.Hour,
+		failedJobTTL:    7 * 24 * time.Hour,
+		hooks:           events.NewRegistry(),
+	}
+}
+
+// SetDequeueTimeout sets how long Dequeue waits for a job to become ready before
+// returning nil. The wait is on the wall clock, not the broker's Clock.
+func (b *MemoryBroker) SetDequeueTimeout(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dequeueTimeout = d
+}
+
+// Hooks returns the registry that receives this broker's job lifecycle events
+func (b *MemoryBroker) Hooks() *events.Registry {
+	return b.hooks
+}
+
+// SetHooks replaces the lifecycle hook registry
+func (b *MemoryBroker) SetHooks(hooks *events.Registry) {
+	b.hooks = hooks
+}
+
+// cloneJob copies a job so callers can't modify the stored one
+func cloneJob(j *job.Job) *job.Job {
+	c := *j
+	c.Payload = append([]byte(nil), j.Payload...)
+	if j.ScheduledFor != nil {
+		t := *j.ScheduledFor
+		c.ScheduledFor = &t
+	}
+	if j.Deadline != nil {
+		t := *j.Deadline
+		c.Deadline = &t
+	}
+	return &c
+}
+
+// memoryRouteQueue names the ready queue of a routing key and priority
+func memoryRouteQueue(routingKey string, priority job.JobPriority) string {
+	if routingKey == "" || routingKey == job.DefaultRoutingKey {
+		return "queue:" + string(priority)
+	}
+	return "route:" + routingKey + ":queue:" + string(priority)
+}
+
+// memoryJobNameQueue names the ready queue of a dedicated job name and priority
+func memoryJobNameQueue(name string, priority job.JobPriority) string {
+	return "jobs:" + name + ":queue:" + string(priority)
+}
+
+// readyQueue names the queue a job is pushed to when it becomes ready
+// The caller must hold b.mu.
+func (b *MemoryBroker) readyQueue(j *job.Job) string {
+	if b.clock.Now().Before(b.dedicated[j.Name]) && (j.RoutingKey == "" || j.RoutingKey == job.DefaultRoutingKey) {
+		return memoryJobNameQueue(j.Name, j.Priority)
+	}
+	return memoryRouteQueue(j.RoutingKey, j.Priority)
+}
+
+// store saves a copy of a job, discarding it ttl from now (0 keeps it)
+// The caller must hold b.mu.
+func (b *MemoryBroker) store(j *job.Job, ttl time.Duration) {
+	entry := &memoryEntry{job: cloneJob(j)}
+	if ttl > 0 {
+		entry.expiresAt = b.clock.Now().Add(ttl)
+	}
+	b.jobs[j.ID] = entry
+}
+
+// lookup returns a stored job unless its data expired
+// The caller must hold b.mu.
+func (b *MemoryBroker) lookup(jobID string) (*job.Job, bool) {
+	entry, ok := b.jobs[jobID]
+	if !ok || (!entry.expiresAt.IsZero() && !b.clock.Now().Before(entry.expiresAt)) {
+		return nil, false
+	}
+	return entry.job, true
+}
+
+// push appends a job to the back of its ready queue and wakes waiting workers
+// The caller must hold b.mu.
+func (b *MemoryBroker) push(j *job.Job) string {
+	queueName := b.readyQueue(j)
+	b.ready[queueName] = append(b.ready[queueName], j.ID)
+	b.wake()
+	return queueName
+}
+
+// wake signals blocked Dequeue calls that a job became ready
+// The caller must hold b.mu.
+func (b *MemoryBroker) wake() {
+	close(b.signal)
+	b.signal = make(chan struct{})
+}
+
+// remove deletes a job ID from a queue, reporting whether it was there
+// The caller must hold b.mu.
+func (b *MemoryBroker) remove(queueName, jobID string) bool {
+	ids := b.ready[queueName]
+	for i, id := range ids {
+		if id == jobID {
+			b.ready[queueName] = append(ids[:i:i], ids[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Enqueue adds a job to the back of its ready queue
+func (b *MemoryBroker) Enqueue(ctx context.Context, j *job.Job) error {
+	b.mu.Lock()
+	b.store(j, 0)
+	queueName := b.push(j)
+	b.mu.Unlock()
+
+	log.Printf("Enqueued job %s to %s", j.ID, queueName)
+	b.hooks.Emit(ctx, events.TypeEnqueue, j)
+	return nil
+}
+
+// Schedule stores a job until at; MoveScheduledToReady then moves it to its ready queue
+func (b *MemoryBroker) Schedule(ctx context.Context, j *job.Job, at time.Time) error {
+	j.ScheduledFor = &at
+	j.UpdateStatus(job.StatusScheduled)
+
+	b.mu.Lock()
+	b.store(j, 0)
+	b.scheduled[j.ID] = at
+	b.mu.Unlock()
+
+	log.Printf("Scheduled job %s for %s", j.ID, at.Format(time.RFC3339))
+	b.hooks.Emit(ctx, events.TypeEnqueue, j)
+	return nil
+}
+
+// Dequeue takes the next job from the shared priority queues, in the order given
+func (b *MemoryBroker) Dequeue(ctx context.Context, priorities []job.JobPriority) (*job.Job, error) {
+	return b.DequeueRoutes(ctx, []string{job.DefaultRoutingKey}, priorities)
+}
+
+// DequeueWithRouting retrieves a job for any of the given routing keys, at any priority
+func (b *MemoryBroker) DequeueWithRouting(ctx context.Context, routingKeys []string) (*job.Job, error) {
+	return b.DequeueRoutes(ctx, routingKeys, []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow})
+}
+
+// DequeueRoutes retrieves a job from the queues of the given routing keys
+// Routing keys are checked in the order given and, within each key, by priority.
+func (b *MemoryBroker) DequeueRoutes(ctx context.Context, routingKeys []string, priorities []job.JobPriority) (*job.Job, error) {
+	queueNames := make([]string, 0, len(routingKeys)*len(priorities))
+	for _, routingKey := range routingKeys {
+		for _, priority := range priorities {
+			queueNames = append(queueNames, memoryRouteQueue(routingKey, priority))
+		}
+	}
+	return b.dequeueFrom(ctx, queueNames)
+}
+
+// DequeueJobNames retrieves a job from the queues of the given dedicated job names
+// Queues are checked by priority first, then in the order the names are given.
+func (b *MemoryBroker) DequeueJobNames(ctx context.Context, names []string, priorities []job.JobPriority) (*job.Job, error) {
+	queueNames := make([]string, 0, len(names)*len(priorities))
+	for _, priority := range priorities {
+		for _, name := range names {
+			queueNames = append(queueNames, memoryJobNameQueue(name, priority))
+		}
+	}
+	return b.dequeueFrom(ctx, queueNames)
+}
+
+// DedicateJobNames gives job names their own queues, served by job-specialized workers
+// Jobs already in the shared queues stay there and the dedication lapses after
+// DedicationTTL on the broker's clock, as with RedisQueue.
+func (b *MemoryBroker) DedicateJobNames(ctx context.Context, names ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	expiry := b.clock.Now().Add(DedicationTTL)
+	for _, name := range names {
+		b.dedicated[name] = expiry
+	}
+	return nil
+}
+
+// ReleaseJobNames returns job names to the shared priority queues
+// Jobs already waiting in the names' queues stay there until a worker for the name drains them.
+func (b *MemoryBroker) ReleaseJobNames(ctx context.Context, names ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, name := range names {
+		delete(b.dedicated, name)
+	}
+	return nil
+}
+
+// dequeueFrom takes the first job found in the given queues, waiting up to the
+// dequeue timeout for one to become ready
+func (b *MemoryBroker) dequeueFrom(ctx context.Context, queueNames []string) (*job.Job, error) {
+	if len(queueNames) == 0 {
+		return nil, nil
+	}
+
+	b.mu.Lock()
+	timer := time.NewTimer(b.dequeueTimeout)
+	defer timer.Stop()
+
+	for {
+		for _, queueName := range queueNames {
+			for len(b.ready[queueName]) > 0 {
+				jobID := b.ready[queueName][0]
+				b.ready[queueName] = b.ready[queueName][1:]
+
+				j, ok := b.lookup(jobID)
+				if !ok {
+					// Like RedisQueue, a job whose data is gone is dead-lettered
+					log.Printf("ERROR: Job data not found for ID %s (corrupted reference) - moving to dead letter queue", jobID)
+					b.dead = append(b.dead, jobID)
+					continue
+				}
+
+				b.processing[jobID] = true
+				b.mu.Unlock()
+				log.Printf("Dequeued job %s from %s", jobID, queueName)
+				return cloneJob(j), nil
+			}
+		}
+
+		if b.closed {
+			b.mu.Unlock()
+			return nil, nil
+		}
+		signal := b.signal
+		b.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		b.mu.Lock()
+	}
+}
+
+// Complete marks a dequeued job as completed
+// Returns an error wrapping ErrLeaseLost if the job is no longer running.
+func (b *MemoryBroker) Complete(ctx context.Context, jobID string) error {
+	return b.CompleteIdempotent(ctx, jobID, "")
+}
+
+// CompleteIdempotent is like Complete, but also records jobID as the job that
+// completed the idempotency key (see RecordCompletion) under the same lock
+func (b *MemoryBroker) CompleteIdempotent(ctx context.Context, jobID, key string) error {
+	b.mu.Lock()
+	stored, ok := b.lookup(jobID)
+	if !ok {
+		b.mu.Unlock()
+		return fmt.Errorf("failed to get job data: %w: %s", ErrJobNotFound, jobID)
+	}
+	if !b.processing[jobID] {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: job %s is not running", ErrLeaseLost, jobID)
+	}
+	j := cloneJob(stored)
+	j.UpdateStatus(job.StatusCompleted)
+	b.store(j, b.completedJobTTL)
+	delete(b.processing, jobID)
+	delete(b.checkpoints, jobID)
+	if key != "" && b.completedBy(key) == "" {
+		b.completions[key] = memoryCompletion{jobID: jobID, expiresAt: b.clock.Now().Add(b.idempotencyTTL)}
+	}
+	b.mu.Unlock()
+
+	log.Printf("Completed job %s (TTL: %v)", jobID, b.completedJobTTL)
+	b.hooks.Emit(ctx, events.TypeSuccess, j)
+	return nil
+}
+
+// Fail records a failed attempt and schedules a retry after 2^attempts seconds,
+// or moves the job to the dead letter queue once its retries are exhausted
+func (b *MemoryBroker) Fail(ctx context.Context, j *job.Job, errMsg string) error {
+	return b.fail(ctx, j, errMsg, 0)
+}
+
+// FailAfter is like Fail, but a retry is scheduled after delay instead of the exponential backoff
+func (b *MemoryBroker) FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	return b.fail(ctx, j, errMsg, delay)
+}
+
+// fail records a failed attempt and schedules a retry after delay (exponential
+// backoff when delay is 0), or moves the job to the dead letter queue
+// Returns an error wrapping ErrLeaseLost if the job is no longer running.
+func (b *MemoryBroker) fail(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	b.mu.Lock()
+	if !b.processing[j.ID] {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: job %s is not running", ErrLeaseLost, j.ID)
+	}
+
+	j.Attempts++
+	j.Error = errMsg
+
+	if j.Attempts >= j.MaxRetries {
+		b.moveToDeadLetter(j)
+		b.mu.Unlock()
+		b.hooks.Emit(ctx, events.TypeDead, j)
+		return nil
+	}
+
+	retryDelay := time.Duration(1<<j.Attempts) * time.Second
+	if delay > 0 {
+		retryDelay = delay
+	}
+
+	nextRetryTime := b.clock.Now().Add(retryDelay)
+	j.UpdateStatus(job.StatusPending)
+	j.ScheduledFor = &nextRetryTime
+	b.store(j, 0)
+	b.scheduled[j.ID] = nextRetryTime
+	// The checkpoint is kept so the next attempt can resume from it
+	delete(b.processing, j.ID)
+	b.mu.Unlock()
+
+	log.Printf("Job %s failed (attempt %d/%d), scheduled for retry in %v at %s",
+		j.ID, j.Attempts, j.MaxRetries, retryDelay, nextRetryTime.Format(time.RFC3339))
+	b.hooks.Emit(ctx, events.TypeFailure, j)
+	return nil
+}
+
+// DeadLetter moves a job straight to the dead letter queue without retrying
+// Returns an error wrapping ErrLeaseLost if the job is no longer running.
+func (b *MemoryBroker) DeadLetter(ctx context.Context, j *job.Job, errMsg string) error {
+	b.mu.Lock()
+	if !b.processing[j.ID] {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: job %s is not running", ErrLeaseLost, j.ID)
+	}
+	j.Attempts++
+	j.Error = errMsg
+	b.moveToDeadLetter(j)
+	b.mu.Unlock()
+
+	b.hooks.Emit(ctx, events.TypeDead, j)
+	return nil
+}
+
+// moveToDeadLetter marks a job as failed and moves it to the dead letter queue
+// The caller must hold b.mu.
+func (b *MemoryBroker) moveToDeadLetter(j *job.Job) {
+	j.UpdateStatus(job.StatusFailed)
+	j.ScheduledFor = nil
+
+	b.store(j, b.failedJobTTL)
+	b.dead = append(b.dead, j.ID)
+	delete(b.processing, j.ID)
+
+	log.Printf("Job %s moved to dead letter queue after %d attempts (TTL: %v)", j.ID, j.Attempts, b.failedJobTTL)
+}
+
+// Expire discards a dequeued job whose deadline passed before it started
+// Returns an error wrapping ErrLeaseLost if the job is no longer running.
+func (b *MemoryBroker) Expire(ctx context.Context, j *job.Job, reason string) error {
+	b.mu.Lock()
+	if !b.processing[j.ID] {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: job %s is not running", ErrLeaseLost, j.ID)
+	}
+	j.UpdateStatus(job.StatusExpired)
+	j.Error = reason
+
+	b.store(j, b.completedJobTTL)
+	delete(b.processing, j.ID)
+	delete(b.checkpoints, j.ID)
+	b.mu.Unlock()
+
+	log.Printf("Expired job %s: %s", j.ID, reason)
+	b.hooks.Emit(ctx, events.TypeExpired, j)
+	return nil
+}
+
+// Requeue returns a dequeued job to the front of its queue without counting an attempt
+// It is a no-op if the job is no longer being processed.
+func (b *MemoryBroker) Requeue(ctx context.Context, j *job.Job) error {
+	b.mu.Lock()
+	if !b.processing[j.ID] {
+		b.mu.Unlock()
+		return nil
+	}
+	delete(b.processing, j.ID)
+
+	j.UpdateStatus(job.StatusPending)
+	b.store(j, 0)
+	queueName := b.readyQueue(j)
+	b.ready[queueName] = append([]string{j.ID}, b.ready[queueName]...)
+	b.wake()
+	b.mu.Unlock()
+
+	log.Printf("Requeued job %s to %s (attempt not counted)", j.ID, queueName)
+	b.hooks.Emit(ctx, events.TypeRequeue, j)
+	return nil
+}
+
+// MoveScheduledToReady moves scheduled jobs and retries whose time has come on the
+// broker's clock to their ready queues, earliest first. It also discards finished
+// jobs whose TTL has passed.
+func (b *MemoryBroker) MoveScheduledToReady(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+
+	due := make([]string, 0)
+	for jobID, at := range b.scheduled {
+		if !at.After(now) {
+			due = append(due, jobID)
+		}
+	}
+	sort.Slice(due, func(i, k int) bool {
+		if !b.scheduled[due[i]].Equal(b.scheduled[due[k]]) {
+			return b.scheduled[due[i]].Before(b.scheduled[due[k]])
+		}
+		return due[i] < due[k]
+	})
+
+	moved := 0
+	for _, jobID := range due {
+		delete(b.scheduled, jobID)
+		stored, ok := b.lookup(jobID)
+		if !ok {
+			log.Printf("Warning: Job %s in scheduled set but data not found, will be removed", jobID)
+			continue
+		}
+
+		j := cloneJob(stored)
+		j.ScheduledFor = nil
+		if j.Status == job.StatusScheduled {
+			j.Status = job.StatusPending
+		}
+		j.UpdatedAt = time.Now()
+		b.store(j, 0)
+		b.push(j)
+		moved++
+	}
+
+	b.purgeExpired(now)
+
+	if moved > 0 {
+		log.Printf("Moved %d scheduled jobs to ready queues", moved)
+	}
+	return moved, nil
+}
+
+// purgeExpired discards finished jobs whose TTL has passed
+// The caller must hold b.mu.
+func (b *MemoryBroker) purgeExpired(now time.Time) {
+	expired := make(map[string]bool)
+	for jobID, entry := range b.jobs {
+		if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			expired[jobID] = true
+			delete(b.jobs, jobID)
+			delete(b.checkpoints, jobID)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	dead := b.dead[:0]
+	for _, jobID := range b.dead {
+		if !expired[jobID] {
+			dead = append(dead, jobID)
+		}
+	}
+	b.dead = dead
+}
+
+// GetJob returns a copy of a job by ID
+func (b *MemoryBroker) GetJob(ctx context.Context, jobID string) (*job.Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	j, ok := b.lookup(jobID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	return cloneJob(j), nil
+}
+
+// Cancel removes a job that is still waiting or scheduled and marks it cancelled
+func (b *MemoryBroker) Cancel(ctx context.Context, jobID string) error {
+	b.mu.Lock()
+	stored, ok := b.lookup(jobID)
+	if !ok {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+
+	_, removed := b.scheduled[jobID]
+	delete(b.scheduled, jobID)
+	// The job name may have been dedicated (or released) since it was enqueued
+	if !removed {
+		removed = b.remove(memoryRouteQueue(stored.RoutingKey, stored.Priority), jobID) ||
+			b.remove(memoryJobNameQueue(stored.Name, stored.Priority), jobID)
+	}
+	if !removed {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: job %s is %s", ErrNotCancellable, jobID, stored.Status)
+	}
+
+	j := cloneJob(stored)
+	j.UpdateStatus(job.StatusCancelled)
+	j.ScheduledFor = nil
+	b.store(j, b.completedJobTTL)
+	delete(b.checkpoints, jobID)
+	b.mu.Unlock()
+
+	log.Printf("Cancelled job %s", jobID)
+	b.hooks.Emit(ctx, events.TypeCancel, j)
+	return nil
+}
+
+// Stats returns the number of jobs in the shared priority queues, scheduled,
+// being processed and in the dead letter queue
+func (b *MemoryBroker) Stats(ctx context.Context) (Stats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+	stats := Stats{
+		Ready:      make(map[job.JobPriority]int64, len(priorities)),
+		Scheduled:  int64(len(b.scheduled)),
+		Processing: int64(len(b.processing)),
+		Dead:       int64(len(b.dead)),
+	}
+	for _, priority := range priorities {
+		stats.Ready[priority] = int64(len(b.ready[memoryRouteQueue(job.DefaultRoutingKey, priority)]))
+	}
+	return stats, nil
+}
+
+// RefreshQueueMetrics records the current depths of the shared priority queues in metrics
+func (b *MemoryBroker) RefreshQueueMetrics(ctx context.Context) {
+	stats, _ := b.Stats(ctx)
+	for priority, depth := range stats.Ready {
+		metrics.Default().RecordQueueDepth(priority, depth)
+	}
+}
+
+// SaveCheckpoint stores a running job's checkpoint, replacing the previous one
+func (b *MemoryBroker) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkpoints[jobID] = append([]byte(nil), data...)
+	return nil
+}
+
+// LoadCheckpoint returns a job's latest checkpoint, or nil if it has none
+func (b *MemoryBroker) LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.checkpoints[jobID]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// SetIdempotencyTTL sets how long completed idempotency keys are remembered, on the broker's clock
+// Non-positive values reset it to DefaultIdempotencyTTL.
+func (b *MemoryBroker) SetIdempotencyTTL(ttl time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	b.idempotencyTTL = ttl
+}
+
+// RecordCompletion records that jobID completed the work identified by key
+// If another job already completed the key, its ID is returned and the record is left unchanged.
+func (b *MemoryBroker) RecordCompletion(ctx context.Context, key, jobID string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if owner := b.completedBy(key); owner != "" {
+		return owner, nil
+	}
+	b.completions[key] = memoryCompletion{jobID: jobID, expiresAt: b.clock.Now().Add(b.idempotencyTTL)}
+	return jobID, nil
+}
+
+// CompletedBy returns the ID of the job that completed key, or "" if none has
+func (b *MemoryBroker) CompletedBy(ctx context.Context, key string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.completedBy(key), nil
+}
+
+// completedBy returns the unexpired completion record of key, discarding an expired one
+// The caller must hold b.mu.
+func (b *MemoryBroker) completedBy(key string) string {
+	c, ok := b.completions[key]
+	if !ok {
+		return ""
+	}
+	if !b.clock.Now().Before(c.expiresAt) {
+		delete(b.completions, key)
+		return ""
+	}
+	return c.jobID
+}
+
+// Close wakes blocked Dequeue calls; later Dequeue calls return nil without waiting
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		b.wake()
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// newTestMemoryBroker creates a memory broker on a fake clock with a short dequeue timeout
+func newTestMemoryBroker() (*MemoryBroker, *FakeClock) {
+	clock := NewFakeClock(time.Now())
+	b := NewMemoryBrokerWithClock(clock)
+	b.SetDequeueTimeout(50 * time.Millisecond)
+	return b, clock
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	clock.Advance(time.Minute)
+	if got := clock.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected %v after Advance, got %v", start.Add(time.Minute), got)
+	}
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("expected %v after Set, got %v", start, got)
+	}
+}
+
+func TestMemoryBroker_RetryBackoffFollowsClock(t *testing.T) {
+	ctx := context.Background()
+	b, clock := newTestMemoryBroker()
+
+	j := job.NewJob("flaky", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 3
+	b.Enqueue(ctx, j)
+
+	// 1st retry after 2s, 2nd after 4s, then the dead letter queue
+	for attempt, backoff := range []time.Duration{2 * time.Second, 4 * time.Second} {
+		dequeued, _ := b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+		if dequeued == nil {
+			t.Fatalf("attempt %d: expected a job", attempt+1)
+		}
+		b.Fail(ctx, dequeued, "boom")
+
+		clock.Advance(backoff - time.Millisecond)
+		if moved, _ := b.MoveScheduledToReady(ctx); moved != 0 {
+			t.Fatalf("attempt %d: expected no retry before %v, moved %d", attempt+1, backoff, moved)
+		}
+		clock.Advance(time.Millisecond)
+		if moved, _ := b.MoveScheduledToReady(ctx); moved != 1 {
+			t.Fatalf("attempt %d: expected retry after %v, moved %d", attempt+1, backoff, moved)
+		}
+	}
+
+	dequeued, _ := b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	b.Fail(ctx, dequeued, "boom")
+	stats, _ := b.Stats(ctx)
+	if stats.Dead != 1 || stats.Scheduled != 0 {
+		t.Errorf("expected job in dead letter queue, got %+v", stats)
+	}
+}
+
+func TestMemoryBroker_DequeueWaitsForJob(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestMemoryBroker()
+	b.SetDequeueTimeout(5 * time.Second)
+
+	j := job.NewJob("late", []byte(`{}`), job.PriorityLow)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Enqueue(ctx, j)
+	}()
+
+	start := time.Now()
+	got, err := b.Dequeue(ctx, []job.JobPriority{job.PriorityHigh, job.PriorityLow})
+	if err != nil || got == nil || got.ID != j.ID {
+		t.Fatalf("expected the late job, got %v, %v", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Dequeue to wake on enqueue, waited %v", elapsed)
+	}
+}
+
+func TestMemoryBroker_DequeueTimeoutAndCancel(t *testing.T) {
+	b, _ := newTestMemoryBroker()
+
+	got, err := b.Dequeue(context.Background(), []job.JobPriority{job.PriorityNormal})
+	if got != nil || err != nil {
+		t.Errorf("expected nil, nil from empty broker, got %v, %v", got, err)
+	}
+
+	b.SetDequeueTimeout(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context error, got %v", err)
+	}
+
+	b.Close()
+	if got, err := b.Dequeue(context.Background(), []job.JobPriority{job.PriorityNormal}); got != nil || err != nil {
+		t.Errorf("expected closed broker to return nil, nil, got %v, %v", got, err)
+	}
+}
+
+func TestMemoryBroker_Routing(t *testing.T) {
+	ctx := context.Background()
+	b, clock := newTestMemoryBroker()
+
+	gpu := job.NewJob("train", []byte(`{}`), job.PriorityNormal)
+	gpu.SetRoutingKey("gpu")
+	b.Enqueue(ctx, gpu)
+	b.DedicateJobNames(ctx, "resize")
+	resize := job.NewJob("resize", []byte(`{}`), job.PriorityNormal)
+	b.Enqueue(ctx, resize)
+
+	if got, _ := b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal}); got != nil {
+		t.Errorf("expected shared queues to be empty, got %s", got.Name)
+	}
+	if got, _ := b.DequeueWithRouting(ctx, []string{"gpu"}); got == nil || got.ID != gpu.ID {
+		t.Errorf("expected the gpu job from its route, got %v", got)
+	}
+	if got, _ := b.DequeueJobNames(ctx, []string{"resize"}, []job.JobPriority{job.PriorityNormal}); got == nil || got.ID != resize.ID {
+		t.Errorf("expected the resize job from its name queue, got %v", got)
+	}
+
+	// Without renewal the name returns to the shared queues
+	clock.Advance(DedicationTTL)
+	lapsed := job.NewJob("resize", []byte(`{}`), job.PriorityNormal)
+	b.Enqueue(ctx, lapsed)
+	if got, _ := b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal}); got == nil || got.ID != lapsed.ID {
+		t.Errorf("expected the resize job in the shared queues after its dedication lapsed, got %v", got)
+	}
+}
+
+func TestMemoryBroker_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestMemoryBroker()
+
+	j := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	b.Enqueue(ctx, j)
+	j.Name = "changed"
+
+	got, _ := b.GetJob(ctx, j.ID)
+	got.Attempts = 99
+	again, _ := b.GetJob(ctx, j.ID)
+	if again.Name != "send_email" || again.Attempts != 0 {
+		t.Errorf("expected stored job to be unaffected by callers, got %s attempt %d", again.Name, again.Attempts)
+	}
+}
+
+func TestMemoryBroker_FinishedJobsExpire(t *testing.T) {
+	ctx := context.Background()
+	b, clock := newTestMemoryBroker()
+
+	j := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	b.Enqueue(ctx, j)
+	b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	b.Complete(ctx, j.ID)
+
+	clock.Advance(23 * time.Hour)
+	if _, err := b.GetJob(ctx, j.ID); err != nil {
+		t.Fatalf("expected completed job to be kept for 24h, got %v", err)
+	}
+	clock.Advance(time.Hour)
+	b.MoveScheduledToReady(ctx)
+	if _, err := b.GetJob(ctx, j.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected completed job to expire after 24h, got %v", err)
+	}
+}
+
+func TestMemoryBroker_HooksAndCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	b, _ := newTestMemoryBroker()
+
+	var seen []events.Type
+	for _, typ := range []events.Type{events.TypeEnqueue, events.TypeSuccess} {
+		typ := typ
+		b.Hooks().On(typ, func(ctx context.Context, e events.Event) { seen = append(seen, typ) })
+	}
+
+	j := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	b.Enqueue(ctx, j)
+	b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	b.SaveCheckpoint(ctx, j.ID, []byte("row=42"))
+	if data, _ := b.LoadCheckpoint(ctx, j.ID); string(data) != "row=42" {
+		t.Errorf("expected checkpoint 'row=42', got %q", data)
+	}
+	b.Complete(ctx, j.ID)
+
+	if data, _ := b.LoadCheckpoint(ctx, j.ID); data != nil {
+		t.Errorf("expected checkpoint to be deleted on completion, got %q", data)
+	}
+	if len(seen) != 2 || seen[0] != events.TypeEnqueue || seen[1] != events.TypeSuccess {
+		t.Errorf("expected enqueue and success events, got %v", seen)
+	}
+}
@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPayloadOffloadThreshold is the payload size above which payloads are offloaded (1 MiB)
+const DefaultPayloadOffloadThreshold = 1 << 20
+
+// SetBlobStore enables claim-check offloading of large payloads
+// Payloads larger than threshold bytes are written to store on Enqueue and replaced by
+// a reference; a threshold <= 0 uses DefaultPayloadOffloadThreshold.
+// Passing a nil store disables offloading.
+func (q *RedisQueue) SetBlobStore(store storage.BlobStore, threshold int) {
+	if threshold <= 0 {
+		threshold = DefaultPayloadOffloadThreshold
+	}
+	q.blobStore = store
+	q.payloadThreshold = threshold
+}
+
+// payloadBlobKey returns the blob key used for a job's offloaded payload
+func payloadBlobKey(jobID string) string {
+	return "payloads/" + jobID
+}
+
+// offloadPayload moves a large payload to the blob store and replaces it with a reference
+func (q *RedisQueue) offloadPayload(ctx context.Context, j *job.Job) error {
+	if q.blobStore == nil || j.HasPayloadRef() || len(j.Payload) <= q.payloadThreshold {
+		return nil
+	}
+
+	key := payloadBlobKey(j.ID)
+	if err := q.blobStore.Put(ctx, key, j.Payload); err != nil {
+		return fmt.Errorf("failed to offload payload: %w", err)
+	}
+
+	log.Printf("Offloaded %d byte payload for job %s to blob %s", len(j.Payload), j.ID, key)
+
+	j.PayloadRef = key
+	j.Payload = nil
+	return nil
+}
+
+// marshalJob serializes a job for storage in Redis
+// Offloaded payloads that were resolved in memory are not written back to Redis
+func (q *RedisQueue) marshalJob(j *job.Job) ([]byte, error) {
+	if !j.HasPayloadRef() {
+		return json.Marshal(j)
+	}
+
+	stored := *j
+	stored.Payload = nil
+	return json.Marshal(&stored)
+}
+
+// releasePayload deletes a job's offloaded payload (best-effort)
+func (q *RedisQueue) releasePayload(ctx context.Context, j *job.Job) {
+	if q.blobStore == nil || !j.HasPayloadRef() {
+		return
+	}
+
+	if err := q.blobStore.Delete(ctx, j.PayloadRef); err != nil {
+		log.Printf("Failed to delete offloaded payload %s for job %s: %v", j.PayloadRef, j.ID, err)
+	}
+}
+
+// CleanupExpiredBlobs deletes offloaded payloads whose jobs have expired from Redis
+//
+// Like MoveScheduledToReady, this should be called periodically by the scheduler process.
+// Returns the number of blobs deleted.
+func (q *RedisQueue) CleanupExpiredBlobs(ctx context.Context) (int, error) {
+	if q.blobStore == nil {
+		return 0, nil
+	}
+
+	now := time.Now().Unix()
+	refs, err := q.client.ZRangeByScore(ctx, q.blobExpiryKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", now),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired blobs: %w", err)
+	}
+
+	deleted := 0
+	for _, ref := range refs {
+		if err := q.blobStore.Delete(ctx, ref); err != nil {
+			// Leave it in the set so the next run retries
+			log.Printf("Failed to delete expired blob %s: %v", ref, err)
+			continue
+		}
+		if err := q.client.ZRem(ctx, q.blobExpiryKey, ref).Err(); err != nil {
+			return deleted, fmt.Errorf("failed to remove expired blob reference: %w", err)
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		log.Printf("Deleted %d expired payload blobs", deleted)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupOffloadQueue(t *testing.T, threshold int) (*RedisQueue, *storage.FileStore) {
+	queue, mr := setupTestRedis(t)
+	t.Cleanup(func() {
+		queue.Close()
+		mr.Close()
+	})
+
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	queue.SetBlobStore(store, threshold)
+
+	return queue, store
+}
+
+func TestEnqueue_OffloadsLargePayload(t *testing.T) {
+	queue, store := setupOffloadQueue(t, 64)
+	ctx := context.Background()
+
+	payload := append([]byte(`{"data":"`), bytes.Repeat([]byte("a"), 256)...)
+	payload = append(payload, []byte(`"}`)...)
+	j := job.NewJob("big_job", payload, job.PriorityNormal)
+
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored, err := queue.GetJob(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if stored.PayloadRef != payloadBlobKey(j.ID) {
+		t.Errorf("expected payload ref %q, got %q", payloadBlobKey(j.ID), stored.PayloadRef)
+	}
+	if len(stored.Payload) > 4 {
+		t.Errorf("expected payload to be removed from Redis, got %d bytes", len(stored.Payload))
+	}
+
+	blob, err := store.Get(ctx, stored.PayloadRef)
+	if err != nil {
+		t.Fatalf("expected blob to exist: %v", err)
+	}
+	if !bytes.Equal(blob, payload) {
+		t.Error("blob content does not match original payload")
+	}
+}
+
+func TestEnqueue_SmallPayloadStaysInline(t *testing.T) {
+	queue, _ := setupOffloadQueue(t, 1024)
+	ctx := context.Background()
+
+	j := job.NewJob("small_job", []byte(`{"a":1}`), job.PriorityNormal)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored, _ := queue.GetJob(ctx, j.ID)
+	if stored.HasPayloadRef() {
+		t.Error("expected small payload not to be offloaded")
+	}
+	if string(stored.Payload) != `{"a":1}` {
+		t.Errorf("expected inline payload, got %s", stored.Payload)
+	}
+}
+
+func TestComplete_DeletesOffloadedPayload(t *testing.T) {
+	queue, store := setupOffloadQueue(t, 8)
+	ctx := context.Background()
+
+	j := job.NewJob("big_job", []byte(`{"data":"0123456789"}`), job.PriorityHigh)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	dequeued, err := queue.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+	if err != nil || dequeued == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+
+	if err := queue.Complete(ctx, dequeued.ID); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	if _, err := store.Get(ctx, payloadBlobKey(j.ID)); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("expected blob to be deleted on completion, got %v", err)
+	}
+}
+
+func TestFail_ResolvedPayloadNotWrittenBack(t *testing.T) {
+	queue, store := setupOffloadQueue(t, 8)
+	ctx := context.Background()
+
+	original := job.DefaultBlobStore
+	job.DefaultBlobStore = store
+	defer func() { job.DefaultBlobStore = original }()
+
+	j := job.NewJob("big_job", []byte(`{"data":"0123456789"}`), job.PriorityHigh)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	dequeued, _ := queue.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+	var payload map[string]string
+	if err := dequeued.UnmarshalPayload(&payload); err != nil {
+		t.Fatalf("failed to resolve payload: %v", err)
+	}
+	if payload["data"] != "0123456789" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+
+	if err := queue.Fail(ctx, dequeued, "boom"); err != nil {
+		t.Fatalf("failed to fail job: %v", err)
+	}
+
+	stored, _ := queue.GetJob(ctx, j.ID)
+	if len(stored.Payload) > 4 {
+		t.Errorf("expected resolved payload not to be written back to Redis, got %s", stored.Payload)
+	}
+	if !stored.HasPayloadRef() {
+		t.Error("expected payload ref to be preserved across retries")
+	}
+}
+
+func TestCleanupExpiredBlobs(t *testing.T) {
+	queue, store := setupOffloadQueue(t, 8)
+	ctx := context.Background()
+
+	j := job.NewJob("big_job", []byte(`{"data":"0123456789"}`), job.PriorityHigh)
+	j.MaxRetries = 1
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	dequeued, _ := queue.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+
+	if err := queue.Fail(ctx, dequeued, "fatal"); err != nil {
+		t.Fatalf("failed to fail job: %v", err)
+	}
+
+	// Not yet expired - nothing to clean up
+	deleted, err := queue.CleanupExpiredBlobs(ctx)
+	if err != nil || deleted != 0 {
+		t.Fatalf("expected no blobs deleted before expiry, got %d (%v)", deleted, err)
+	}
+	if _, err := store.Get(ctx, payloadBlobKey(j.ID)); err != nil {
+		t.Fatalf("expected blob to survive until cleanup, got %v", err)
+	}
+
+	// Simulate the dead-lettered job data expiring
+	queue.client.ZAdd(ctx, queue.blobExpiryKey, redis.Z{
+		Score:  float64(time.Now().Add(-time.Second).Unix()),
+		Member: payloadBlobKey(j.ID),
+	})
+
+	deleted, err = queue.CleanupExpiredBlobs(ctx)
+	if err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 blob deleted, got %d", deleted)
+	}
+	if _, err := store.Get(ctx, payloadBlobKey(j.ID)); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("expected blob to be deleted, got %v", err)
+	}
+
+	// Second run has nothing to do
+	deleted, _ = queue.CleanupExpiredBlobs(ctx)
+	if deleted != 0 {
+		t.Errorf("expected 0 blobs deleted on second run, got %d", deleted)
+	}
+}
@@ -0,0 +1,849 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+)
+
+// PostgresSchema creates the tables PostgresQueue uses
+// NewPostgresQueue applies it (it is idempotent); teams that manage their own
+// migrations can apply it themselves.
+const PostgresSchema = `
+CREATE SEQUENCE IF NOT EXISTS bananas_jobs_position;
+
+CREATE TABLE IF NOT EXISTS bananas_jobs (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	priority    TEXT NOT NULL,
+	routing_key TEXT NOT NULL,
+	-- ready, scheduled, processing, dead or done
+	state       TEXT NOT NULL,
+	run_at      TIMESTAMPTZ,
+	-- dequeue order within a priority; requeued jobs get negative positions to run first
+	position    BIGINT NOT NULL,
+	data        BYTEA NOT NULL,
+	checkpoint  BYTEA,
+	expires_at  TIMESTAMPTZ,
+	updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- lease of a processing job: workers extend it with Heartbeat, ReapExpiredLeases retries it once it passes
+ALTER TABLE bananas_jobs ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS bananas_jobs_ready ON bananas_jobs (priority, position) WHERE state = 'ready';
+CREATE INDEX IF NOT EXISTS bananas_jobs_scheduled ON bananas_jobs (run_at) WHERE state = 'scheduled';
+CREATE INDEX IF NOT EXISTS bananas_jobs_expires ON bananas_jobs (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS bananas_jobs_leases ON bananas_jobs (locked_until) WHERE state = 'processing';
+`
+
+// postgresReadyChannel is the LISTEN/NOTIFY channel announcing ready jobs
+const postgresReadyChannel = "bananas_jobs_ready"
+
+// postgresSchemaLock is the advisory lock key serializing schema creation
+const postgresSchemaLock = 0x62616e616e6173
+
+// DefaultPostgresDequeueTimeout is how long PostgresQueue.Dequeue waits for a job by default
+const DefaultPostgresDequeueTimeout = time.Second
+
+// DBTX is the part of a pgx connection, pool or transaction PostgresQueue writes through
+// *pgxpool.Pool, *pgx.Conn and pgx.Tx all implement it.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgresQueue is a Broker that stores jobs in PostgreSQL
+//
+// Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any number of them
+// can dequeue concurrently without blocking each other, and idle workers are woken
+// by LISTEN/NOTIFY instead of polling. EnqueueTx inserts a job inside the caller's
+// transaction, so it becomes visible (and workers are notified) only if the
+// business write it belongs to commits. Dequeued jobs are leased like on
+// RedisQueue (see Heartbeat and ReapExpiredLeases).
+//
+// Dequeue serves the default route only; routing keys and job-specialized workers
+// need RedisQueue or MemoryBroker.
+type PostgresQueue struct {
+	pool *pgxpool.Pool
+	// signal is closed and replaced whenever a notification arrives, waking blocked Dequeue calls
+	signalMu sync.Mutex
+	signal   chan struct{}
+	// stopListen stops the LISTEN loop; listenDone is closed when it has stopped
+	stopListen context.CancelFunc
+	listenDone chan struct{}
+	// How long Dequeue waits for a job before returning nil
+	dequeueTimeout time.Duration
+	// How long a dequeued job stays leased without a heartbeat
+	leaseDuration time.Duration
+	// TTL configuration for finished job data
+	completedJobTTL time.Duration
+	failedJobTTL    time.Duration
+	hooks           *events.Registry
+}
+
+// PostgresQueue implements Broker
+var _ Broker = (*PostgresQueue)(nil)
+
+// NewPostgresQueue connects to PostgreSQL, creates the schema if needed and starts
+// listening for ready jobs
+func NewPostgresQueue(databaseURL string) (*PostgresQueue, error) {
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	if err := migratePostgres(ctx, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	listenCtx, stop := context.WithCancel(context.Background())
+	q := &PostgresQueue{
+		pool:            pool,
+		signal:          make(chan struct{}),
+		stopListen:      stop,
+		listenDone:      make(chan struct{}),
+		dequeueTimeout:  DefaultPostgresDequeueTimeout,
+		leaseDuration:   DefaultLeaseDuration,
+		completedJobTTL: 24 * time.Hour,
+		failedJobTTL:    7 * 24 * time.Hour,
+		hooks:           events.NewRegistry(),
+	}
+	go q.listen(listenCtx)
+
+	log.Printf("Connected to PostgreSQL (pool: %d max)", pool.Config().MaxConns)
+	return q, nil
+}
+
+// migratePostgres applies PostgresSchema, serialized across processes starting together
+func migratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(postgresSchemaLock)); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+	if _, err := tx.Exec(ctx, PostgresSchema); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	return nil
+}
+
+// listen holds a connection LISTENing for ready jobs until ctx is cancelled,
+// reconnecting after connection errors
+func (q *PostgresQueue) listen(ctx context.Context) {
+	defer close(q.listenDone)
+
+	for ctx.Err() == nil {
+		pooled, err := q.pool.Acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to acquire LISTEN connection: %v", err)
+				sleepContext(ctx, time.Second)
+			}
+			continue
+		}
+		// The connection stays LISTENing, so it must never go back to the pool
+		conn := pooled.Hijack()
+
+		_, err = conn.Exec(ctx, "LISTEN "+postgresReadyChannel)
+		for err == nil {
+			_, err = conn.WaitForNotification(ctx)
+			if err == nil {
+				q.wake()
+			}
+		}
+		conn.Close(context.Background())
+
+		if ctx.Err() == nil {
+			log.Printf("LISTEN connection lost, reconnecting: %v", err)
+			// Notifications may have been missed while reconnecting
+			q.wake()
+			sleepContext(ctx, time.Second)
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// wake signals blocked Dequeue calls that a job may have become ready
+func (q *PostgresQueue) wake() {
+	q.signalMu.Lock()
+	defer q.signalMu.Unlock()
+	close(q.signal)
+	q.signal = make(chan struct{})
+}
+
+// waitSignal returns the channel closed by the next wake
+func (q *PostgresQueue) waitSignal() <-chan struct{} {
+	q.signalMu.Lock()
+	defer q.signalMu.Unlock()
+	return q.signal
+}
+
+// SetDequeueTimeout sets how long Dequeue waits for a job to become ready before returning nil
+func (q *PostgresQueue) SetDequeueTimeout(d time.Duration) {
+	q.dequeueTimeout = d
+}
+
+// SetLeaseDuration sets how long a dequeued job is leased to its worker
+// Non-positive values reset it to DefaultLeaseDuration (see RedisQueue.SetLeaseDuration).
+func (q *PostgresQueue) SetLeaseDuration(d time.Duration) {
+	if d <= 0 {
+		d = DefaultLeaseDuration
+	}
+	q.leaseDuration = d
+}
+
+// LeaseDuration returns how long a dequeued job is leased without a heartbeat
+func (q *PostgresQueue) LeaseDuration() time.Duration {
+	return q.leaseDuration
+}
+
+// Pool returns the queue's connection pool, e.g. to begin transactions for EnqueueTx
+func (q *PostgresQueue) Pool() *pgxpool.Pool {
+	return q.pool
+}
+
+// Hooks returns the registry that receives this queue's job lifecycle events
+func (q *PostgresQueue) Hooks() *events.Registry {
+	return q.hooks
+}
+
+// SetHooks replaces the lifecycle hook registry
+func (q *PostgresQueue) SetHooks(hooks *events.Registry) {
+	q.hooks = hooks
+}
+
+// postgresRoutingKey normalizes a job's routing key ("" is the default route)
+func postgresRoutingKey(j *job.Job) string {
+	if j.RoutingKey == "" {
+		return job.DefaultRoutingKey
+	}
+	return j.RoutingKey
+}
+
+// Enqueue inserts a job as ready for workers
+func (q *PostgresQueue) Enqueue(ctx context.Context, j *job.Job) error {
+	return q.insert(ctx, q.pool, j, nil)
+}
+
+// EnqueueTx inserts a job in the caller's transaction
+// Workers see the job (and are notified) only once tx commits; if it rolls back,
+// the job was never enqueued. Lifecycle hooks run when EnqueueTx returns.
+func (q *PostgresQueue) EnqueueTx(ctx context.Context, tx DBTX, j *job.Job) error {
+	return q.insert(ctx, tx, j, nil)
+}
+
+// Schedule inserts a job that MoveScheduledToReady makes ready once at has passed
+func (q *PostgresQueue) Schedule(ctx context.Context, j *job.Job, at time.Time) error {
+	return q.ScheduleTx(ctx, q.pool, j, at)
+}
+
+// ScheduleTx is like Schedule, but inserts the job in the caller's transaction
+func (q *PostgresQueue) ScheduleTx(ctx context.Context, tx DBTX, j *job.Job, at time.Time) error {
+	j.ScheduledFor = &at
+	j.UpdateStatus(job.StatusScheduled)
+	return q.insert(ctx, tx, j, &at)
+}
+
+// insert stores a new job as ready, or as scheduled when at is set
+func (q *PostgresQueue) insert(ctx context.Context, db DBTX, j *job.Job, at *time.Time) error {
+	jobData, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	state := "ready"
+	if at != nil {
+		state = "scheduled"
+	}
+
+	_, err = db.Exec(ctx, `
+		INSERT INTO bananas_jobs (id, name, priority, routing_key, state, run_at, position, data)
+		VALUES ($1, $2, $3, $4, $5, $6, nextval('bananas_jobs_position'), $7)`,
+		j.ID, j.Name, string(j.Priority), postgresRoutingKey(j), state, at, jobData)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	if at == nil {
+		// NOTIFY is transactional: inside EnqueueTx it is delivered on commit
+		if _, err := db.Exec(ctx, "SELECT pg_notify($1, '')", postgresReadyChannel); err != nil {
+			return fmt.Errorf("failed to notify workers: %w", err)
+		}
+	}
+
+	log.Printf("Enqueued job %s (%s)", j.ID, state)
+	q.hooks.Emit(ctx, events.TypeEnqueue, j)
+	return nil
+}
+
+// Dequeue claims the next ready job on the default route from the highest priority
+// given, waiting up to the dequeue timeout for one to be enqueued
+func (q *PostgresQueue) Dequeue(ctx context.Context, priorities []job.JobPriority) (*job.Job, error) {
+	if len(priorities) == 0 {
+		return nil, nil
+	}
+	order := make([]string, len(priorities))
+	for i, priority := range priorities {
+		order[i] = string(priority)
+	}
+
+	timer := time.NewTimer(q.dequeueTimeout)
+	defer timer.Stop()
+
+	for {
+		// Take the signal before looking, so a job enqueued in between still wakes us
+		signal := q.waitSignal()
+
+		var jobID string
+		var jobData []byte
+		err := q.pool.QueryRow(ctx, `
+			UPDATE bananas_jobs
+			SET state = 'processing', locked_until = now() + $3 * interval '1 millisecond', updated_at = now()
+			WHERE id = (
+				SELECT id FROM bananas_jobs
+				WHERE state = 'ready' AND routing_key = $2 AND priority = ANY($1::text[])
+				ORDER BY array_position($1::text[], priority), position
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, data`, order, job.DefaultRoutingKey, q.leaseDuration.Milliseconds()).Scan(&jobID, &jobData)
+		if err == nil {
+			var j job.Job
+			if err := json.Unmarshal(jobData, &j); err != nil {
+				q.buryUndecodable(ctx, jobID, err)
+				continue
+			}
+			log.Printf("Dequeued job %s from %s queue", j.ID, j.Priority)
+			return &j, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to dequeue job: %w", err)
+		}
+
+		select {
+		case <-signal:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// buryUndecodable moves a claimed job whose data can't be decoded to the dead
+// letter queue, so it isn't left processing forever (best-effort)
+func (q *PostgresQueue) buryUndecodable(ctx context.Context, jobID string, decodeErr error) {
+	log.Printf("Moving job %s to dead letter queue: failed to unmarshal job: %v", jobID, decodeErr)
+	_, err := q.pool.Exec(ctx, `
+		UPDATE bananas_jobs
+		SET state = 'dead', locked_until = NULL, expires_at = $2, updated_at = now()
+		WHERE id = $1 AND state = 'processing'`, jobID, time.Now().Add(q.failedJobTTL))
+	if err != nil {
+		log.Printf("Failed to move undecodable job %s to dead letter queue: %v", jobID, err)
+	}
+}
+
+// settle stores a processing job's new state, data and retention in one update
+// Returns ErrLeaseLost if the job is no longer processing, e.g. because it was
+// already finished or reaped.
+func (q *PostgresQueue) settle(ctx context.Context, j *job.Job, state string, runAt *time.Time, ttl time.Duration, keepCheckpoint bool) error {
+	jobData, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	var expiresAt *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	tag, err := q.pool.Exec(ctx, `
+		UPDATE bananas_jobs
+		SET state = $2, run_at = $3, data = $4, expires_at = $5, locked_until = NULL, updated_at = now(),
+			checkpoint = CASE WHEN $6::boolean THEN checkpoint END
+		WHERE id = $1 AND state = 'processing'`,
+		j.ID, state, runAt, jobData, expiresAt, keepCheckpoint)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: job %s is not running", ErrLeaseLost, j.ID)
+	}
+	return nil
+}
+
+// Complete marks a job as completed
+func (q *PostgresQueue) Complete(ctx context.Context, jobID string) error {
+	j, err := q.GetJob(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job data: %w", err)
+	}
+
+	j.UpdateStatus(job.StatusCompleted)
+	if err := q.settle(ctx, j, "done", nil, q.completedJobTTL, false); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+
+	log.Printf("Completed job %s (TTL: %v)", jobID, q.completedJobTTL)
+	q.hooks.Emit(ctx, events.TypeSuccess, j)
+	return nil
+}
+
+// Fail records a failed attempt and schedules a retry after 2^attempts seconds,
+// or moves the job to the dead letter queue once its retries are exhausted
+func (q *PostgresQueue) Fail(ctx context.Context, j *job.Job, errMsg string) error {
+	return q.fail(ctx, j, errMsg, 0)
+}
+
+// FailAfter is like Fail, but a retry is scheduled after delay instead of the exponential backoff
+func (q *PostgresQueue) FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	return q.fail(ctx, j, errMsg, delay)
+}
+
+// fail records a failed attempt and schedules a retry after delay (exponential
+// backoff when delay is 0), or moves the job to the dead letter queue
+// j is only updated once the transition has been stored.
+func (q *PostgresQueue) fail(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	updated := *j
+	updated.Attempts++
+	updated.Error = errMsg
+
+	if updated.Attempts >= updated.MaxRetries {
+		return q.moveToDeadLetter(ctx, j, &updated)
+	}
+
+	retryDelay := time.Duration(1<<updated.Attempts) * time.Second
+	if delay > 0 {
+		retryDelay = delay
+	}
+	nextRetryTime := time.Now().Add(retryDelay)
+	updated.UpdateStatus(job.StatusPending)
+	updated.ScheduledFor = &nextRetryTime
+
+	// The checkpoint is kept so the next attempt can resume from it
+	if err := q.settle(ctx, &updated, "scheduled", &nextRetryTime, 0, true); err != nil {
+		return fmt.Errorf("failed to schedule job for retry: %w", err)
+	}
+	copyState(j, &updated)
+
+	log.Printf("Job %s failed (attempt %d/%d), scheduled for retry in %v at %s",
+		j.ID, j.Attempts, j.MaxRetries, retryDelay, nextRetryTime.Format(time.RFC3339))
+	q.hooks.Emit(ctx, events.TypeFailure, j)
+	return nil
+}
+
+// DeadLetter moves a job straight to the dead letter queue without retrying
+func (q *PostgresQueue) DeadLetter(ctx context.Context, j *job.Job, errMsg string) error {
+	updated := *j
+	updated.Attempts++
+	updated.Error = errMsg
+	return q.moveToDeadLetter(ctx, j, &updated)
+}
+
+// moveToDeadLetter marks updated (a copy of j) as failed and moves it to the dead
+// letter queue, then copies its state to j
+func (q *PostgresQueue) moveToDeadLetter(ctx context.Context, j, updated *job.Job) error {
+	updated.UpdateStatus(job.StatusFailed)
+	updated.ScheduledFor = nil
+
+	if err := q.settle(ctx, updated, "dead", nil, q.failedJobTTL, true); err != nil {
+		return fmt.Errorf("failed to move job to dead letter queue: %w", err)
+	}
+	copyState(j, updated)
+
+	log.Printf("Job %s moved to dead letter queue after %d attempts (TTL: %v)", j.ID, j.Attempts, q.failedJobTTL)
+	q.hooks.Emit(ctx, events.TypeDead, j)
+	return nil
+}
+
+// Expire discards a dequeued job whose deadline passed before it started
+func (q *PostgresQueue) Expire(ctx context.Context, j *job.Job, reason string) error {
+	updated := *j
+	updated.UpdateStatus(job.StatusExpired)
+	updated.Error = reason
+
+	if err := q.settle(ctx, &updated, "done", nil, q.completedJobTTL, false); err != nil {
+		return fmt.Errorf("failed to expire job: %w", err)
+	}
+	copyState(j, &updated)
+
+	log.Printf("Expired job %s: %s", j.ID, reason)
+	q.hooks.Emit(ctx, events.TypeExpired, j)
+	return nil
+}
+
+// Requeue returns a dequeued job to the front of its queue without counting an attempt
+// It is a no-op if the job is no longer being processed.
+func (q *PostgresQueue) Requeue(ctx context.Context, j *job.Job) error {
+	j.UpdateStatus(job.StatusPending)
+	jobData, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	tag, err := q.pool.Exec(ctx, `
+		UPDATE bananas_jobs
+		SET state = 'ready', position = -nextval('bananas_jobs_position'), data = $2, locked_until = NULL, updated_at = now()
+		WHERE id = $1 AND state = 'processing'`, j.ID, jobData)
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := q.pool.Exec(ctx, "SELECT pg_notify($1, '')", postgresReadyChannel); err != nil {
+		log.Printf("Failed to notify workers of requeued job %s: %v", j.ID, err)
+	}
+
+	log.Printf("Requeued job %s (attempt not counted)", j.ID)
+	q.hooks.Emit(ctx, events.TypeRequeue, j)
+	return nil
+}
+
+// Heartbeat extends a running job's lease by the lease duration
+// Returns ErrLeaseLost if the job is no longer processing or its lease was reaped.
+func (q *PostgresQueue) Heartbeat(ctx context.Context, jobID string) error {
+	tag, err := q.pool.Exec(ctx, `
+		UPDATE bananas_jobs SET locked_until = now() + $2 * interval '1 millisecond'
+		WHERE id = $1 AND state = 'processing' AND locked_until IS NOT NULL`,
+		jobID, q.leaseDuration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReapExpiredLeases returns processing jobs whose lease expired to the retry path
+//
+// Like RedisQueue.ReapExpiredLeases, a job whose worker stopped heartbeating is
+// failed like any other attempt, keeping its checkpoint. Jobs that were processing
+// before leases existed have none and are reaped too. Rows whose data can't be
+// decoded are moved to the dead letter queue. Returns the number of jobs reaped.
+func (q *PostgresQueue) ReapExpiredLeases(ctx context.Context) (int, error) {
+	// Clearing the lease first claims the job for this reaper: concurrent reapers
+	// skip it, and a late heartbeat fails with ErrLeaseLost
+	rows, err := q.pool.Query(ctx, `
+		UPDATE bananas_jobs SET locked_until = NULL
+		WHERE id IN (
+			SELECT id FROM bananas_jobs
+			WHERE state = 'processing' AND (locked_until IS NULL OR locked_until <= now())
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, data`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired leases: %w", err)
+	}
+	type expired struct {
+		id   string
+		data []byte
+	}
+	var expiredJobs []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read expired lease: %w", err)
+		}
+		expiredJobs = append(expiredJobs, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get expired leases: %w", err)
+	}
+
+	reaped := 0
+	for _, e := range expiredJobs {
+		var j job.Job
+		if err := json.Unmarshal(e.data, &j); err != nil {
+			q.buryUndecodable(ctx, e.id, err)
+			continue
+		}
+
+		err := q.fail(ctx, &j, "lease expired: worker stopped sending heartbeats", 0)
+		if errors.Is(err, ErrLeaseLost) {
+			// The worker finished the job after all
+			continue
+		}
+		if err != nil {
+			return reaped, err
+		}
+		log.Printf("Reaped job %s after its lease expired", e.id)
+		reaped++
+	}
+	return reaped, nil
+}
+
+// MoveScheduledToReady makes scheduled jobs and retries whose time has come ready,
+// earliest first, and deletes finished jobs whose TTL has passed
+func (q *PostgresQueue) MoveScheduledToReady(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scheduled jobs: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// SKIP LOCKED lets several schedulers run without moving a job twice
+	rows, err := tx.Query(ctx, `
+		SELECT id, data FROM bananas_jobs
+		WHERE state = 'scheduled' AND run_at <= $1
+		ORDER BY run_at, id
+		FOR UPDATE SKIP LOCKED`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scheduled jobs: %w", err)
+	}
+	type due struct {
+		id   string
+		data []byte
+	}
+	var dueJobs []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read scheduled job: %w", err)
+		}
+		dueJobs = append(dueJobs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get scheduled jobs: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, d := range dueJobs {
+		var j job.Job
+		if err := json.Unmarshal(d.data, &j); err != nil {
+			log.Printf("Error unmarshaling job %s: %v", d.id, err)
+			continue
+		}
+
+		// Clear scheduled time - the job is ready from now on
+		j.ScheduledFor = nil
+		if j.Status == job.StatusScheduled {
+			j.Status = job.StatusPending
+		}
+		j.UpdatedAt = now
+		updatedData, err := json.Marshal(j)
+		if err != nil {
+			log.Printf("Error marshaling job %s: %v", d.id, err)
+			continue
+		}
+
+		batch.Queue(`
+			UPDATE bananas_jobs
+			SET state = 'ready', run_at = NULL, position = nextval('bananas_jobs_position'), data = $2, updated_at = now()
+			WHERE id = $1`, d.id, updatedData)
+	}
+	moved := batch.Len()
+	if moved > 0 {
+		batch.Queue("SELECT pg_notify($1, '')", postgresReadyChannel)
+	}
+	batch.Queue("DELETE FROM bananas_jobs WHERE expires_at <= $1", now)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, fmt.Errorf("failed to execute batch job updates: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to execute batch job updates: %w", err)
+	}
+
+	if moved > 0 {
+		log.Printf("Moved %d scheduled jobs to ready queues", moved)
+	}
+	return moved, nil
+}
+
+// GetJob returns a job by ID
+func (q *PostgresQueue) GetJob(ctx context.Context, jobID string) (*job.Job, error) {
+	var jobData []byte
+	err := q.pool.QueryRow(ctx, `
+		SELECT data FROM bananas_jobs
+		WHERE id = $1 AND (expires_at IS NULL OR expires_at > now())`, jobID).Scan(&jobData)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var j job.Job
+	if err := json.Unmarshal(jobData, &j); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	return &j, nil
+}
+
+// Cancel removes a job that is still waiting or scheduled and marks it cancelled
+func (q *PostgresQueue) Cancel(ctx context.Context, jobID string) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the row keeps a concurrent Dequeue from claiming it meanwhile
+	var state string
+	var jobData []byte
+	err = tx.QueryRow(ctx, `
+		SELECT state, data FROM bananas_jobs
+		WHERE id = $1 AND (expires_at IS NULL OR expires_at > now())
+		FOR UPDATE`, jobID).Scan(&state, &jobData)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	var j job.Job
+	if err := json.Unmarshal(jobData, &j); err != nil {
+		return fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	if state != "ready" && state != "scheduled" {
+		return fmt.Errorf("%w: job %s is %s", ErrNotCancellable, jobID, j.Status)
+	}
+
+	j.UpdateStatus(job.StatusCancelled)
+	j.ScheduledFor = nil
+	updatedData, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE bananas_jobs
+		SET state = 'done', run_at = NULL, data = $2, checkpoint = NULL, expires_at = $3, updated_at = now()
+		WHERE id = $1`, jobID, updatedData, time.Now().Add(q.completedJobTTL))
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	log.Printf("Cancelled job %s", jobID)
+	q.hooks.Emit(ctx, events.TypeCancel, &j)
+	return nil
+}
+
+// Stats returns the number of ready jobs on the default route, and of scheduled,
+// processing and dead jobs
+func (q *PostgresQueue) Stats(ctx context.Context) (Stats, error) {
+	rows, err := q.pool.Query(ctx, `
+		SELECT state, priority, count(*) FROM bananas_jobs
+		WHERE state IN ('scheduled', 'processing', 'dead')
+			OR (state = 'ready' AND routing_key = $1)
+		GROUP BY state, priority`, job.DefaultRoutingKey)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := Stats{Ready: map[job.JobPriority]int64{
+		job.PriorityHigh:   0,
+		job.PriorityNormal: 0,
+		job.PriorityLow:    0,
+	}}
+	for rows.Next() {
+		var state, priority string
+		var n int64
+		if err := rows.Scan(&state, &priority, &n); err != nil {
+			return Stats{}, fmt.Errorf("failed to read queue stats: %w", err)
+		}
+		switch state {
+		case "ready":
+			stats.Ready[job.JobPriority(priority)] += n
+		case "scheduled":
+			stats.Scheduled += n
+		case "processing":
+			stats.Processing += n
+		case "dead":
+			stats.Dead += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Stats{}, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	return stats, nil
+}
+
+// RefreshQueueMetrics records the current depths of the priority queues in metrics
+func (q *PostgresQueue) RefreshQueueMetrics(ctx context.Context) {
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		log.Printf("Failed to get queue depths: %v", err)
+		return
+	}
+	for priority, depth := range stats.Ready {
+		metrics.Default().RecordQueueDepth(priority, depth)
+	}
+}
+
+// SaveCheckpoint stores a running job's checkpoint, replacing the previous one
+func (q *PostgresQueue) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	if _, err := q.pool.Exec(ctx, "UPDATE bananas_jobs SET checkpoint = $2 WHERE id = $1", jobID, data); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint returns a job's latest checkpoint, or nil if it has none
+func (q *PostgresQueue) LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error) {
+	var data []byte
+	err := q.pool.QueryRow(ctx, "SELECT checkpoint FROM bananas_jobs WHERE id = $1", jobID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return data, nil
+}
+
+// Close stops listening for notifications and closes the connection pool
+func (q *PostgresQueue) Close() error {
+	q.stopListen()
+	<-q.listenDone
+	q.pool.Close()
+	return nil
+}
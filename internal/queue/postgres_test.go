@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// newTestPostgresQueue connects to the database in BANANAS_TEST_POSTGRES_URL and
+// empties the jobs table, or skips the test when it isn't set
+// Start one with: docker compose -f docker-compose.dev.yml up -d postgres
+func newTestPostgresQueue(t *testing.T) *PostgresQueue {
+	t.Helper()
+	url := os.Getenv("BANANAS_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("BANANAS_TEST_POSTGRES_URL not set")
+	}
+
+	q, err := NewPostgresQueue(url)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	if _, err := q.Pool().Exec(context.Background(), "TRUNCATE bananas_jobs"); err != nil {
+		t.Fatalf("failed to empty jobs table: %v", err)
+	}
+	q.SetDequeueTimeout(200 * time.Millisecond)
+	return q
+}
+
+func TestNewPostgresQueue_ConnectionFailure(t *testing.T) {
+	q, err := NewPostgresQueue("postgres://bananas@127.0.0.1:1/bananas?connect_timeout=1")
+	if err == nil {
+		q.Close()
+		t.Fatal("expected error connecting to a closed port")
+	}
+}
+
+func TestPostgresQueue_EnqueueTx(t *testing.T) {
+	ctx := context.Background()
+	q := newTestPostgresQueue(t)
+
+	// Rolled back: the job never existed
+	tx, err := q.Pool().Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	rolledBack := job.NewJob("send_receipt", []byte(`{}`), job.PriorityNormal)
+	if err := q.EnqueueTx(ctx, tx, rolledBack); err != nil {
+		t.Fatalf("EnqueueTx failed: %v", err)
+	}
+	tx.Rollback(ctx)
+	if _, err := q.GetJob(ctx, rolledBack.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected rolled back job to not exist, got %v", err)
+	}
+
+	// Committed: invisible until commit, then ready
+	tx, err = q.Pool().Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	committed := job.NewJob("send_receipt", []byte(`{}`), job.PriorityNormal)
+	if err := q.EnqueueTx(ctx, tx, committed); err != nil {
+		t.Fatalf("EnqueueTx failed: %v", err)
+	}
+	if _, err := q.GetJob(ctx, committed.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected job to be invisible before commit, got %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	got, err := q.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	if err != nil || got == nil || got.ID != committed.ID {
+		t.Errorf("expected committed job to be dequeued, got %v, %v", got, err)
+	}
+}
+
+func TestPostgresQueue_NotifyWakesDequeue(t *testing.T) {
+	ctx := context.Background()
+	q := newTestPostgresQueue(t)
+	q.SetDequeueTimeout(10 * time.Second)
+
+	j := job.NewJob("late", []byte(`{}`), job.PriorityHigh)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		q.Enqueue(ctx, j)
+	}()
+
+	start := time.Now()
+	got, err := q.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+	if err != nil || got == nil || got.ID != j.ID {
+		t.Fatalf("expected the late job, got %v, %v", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected NOTIFY to wake Dequeue, waited %v", elapsed)
+	}
+}
+
+func TestPostgresQueue_Checkpoints(t *testing.T) {
+	ctx := context.Background()
+	q := newTestPostgresQueue(t)
+
+	j := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	q.Enqueue(ctx, j)
+	q.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+
+	if err := q.SaveCheckpoint(ctx, j.ID, []byte("row=42")); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+	if data, _ := q.LoadCheckpoint(ctx, j.ID); string(data) != "row=42" {
+		t.Errorf("expected checkpoint 'row=42', got %q", data)
+	}
+
+	q.Complete(ctx, j.ID)
+	if data, _ := q.LoadCheckpoint(ctx, j.ID); data != nil {
+		t.Errorf("expected checkpoint to be cleared on completion, got %q", data)
+	}
+}
+
+func TestPostgresQueue_DequeueBuriesUndecodableJobs(t *testing.T) {
+	ctx := context.Background()
+	q := newTestPostgresQueue(t)
+
+	_, err := q.Pool().Exec(ctx, `
+		INSERT INTO bananas_jobs (id, name, priority, routing_key, state, position, data)
+		VALUES ('corrupt', 'import', 'normal', $1, 'ready', 0, 'not json')`, job.DefaultRoutingKey)
+	if err != nil {
+		t.Fatalf("failed to insert corrupt job: %v", err)
+	}
+	j := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	q.Enqueue(ctx, j)
+
+	// The corrupt row is skipped, not returned as an error
+	got, err := q.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	if err != nil || got == nil || got.ID != j.ID {
+		t.Fatalf("expected the valid job, got %v, %v", got, err)
+	}
+	var state string
+	q.Pool().QueryRow(ctx, "SELECT state FROM bananas_jobs WHERE id = 'corrupt'").Scan(&state)
+	if state != "dead" {
+		t.Errorf("expected the corrupt job to be dead, got %s", state)
+	}
+}
+
+func TestPostgresQueue_ReapsJobsWithoutLease(t *testing.T) {
+	ctx := context.Background()
+	q := newTestPostgresQueue(t)
+
+	j := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	q.Enqueue(ctx, j)
+	q.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+
+	// Jobs that were processing before leases existed have none
+	if _, err := q.Pool().Exec(ctx, "UPDATE bananas_jobs SET locked_until = NULL WHERE id = $1", j.ID); err != nil {
+		t.Fatalf("failed to clear lease: %v", err)
+	}
+	if reaped, err := q.ReapExpiredLeases(ctx); err != nil || reaped != 1 {
+		t.Fatalf("expected 1 reaped job, got %d, %v", reaped, err)
+	}
+	if got, _ := q.GetJob(ctx, j.ID); got.Status != job.StatusPending || got.Attempts != 1 {
+		t.Errorf("expected a pending retry, got %s attempt %d", got.Status, got.Attempts)
+	}
+}
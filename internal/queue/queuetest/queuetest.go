@@ -0,0 +1,550 @@
+// Package queuetest is a conformance test suite for queue.Broker implementations.
+//
+// Every broker runs the same suite from its own tests:
+//
+//	func TestBrokerConformance(t *testing.T) {
+//		queuetest.Run(t, func(t *testing.T) (queue.Broker, func(time.Duration)) {
+//			return newTestBroker(t), nil
+//		})
+//	}
+package queuetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/queue"
+)
+
+// Factory creates an empty broker for one test
+// advance moves the broker's clock forward. It is nil for brokers that use the
+// wall clock, and the tests that need time to pass are skipped for them.
+type Factory func(t *testing.T) (b queue.Broker, advance func(time.Duration))
+
+// test is one conformance check
+type test struct {
+	name string
+	run  func(t *testing.T, b queue.Broker, advance func(time.Duration))
+}
+
+var tests = []test{
+	{"EnqueueDequeue", testEnqueueDequeue},
+	{"PriorityOrder", testPriorityOrder},
+	{"Complete", testComplete},
+	{"FailSchedulesRetry", testFailSchedulesRetry},
+	{"FailAfterDelay", testFailAfterDelay},
+	{"RetriesExhausted", testRetriesExhausted},
+	{"DeadLetter", testDeadLetter},
+	{"Expire", testExpire},
+	{"Requeue", testRequeue},
+	{"Schedule", testSchedule},
+	{"ScheduleInFuture", testScheduleInFuture},
+	{"GetJobNotFound", testGetJobNotFound},
+	{"CancelWaiting", testCancelWaiting},
+	{"CancelScheduled", testCancelScheduled},
+	{"CancelRunning", testCancelRunning},
+	{"Stats", testStats},
+	{"ConcurrentDequeue", testConcurrentDequeue},
+	{"StaleTransitions", testStaleTransitions},
+	{"LeaseExpiry", testLeaseExpiry},
+}
+
+// Run runs the conformance suite against brokers created by newBroker
+func Run(t *testing.T, newBroker Factory) {
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b, advance := newBroker(t)
+			t.Cleanup(func() { b.Close() })
+			tc.run(t, b, advance)
+		})
+	}
+}
+
+var (
+	high   = []job.JobPriority{job.PriorityHigh}
+	normal = []job.JobPriority{job.PriorityNormal}
+	all    = []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+)
+
+// newJob creates a job with a distinct payload
+func newJob(name string, priority job.JobPriority) *job.Job {
+	return job.NewJob(name, []byte(fmt.Sprintf(`{"name":%q}`, name)), priority)
+}
+
+// mustEnqueue enqueues a job or fails the test
+func mustEnqueue(t *testing.T, b queue.Broker, j *job.Job) {
+	t.Helper()
+	if err := b.Enqueue(context.Background(), j); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+}
+
+// mustDequeue dequeues a job or fails the test
+func mustDequeue(t *testing.T, b queue.Broker, priorities []job.JobPriority) *job.Job {
+	t.Helper()
+	j, err := b.Dequeue(context.Background(), priorities)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if j == nil {
+		t.Fatal("Dequeue() returned no job, want one")
+	}
+	return j
+}
+
+// mustGet loads a job or fails the test
+func mustGet(t *testing.T, b queue.Broker, jobID string) *job.Job {
+	t.Helper()
+	j, err := b.GetJob(context.Background(), jobID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	return j
+}
+
+// mustStats reads stats or fails the test
+func mustStats(t *testing.T, b queue.Broker) queue.Stats {
+	t.Helper()
+	stats, err := b.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	return stats
+}
+
+func testEnqueueDequeue(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("send_email", job.PriorityNormal)
+	mustEnqueue(t, b, j)
+
+	got := mustDequeue(t, b, normal)
+	if got.ID != j.ID || got.Name != j.Name || string(got.Payload) != string(j.Payload) {
+		t.Errorf("Dequeue() = %s %s %s, want %s %s %s", got.ID, got.Name, got.Payload, j.ID, j.Name, j.Payload)
+	}
+	if stats := mustStats(t, b); stats.Processing != 1 || stats.TotalReady() != 0 {
+		t.Errorf("Stats() = %+v, want 1 processing and nothing ready", stats)
+	}
+}
+
+func testPriorityOrder(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	low := newJob("low", job.PriorityLow)
+	first := newJob("normal-1", job.PriorityNormal)
+	second := newJob("normal-2", job.PriorityNormal)
+	urgent := newJob("high", job.PriorityHigh)
+	for _, j := range []*job.Job{low, first, second, urgent} {
+		mustEnqueue(t, b, j)
+	}
+
+	// Strict priority order, FIFO within a priority
+	for _, want := range []*job.Job{urgent, first, second, low} {
+		if got := mustDequeue(t, b, all); got.ID != want.ID {
+			t.Errorf("Dequeue() = %s, want %s", got.Name, want.Name)
+		}
+	}
+}
+
+func testComplete(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("send_email", job.PriorityHigh)
+	mustEnqueue(t, b, j)
+	mustDequeue(t, b, high)
+
+	if err := b.Complete(context.Background(), j.ID); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusCompleted {
+		t.Errorf("status = %s, want %s", got.Status, job.StatusCompleted)
+	}
+	if stats := mustStats(t, b); stats.Processing != 0 {
+		t.Errorf("Processing = %d, want 0", stats.Processing)
+	}
+}
+
+func testFailSchedulesRetry(t *testing.T, b queue.Broker, advance func(time.Duration)) {
+	ctx := context.Background()
+	j := newJob("flaky", job.PriorityNormal)
+	j.MaxRetries = 3
+	mustEnqueue(t, b, j)
+	dequeued := mustDequeue(t, b, normal)
+
+	if err := b.Fail(ctx, dequeued, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	got := mustGet(t, b, j.ID)
+	if got.Status != job.StatusPending || got.Attempts != 1 || got.Error != "boom" {
+		t.Errorf("job = %s attempt %d %q, want pending attempt 1 \"boom\"", got.Status, got.Attempts, got.Error)
+	}
+	if got.ScheduledFor == nil {
+		t.Fatal("ScheduledFor is nil, want the retry time")
+	}
+	if stats := mustStats(t, b); stats.Scheduled != 1 || stats.Processing != 0 {
+		t.Errorf("Stats() = %+v, want 1 scheduled and nothing processing", stats)
+	}
+
+	// First retry backs off 2 seconds
+	if moved, err := b.MoveScheduledToReady(ctx); err != nil || moved != 0 {
+		t.Errorf("MoveScheduledToReady() = %d, %v, want 0 before the backoff", moved, err)
+	}
+	if advance == nil {
+		return
+	}
+	advance(3 * time.Second)
+	if moved, err := b.MoveScheduledToReady(ctx); err != nil || moved != 1 {
+		t.Fatalf("MoveScheduledToReady() = %d, %v, want 1 after the backoff", moved, err)
+	}
+	if retried := mustDequeue(t, b, normal); retried.ID != j.ID || retried.Attempts != 1 {
+		t.Errorf("Dequeue() = %s attempt %d, want %s attempt 1", retried.ID, retried.Attempts, j.ID)
+	}
+}
+
+func testFailAfterDelay(t *testing.T, b queue.Broker, advance func(time.Duration)) {
+	if advance == nil {
+		t.Skip("broker has no fake clock")
+	}
+	ctx := context.Background()
+	j := newJob("rate_limited", job.PriorityNormal)
+	mustEnqueue(t, b, j)
+
+	if err := b.FailAfter(ctx, mustDequeue(t, b, normal), "429", time.Minute); err != nil {
+		t.Fatalf("FailAfter() error = %v", err)
+	}
+
+	// The explicit delay replaces the 2 second backoff
+	advance(30 * time.Second)
+	if moved, _ := b.MoveScheduledToReady(ctx); moved != 0 {
+		t.Errorf("MoveScheduledToReady() = %d before the delay, want 0", moved)
+	}
+	advance(31 * time.Second)
+	if moved, _ := b.MoveScheduledToReady(ctx); moved != 1 {
+		t.Errorf("MoveScheduledToReady() = %d after the delay, want 1", moved)
+	}
+}
+
+func testRetriesExhausted(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("doomed", job.PriorityNormal)
+	j.MaxRetries = 1
+	mustEnqueue(t, b, j)
+
+	if err := b.Fail(context.Background(), mustDequeue(t, b, normal), "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusFailed || got.Attempts != 1 {
+		t.Errorf("job = %s attempt %d, want failed attempt 1", got.Status, got.Attempts)
+	}
+	if stats := mustStats(t, b); stats.Dead != 1 || stats.Scheduled != 0 || stats.Processing != 0 {
+		t.Errorf("Stats() = %+v, want only 1 dead", stats)
+	}
+}
+
+func testDeadLetter(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("bad_payload", job.PriorityNormal)
+	j.MaxRetries = 5
+	mustEnqueue(t, b, j)
+
+	if err := b.DeadLetter(context.Background(), mustDequeue(t, b, normal), "invalid"); err != nil {
+		t.Fatalf("DeadLetter() error = %v", err)
+	}
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusFailed || got.Error != "invalid" {
+		t.Errorf("job = %s %q, want failed \"invalid\"", got.Status, got.Error)
+	}
+	if stats := mustStats(t, b); stats.Dead != 1 || stats.Scheduled != 0 {
+		t.Errorf("Stats() = %+v, want 1 dead and no retry", stats)
+	}
+}
+
+func testExpire(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("stale", job.PriorityNormal)
+	mustEnqueue(t, b, j)
+
+	if err := b.Expire(context.Background(), mustDequeue(t, b, normal), "deadline passed"); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusExpired {
+		t.Errorf("status = %s, want %s", got.Status, job.StatusExpired)
+	}
+	if stats := mustStats(t, b); stats.Processing != 0 || stats.Dead != 0 {
+		t.Errorf("Stats() = %+v, want nothing processing or dead", stats)
+	}
+}
+
+func testRequeue(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	ctx := context.Background()
+	first := newJob("first", job.PriorityNormal)
+	second := newJob("second", job.PriorityNormal)
+	mustEnqueue(t, b, first)
+	mustEnqueue(t, b, second)
+
+	dequeued := mustDequeue(t, b, normal)
+	if err := b.Requeue(ctx, dequeued); err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+	// Requeueing twice is a no-op
+	if err := b.Requeue(ctx, dequeued); err != nil {
+		t.Fatalf("second Requeue() error = %v", err)
+	}
+
+	if stats := mustStats(t, b); stats.Processing != 0 || stats.Ready[job.PriorityNormal] != 2 {
+		t.Errorf("Stats() = %+v, want 2 ready and nothing processing", stats)
+	}
+	// The requeued job runs next, without an attempt counted
+	if got := mustDequeue(t, b, normal); got.ID != first.ID || got.Attempts != 0 {
+		t.Errorf("Dequeue() = %s attempt %d, want %s attempt 0", got.Name, got.Attempts, first.Name)
+	}
+}
+
+func testSchedule(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	ctx := context.Background()
+	j := newJob("report", job.PriorityHigh)
+
+	if err := b.Schedule(ctx, j, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusScheduled || got.ScheduledFor == nil {
+		t.Errorf("job = %s scheduled for %v, want scheduled with a time", got.Status, got.ScheduledFor)
+	}
+	if stats := mustStats(t, b); stats.Scheduled != 1 || stats.TotalReady() != 0 {
+		t.Errorf("Stats() = %+v, want 1 scheduled and nothing ready", stats)
+	}
+
+	if moved, err := b.MoveScheduledToReady(ctx); err != nil || moved != 1 {
+		t.Fatalf("MoveScheduledToReady() = %d, %v, want 1", moved, err)
+	}
+	got := mustDequeue(t, b, high)
+	if got.ID != j.ID || got.Status != job.StatusPending || got.ScheduledFor != nil {
+		t.Errorf("Dequeue() = %s %s scheduled for %v, want %s pending and unscheduled", got.ID, got.Status, got.ScheduledFor, j.ID)
+	}
+}
+
+func testScheduleInFuture(t *testing.T, b queue.Broker, advance func(time.Duration)) {
+	ctx := context.Background()
+	j := newJob("report", job.PriorityNormal)
+	if err := b.Schedule(ctx, j, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	if moved, err := b.MoveScheduledToReady(ctx); err != nil || moved != 0 {
+		t.Errorf("MoveScheduledToReady() = %d, %v, want 0 before the scheduled time", moved, err)
+	}
+	if advance == nil {
+		return
+	}
+	advance(time.Hour + time.Second)
+	if moved, err := b.MoveScheduledToReady(ctx); err != nil || moved != 1 {
+		t.Errorf("MoveScheduledToReady() = %d, %v, want 1 at the scheduled time", moved, err)
+	}
+}
+
+func testGetJobNotFound(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	if _, err := b.GetJob(context.Background(), "missing"); !errors.Is(err, queue.ErrJobNotFound) {
+		t.Errorf("GetJob() error = %v, want ErrJobNotFound", err)
+	}
+	if err := b.Cancel(context.Background(), "missing"); !errors.Is(err, queue.ErrJobNotFound) {
+		t.Errorf("Cancel() error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testCancelWaiting(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("send_email", job.PriorityNormal)
+	mustEnqueue(t, b, j)
+
+	if err := b.Cancel(context.Background(), j.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusCancelled {
+		t.Errorf("status = %s, want %s", got.Status, job.StatusCancelled)
+	}
+	if stats := mustStats(t, b); stats.TotalReady() != 0 {
+		t.Errorf("Ready = %v, want cancelled job removed", stats.Ready)
+	}
+	// Cancelling twice fails: the job is no longer waiting
+	if err := b.Cancel(context.Background(), j.ID); !errors.Is(err, queue.ErrNotCancellable) {
+		t.Errorf("second Cancel() error = %v, want ErrNotCancellable", err)
+	}
+}
+
+func testCancelScheduled(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	ctx := context.Background()
+	j := newJob("report", job.PriorityNormal)
+	if err := b.Schedule(ctx, j, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	if err := b.Cancel(ctx, j.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if moved, _ := b.MoveScheduledToReady(ctx); moved != 0 {
+		t.Errorf("MoveScheduledToReady() = %d, want cancelled job not moved", moved)
+	}
+	if stats := mustStats(t, b); stats.Scheduled != 0 {
+		t.Errorf("Scheduled = %d, want 0", stats.Scheduled)
+	}
+}
+
+func testCancelRunning(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	j := newJob("send_email", job.PriorityNormal)
+	mustEnqueue(t, b, j)
+	mustDequeue(t, b, normal)
+
+	if err := b.Cancel(context.Background(), j.ID); !errors.Is(err, queue.ErrNotCancellable) {
+		t.Errorf("Cancel() error = %v, want ErrNotCancellable", err)
+	}
+}
+
+func testStats(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	ctx := context.Background()
+	mustEnqueue(t, b, newJob("a", job.PriorityHigh))
+	mustEnqueue(t, b, newJob("b", job.PriorityNormal))
+	mustEnqueue(t, b, newJob("c", job.PriorityLow))
+	mustEnqueue(t, b, newJob("d", job.PriorityLow))
+	if err := b.Schedule(ctx, newJob("e", job.PriorityLow), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	mustDequeue(t, b, high)
+
+	stats := mustStats(t, b)
+	want := map[job.JobPriority]int64{job.PriorityHigh: 0, job.PriorityNormal: 1, job.PriorityLow: 2}
+	for priority, n := range want {
+		if stats.Ready[priority] != n {
+			t.Errorf("Ready[%s] = %d, want %d", priority, stats.Ready[priority], n)
+		}
+	}
+	if stats.Scheduled != 1 || stats.Processing != 1 || stats.Dead != 0 {
+		t.Errorf("Stats() = %+v, want 1 scheduled, 1 processing, 0 dead", stats)
+	}
+}
+
+func testConcurrentDequeue(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	const jobs, workers = 50, 8
+	for i := 0; i < jobs; i++ {
+		mustEnqueue(t, b, newJob(fmt.Sprintf("job-%d", i), job.PriorityNormal))
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]int)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, err := b.Dequeue(context.Background(), normal)
+				if err != nil {
+					t.Errorf("Dequeue() error = %v", err)
+					return
+				}
+				if j == nil {
+					return
+				}
+				mu.Lock()
+				seen[j.ID]++
+				mu.Unlock()
+				if err := b.Complete(context.Background(), j.ID); err != nil {
+					t.Errorf("Complete() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != jobs {
+		t.Errorf("dequeued %d distinct jobs, want %d", len(seen), jobs)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("job %s dequeued %d times, want once", id, n)
+		}
+	}
+}
+
+func testStaleTransitions(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	ctx := context.Background()
+	j := newJob("send_email", job.PriorityNormal)
+	j.MaxRetries = 3
+	mustEnqueue(t, b, j)
+	dequeued := mustDequeue(t, b, normal)
+	before := *dequeued
+	if err := b.Complete(ctx, j.ID); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	// A duplicate or late worker can't change a job that is no longer running
+	stale := map[string]error{
+		"Complete":   b.Complete(ctx, j.ID),
+		"Fail":       b.Fail(ctx, dequeued, "late"),
+		"FailAfter":  b.FailAfter(ctx, dequeued, "late", time.Second),
+		"DeadLetter": b.DeadLetter(ctx, dequeued, "late"),
+		"Expire":     b.Expire(ctx, dequeued, "late"),
+	}
+	for name, err := range stale {
+		if !errors.Is(err, queue.ErrLeaseLost) {
+			t.Errorf("%s() on a finished job error = %v, want ErrLeaseLost", name, err)
+		}
+	}
+	// Nor is the caller's copy changed by a transition that didn't apply
+	if dequeued.Attempts != before.Attempts || dequeued.Error != before.Error || dequeued.Status != before.Status {
+		t.Errorf("dequeued job = %s attempt %d %q, want it unchanged", dequeued.Status, dequeued.Attempts, dequeued.Error)
+	}
+
+	if got := mustGet(t, b, j.ID); got.Status != job.StatusCompleted || got.Error != "" {
+		t.Errorf("job = %s %q, want completed without error", got.Status, got.Error)
+	}
+	if stats := mustStats(t, b); stats.Dead != 0 || stats.Scheduled != 0 || stats.Processing != 0 {
+		t.Errorf("Stats() = %+v, want nothing dead, scheduled or processing", stats)
+	}
+}
+
+// leaser is implemented by brokers that lease dequeued jobs to their worker
+type leaser interface {
+	SetLeaseDuration(d time.Duration)
+	Heartbeat(ctx context.Context, jobID string) error
+	ReapExpiredLeases(ctx context.Context) (int, error)
+}
+
+func testLeaseExpiry(t *testing.T, b queue.Broker, _ func(time.Duration)) {
+	l, ok := b.(leaser)
+	if !ok {
+		t.Skip("broker has no leases")
+	}
+	ctx := context.Background()
+	l.SetLeaseDuration(time.Second)
+
+	alive := newJob("alive", job.PriorityNormal)
+	crashed := newJob("crashed", job.PriorityNormal)
+	crashed.MaxRetries = 3
+	mustEnqueue(t, b, alive)
+	mustEnqueue(t, b, crashed)
+	mustDequeue(t, b, normal)
+	mustDequeue(t, b, normal)
+
+	// Only the job whose worker keeps heartbeating keeps its lease
+	for i := 0; i < 3; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if err := l.Heartbeat(ctx, alive.ID); err != nil {
+			t.Fatalf("Heartbeat() error = %v", err)
+		}
+	}
+	reaped, err := l.ReapExpiredLeases(ctx)
+	if err != nil || reaped != 1 {
+		t.Fatalf("ReapExpiredLeases() = %d, %v, want 1", reaped, err)
+	}
+
+	if got := mustGet(t, b, crashed.ID); got.Status != job.StatusPending || got.Attempts != 1 {
+		t.Errorf("reaped job = %s attempt %d, want pending attempt 1", got.Status, got.Attempts)
+	}
+	if err := l.Heartbeat(ctx, crashed.ID); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Errorf("Heartbeat() on a reaped job error = %v, want ErrLeaseLost", err)
+	}
+	if err := b.Complete(ctx, crashed.ID); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Errorf("Complete() on a reaped job error = %v, want ErrLeaseLost", err)
+	}
+	if err := b.Complete(ctx, alive.ID); err != nil {
+		t.Errorf("Complete() on a leased job error = %v", err)
+	}
+}
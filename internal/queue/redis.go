@@ -3,19 +3,26 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/muaviaUsmani/bananas/internal/events"
 	"github.com/muaviaUsmani/bananas/internal/job"
 	"github.com/muaviaUsmani/bananas/internal/metrics"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
 	"github.com/redis/go-redis/v9"
 )
 
 // RedisQueue manages job queues in Redis
 type RedisQueue struct {
-	client    *redis.Client
+	client    redis.UniversalClient
+	namespace string
 	keyPrefix string
 	// Pre-computed keys for better performance (avoid string allocations)
 	queueHighKey    string
@@ -24,19 +31,36 @@ type RedisQueue struct {
 	processingKey   string
 	deadLetterKey   string
 	scheduledSetKey string
+	blobExpiryKey   string
+	leasesKey       string
+	// Set of jobs waiting for the jobs they depend on (see EnqueueDAG)
+	waitingKey string
+	// Sorted set of job names with their own queues, scored by expiry (see DedicateJobNames)
+	dedicatedKey string
+	// Set of unfinished job IDs counted against the max queued jobs quota
+	outstandingKey string
 	// TTL configuration for job data retention
 	completedJobTTL time.Duration // TTL for completed jobs (default: 24 hours)
 	failedJobTTL    time.Duration // TTL for failed jobs in dead letter queue (default: 7 days)
+	// How long a dequeued job is leased to its worker without a heartbeat (see lease.go)
+	leaseDuration time.Duration
+	// Optional claim-check offloading of large payloads (see SetBlobStore)
+	blobStore        storage.BlobStore
+	payloadThreshold int
+	// Optional payload encryption at rest (see SetEncryptor)
+	encryptor *serialization.Encryptor
+	// Lifecycle hooks and event sinks for job state transitions
+	hooks *events.Registry
+	// Per-namespace limits enforced at Enqueue (see SetQuota)
+	quota Quota
+	// How long completed idempotency keys are remembered (see idempotency.go)
+	idempotencyTTL time.Duration
 }
 
 // NewRedisQueue creates a new Redis queue and tests the connection
+// The URL may point at a single server, a Sentinel-managed master
+// (redis+sentinel://) or a Cluster (redis+cluster://); see redisconn.NewClient.
 func NewRedisQueue(redisURL string) (*RedisQueue, error) {
-	// Parse Redis URL
-	opts, err := redis.ParseURL(redisURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
-	}
-
 	// Configure connection pool for job queue workload
 	// These settings are optimized for:
 	// - Multiple concurrent workers (default 10, configurable up to 50+)
@@ -46,48 +70,75 @@ func NewRedisQueue(redisURL string) (*RedisQueue, error) {
 	//
 	// Pool size calculation: workers + API concurrency + scheduler + buffer
 	// Example: 10 workers + 10 API + 1 scheduler + 5 buffer = 26 connections
-	opts.PoolSize = 50 // Maximum connections in pool (handles up to ~40 workers)
-	opts.MinIdleConns = 5 // Keep 5 idle connections ready (reduces connection setup latency)
-	opts.ConnMaxIdleTime = 10 * time.Minute // Close idle connections after 10 minutes
-	opts.PoolTimeout = 5 * time.Second       // Wait up to 5 seconds for connection from pool
-
-	// Retry configuration for transient failures
-	opts.MaxRetries = 3                               // Retry failed commands up to 3 times
-	opts.MinRetryBackoff = 8 * time.Millisecond       // Minimum 8ms between retries
-	opts.MaxRetryBackoff = 512 * time.Millisecond     // Maximum 512ms between retries
-	opts.DialTimeout = 5 * time.Second                // Timeout for establishing connection
-	opts.ReadTimeout = 10 * time.Second               // Longer timeout for blocking operations (BRPOPLPUSH)
-	opts.WriteTimeout = 3 * time.Second               // Timeout for write operations
-	opts.ContextTimeoutEnabled = true                 // Respect context timeouts
-
-	// Create client with optimized options
-	client := redis.NewClient(opts)
-
-	// Test connection
-	ctx := context.Background()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
-	}
-
-	log.Printf("Connected to Redis at %s (pool: %d max, %d min idle)",
-		redisURL, opts.PoolSize, opts.MinIdleConns)
-
-	prefix := "bananas:"
-	return &RedisQueue{
-		client:          client,
-		keyPrefix:       prefix,
-		// Pre-compute all static keys once to avoid repeated string allocations
-		queueHighKey:    prefix + "queue:high",
-		queueNormalKey:  prefix + "queue:normal",
-		queueLowKey:     prefix + "queue:low",
-		processingKey:   prefix + "queue:processing",
-		deadLetterKey:   prefix + "queue:dead",
-		scheduledSetKey: prefix + "queue:scheduled",
+	// On Cluster the pool settings apply per node.
+	tuning := &redisconn.Tuning{
+		PoolSize:        50,               // Maximum connections in pool (handles up to ~40 workers)
+		MinIdleConns:    5,                // Keep 5 idle connections ready (reduces connection setup latency)
+		ConnMaxIdleTime: 10 * time.Minute, // Close idle connections after 10 minutes
+		PoolTimeout:     5 * time.Second,  // Wait up to 5 seconds for connection from pool
+
+		// Retry configuration for transient failures
+		MaxRetries:            3,                      // Retry failed commands up to 3 times
+		MinRetryBackoff:       8 * time.Millisecond,   // Minimum 8ms between retries
+		MaxRetryBackoff:       512 * time.Millisecond, // Maximum 512ms between retries
+		DialTimeout:           5 * time.Second,        // Timeout for establishing connection
+		ReadTimeout:           10 * time.Second,       // Longer timeout for blocking operations (BRPOPLPUSH)
+		WriteTimeout:          3 * time.Second,        // Timeout for write operations
+		ContextTimeoutEnabled: true,                   // Respect context timeouts
+	}
+
+	// Create client with optimized options and test the connection
+	client, err := redisconn.Connect(context.Background(), redisURL, tuning)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, _ := redisconn.ParseMode(redisURL)
+	log.Printf("Connected to Redis (%s) at %s (pool: %d max, %d min idle)",
+		mode, redisURL, tuning.PoolSize, tuning.MinIdleConns)
+
+	return newRedisQueue(client), nil
+}
+
+// NewRedisQueueFromClient creates a Redis queue on an existing client
+// Use it to share a redis.UniversalClient (standalone, Sentinel failover or Cluster)
+// with other components. Close closes the client.
+func NewRedisQueueFromClient(client redis.UniversalClient) *RedisQueue {
+	return newRedisQueue(client)
+}
+
+func newRedisQueue(client redis.UniversalClient) *RedisQueue {
+	q := &RedisQueue{
+		client: client,
 		// Set default TTL values for job data retention
 		// These prevent Redis from growing unbounded with old job data
-		completedJobTTL: 24 * time.Hour, // Keep completed jobs for 24 hours
+		completedJobTTL: 24 * time.Hour,     // Keep completed jobs for 24 hours
 		failedJobTTL:    7 * 24 * time.Hour, // Keep failed jobs for 7 days
-	}, nil
+		leaseDuration:   DefaultLeaseDuration,
+		idempotencyTTL:  DefaultIdempotencyTTL,
+		hooks:           events.NewRegistry(),
+	}
+	q.useNamespace(namespace.Default)
+	return q
+}
+
+// useNamespace points the queue at a namespace's keyspace
+func (q *RedisQueue) useNamespace(ns string) {
+	prefix := redisconn.KeyPrefix(q.client, ns)
+	q.namespace = ns
+	q.keyPrefix = prefix
+	// Pre-compute all static keys once to avoid repeated string allocations
+	q.queueHighKey = prefix + "queue:high"
+	q.queueNormalKey = prefix + "queue:normal"
+	q.queueLowKey = prefix + "queue:low"
+	q.processingKey = prefix + "queue:processing"
+	q.deadLetterKey = prefix + "queue:dead"
+	q.scheduledSetKey = prefix + "queue:scheduled"
+	q.blobExpiryKey = prefix + "blobs:expiring"
+	q.leasesKey = prefix + "queue:leases"
+	q.waitingKey = prefix + "queue:waiting"
+	q.dedicatedKey = prefix + "queue:dedicated"
+	q.outstandingKey = prefix + "quota:outstanding"
 }
 
 // Key generation helpers
@@ -129,27 +180,78 @@ func (q *RedisQueue) getScheduledSetKey() string {
 
 // Enqueue adds a job to the appropriate priority queue
 func (q *RedisQueue) Enqueue(ctx context.Context, j *job.Job) error {
+	return q.submit(ctx, j, nil)
+}
+
+// Schedule adds a job to the scheduled set; MoveScheduledToReady moves it to its
+// priority queue once at has passed
+func (q *RedisQueue) Schedule(ctx context.Context, j *job.Job, at time.Time) error {
+	j.ScheduledFor = &at
+	j.UpdateStatus(job.StatusScheduled)
+	return q.submit(ctx, j, &at)
+}
+
+// submit stores a new job and pushes it to its ready queue, or to the scheduled set when at is set
+func (q *RedisQueue) submit(ctx context.Context, j *job.Job, at *time.Time) error {
+	// Encrypt the payload at rest, then move large payloads to the blob store
+	if err := q.encryptPayload(j); err != nil {
+		return err
+	}
+	if err := q.offloadPayload(ctx, j); err != nil {
+		return err
+	}
+
 	// Serialize job to JSON
 	jobData, err := json.Marshal(j)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	// Use pipeline for atomic operations
-	pipe := q.client.Pipeline()
+	queueKey := q.getScheduledSetKey()
+	if at == nil {
+		dedicated, err := q.isDedicated(ctx, j.Name)
+		if err != nil {
+			q.releasePayload(ctx, j)
+			return err
+		}
+		queueKey = q.readyQueueKey(j, dedicated)
+	}
 
-	// Store job data in hash
-	pipe.Set(ctx, q.jobKey(j.ID), jobData, 0)
+	if q.quota.enabled() {
+		// Quota checks and the enqueue itself run in one script so concurrent
+		// producers can't overshoot the tenant's limits
+		if err := q.enqueueWithQuota(ctx, j, jobData, queueKey, at); err != nil {
+			q.releasePayload(ctx, j)
+			return err
+		}
+	} else {
+		// Use pipeline for atomic operations
+		pipe := q.client.Pipeline()
 
-	// Push job ID to priority queue
-	pipe.LPush(ctx, q.queueKey(j.Priority), j.ID)
+		// Store job data in hash
+		pipe.Set(ctx, q.jobKey(j.ID), jobData, 0)
 
-	// Execute pipeline
-	if _, err := pipe.Exec(ctx); err != nil {
-		return fmt.Errorf("failed to enqueue job: %w", err)
+		if at == nil {
+			// Push job ID to its ready queue (priority queue, route queue or job name queue)
+			pipe.LPush(ctx, queueKey, j.ID)
+		} else {
+			// Scheduled jobs wait in the scheduled set, scored by their start time
+			pipe.ZAdd(ctx, queueKey, redis.Z{Score: float64(at.Unix()), Member: j.ID})
+		}
+
+		// Count the job as unfinished until it completes, expires or is dead-lettered
+		pipe.SAdd(ctx, q.outstandingKey, j.ID)
+
+		// Execute pipeline
+		if _, err := pipe.Exec(ctx); err != nil {
+			q.releasePayload(ctx, j)
+			return fmt.Errorf("failed to enqueue job: %w", err)
+		}
 	}
 
-	log.Printf("Enqueued job %s to %s queue", j.ID, j.Priority)
+	log.Printf("Enqueued job %s to %s", j.ID, queueKey)
+	metrics.Default().RecordTenantEnqueued(q.namespace)
+	q.hooks.Emit(ctx, events.TypeEnqueue, j)
 
 	// Update queue depth metrics (best-effort, don't fail enqueue on error)
 	q.updateQueueMetrics(ctx)
@@ -207,97 +309,142 @@ func (q *RedisQueue) Dequeue(ctx context.Context, priorities []job.JobPriority)
 			return nil, fmt.Errorf("failed to dequeue job: %w", err)
 		}
 
-		jobID := result
-
-		// Retrieve job data
-		jobData, err := q.client.Get(ctx, q.jobKey(jobID)).Result()
-		if err != nil {
-			// Job data not found (corrupted reference) - move to dead letter queue
-			log.Printf("ERROR: Job data not found for ID %s (corrupted reference) - moving to dead letter queue", jobID)
-
-			pipe := q.client.Pipeline()
-			pipe.LPush(ctx, q.deadLetterQueueKey(), jobID)
-			pipe.LRem(ctx, processingKey, 1, jobID)
-			// Store error info as job data with TTL
-			errorJob := map[string]interface{}{
-				"id":    jobID,
-				"error": "Job data not found (corrupted reference)",
-			}
-			errorData, _ := json.Marshal(errorJob)
-			pipe.Set(ctx, q.jobKey(jobID), errorData, q.failedJobTTL)
-			pipe.Exec(ctx)
-
-			// Skip this corrupted job and continue processing other jobs
-			continue
-		}
-
-		// Deserialize job
-		var j job.Job
-		if err := json.Unmarshal([]byte(jobData), &j); err != nil {
-			// Invalid/corrupted job data - move to dead letter queue WITHOUT RETRY
-			log.Printf("ERROR: Failed to unmarshal job %s (corrupted data) - moving to dead letter queue", jobID)
-			log.Printf("Corrupted job data (first 200 chars): %s", truncate(jobData, 200))
-
-			pipe := q.client.Pipeline()
-			pipe.LPush(ctx, q.deadLetterQueueKey(), jobID)
-			pipe.LRem(ctx, processingKey, 1, jobID)
-			// Update job data to mark as corrupted with TTL
-			errorJob := map[string]interface{}{
-				"id":            jobID,
-				"error":         fmt.Sprintf("Failed to unmarshal job: %v", err),
-				"corrupted_data": truncate(jobData, 500), // Store truncated data for debugging
-			}
-			errorData, _ := json.Marshal(errorJob)
-			pipe.Set(ctx, q.jobKey(jobID), errorData, q.failedJobTTL)
-			pipe.Exec(ctx)
-
-			// Skip this corrupted job and continue processing other jobs
+		j := q.claimDequeued(ctx, result)
+		if j == nil {
+			// Corrupted job was moved to the dead letter queue - keep looking
 			continue
 		}
 
 		log.Printf("Dequeued job %s from %s queue", j.ID, priority)
-		return &j, nil
+		return j, nil
 	}
 
 	// All queues are empty after checking with timeouts
 	return nil, nil
 }
 
-// Complete marks a job as completed and removes it from the processing queue
-func (q *RedisQueue) Complete(ctx context.Context, jobID string) error {
-	// Retrieve job data first (must be done before updates)
+// claimDequeued loads a job that was just moved to the processing queue and leases it
+// Jobs whose data is missing or corrupted are moved to the dead letter queue and nil is returned.
+func (q *RedisQueue) claimDequeued(ctx context.Context, jobID string) *job.Job {
+	j := q.loadDequeued(ctx, jobID)
+	if j == nil {
+		return nil
+	}
+
+	// Lease the job to this worker; the executor's heartbeats keep it alive
+	if err := q.acquireLease(ctx, j.ID); err != nil {
+		log.Printf("Failed to record lease for job %s: %v", j.ID, err)
+	}
+
+	return j
+}
+
+// loadDequeued loads a job that was just dequeued
+// Jobs whose data is missing or corrupted are moved to the dead letter queue and nil is returned.
+func (q *RedisQueue) loadDequeued(ctx context.Context, jobID string) *job.Job {
+	// Retrieve job data
 	jobData, err := q.client.Get(ctx, q.jobKey(jobID)).Result()
 	if err != nil {
-		return fmt.Errorf("failed to get job data: %w", err)
+		// Job data not found (corrupted reference) - move to dead letter queue
+		log.Printf("ERROR: Job data not found for ID %s (corrupted reference) - moving to dead letter queue", jobID)
+
+		pipe := q.client.Pipeline()
+		pipe.LPush(ctx, q.deadLetterQueueKey(), jobID)
+		pipe.LRem(ctx, q.processingQueueKey(), 1, jobID)
+		// Store error info as job data with TTL
+		errorJob := map[string]interface{}{
+			"id":    jobID,
+			"error": "Job data not found (corrupted reference)",
+		}
+		errorData, _ := json.Marshal(errorJob)
+		pipe.Set(ctx, q.jobKey(jobID), errorData, q.failedJobTTL)
+		pipe.SRem(ctx, q.outstandingKey, jobID)
+		pipe.Exec(ctx)
+
+		// Skip this corrupted job so the caller keeps looking
+		return nil
 	}
 
+	// Deserialize job
 	var j job.Job
 	if err := json.Unmarshal([]byte(jobData), &j); err != nil {
-		return fmt.Errorf("failed to unmarshal job: %w", err)
+		// Invalid/corrupted job data - move to dead letter queue WITHOUT RETRY
+		log.Printf("ERROR: Failed to unmarshal job %s (corrupted data) - moving to dead letter queue", jobID)
+		log.Printf("Corrupted job data (first 200 chars): %s", truncate(jobData, 200))
+
+		pipe := q.client.Pipeline()
+		pipe.LPush(ctx, q.deadLetterQueueKey(), jobID)
+		pipe.LRem(ctx, q.processingQueueKey(), 1, jobID)
+		// Update job data to mark as corrupted with TTL
+		errorJob := map[string]interface{}{
+			"id":            jobID,
+			"error":         fmt.Sprintf("Failed to unmarshal job: %v", err),
+			"corrupted_data": truncate(jobData, 500), // Store truncated data for debugging
+		}
+		errorData, _ := json.Marshal(errorJob)
+		pipe.Set(ctx, q.jobKey(jobID), errorData, q.failedJobTTL)
+		pipe.SRem(ctx, q.outstandingKey, jobID)
+		pipe.Exec(ctx)
+
+		// Skip this corrupted job so the caller keeps looking
+		return nil
 	}
 
-	j.UpdateStatus(job.StatusCompleted)
+	return &j
+}
 
-	updatedData, err := json.Marshal(j)
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated job: %w", err)
-	}
+// Complete marks a job as completed and removes it from the processing queue
+// Returns an error wrapping ErrLeaseLost if the job is no longer in the processing
+// queue, e.g. because its lease expired and it was reaped.
+func (q *RedisQueue) Complete(ctx context.Context, jobID string) error {
+	return q.complete(ctx, jobID, "", q.processingClaim(jobID))
+}
 
-	// Use pipeline to batch removal from processing queue and status update
-	// This reduces 2 round trips to 1
-	// Set TTL on completed job data to prevent unbounded Redis growth
-	pipe := q.client.Pipeline()
-	pipe.LRem(ctx, q.processingQueueKey(), 1, jobID)
-	pipe.Set(ctx, q.jobKey(jobID), updatedData, q.completedJobTTL)
+// CompleteIdempotent is like Complete, but also records jobID as the job that
+// completed the idempotency key (see RecordCompletion) in the same script, so a
+// job is never acknowledged without its record or recorded without being acknowledged
+func (q *RedisQueue) CompleteIdempotent(ctx context.Context, jobID, key string) error {
+	return q.complete(ctx, jobID, key, q.processingClaim(jobID))
+}
 
-	if _, err := pipe.Exec(ctx); err != nil {
+// complete stores a job as completed if it still holds its running claim,
+// recording it as the job that completed key unless key is empty
+func (q *RedisQueue) complete(ctx context.Context, jobID, key string, c runningClaim) error {
+	// Removal from the processing queue and the status update happen in one script
+	// Set TTL on completed job data to prevent unbounded Redis growth
+	// Completed jobs need neither a lease nor their checkpoint
+	j, err := q.updateRunning(ctx, c, jobID, func(j *job.Job) storedTransition {
+		j.UpdateStatus(job.StatusCompleted)
+		return q.finishTransition(j, key)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to complete job: %w", err)
 	}
+	metrics.Default().RecordTenantCompleted(q.namespace)
+
+	// The payload is no longer needed once the job has completed
+	q.releasePayload(ctx, j)
 
 	log.Printf("Completed job %s (TTL: %v)", jobID, q.completedJobTTL)
+	q.hooks.Emit(ctx, events.TypeSuccess, j)
+	q.settleDependents(ctx, j, true)
 	return nil
 }
 
+// finishTransition stores a finished (completed or expired) job for the completed job TTL
+// A non-empty idempotency key is recorded as completed by the job in the same script.
+func (q *RedisQueue) finishTransition(j *job.Job, key string) storedTransition {
+	recordKey, recordTTL := q.jobKey(j.ID), int64(0) // Not written without a key
+	if key != "" {
+		recordKey, recordTTL = q.idempotencyKey(key), q.idempotencyTTL.Milliseconds()
+	}
+	return storedTransition{
+		script: finishScript,
+		keys:   []string{q.jobKey(j.ID), q.leasesKey, q.checkpointKey(j.ID), q.outstandingKey, recordKey},
+		args:   []interface{}{q.completedJobTTL.Milliseconds(), recordTTL},
+	}
+}
+
 // Fail handles a failed job with exponential backoff retry or moves to dead letter queue
 //
 // Retry Strategy:
@@ -317,74 +464,174 @@ func (q *RedisQueue) Complete(ctx context.Context, jobID string) error {
 // A background process (scheduler) periodically calls MoveScheduledToReady() to move
 // jobs from the scheduled set back to their priority queues when ready.
 func (q *RedisQueue) Fail(ctx context.Context, j *job.Job, errMsg string) error {
-	// Update job state
-	j.Attempts++
-	j.Error = errMsg
+	return q.fail(ctx, j, errMsg, 0, q.processingClaim(j.ID))
+}
 
-	pipe := q.client.Pipeline()
+// FailAfter is like Fail, but a retry is scheduled after delay instead of the
+// exponential backoff (e.g. to honor a remote service's Retry-After)
+func (q *RedisQueue) FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	return q.fail(ctx, j, errMsg, delay, q.processingClaim(j.ID))
+}
+
+// fail records a failed attempt and schedules a retry after delay (exponential
+// backoff when delay is 0), or moves the job to the dead letter queue
+// The job must still hold its running claim c, otherwise ErrLeaseLost is returned.
+// j is only updated once the transition has been stored.
+func (q *RedisQueue) fail(ctx context.Context, j *job.Job, errMsg string, delay time.Duration, c runningClaim) error {
+	var retryDelay time.Duration
+	updated, err := q.updateRunning(ctx, c, j.ID, func(stored *job.Job) storedTransition {
+		stored.Attempts++
+		stored.Error = errMsg
+
+		// Max retries exceeded, move to dead letter queue
+		if stored.Attempts >= stored.MaxRetries {
+			return q.deadLetterTransition(stored)
+		}
 
-	// Check if we should retry
-	if j.Attempts < j.MaxRetries {
 		// Calculate exponential backoff delay: 2^attempts seconds
-		delaySecs := 1 << j.Attempts // Bit shift: 2^attempts
-		retryDelay := time.Duration(delaySecs) * time.Second
+		delaySecs := 1 << stored.Attempts // Bit shift: 2^attempts
+		retryDelay = time.Duration(delaySecs) * time.Second
+		if delay > 0 {
+			retryDelay = delay
+		}
 		nextRetryTime := time.Now().Add(retryDelay)
 
 		// Update job for retry
-		j.UpdateStatus(job.StatusPending)
-		j.ScheduledFor = &nextRetryTime
-
-		// Serialize updated job
-		jobData, err := json.Marshal(j)
-		if err != nil {
-			return fmt.Errorf("failed to marshal job: %w", err)
+		stored.UpdateStatus(job.StatusPending)
+		stored.ScheduledFor = &nextRetryTime
+
+		// Remove from processing queue, update job data, add to scheduled set with
+		// retry time as score and release the lease, all in one script
+		// The checkpoint is kept so the next attempt can resume from it
+		return storedTransition{
+			script: retryScript,
+			keys:   []string{q.jobKey(stored.ID), q.getScheduledSetKey(), q.leasesKey},
+			args:   []interface{}{nextRetryTime.Unix()},
 		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record job failure: %w", err)
+	}
+	copyState(j, updated)
 
-		// Update job data in Redis
-		pipe.Set(ctx, q.jobKey(j.ID), jobData, 0)
-
-		// Add to scheduled set with retry time as score
-		pipe.ZAdd(ctx, q.getScheduledSetKey(), redis.Z{
-			Score:  float64(nextRetryTime.Unix()),
-			Member: j.ID,
-		})
-
-		// Remove from processing queue
-		pipe.LRem(ctx, q.processingQueueKey(), 1, j.ID)
+	if j.Status == job.StatusFailed {
+		q.deadLettered(ctx, j)
+		return nil
+	}
+	log.Printf("Job %s failed (attempt %d/%d), scheduled for retry in %v at %s",
+		j.ID, j.Attempts, j.MaxRetries, retryDelay, j.ScheduledFor.Format(time.RFC3339))
+	q.hooks.Emit(ctx, events.TypeFailure, j)
+	return nil
+}
 
-		if _, err := pipe.Exec(ctx); err != nil {
-			return fmt.Errorf("failed to schedule job for retry: %w", err)
-		}
+// Expire discards a job whose deadline passed before it started
+// The job is kept with status expired (for the completed job TTL) and removed from the processing queue
+func (q *RedisQueue) Expire(ctx context.Context, j *job.Job, reason string) error {
+	return q.expire(ctx, j, reason, q.processingClaim(j.ID))
+}
 
-		log.Printf("Job %s failed (attempt %d/%d), scheduled for retry in %v at %s",
-			j.ID, j.Attempts, j.MaxRetries, retryDelay, nextRetryTime.Format(time.RFC3339))
-		return nil
+// expire stores a job as expired if it still holds its running claim
+func (q *RedisQueue) expire(ctx context.Context, j *job.Job, reason string, c runningClaim) error {
+	updated, err := q.updateRunning(ctx, c, j.ID, func(stored *job.Job) storedTransition {
+		stored.UpdateStatus(job.StatusExpired)
+		stored.Error = reason
+		return q.finishTransition(stored, "")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to expire job: %w", err)
 	}
+	copyState(j, updated)
 
-	// Max retries exceeded, move to dead letter queue
-	j.UpdateStatus(job.StatusFailed)
-	j.ScheduledFor = nil // Clear scheduled time
+	// The payload will never be needed
+	q.releasePayload(ctx, j)
 
-	jobData, err := json.Marshal(j)
+	log.Printf("Expired job %s: %s", j.ID, reason)
+	q.hooks.Emit(ctx, events.TypeExpired, j)
+	q.settleDependents(ctx, j, false)
+	return nil
+}
+
+// Requeue returns a job this worker dequeued to the front of its priority queue
+// without counting an attempt, e.g. when the worker shuts down before it finished.
+// It is a no-op if the job is no longer in the processing queue (it was already
+// requeued, reaped or finished).
+func (q *RedisQueue) Requeue(ctx context.Context, j *job.Job) error {
+	j.UpdateStatus(job.StatusPending)
+	jobData, err := q.marshalJob(j)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	// Update job data with TTL to prevent unbounded growth of failed jobs
-	pipe.Set(ctx, q.jobKey(j.ID), jobData, q.failedJobTTL)
+	dedicated, err := q.isDedicated(ctx, j.Name)
+	if err != nil {
+		return err
+	}
+	queueKey := q.readyQueueKey(j, dedicated)
+
+	// RPUSH puts it at the end Dequeue pops from, so it runs next
+	keys := []string{q.jobKey(j.ID), queueKey, q.leasesKey}
+	err = q.transition(ctx, requeueScript, q.processingClaim(j.ID), j.ID, keys, jobData)
+	if errors.Is(err, ErrLeaseLost) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
 
-	// Move to dead letter queue
-	pipe.LPush(ctx, q.deadLetterQueueKey(), j.ID)
+	log.Printf("Requeued job %s to %s (attempt not counted)", j.ID, queueKey)
+	q.hooks.Emit(ctx, events.TypeRequeue, j)
+	return nil
+}
 
-	// Remove from processing queue
-	pipe.LRem(ctx, q.processingQueueKey(), 1, j.ID)
+// DeadLetter moves a job straight to the dead letter queue without retrying
+// Used for failures that retries cannot fix, such as payloads that fail schema validation
+func (q *RedisQueue) DeadLetter(ctx context.Context, j *job.Job, errMsg string) error {
+	return q.deadLetter(ctx, j, errMsg, q.processingClaim(j.ID))
+}
 
-	if _, err := pipe.Exec(ctx); err != nil {
+// deadLetter counts a failed attempt and moves a job to the dead letter queue
+// The job must still hold its running claim c, otherwise ErrLeaseLost is returned.
+// j is only updated once the transition has been stored.
+func (q *RedisQueue) deadLetter(ctx context.Context, j *job.Job, errMsg string, c runningClaim) error {
+	updated, err := q.updateRunning(ctx, c, j.ID, func(stored *job.Job) storedTransition {
+		stored.Attempts++
+		stored.Error = errMsg
+		return q.deadLetterTransition(stored)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to move job to dead letter queue: %w", err)
 	}
+	copyState(j, updated)
+	q.deadLettered(ctx, j)
+	return nil
+}
+
+// deadLetterTransition marks a job as failed and moves it from the processing queue
+// to the dead letter queue
+//
+// One script removes the job from the processing queue, releases the lease, moves
+// it to the dead letter queue and updates its data with a TTL to prevent unbounded
+// growth of failed jobs. The checkpoint expires together with the job data, and
+// the offloaded payload is scheduled for deletion when it does.
+func (q *RedisQueue) deadLetterTransition(j *job.Job) storedTransition {
+	j.UpdateStatus(job.StatusFailed)
+	j.ScheduledFor = nil // Clear scheduled time
+
+	return storedTransition{
+		script: deadLetterScript,
+		keys: []string{q.jobKey(j.ID), q.deadLetterQueueKey(), q.leasesKey, q.outstandingKey,
+			q.checkpointKey(j.ID), q.blobExpiryKey},
+		args: []interface{}{q.failedJobTTL.Milliseconds(), j.PayloadRef, time.Now().Add(q.failedJobTTL).Unix()},
+	}
+}
+
+// deadLettered runs the side effects of a job moving to the dead letter queue
+func (q *RedisQueue) deadLettered(ctx context.Context, j *job.Job) {
+	metrics.Default().RecordTenantFailed(q.namespace)
 
 	log.Printf("Job %s moved to dead letter queue after %d attempts (TTL: %v)", j.ID, j.Attempts, q.failedJobTTL)
-	return nil
+	q.hooks.Emit(ctx, events.TypeDead, j)
+	q.settleDependents(ctx, j, false)
 }
 
 // MoveScheduledToReady moves jobs from the scheduled set to their priority queues when ready
@@ -446,8 +693,12 @@ func (q *RedisQueue) MoveScheduledToReady(ctx context.Context) (int, error) {
 			continue
 		}
 
-		// Clear scheduled time
+		// Clear scheduled time - the job is ready from now on
 		j.ScheduledFor = nil
+		if j.Status == job.StatusScheduled {
+			j.Status = job.StatusPending
+		}
+		j.UpdatedAt = time.Now()
 
 		// Update job data
 		updatedJobData, err := json.Marshal(j)
@@ -467,34 +718,36 @@ func (q *RedisQueue) MoveScheduledToReady(ctx context.Context) (int, error) {
 		return 0, nil
 	}
 
-	// Execute all updates in a single pipeline for maximum efficiency
-	// This reduces N round trips to 1 when moving N jobs
-	pipe := q.client.Pipeline()
+	dedicated, err := q.dedicatedNames(ctx)
+	if err != nil {
+		return 0, err
+	}
 
+	// Move the whole batch in one script; each job is only moved by the caller that
+	// removes it from the scheduled set, so concurrent schedulers can't push it twice
+	keys := make([]string, 0, 1+2*len(updates))
+	args := make([]interface{}, 0, 2*len(updates))
+	keys = append(keys, q.getScheduledSetKey())
 	for _, update := range updates {
-		// Update job data (clear ScheduledFor)
-		pipe.Set(ctx, q.jobKey(update.job.ID), update.updatedData, 0)
-
-		// Ensure job has a valid routing key (default to "default" for backward compatibility)
-		routingKey := update.job.RoutingKey
-		if routingKey == "" {
-			routingKey = "default"
-		}
-
-		// Enqueue to appropriate routed priority queue
-		pipe.LPush(ctx, q.routeQueueKey(routingKey, update.job.Priority), update.job.ID)
-
-		// Remove from scheduled set
-		pipe.ZRem(ctx, q.getScheduledSetKey(), update.job.ID)
+		// Enqueue to the job's routed (or dedicated job name) priority queue
+		keys = append(keys, q.jobKey(update.job.ID), q.readyQueueKey(update.job, dedicated[update.job.Name]))
+		args = append(args, update.job.ID, update.updatedData)
 	}
 
-	// Execute the entire batch
-	if _, err := pipe.Exec(ctx); err != nil {
+	movedIDs, err := promoteScript.Run(ctx, q.client, keys, args...).StringSlice()
+	if err != nil {
 		return 0, fmt.Errorf("failed to execute batch job updates: %w", err)
 	}
+	moved := make(map[string]bool, len(movedIDs))
+	for _, id := range movedIDs {
+		moved[id] = true
+	}
 
 	// Log each moved job
 	for _, update := range updates {
+		if !moved[update.job.ID] {
+			continue
+		}
 		routingKey := update.job.RoutingKey
 		if routingKey == "" {
 			routingKey = "default"
@@ -503,7 +756,7 @@ func (q *RedisQueue) MoveScheduledToReady(ctx context.Context) (int, error) {
 			update.job.ID, routingKey, update.job.Priority, update.job.Attempts, update.job.MaxRetries)
 	}
 
-	movedCount := len(updates)
+	movedCount := len(movedIDs)
 
 	if movedCount > 0 {
 		log.Printf("Moved %d scheduled jobs to ready queues", movedCount)
@@ -516,7 +769,7 @@ func (q *RedisQueue) MoveScheduledToReady(ctx context.Context) (int, error) {
 func (q *RedisQueue) GetJob(ctx context.Context, jobID string) (*job.Job, error) {
 	jobData, err := q.client.Get(ctx, q.jobKey(jobID)).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("job not found: %s", jobID)
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job: %w", err)
@@ -530,6 +783,102 @@ func (q *RedisQueue) GetJob(ctx context.Context, jobID string) (*job.Job, error)
 	return &j, nil
 }
 
+// Cancel removes a job waiting in its ready queue, the scheduled set or for its
+// dependencies and marks it cancelled. The job data is kept for the completed job TTL.
+// Jobs depending on it are then cancelled or released according to their policy.
+func (q *RedisQueue) Cancel(ctx context.Context, jobID string) error {
+	j, err := q.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	status := j.Status
+	j.UpdateStatus(job.StatusCancelled)
+	j.ScheduledFor = nil
+	jobData, err := q.marshalJob(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	// Removing the ID claims the job, so a concurrent Dequeue can't also start it
+	// The job name may have been dedicated (or released) since it was enqueued
+	keys := []string{q.jobKey(jobID), q.getScheduledSetKey(), q.checkpointKey(jobID), q.outstandingKey,
+		q.waitingKey, q.dependenciesKey(jobID), q.readyQueueKey(j, false), q.readyQueueKey(j, true)}
+	cancelled, err := cancelScript.Run(ctx, q.client, keys, jobID, jobData, q.completedJobTTL.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if cancelled == 0 {
+		return fmt.Errorf("%w: job %s is %s", ErrNotCancellable, jobID, status)
+	}
+
+	// The payload will never be needed
+	q.releasePayload(ctx, j)
+
+	log.Printf("Cancelled job %s", jobID)
+	q.hooks.Emit(ctx, events.TypeCancel, j)
+	q.settleDependents(ctx, j, false)
+	return nil
+}
+
+// markCancelled stores a job that was removed from its queue as cancelled
+// StreamQueue.Cancel uses it after claiming the job from its stream.
+func (q *RedisQueue) markCancelled(ctx context.Context, j *job.Job) error {
+	jobID := j.ID
+	j.UpdateStatus(job.StatusCancelled)
+	j.ScheduledFor = nil
+	jobData, err := q.marshalJob(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	pipe := q.client.Pipeline()
+	pipe.Set(ctx, q.jobKey(jobID), jobData, q.completedJobTTL)
+	pipe.Del(ctx, q.checkpointKey(jobID))
+	pipe.SRem(ctx, q.outstandingKey, jobID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	// The payload will never be needed
+	q.releasePayload(ctx, j)
+
+	log.Printf("Cancelled job %s", jobID)
+	q.hooks.Emit(ctx, events.TypeCancel, j)
+	return nil
+}
+
+// Stats returns the number of jobs in the shared priority queues, the scheduled set,
+// the waiting set, the processing queue and the dead letter queue
+func (q *RedisQueue) Stats(ctx context.Context) (Stats, error) {
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+
+	pipe := q.client.Pipeline()
+	ready := make([]*redis.IntCmd, len(priorities))
+	for i, priority := range priorities {
+		ready[i] = pipe.LLen(ctx, q.queueKey(priority))
+	}
+	scheduled := pipe.ZCard(ctx, q.getScheduledSetKey())
+	waiting := pipe.SCard(ctx, q.waitingKey)
+	processing := pipe.LLen(ctx, q.processingQueueKey())
+	dead := pipe.LLen(ctx, q.deadLetterQueueKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Stats{}, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	stats := Stats{
+		Ready:      make(map[job.JobPriority]int64, len(priorities)),
+		Scheduled:  scheduled.Val(),
+		Waiting:    waiting.Val(),
+		Processing: processing.Val(),
+		Dead:       dead.Val(),
+	}
+	for i, priority := range priorities {
+		stats.Ready[priority] = ready[i].Val()
+	}
+	return stats, nil
+}
+
 // updateQueueMetrics updates metrics with current queue depths
 // This is called periodically and best-effort (errors are logged but not returned)
 func (q *RedisQueue) updateQueueMetrics(ctx context.Context) {
@@ -545,6 +894,19 @@ func (q *RedisQueue) updateQueueMetrics(ctx context.Context) {
 		}
 		metrics.Default().RecordQueueDepth(priority, depth)
 	}
+
+	outstanding, err := q.client.SCard(ctx, q.outstandingKey).Result()
+	if err != nil {
+		log.Printf("Failed to count unfinished jobs in namespace %s: %v", q.namespace, err)
+		return
+	}
+	metrics.Default().RecordTenantOutstanding(q.namespace, outstanding)
+}
+
+// RefreshQueueMetrics records the current depths of the shared priority queues in metrics
+// Autoscaling worker pools call it before each evaluation (see worker.Autoscaler)
+func (q *RedisQueue) RefreshQueueMetrics(ctx context.Context) {
+	q.updateQueueMetrics(ctx)
 }
 
 // Close closes the Redis connection
@@ -273,6 +273,118 @@ func TestFail_WithRetry(t *testing.T) {
 	}
 }
 
+func TestFailAfter_UsesGivenDelay(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 3
+	queue.Enqueue(ctx, j)
+
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+	dequeuedJob, _ := queue.Dequeue(ctx, priorities)
+
+	before := time.Now()
+	if err := queue.FailAfter(ctx, dequeuedJob, "rate limited", 2*time.Minute); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	retriedJob, _ := queue.GetJob(ctx, j.ID)
+	if retriedJob.ScheduledFor == nil {
+		t.Fatal("expected ScheduledFor to be set")
+	}
+	delay := retriedJob.ScheduledFor.Sub(before)
+	if delay < 2*time.Minute || delay > 2*time.Minute+5*time.Second {
+		t.Errorf("expected retry in ~2m, got %v", delay)
+	}
+	if retriedJob.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", retriedJob.Attempts)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	j.SetDeadline(time.Now().Add(-time.Second))
+	queue.Enqueue(ctx, j)
+
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+	dequeuedJob, _ := queue.Dequeue(ctx, priorities)
+
+	if err := queue.Expire(ctx, dequeuedJob, "deadline passed"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if n, _ := queue.client.LLen(ctx, queue.processingQueueKey()).Result(); n != 0 {
+		t.Errorf("expected processing queue empty, got length %d", n)
+	}
+	if n, _ := queue.client.LLen(ctx, queue.deadLetterQueueKey()).Result(); n != 0 {
+		t.Errorf("expected expired job not in dead letter queue, got length %d", n)
+	}
+
+	expired, _ := queue.GetJob(ctx, j.ID)
+	if expired.Status != job.StatusExpired || expired.Error != "deadline passed" {
+		t.Errorf("expected expired status with reason, got %s %q", expired.Status, expired.Error)
+	}
+	if ttl := mr.TTL(queue.jobKey(j.ID)); ttl <= 0 {
+		t.Errorf("expected expired job data to have a TTL, got %v", ttl)
+	}
+}
+
+func TestRequeue_DoesNotCountAttempt(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	first := job.NewJob("first", []byte(`{}`), job.PriorityNormal)
+	second := job.NewJob("second", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, first)
+	queue.Enqueue(ctx, second)
+
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+	dequeued, _ := queue.Dequeue(ctx, priorities)
+	if dequeued.ID != first.ID {
+		t.Fatalf("expected first job, got %s", dequeued.Name)
+	}
+
+	if err := queue.Requeue(ctx, dequeued); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// A second requeue of the same job is a no-op
+	if err := queue.Requeue(ctx, dequeued); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if n, _ := queue.client.LLen(ctx, queue.processingQueueKey()).Result(); n != 0 {
+		t.Errorf("expected processing queue empty, got length %d", n)
+	}
+	if n, _ := queue.client.LLen(ctx, queue.queueKey(job.PriorityNormal)).Result(); n != 2 {
+		t.Errorf("expected 2 queued jobs, got %d", n)
+	}
+	if n, _ := queue.client.ZCard(ctx, queue.leasesKey).Result(); n != 0 {
+		t.Errorf("expected lease to be released, got %d leases", n)
+	}
+
+	// The requeued job runs next, with no attempt recorded
+	again, _ := queue.Dequeue(ctx, priorities)
+	if again.ID != first.ID {
+		t.Fatalf("expected requeued job to be dequeued next, got %s", again.Name)
+	}
+	if again.Attempts != 0 || again.Status != job.StatusPending {
+		t.Errorf("expected pending job with 0 attempts, got %s with %d", again.Status, again.Attempts)
+	}
+}
+
 func TestFail_MaxRetriesExceeded(t *testing.T) {
 	queue, mr := setupTestRedis(t)
 	defer mr.Close()
@@ -316,6 +428,50 @@ func TestFail_MaxRetriesExceeded(t *testing.T) {
 	}
 }
 
+func TestDeadLetter_SkipsRetries(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	// Fresh job with retries remaining
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+	dequeuedJob, _ := queue.Dequeue(ctx, priorities)
+
+	if err := queue.DeadLetter(ctx, dequeuedJob, "invalid payload"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Verify job in dead letter queue and not scheduled for retry
+	length, _ := queue.client.LLen(ctx, queue.deadLetterQueueKey()).Result()
+	if length != 1 {
+		t.Errorf("expected job in dead letter queue, got length %d", length)
+	}
+	scheduled, _ := queue.client.ZCard(ctx, queue.getScheduledSetKey()).Result()
+	if scheduled != 0 {
+		t.Errorf("expected no scheduled retries, got %d", scheduled)
+	}
+	processing, _ := queue.client.LLen(ctx, queue.processingQueueKey()).Result()
+	if processing != 0 {
+		t.Errorf("expected processing queue empty, got length %d", processing)
+	}
+
+	failedJob, _ := queue.GetJob(ctx, j.ID)
+	if failedJob.Status != job.StatusFailed {
+		t.Errorf("expected status %s, got %s", job.StatusFailed, failedJob.Status)
+	}
+	if failedJob.Error != "invalid payload" {
+		t.Errorf("expected error 'invalid payload', got '%s'", failedJob.Error)
+	}
+	if failedJob.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", failedJob.Attempts)
+	}
+}
+
 func TestGetJob_Success(t *testing.T) {
 	queue, mr := setupTestRedis(t)
 	defer mr.Close()
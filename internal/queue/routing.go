@@ -0,0 +1,225 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/redis/go-redis/v9"
+)
+
+// routeQueueKey returns the ready queue for a routing key and priority
+// The default route uses the shared priority queues, so jobs enqueued before
+// routing existed (and workers that don't use routing) keep working.
+func (q *RedisQueue) routeQueueKey(routingKey string, priority job.JobPriority) string {
+	if routingKey == "" || routingKey == job.DefaultRoutingKey {
+		return q.queueKey(priority)
+	}
+	return q.keyPrefix + "route:" + routingKey + ":queue:" + string(priority)
+}
+
+// jobNameQueueKey returns the ready queue for a dedicated job name and priority
+func (q *RedisQueue) jobNameQueueKey(name string, priority job.JobPriority) string {
+	return q.keyPrefix + "jobs:" + name + ":queue:" + string(priority)
+}
+
+// readyQueueKey returns the queue a job is pushed to when it becomes ready
+// Jobs on the default route whose name is dedicated go to their name's queue;
+// all others go to their route's queue.
+func (q *RedisQueue) readyQueueKey(j *job.Job, dedicated bool) string {
+	if dedicated && (j.RoutingKey == "" || j.RoutingKey == job.DefaultRoutingKey) {
+		return q.jobNameQueueKey(j.Name, j.Priority)
+	}
+	return q.routeQueueKey(j.RoutingKey, j.Priority)
+}
+
+// DedicationTTL is how long a job name keeps its own queues without being renewed
+// (see DedicateJobNames)
+const DedicationTTL = 30 * time.Second
+
+// isDedicated reports whether a job name has its own queues (see DedicateJobNames)
+func (q *RedisQueue) isDedicated(ctx context.Context, name string) (bool, error) {
+	expiry, err := q.client.ZScore(ctx, q.dedicatedKey, name).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check dedicated job names: %w", err)
+	}
+	return expiry > float64(time.Now().UnixMilli()), nil
+}
+
+// dedicatedNames returns the set of job names that have their own queues
+func (q *RedisQueue) dedicatedNames(ctx context.Context) (map[string]bool, error) {
+	names, err := q.client.ZRangeByScore(ctx, q.dedicatedKey, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dedicated job names: %w", err)
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set, nil
+}
+
+// DedicateJobNames gives job names their own queues, served by job-specialized workers
+//
+// From then on, jobs with these names on the default route are enqueued (and retried)
+// to bananas:jobs:<name>:queue:<priority> instead of the shared priority queues, so
+// only workers that dequeue those names (DequeueJobNames) see them. Jobs already in
+// the shared queues stay there.
+//
+// A dedication lapses DedicationTTL after it was last renewed, so names return to
+// the shared queues once no worker serves them, even if the workers crashed.
+// Job-specialized worker pools call this on Start and renew it while they run.
+func (q *RedisQueue) DedicateJobNames(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	expiry := float64(time.Now().Add(DedicationTTL).UnixMilli())
+	members := make([]redis.Z, len(names))
+	for i, name := range names {
+		members[i] = redis.Z{Score: expiry, Member: name}
+	}
+	if err := q.client.ZAdd(ctx, q.dedicatedKey, members...).Err(); err != nil {
+		return fmt.Errorf("failed to dedicate job names: %w", err)
+	}
+	return nil
+}
+
+// ReleaseJobNames returns job names to the shared priority queues
+// Jobs already waiting in the names' queues stay there until a worker for the name drains them.
+func (q *RedisQueue) ReleaseJobNames(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(names))
+	for i, name := range names {
+		members[i] = name
+	}
+	if err := q.client.ZRem(ctx, q.dedicatedKey, members...).Err(); err != nil {
+		return fmt.Errorf("failed to release job names: %w", err)
+	}
+	return nil
+}
+
+// DequeueWithRouting retrieves a job for any of the given routing keys, at any priority
+// See DequeueRoutes for the order the queues are checked in.
+func (q *RedisQueue) DequeueWithRouting(ctx context.Context, routingKeys []string) (*job.Job, error) {
+	return q.DequeueRoutes(ctx, routingKeys, []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow})
+}
+
+// DequeueRoutes retrieves a job from the queues of the given routing keys
+//
+// Routing keys are checked in the order given and, within each key, by priority,
+// so a worker for ["gpu", "default"] drains every GPU job before any default one.
+// Returns nil if all the queues stay empty for the blocking timeout.
+func (q *RedisQueue) DequeueRoutes(ctx context.Context, routingKeys []string, priorities []job.JobPriority) (*job.Job, error) {
+	keys := make([]string, 0, len(routingKeys)*len(priorities))
+	for _, routingKey := range routingKeys {
+		for _, priority := range priorities {
+			keys = append(keys, q.routeQueueKey(routingKey, priority))
+		}
+	}
+	return q.dequeueFrom(ctx, keys)
+}
+
+// DequeueJobNames retrieves a job from the queues of the given dedicated job names
+// Queues are checked by priority first, then in the order the names are given.
+// Returns nil if all the queues stay empty for the blocking timeout.
+func (q *RedisQueue) DequeueJobNames(ctx context.Context, names []string, priorities []job.JobPriority) (*job.Job, error) {
+	keys := make([]string, 0, len(names)*len(priorities))
+	for _, priority := range priorities {
+		for _, name := range names {
+			keys = append(keys, q.jobNameQueueKey(name, priority))
+		}
+	}
+	return q.dequeueFrom(ctx, keys)
+}
+
+// dequeuePollInterval is how often dequeueFrom sweeps empty queues while it waits
+const dequeuePollInterval = 100 * time.Millisecond
+
+// dequeueFrom moves the first available job from the given queues to the processing queue
+//
+// The queues are swept in order with non-blocking RPOPLPUSH. If they are all empty,
+// a single queue is watched with BRPOPLPUSH for a second, so idle workers don't poll.
+// BRPOPLPUSH only watches one key (and BLMPOP can't move the job to the processing
+// queue atomically), so several queues are swept again every dequeuePollInterval
+// for up to a second instead.
+func (q *RedisQueue) dequeueFrom(ctx context.Context, queueKeys []string) (*job.Job, error) {
+	if len(queueKeys) == 0 {
+		return nil, nil
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		j, err := q.sweep(ctx, queueKeys)
+		if err != nil || j != nil {
+			return j, err
+		}
+		if len(queueKeys) == 1 {
+			return q.blockingDequeue(ctx, queueKeys[0])
+		}
+		if !time.Now().Before(deadline) {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(dequeuePollInterval):
+		}
+	}
+}
+
+// sweep moves the first available job from the given queues to the processing
+// queue without blocking. Returns nil if they are all empty.
+func (q *RedisQueue) sweep(ctx context.Context, queueKeys []string) (*job.Job, error) {
+	for _, queueKey := range queueKeys {
+		for {
+			jobID, err := q.client.RPopLPush(ctx, queueKey, q.processingQueueKey()).Result()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, fmt.Errorf("failed to dequeue job: %w", err)
+			}
+
+			if j := q.claimDequeued(ctx, jobID); j != nil {
+				log.Printf("Dequeued job %s from %s", j.ID, queueKey)
+				return j, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// blockingDequeue waits up to a second for a job on one queue
+func (q *RedisQueue) blockingDequeue(ctx context.Context, queueKey string) (*job.Job, error) {
+	jobID, err := q.client.BRPopLPush(ctx, queueKey, q.processingQueueKey(), time.Second).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to dequeue job: %w", err)
+	}
+
+	j := q.claimDequeued(ctx, jobID)
+	if j != nil {
+		log.Printf("Dequeued job %s from %s", j.ID, queueKey)
+	}
+	return j, nil
+}
@@ -0,0 +1,250 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func TestEnqueue_RoutesByRoutingKey(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	gpuJob := job.NewJob("process_image", []byte(`{}`), job.PriorityHigh)
+	gpuJob.SetRoutingKey("gpu")
+	defaultJob := job.NewJob("send_email", []byte(`{}`), job.PriorityHigh)
+	queue.Enqueue(ctx, gpuJob)
+	queue.Enqueue(ctx, defaultJob)
+
+	// The default route keeps using the shared priority queues
+	if n, _ := queue.client.LLen(ctx, "bananas:queue:high").Result(); n != 1 {
+		t.Errorf("expected 1 job in shared high queue, got %d", n)
+	}
+	if n, _ := queue.client.LLen(ctx, "bananas:route:gpu:queue:high").Result(); n != 1 {
+		t.Errorf("expected 1 job in gpu high queue, got %d", n)
+	}
+
+	dequeued, err := queue.DequeueWithRouting(ctx, []string{"gpu"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if dequeued == nil || dequeued.ID != gpuJob.ID {
+		t.Fatalf("expected gpu job, got %v", dequeued)
+	}
+
+	// Workers without routing keys never see routed jobs
+	gpuJob2 := job.NewJob("process_image", []byte(`{}`), job.PriorityHigh)
+	gpuJob2.SetRoutingKey("gpu")
+	queue.Enqueue(ctx, gpuJob2)
+
+	dequeued, _ = queue.Dequeue(ctx, allPriorities)
+	if dequeued == nil || dequeued.ID != defaultJob.ID {
+		t.Fatalf("expected default job, got %v", dequeued)
+	}
+}
+
+func TestDequeueRoutes_KeyOrder(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	defaultJob := job.NewJob("send_email", []byte(`{}`), job.PriorityHigh)
+	gpuJob := job.NewJob("process_image", []byte(`{}`), job.PriorityLow)
+	gpuJob.SetRoutingKey("gpu")
+	queue.Enqueue(ctx, defaultJob)
+	queue.Enqueue(ctx, gpuJob)
+
+	// Routing keys are drained in order, before priority across keys
+	first, _ := queue.DequeueRoutes(ctx, []string{"gpu", "default"}, allPriorities)
+	second, _ := queue.DequeueRoutes(ctx, []string{"gpu", "default"}, allPriorities)
+	if first == nil || first.ID != gpuJob.ID {
+		t.Fatalf("expected gpu job first, got %v", first)
+	}
+	if second == nil || second.ID != defaultJob.ID {
+		t.Fatalf("expected default job second, got %v", second)
+	}
+	if n, _ := queue.client.ZCard(ctx, queue.leasesKey).Result(); n != 2 {
+		t.Errorf("expected both jobs to be leased, got %d leases", n)
+	}
+}
+
+func TestDequeueRoutes_Empty(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	start := time.Now()
+	j, err := queue.DequeueRoutes(context.Background(), []string{"gpu"}, allPriorities)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if j != nil {
+		t.Errorf("expected nil job, got %v", j)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected a single short blocking wait, took %v", elapsed)
+	}
+}
+
+func TestDedicateJobNames_EnqueueToNameQueues(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	if err := queue.DedicateJobNames(ctx, "send_email"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	emailJob := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	reportJob := job.NewJob("generate_report", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, emailJob)
+	queue.Enqueue(ctx, reportJob)
+
+	if n, _ := queue.client.LLen(ctx, "bananas:jobs:send_email:queue:normal").Result(); n != 1 {
+		t.Errorf("expected 1 job in send_email queue, got %d", n)
+	}
+
+	// Shared-queue workers only see the job whose name isn't dedicated
+	shared, _ := queue.Dequeue(ctx, allPriorities)
+	if shared == nil || shared.ID != reportJob.ID {
+		t.Fatalf("expected report job from shared queues, got %v", shared)
+	}
+
+	dedicated, err := queue.DequeueJobNames(ctx, []string{"send_email"}, allPriorities)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if dedicated == nil || dedicated.ID != emailJob.ID {
+		t.Fatalf("expected email job from its name queue, got %v", dedicated)
+	}
+
+	// Routed jobs stay on their route even when their name is dedicated
+	routed := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	routed.SetRoutingKey("bulk")
+	queue.Enqueue(ctx, routed)
+	if n, _ := queue.client.LLen(ctx, "bananas:route:bulk:queue:normal").Result(); n != 1 {
+		t.Errorf("expected routed job in its route queue, got %d", n)
+	}
+
+	// Released names go back to the shared queues
+	queue.ReleaseJobNames(ctx, "send_email")
+	queue.Enqueue(ctx, job.NewJob("send_email", []byte(`{}`), job.PriorityNormal))
+	if n, _ := queue.client.LLen(ctx, queue.queueKey(job.PriorityNormal)).Result(); n != 1 {
+		t.Errorf("expected released job in shared queue, got %d", n)
+	}
+}
+
+func TestRequeue_UsesJobNameQueue(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	// Enqueued before its name was dedicated, then picked up by a shared-queue worker
+	emailJob := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, emailJob)
+	dequeued, _ := queue.Dequeue(ctx, allPriorities)
+
+	queue.DedicateJobNames(ctx, "send_email")
+	if err := queue.Requeue(ctx, dequeued); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if n, _ := queue.client.LLen(ctx, queue.queueKey(job.PriorityNormal)).Result(); n != 0 {
+		t.Errorf("expected shared queue empty, got %d", n)
+	}
+	again, _ := queue.DequeueJobNames(ctx, []string{"send_email"}, allPriorities)
+	if again == nil || again.ID != emailJob.ID {
+		t.Fatalf("expected requeued job in its name queue, got %v", again)
+	}
+}
+
+func TestMoveScheduledToReady_Routing(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+
+	queue.DedicateJobNames(ctx, "send_email")
+
+	gpuJob := job.NewJob("process_image", []byte(`{}`), job.PriorityNormal)
+	gpuJob.SetRoutingKey("gpu")
+	emailJob := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	for _, j := range []*job.Job{gpuJob, emailJob} {
+		queue.Enqueue(ctx, j)
+	}
+	gpuDequeued, _ := queue.DequeueWithRouting(ctx, []string{"gpu"})
+	emailDequeued, _ := queue.DequeueJobNames(ctx, []string{"send_email"}, allPriorities)
+	queue.FailAfter(ctx, gpuDequeued, "boom", time.Millisecond)
+	queue.FailAfter(ctx, emailDequeued, "boom", time.Millisecond)
+
+	time.Sleep(5 * time.Millisecond)
+	moved, err := queue.MoveScheduledToReady(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if moved != 2 {
+		t.Fatalf("expected 2 jobs moved, got %d", moved)
+	}
+
+	if n, _ := queue.client.LLen(ctx, "bananas:route:gpu:queue:normal").Result(); n != 1 {
+		t.Errorf("expected retried gpu job in its route queue, got %d", n)
+	}
+	if n, _ := queue.client.LLen(ctx, "bananas:jobs:send_email:queue:normal").Result(); n != 1 {
+		t.Errorf("expected retried email job in its name queue, got %d", n)
+	}
+}
+
+func TestDequeueJobNames_WaitsOnEveryQueue(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.DedicateJobNames(ctx, "send_email", "resize")
+
+	// The job arrives on the last queue while the worker waits
+	resize := job.NewJob("resize", []byte(`{}`), job.PriorityLow)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		queue.Enqueue(ctx, resize)
+	}()
+
+	got, err := queue.DequeueJobNames(ctx, []string{"send_email", "resize"}, allPriorities)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got == nil || got.ID != resize.ID {
+		t.Fatalf("expected the resize job, got %v", got)
+	}
+}
+
+func TestDedicateJobNames_Lapses(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.DedicateJobNames(ctx, "send_email")
+
+	// No worker renewed the dedication in time
+	mr.ZAdd(queue.dedicatedKey, float64(time.Now().Add(-time.Second).UnixMilli()), "send_email")
+
+	emailJob := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, emailJob)
+	shared, _ := queue.Dequeue(ctx, allPriorities)
+	if shared == nil || shared.ID != emailJob.ID {
+		t.Fatalf("expected the job in the shared queues, got %v", shared)
+	}
+}
@@ -0,0 +1,1025 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamGroup is the consumer group every worker of a StreamQueue reads through
+const StreamGroup = "workers"
+
+// DefaultStreamDequeueTimeout is how long Dequeue blocks on empty streams
+const DefaultStreamDequeueTimeout = time.Second
+
+// streamRequeuedConsumer owns pending entries returned by Requeue until Dequeue claims them
+const streamRequeuedConsumer = "requeued"
+
+// streamReapBatch is how many orphaned entries ReapExpiredLeases claims per XAUTOCLAIM call
+const streamReapBatch = 100
+
+// Redis queue implementations, selected with QUEUE_BACKEND (see NewRedisBroker)
+const (
+	BackendList    = "list"
+	BackendStreams = "streams"
+)
+
+// RedisBroker is a Broker stored in Redis: the list-based RedisQueue or the
+// streams-based StreamQueue. Workers and schedulers use it so either can be configured.
+type RedisBroker interface {
+	Broker
+	Heartbeat(ctx context.Context, jobID string) error
+	ReapExpiredLeases(ctx context.Context) (int, error)
+	CleanupExpiredBlobs(ctx context.Context) (int, error)
+	RefreshQueueMetrics(ctx context.Context)
+	SaveCheckpoint(ctx context.Context, jobID string, data []byte) error
+	LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error)
+	SetNamespace(ns string) error
+	SetQuota(quota Quota)
+	SetLeaseDuration(d time.Duration)
+	SetEncryptor(enc *serialization.Encryptor)
+	SetBlobStore(store storage.BlobStore, threshold int)
+	EnableEventStream(maxLen int64)
+	EventStreamKey() string
+	Hooks() *events.Registry
+}
+
+// NewRedisBroker connects to Redis with the named queue implementation
+// backend is BackendList (the default when empty) or BackendStreams.
+func NewRedisBroker(redisURL, backend string) (RedisBroker, error) {
+	switch backend {
+	case "", BackendList:
+		return NewRedisQueue(redisURL)
+	case BackendStreams:
+		return NewStreamQueue(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q (want %q or %q)", backend, BackendList, BackendStreams)
+	}
+}
+
+// streamDequeueScript takes the oldest requeued entry of a stream, or else reads a new one
+// KEYS: stream, requeued job IDs set
+// ARGV: consumer group, consumer, requeued pseudo-consumer
+// Returns {entry ID, job ID}, or nil when the stream has nothing to deliver.
+var streamDequeueScript = redis.NewScript(`
+local requeued = redis.call("XPENDING", KEYS[1], ARGV[1], "-", "+", 1, ARGV[3])
+if #requeued > 0 then
+	local claimed = redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[2], 0, requeued[1][1])
+	if #claimed > 0 and claimed[1] then
+		local jobID = claimed[1][2][2]
+		redis.call("SREM", KEYS[2], jobID)
+		return {claimed[1][1], jobID}
+	end
+	redis.call("XACK", KEYS[1], ARGV[1], requeued[1][1])
+end
+local read = redis.call("XREADGROUP", "GROUP", ARGV[1], ARGV[2], "COUNT", 1, "STREAMS", KEYS[1], ">")
+if not read or #read == 0 then
+	return nil
+end
+local entry = read[1][2][1]
+return {entry[1], entry[2][2]}
+`)
+
+// streamAckScript acknowledges and deletes an entry the consumer still owns
+// KEYS: stream, stream entries hash
+// ARGV: consumer group, consumer, entry ID, job ID
+// Returns 0 when the entry was acknowledged already or belongs to another consumer.
+var streamAckScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+redis.call("XACK", KEYS[1], ARGV[1], ARGV[3])
+redis.call("XDEL", KEYS[1], ARGV[3])
+redis.call("HDEL", KEYS[2], ARGV[4])
+return 1
+`)
+
+// streamPutBackScript moves an entry the consumer owns to the end of its stream
+// The entry is acknowledged, deleted and re-added in one step, so the job can't be
+// lost (or added twice) if the process dies halfway.
+// KEYS: stream, stream entries hash
+// ARGV: consumer group, consumer, entry ID, job ID
+// Returns 0 when the entry was acknowledged already or belongs to another consumer.
+var streamPutBackScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+redis.call("XACK", KEYS[1], ARGV[1], ARGV[3])
+redis.call("XDEL", KEYS[1], ARGV[3])
+local entry = redis.call("XADD", KEYS[1], "*", "job", ARGV[4])
+redis.call("HSET", KEYS[2], ARGV[4], KEYS[1] .. " " .. entry)
+return 1
+`)
+
+// streamRequeueScript hands an entry the consumer owns to the requeued pseudo-consumer
+// and stores the job. The entry keeps its place in the stream, so Dequeue delivers
+// it before newer entries.
+// KEYS: stream, requeued job IDs set, job key
+// ARGV: consumer group, consumer, entry ID, job ID, requeued pseudo-consumer, job data
+// Returns 0 when the entry was acknowledged already or belongs to another consumer.
+var streamRequeueScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[5], 0, ARGV[3], "JUSTID")
+redis.call("SADD", KEYS[2], ARGV[4])
+redis.call("SET", KEYS[3], ARGV[6])
+return 1
+`)
+
+// streamPromoteScript appends due jobs from the scheduled set to their streams
+// Jobs another caller already removed from the scheduled set (moved or cancelled) are skipped.
+// KEYS: scheduled set, stream entries hash, then job and stream per job
+// ARGV: job ID and job data per job
+// Returns the IDs of the jobs it moved.
+var streamPromoteScript = redis.NewScript(`
+local moved = {}
+for i = 1, #ARGV, 2 do
+	local id = ARGV[i]
+	if redis.call("ZREM", KEYS[1], id) == 1 then
+		redis.call("SET", KEYS[i + 2], ARGV[i + 1])
+		local entry = redis.call("XADD", KEYS[i + 3], "*", "job", id)
+		redis.call("HSET", KEYS[2], id, KEYS[i + 3] .. " " .. entry)
+		moved[#moved + 1] = id
+	end
+end
+return moved
+`)
+
+// streamCancelScript deletes a stream entry no worker is running
+// KEYS: stream, stream entries hash, requeued job IDs set
+// ARGV: consumer group, entry ID, job ID, requeued pseudo-consumer
+// Returns 1 when the entry was deleted, 0 when a worker has it or it is gone.
+var streamCancelScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[2], ARGV[2], 1)
+if #pending > 0 then
+	if pending[1][2] ~= ARGV[4] then
+		return 0
+	end
+	redis.call("XACK", KEYS[1], ARGV[1], ARGV[2])
+	redis.call("SREM", KEYS[3], ARGV[3])
+end
+if redis.call("XDEL", KEYS[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[2], ARGV[3])
+return 1
+`)
+
+// streamHeartbeatScript resets the idle time of an entry its consumer still owns
+// KEYS: stream
+// ARGV: consumer group, consumer, entry ID
+// Returns 0 when the entry was acknowledged or claimed by someone else.
+var streamHeartbeatScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[2], 0, ARGV[3], "JUSTID")
+return 1
+`)
+
+// streamDeleteConsumerScript removes a consumer from the group on every stream
+// where it has no pending entries; entries it still owns stay claimable by
+// ReapExpiredLeases, which deleting the consumer would prevent
+// KEYS: streams
+// ARGV: consumer group, consumer
+// Returns the number of streams the consumer was kept on.
+var streamDeleteConsumerScript = redis.NewScript(`
+local kept = 0
+for _, stream in ipairs(KEYS) do
+	if #redis.call("XPENDING", stream, ARGV[1], "-", "+", 1, ARGV[2]) == 0 then
+		redis.call("XGROUP", "DELCONSUMER", stream, ARGV[1], ARGV[2])
+	else
+		kept = kept + 1
+	end
+end
+return kept
+`)
+
+// StreamQueue manages job queues in Redis Streams
+//
+// Each priority is a stream (<prefix>stream:high, :normal and :low) read through
+// the consumer group "workers". Dequeue reads with XREADGROUP, so a job stays in
+// the group's pending entries list until Complete, Fail, DeadLetter, Expire or
+// Requeue acknowledges it with XACK. The pending entry's idle time is the job's
+// lease: Heartbeat resets it, and ReapExpiredLeases takes over entries idle for
+// longer than the lease duration with XAUTOCLAIM and fails them.
+//
+// Job data, the scheduled set, the dead letter queue, checkpoints, quotas,
+// encryption, payload offloading and hooks are shared with RedisQueue, so jobs
+// get the same retry and dead letter semantics on both. Routing keys, dedicated
+// job name queues and job dependencies (EnqueueDAG) are not supported: Enqueue
+// and Schedule reject jobs with a routing key or dependencies. All processes of a
+// namespace must use the same queue implementation.
+type StreamQueue struct {
+	base *RedisQueue
+	// Consumer name of this process in the group
+	consumer       string
+	dequeueTimeout time.Duration
+}
+
+// NewStreamQueue creates a new Redis Streams queue and tests the connection
+func NewStreamQueue(redisURL string) (*StreamQueue, error) {
+	base, err := NewRedisQueue(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StreamQueue{
+		base:           base,
+		consumer:       streamConsumerName(),
+		dequeueTimeout: DefaultStreamDequeueTimeout,
+	}
+	if err := s.ensureGroups(context.Background()); err != nil {
+		base.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// streamConsumerName returns a consumer name unique to this process
+func streamConsumerName() string {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// streamKey returns the stream of a priority
+func (s *StreamQueue) streamKey(priority job.JobPriority) string {
+	switch priority {
+	case job.PriorityHigh, job.PriorityLow:
+		return s.base.keyPrefix + "stream:" + string(priority)
+	default:
+		return s.base.keyPrefix + "stream:" + string(job.PriorityNormal)
+	}
+}
+
+// entriesKey returns the hash mapping job IDs to "<stream> <entry ID>"
+func (s *StreamQueue) entriesKey() string {
+	return s.base.keyPrefix + "stream:entries"
+}
+
+// requeuedKey returns the set of job IDs whose entries were handed back by Requeue
+func (s *StreamQueue) requeuedKey() string {
+	return s.base.keyPrefix + "stream:requeued"
+}
+
+// ensureGroups creates the consumer group on every priority stream
+// New groups start at the beginning of the stream, so jobs enqueued before are delivered.
+func (s *StreamQueue) ensureGroups(ctx context.Context) error {
+	for _, priority := range []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow} {
+		err := s.base.client.XGroupCreateMkStream(ctx, s.streamKey(priority), StreamGroup, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create consumer group: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetDequeueTimeout sets how long Dequeue blocks on empty streams
+func (s *StreamQueue) SetDequeueTimeout(d time.Duration) {
+	s.dequeueTimeout = d
+}
+
+// Consumer returns this process's consumer name in the group
+func (s *StreamQueue) Consumer() string {
+	return s.consumer
+}
+
+// checkSupported rejects jobs that need routing or dependencies, which streams don't support
+func (s *StreamQueue) checkSupported(j *job.Job) error {
+	if j.RoutingKey != "" && j.RoutingKey != job.DefaultRoutingKey {
+		return fmt.Errorf("stream queue does not support routing key %q", j.RoutingKey)
+	}
+	if j.HasDependencies() {
+		return fmt.Errorf("stream queue does not support job dependencies (job %s)", j.ID)
+	}
+	return nil
+}
+
+// Enqueue appends a job to its priority stream
+func (s *StreamQueue) Enqueue(ctx context.Context, j *job.Job) error {
+	if err := s.checkSupported(j); err != nil {
+		return err
+	}
+
+	q := s.base
+	if err := q.encryptPayload(j); err != nil {
+		return err
+	}
+	if err := q.offloadPayload(ctx, j); err != nil {
+		return err
+	}
+
+	jobData, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	// Job data, stream entry and quota checks are written by one script, so the
+	// entry can be found again by job ID (see entriesKey)
+	streamKey := s.streamKey(j.Priority)
+	rateKey := q.outstandingKey // Not read while the quota is disabled
+	if q.quota.enabled() {
+		rateKey = q.rateWindowKey(time.Now())
+	}
+	keys := []string{q.jobKey(j.ID), streamKey, q.outstandingKey, rateKey, s.entriesKey()}
+	if err := q.runEnqueueScript(ctx, keys, jobData, j.ID, "", "stream"); err != nil {
+		q.releasePayload(ctx, j)
+		return err
+	}
+
+	log.Printf("Enqueued job %s to %s", j.ID, streamKey)
+	metrics.Default().RecordTenantEnqueued(q.namespace)
+	q.hooks.Emit(ctx, events.TypeEnqueue, j)
+
+	// Update queue depth metrics (best-effort, don't fail enqueue on error)
+	s.updateQueueMetrics(ctx)
+
+	return nil
+}
+
+// Schedule adds a job to the scheduled set; MoveScheduledToReady appends it to
+// its priority stream once at has passed
+func (s *StreamQueue) Schedule(ctx context.Context, j *job.Job, at time.Time) error {
+	if err := s.checkSupported(j); err != nil {
+		return err
+	}
+	return s.base.Schedule(ctx, j, at)
+}
+
+// Dequeue reads a job from the highest priority non-empty stream
+//
+// Each stream is first checked without blocking, in priority order, so jobs are
+// always taken in strict priority order; requeued jobs are taken before new ones.
+// If all are empty, Dequeue blocks on all of them at once for the dequeue timeout
+// and takes the first job to arrive. Returns nil when no job arrived in time.
+func (s *StreamQueue) Dequeue(ctx context.Context, priorities []job.JobPriority) (*job.Job, error) {
+	keys := make([]string, len(priorities))
+	for i, priority := range priorities {
+		keys[i] = s.streamKey(priority)
+	}
+
+	for _, key := range keys {
+		j, err := s.take(ctx, key)
+		if err != nil || j != nil {
+			return j, err
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return s.wait(ctx, keys)
+}
+
+// take takes the next job from a stream without blocking
+func (s *StreamQueue) take(ctx context.Context, streamKey string) (*job.Job, error) {
+	keys := []string{streamKey, s.requeuedKey()}
+	res, err := streamDequeueScript.Run(ctx, s.base.client, keys, StreamGroup, s.consumer, streamRequeuedConsumer).StringSlice()
+	if isNoGroup(err) {
+		// The stream was deleted (e.g. by FLUSHDB) since the groups were created
+		if err := s.ensureGroups(ctx); err != nil {
+			return nil, err
+		}
+		res, err = streamDequeueScript.Run(ctx, s.base.client, keys, StreamGroup, s.consumer, streamRequeuedConsumer).StringSlice()
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to dequeue job: %w", err)
+	}
+	return s.claim(ctx, streamKey, res[0], res[1]), nil
+}
+
+// wait blocks until a new job arrives on one of the streams or the dequeue timeout passes
+func (s *StreamQueue) wait(ctx context.Context, keys []string) (*job.Job, error) {
+	args := &redis.XReadGroupArgs{
+		Group:    StreamGroup,
+		Consumer: s.consumer,
+		Streams:  make([]string, 0, 2*len(keys)),
+		Count:    1,
+		Block:    s.dequeueTimeout,
+	}
+	args.Streams = append(args.Streams, keys...)
+	for range keys {
+		args.Streams = append(args.Streams, ">")
+	}
+
+	streams, err := s.base.client.XReadGroup(ctx, args).Result()
+	if isNoGroup(err) {
+		return nil, s.ensureGroups(ctx)
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to dequeue job: %w", err)
+	}
+
+	// Jobs arriving together on several streams are all delivered; streams come
+	// back in the order asked for, so the first is the highest priority and the
+	// others are put back
+	var claimed *job.Job
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			jobID, _ := msg.Values["job"].(string)
+			if claimed == nil {
+				claimed = s.claim(ctx, stream.Stream, msg.ID, jobID)
+				continue
+			}
+			if err := s.putBack(ctx, stream.Stream, msg.ID, jobID); err != nil {
+				log.Printf("Failed to put back job %s: %v", jobID, err)
+			}
+		}
+	}
+	return claimed, nil
+}
+
+// claim loads the job of an entry this consumer just read
+// Entries without a job, or whose job is missing or corrupted, are dropped and nil is returned.
+func (s *StreamQueue) claim(ctx context.Context, streamKey, entryID, jobID string) *job.Job {
+	if jobID == "" {
+		log.Printf("ERROR: Stream entry %s in %s has no job ID - dropping it", entryID, streamKey)
+		s.ackEntry(ctx, streamKey, entryID, "")
+		return nil
+	}
+
+	j := s.base.loadDequeued(ctx, jobID)
+	if j == nil {
+		// Corrupted job was moved to the dead letter queue
+		s.ackEntry(ctx, streamKey, entryID, jobID)
+		return nil
+	}
+
+	log.Printf("Dequeued job %s from %s", j.ID, streamKey)
+	return j
+}
+
+// isNoGroup reports whether err says the consumer group doesn't exist
+func isNoGroup(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "NOGROUP")
+}
+
+// putBack returns an entry this consumer read but won't process to the end of its stream
+func (s *StreamQueue) putBack(ctx context.Context, streamKey, entryID, jobID string) error {
+	keys := []string{streamKey, s.entriesKey()}
+	if err := streamPutBackScript.Run(ctx, s.base.client, keys, StreamGroup, s.consumer, entryID, jobID).Err(); err != nil {
+		return fmt.Errorf("failed to put back stream entry: %w", err)
+	}
+	return nil
+}
+
+// entry returns the stream and entry ID of a job's current stream entry
+// ok is false if the job has no entry (it is scheduled, finished or cancelled).
+func (s *StreamQueue) entry(ctx context.Context, jobID string) (streamKey, entryID string, ok bool, err error) {
+	ref, err := s.base.client.HGet(ctx, s.entriesKey(), jobID).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to get stream entry: %w", err)
+	}
+	streamKey, entryID, ok = strings.Cut(ref, " ")
+	return streamKey, entryID, ok, nil
+}
+
+// ackEntry acknowledges and deletes an entry this consumer just read
+// jobID may be empty for entries without a job.
+func (s *StreamQueue) ackEntry(ctx context.Context, streamKey, entryID, jobID string) error {
+	pipe := s.base.client.Pipeline()
+	pipe.XAck(ctx, streamKey, StreamGroup, entryID)
+	pipe.XDel(ctx, streamKey, entryID)
+	if jobID != "" {
+		pipe.HDel(ctx, s.entriesKey(), jobID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to acknowledge stream entry: %w", err)
+	}
+	return nil
+}
+
+// ack acknowledges a job's stream entry if this consumer owns it
+// Returns false if the entry was already acknowledged, or was taken over by
+// another consumer (e.g. ReapExpiredLeases) and may have been delivered again.
+func (s *StreamQueue) ack(ctx context.Context, jobID string) (bool, error) {
+	streamKey, entryID, ok, err := s.entry(ctx, jobID)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	acked, err := streamAckScript.Run(ctx, s.base.client, []string{streamKey, s.entriesKey()},
+		StreamGroup, s.consumer, entryID, jobID).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acknowledge stream entry: %w", err)
+	}
+	return acked > 0, nil
+}
+
+// runningClaim returns the claim on the stream entry this consumer runs a job from
+// Transitions through it acknowledge the entry in the same script that stores the
+// job, and only if this consumer still owns it. Returns an error wrapping
+// ErrLeaseLost if the job has no entry (it was already finished).
+func (s *StreamQueue) runningClaim(ctx context.Context, jobID string) (runningClaim, error) {
+	streamKey, entryID, ok, err := s.entry(ctx, jobID)
+	if err != nil {
+		return runningClaim{}, err
+	}
+	if !ok {
+		return runningClaim{}, fmt.Errorf("%w: job %s is not running", ErrLeaseLost, jobID)
+	}
+	return streamEntryClaim(streamKey, s.entriesKey(), jobID, s.consumer, entryID), nil
+}
+
+// Complete marks a job as completed and acknowledges its stream entry
+// Returns an error wrapping ErrLeaseLost if this consumer no longer owns the entry,
+// e.g. because ReapExpiredLeases took it over.
+func (s *StreamQueue) Complete(ctx context.Context, jobID string) error {
+	return s.CompleteIdempotent(ctx, jobID, "")
+}
+
+// CompleteIdempotent is like Complete, but also records jobID as the job that
+// completed the idempotency key in the same script (see RedisQueue.CompleteIdempotent)
+func (s *StreamQueue) CompleteIdempotent(ctx context.Context, jobID, key string) error {
+	c, err := s.runningClaim(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return s.base.complete(ctx, jobID, key, c)
+}
+
+// Fail handles a failed job with exponential backoff retry or moves it to the
+// dead letter queue (see RedisQueue.Fail)
+func (s *StreamQueue) Fail(ctx context.Context, j *job.Job, errMsg string) error {
+	return s.FailAfter(ctx, j, errMsg, 0)
+}
+
+// FailAfter is like Fail, but a retry is scheduled after delay instead of the
+// exponential backoff
+func (s *StreamQueue) FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	c, err := s.runningClaim(ctx, j.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fail job: %w", err)
+	}
+	return s.base.fail(ctx, j, errMsg, delay, c)
+}
+
+// DeadLetter moves a job straight to the dead letter queue without retrying
+func (s *StreamQueue) DeadLetter(ctx context.Context, j *job.Job, errMsg string) error {
+	c, err := s.runningClaim(ctx, j.ID)
+	if err != nil {
+		return fmt.Errorf("failed to move job to dead letter queue: %w", err)
+	}
+	return s.base.deadLetter(ctx, j, errMsg, c)
+}
+
+// Expire discards a job whose deadline passed before it started
+func (s *StreamQueue) Expire(ctx context.Context, j *job.Job, reason string) error {
+	c, err := s.runningClaim(ctx, j.ID)
+	if err != nil {
+		return fmt.Errorf("failed to expire job: %w", err)
+	}
+	return s.base.expire(ctx, j, reason, c)
+}
+
+// Requeue returns a job this worker read to its stream without counting an attempt
+// Its entry keeps its place and is handed to the "requeued" pseudo-consumer, from
+// which Dequeue takes it before any new entry. It is a no-op if this worker no
+// longer owns the entry (it was already requeued, reaped or finished).
+func (s *StreamQueue) Requeue(ctx context.Context, j *job.Job) error {
+	streamKey, entryID, ok, err := s.entry(ctx, j.ID)
+	if err != nil || !ok {
+		return err
+	}
+
+	j.UpdateStatus(job.StatusPending)
+	jobData, err := s.base.marshalJob(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	keys := []string{streamKey, s.requeuedKey(), s.base.jobKey(j.ID)}
+	requeued, err := streamRequeueScript.Run(ctx, s.base.client, keys,
+		StreamGroup, s.consumer, entryID, j.ID, streamRequeuedConsumer, string(jobData)).Int()
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	if requeued == 0 {
+		return nil
+	}
+
+	log.Printf("Requeued job %s to %s (attempt not counted)", j.ID, streamKey)
+	s.base.hooks.Emit(ctx, events.TypeRequeue, j)
+	return nil
+}
+
+// MoveScheduledToReady appends jobs from the scheduled set to their priority
+// streams when ready. Like RedisQueue.MoveScheduledToReady, it should be called
+// periodically by the scheduler process. Returns the count of jobs moved.
+func (s *StreamQueue) MoveScheduledToReady(ctx context.Context) (int, error) {
+	q := s.base
+	jobIDs, err := q.client.ZRangeByScore(ctx, q.getScheduledSetKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", time.Now().Unix()),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scheduled jobs: %w", err)
+	}
+
+	if len(jobIDs) == 0 {
+		return 0, nil
+	}
+
+	jobKeys := make([]string, len(jobIDs))
+	for i, jobID := range jobIDs {
+		jobKeys[i] = q.jobKey(jobID)
+	}
+	jobDataList, err := q.client.MGet(ctx, jobKeys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get job data: %w", err)
+	}
+
+	// Move the whole batch in one script; each job is only moved by the caller that
+	// removes it from the scheduled set, so concurrent schedulers can't add it twice
+	keys := make([]string, 0, 2+2*len(jobIDs))
+	args := make([]interface{}, 0, 2*len(jobIDs))
+	keys = append(keys, q.getScheduledSetKey(), s.entriesKey())
+	jobs := make(map[string]*job.Job, len(jobIDs))
+	for i, jobID := range jobIDs {
+		jobData, ok := jobDataList[i].(string)
+		if !ok {
+			log.Printf("Warning: Job %s in scheduled set but data not found, removing it", jobID)
+			if err := q.client.ZRem(ctx, q.getScheduledSetKey(), jobID).Err(); err != nil {
+				return 0, fmt.Errorf("failed to remove scheduled job: %w", err)
+			}
+			continue
+		}
+
+		var j job.Job
+		if err := json.Unmarshal([]byte(jobData), &j); err != nil {
+			log.Printf("Error unmarshaling job %s: %v", jobID, err)
+			continue
+		}
+
+		// Clear scheduled time - the job is ready from now on
+		j.ScheduledFor = nil
+		if j.Status == job.StatusScheduled {
+			j.Status = job.StatusPending
+		}
+		j.UpdatedAt = time.Now()
+
+		updatedData, err := json.Marshal(&j)
+		if err != nil {
+			log.Printf("Error marshaling job %s: %v", jobID, err)
+			continue
+		}
+		keys = append(keys, q.jobKey(jobID), s.streamKey(j.Priority))
+		args = append(args, jobID, updatedData)
+		jobs[jobID] = &j
+	}
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	movedIDs, err := streamPromoteScript.Run(ctx, q.client, keys, args...).StringSlice()
+	if err != nil {
+		return 0, fmt.Errorf("failed to move scheduled jobs: %w", err)
+	}
+
+	for _, id := range movedIDs {
+		j := jobs[id]
+		log.Printf("Moved scheduled job %s with priority %s (attempt %d/%d)",
+			j.ID, j.Priority, j.Attempts, j.MaxRetries)
+	}
+	if len(movedIDs) > 0 {
+		log.Printf("Moved %d scheduled jobs to ready streams", len(movedIDs))
+	}
+
+	return len(movedIDs), nil
+}
+
+// Heartbeat extends a running job's lease by resetting its pending entry's idle time
+// Returns ErrLeaseLost if the entry was acknowledged or claimed by another consumer.
+func (s *StreamQueue) Heartbeat(ctx context.Context, jobID string) error {
+	streamKey, entryID, ok, err := s.entry(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if !ok {
+		return ErrLeaseLost
+	}
+
+	renewed, err := streamHeartbeatScript.Run(ctx, s.base.client, []string{streamKey},
+		StreamGroup, s.consumer, entryID).Int()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if renewed == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReapExpiredLeases fails jobs whose stream entry has been pending for longer
+// than the lease duration, i.e. whose worker stopped sending heartbeats
+//
+// Entries are taken over with XAUTOCLAIM, which only one caller can win, and
+// then failed like any other attempt (retried with backoff or dead-lettered).
+// Returns the number of jobs reaped.
+func (s *StreamQueue) ReapExpiredLeases(ctx context.Context) (int, error) {
+	reaped := 0
+	for _, priority := range []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow} {
+		streamKey := s.streamKey(priority)
+		start := "0-0"
+		for {
+			msgs, next, err := s.base.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   streamKey,
+				Group:    StreamGroup,
+				Consumer: s.consumer,
+				MinIdle:  s.base.leaseDuration,
+				Start:    start,
+				Count:    streamReapBatch,
+			}).Result()
+			if err != nil {
+				return reaped, fmt.Errorf("failed to claim expired entries: %w", err)
+			}
+
+			for _, msg := range msgs {
+				jobID, _ := msg.Values["job"].(string)
+				if requeued, err := s.base.client.SIsMember(ctx, s.requeuedKey(), jobID).Result(); err != nil {
+					return reaped, fmt.Errorf("failed to check requeued jobs: %w", err)
+				} else if requeued {
+					// Requeued jobs wait for a worker, they aren't orphaned - hand them back
+					err := s.base.client.XClaimJustID(ctx, &redis.XClaimArgs{
+						Stream:   streamKey,
+						Group:    StreamGroup,
+						Consumer: streamRequeuedConsumer,
+						Messages: []string{msg.ID},
+					}).Err()
+					if err != nil {
+						return reaped, fmt.Errorf("failed to return requeued entry: %w", err)
+					}
+					continue
+				}
+
+				j, err := s.base.GetJob(ctx, jobID)
+				if err != nil {
+					// Nothing left to retry - just drop the orphaned entry
+					log.Printf("Failed to load job %s with expired lease: %v", jobID, err)
+					s.ackEntry(ctx, streamKey, msg.ID, jobID)
+					continue
+				}
+
+				if err := s.FailAfter(ctx, j, "lease expired: worker stopped sending heartbeats", 0); err != nil {
+					return reaped, err
+				}
+				log.Printf("Reaped job %s after its lease expired", jobID)
+				reaped++
+			}
+
+			if next == "" || next == "0-0" {
+				break
+			}
+			start = next
+		}
+	}
+	return reaped, nil
+}
+
+// GetJob retrieves a job by ID from Redis
+func (s *StreamQueue) GetJob(ctx context.Context, jobID string) (*job.Job, error) {
+	return s.base.GetJob(ctx, jobID)
+}
+
+// Cancel removes a job waiting in its stream (including requeued jobs) or the
+// scheduled set and marks it cancelled. Jobs a worker is running can't be cancelled.
+func (s *StreamQueue) Cancel(ctx context.Context, jobID string) error {
+	j, err := s.base.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.base.client.ZRem(ctx, s.base.getScheduledSetKey(), jobID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if removed == 0 {
+		streamKey, entryID, ok, err := s.entry(ctx, jobID)
+		if err != nil {
+			return fmt.Errorf("failed to cancel job: %w", err)
+		}
+		if ok {
+			// Deleting the entry and checking it wasn't delivered is atomic, so a
+			// concurrent Dequeue can't also start the job
+			keys := []string{streamKey, s.entriesKey(), s.requeuedKey()}
+			removed, err = streamCancelScript.Run(ctx, s.base.client, keys,
+				StreamGroup, entryID, jobID, streamRequeuedConsumer).Int64()
+			if err != nil {
+				return fmt.Errorf("failed to cancel job: %w", err)
+			}
+		}
+	}
+	if removed == 0 {
+		return fmt.Errorf("%w: job %s is %s", ErrNotCancellable, jobID, j.Status)
+	}
+
+	return s.base.markCancelled(ctx, j)
+}
+
+// Stats returns the number of jobs waiting in the priority streams, the scheduled
+// set, the group's pending entries (processing) and the dead letter queue
+// Requeued jobs count as ready.
+func (s *StreamQueue) Stats(ctx context.Context) (Stats, error) {
+	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
+
+	pipe := s.base.client.Pipeline()
+	lengths := make([]*redis.IntCmd, len(priorities))
+	pending := make([]*redis.XPendingCmd, len(priorities))
+	for i, priority := range priorities {
+		lengths[i] = pipe.XLen(ctx, s.streamKey(priority))
+		pending[i] = pipe.XPending(ctx, s.streamKey(priority), StreamGroup)
+	}
+	scheduled := pipe.ZCard(ctx, s.base.getScheduledSetKey())
+	dead := pipe.LLen(ctx, s.base.deadLetterQueueKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Stats{}, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	stats := Stats{
+		Ready:     make(map[job.JobPriority]int64, len(priorities)),
+		Scheduled: scheduled.Val(),
+		Dead:      dead.Val(),
+	}
+	// Delivered entries stay in the stream until they are acknowledged; requeued
+	// ones are pending but wait for a worker
+	for i, priority := range priorities {
+		running := pending[i].Val().Count - pending[i].Val().Consumers[streamRequeuedConsumer]
+		stats.Ready[priority] = lengths[i].Val() - running
+		stats.Processing += running
+	}
+	return stats, nil
+}
+
+// updateQueueMetrics updates metrics with current queue depths (best-effort)
+func (s *StreamQueue) updateQueueMetrics(ctx context.Context) {
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		log.Printf("Failed to get queue depths: %v", err)
+		return
+	}
+	for priority, depth := range stats.Ready {
+		metrics.Default().RecordQueueDepth(priority, depth)
+	}
+
+	outstanding, err := s.base.QueuedJobs(ctx)
+	if err != nil {
+		log.Printf("Failed to count unfinished jobs in namespace %s: %v", s.base.namespace, err)
+		return
+	}
+	metrics.Default().RecordTenantOutstanding(s.base.namespace, outstanding)
+}
+
+// RefreshQueueMetrics records the current depths of the priority streams in metrics
+func (s *StreamQueue) RefreshQueueMetrics(ctx context.Context) {
+	s.updateQueueMetrics(ctx)
+}
+
+// SaveCheckpoint stores a running job's checkpoint (see RedisQueue.SaveCheckpoint)
+func (s *StreamQueue) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	return s.base.SaveCheckpoint(ctx, jobID, data)
+}
+
+// LoadCheckpoint returns a job's latest checkpoint (see RedisQueue.LoadCheckpoint)
+func (s *StreamQueue) LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error) {
+	return s.base.LoadCheckpoint(ctx, jobID)
+}
+
+// SetIdempotencyTTL sets how long completed idempotency keys are remembered (see RedisQueue.SetIdempotencyTTL)
+func (s *StreamQueue) SetIdempotencyTTL(ttl time.Duration) {
+	s.base.SetIdempotencyTTL(ttl)
+}
+
+// RecordCompletion records that jobID completed the work identified by key (see RedisQueue.RecordCompletion)
+func (s *StreamQueue) RecordCompletion(ctx context.Context, key, jobID string) (string, error) {
+	return s.base.RecordCompletion(ctx, key, jobID)
+}
+
+// CompletedBy returns the ID of the job that completed key, or "" if none has
+func (s *StreamQueue) CompletedBy(ctx context.Context, key string) (string, error) {
+	return s.base.CompletedBy(ctx, key)
+}
+
+// SetNamespace moves the queue into a namespace's isolated keyspace (see RedisQueue.SetNamespace)
+func (s *StreamQueue) SetNamespace(ns string) error {
+	if err := s.base.SetNamespace(ns); err != nil {
+		return err
+	}
+	return s.ensureGroups(context.Background())
+}
+
+// Namespace returns the namespace the queue's keys live in
+func (s *StreamQueue) Namespace() string {
+	return s.base.Namespace()
+}
+
+// SetQuota limits the jobs this queue's namespace can enqueue (see RedisQueue.SetQuota)
+func (s *StreamQueue) SetQuota(quota Quota) {
+	s.base.SetQuota(quota)
+}
+
+// QueuedJobs returns how many unfinished jobs the namespace holds
+func (s *StreamQueue) QueuedJobs(ctx context.Context) (int64, error) {
+	return s.base.QueuedJobs(ctx)
+}
+
+// SetLeaseDuration sets how long a read entry may stay idle before it is reaped
+func (s *StreamQueue) SetLeaseDuration(d time.Duration) {
+	s.base.SetLeaseDuration(d)
+}
+
+// LeaseDuration returns how long a read entry may stay idle before it is reaped
+func (s *StreamQueue) LeaseDuration() time.Duration {
+	return s.base.LeaseDuration()
+}
+
+// SetEncryptor enables encryption of job payloads at rest (see RedisQueue.SetEncryptor)
+func (s *StreamQueue) SetEncryptor(enc *serialization.Encryptor) {
+	s.base.SetEncryptor(enc)
+}
+
+// SetBlobStore enables offloading of large payloads (see RedisQueue.SetBlobStore)
+func (s *StreamQueue) SetBlobStore(store storage.BlobStore, threshold int) {
+	s.base.SetBlobStore(store, threshold)
+}
+
+// CleanupExpiredBlobs deletes offloaded payloads whose jobs have expired
+func (s *StreamQueue) CleanupExpiredBlobs(ctx context.Context) (int, error) {
+	return s.base.CleanupExpiredBlobs(ctx)
+}
+
+// Hooks returns the registry that receives this queue's job lifecycle events
+func (s *StreamQueue) Hooks() *events.Registry {
+	return s.base.Hooks()
+}
+
+// SetHooks replaces the lifecycle hook registry
+func (s *StreamQueue) SetHooks(hooks *events.Registry) {
+	s.base.SetHooks(hooks)
+}
+
+// EnableEventStream appends every job state transition to the <prefix>events stream
+func (s *StreamQueue) EnableEventStream(maxLen int64) {
+	s.base.EnableEventStream(maxLen)
+}
+
+// EventStreamKey returns the Redis key of the job event stream
+func (s *StreamQueue) EventStreamKey() string {
+	return s.base.EventStreamKey()
+}
+
+// DeadLetterQueueLength returns the number of jobs in the dead letter queue
+func (s *StreamQueue) DeadLetterQueueLength(ctx context.Context) (int64, error) {
+	return s.base.DeadLetterQueueLength(ctx)
+}
+
+// Close removes this process's consumer from the group and closes the Redis connection
+// The consumer is kept on streams where it still has pending entries, so they are
+// reaped once their lease expires.
+func (s *StreamQueue) Close() error {
+	keys := make([]string, 0, 3)
+	for _, priority := range []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow} {
+		keys = append(keys, s.streamKey(priority))
+	}
+	kept, err := streamDeleteConsumerScript.Run(context.Background(), s.base.client, keys, StreamGroup, s.consumer).Int()
+	if err != nil {
+		log.Printf("Failed to delete stream consumer %s: %v", s.consumer, err)
+	} else if kept > 0 {
+		log.Printf("Kept stream consumer %s on %d streams with unacknowledged jobs", s.consumer, kept)
+	}
+	return s.base.Close()
+}
@@ -0,0 +1,486 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func setupTestStream(t *testing.T) (*StreamQueue, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+
+	queue, err := NewStreamQueue("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	queue.SetDequeueTimeout(50 * time.Millisecond)
+	t.Cleanup(func() { queue.Close() })
+
+	return queue, mr
+}
+
+// newStreamConsumer opens a second consumer (another worker process) on the same Redis
+func newStreamConsumer(t *testing.T, mr *miniredis.Miniredis) *StreamQueue {
+	queue, err := NewStreamQueue("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	queue.SetDequeueTimeout(50 * time.Millisecond)
+	t.Cleanup(func() { queue.Close() })
+	return queue
+}
+
+func TestNewStreamQueue_CreatesGroups(t *testing.T) {
+	queue, mr := setupTestStream(t)
+
+	for _, priority := range allPriorities {
+		groups, err := queue.base.client.XInfoGroups(context.Background(), queue.streamKey(priority)).Result()
+		if err != nil {
+			t.Fatalf("expected stream %s to exist: %v", priority, err)
+		}
+		if len(groups) != 1 || groups[0].Name != StreamGroup {
+			t.Errorf("expected group %s on %s, got %+v", StreamGroup, priority, groups)
+		}
+	}
+	if !mr.Exists("bananas:stream:high") {
+		t.Error("expected streams under the bananas: prefix")
+	}
+}
+
+func TestStreamQueue_EnqueueAddsEntry(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityHigh)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, err := queue.base.client.XRange(ctx, queue.streamKey(job.PriorityHigh), "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one stream entry, got %v (err: %v)", entries, err)
+	}
+	if entries[0].Values["job"] != j.ID {
+		t.Errorf("expected entry for job %s, got %v", j.ID, entries[0].Values)
+	}
+
+	streamKey, entryID, ok, err := queue.entry(ctx, j.ID)
+	if err != nil || !ok || streamKey != queue.streamKey(job.PriorityHigh) || entryID != entries[0].ID {
+		t.Errorf("expected entry %s in %s to be recorded, got %s %s (ok: %v, err: %v)",
+			entries[0].ID, queue.streamKey(job.PriorityHigh), streamKey, entryID, ok, err)
+	}
+}
+
+func TestStreamQueue_CompleteAcknowledges(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	if _, err := queue.Dequeue(ctx, allPriorities); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	streamKey := queue.streamKey(job.PriorityNormal)
+	pending, _ := queue.base.client.XPending(ctx, streamKey, StreamGroup).Result()
+	if pending.Count != 1 || pending.Consumers[queue.Consumer()] != 1 {
+		t.Fatalf("expected the entry to be pending for %s, got %+v", queue.Consumer(), pending)
+	}
+
+	if err := queue.Complete(ctx, j.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	pending, _ = queue.base.client.XPending(ctx, streamKey, StreamGroup).Result()
+	if pending.Count != 0 {
+		t.Errorf("expected no pending entries, got %d", pending.Count)
+	}
+	if n, _ := queue.base.client.XLen(ctx, streamKey).Result(); n != 0 {
+		t.Errorf("expected the entry to be deleted, got stream length %d", n)
+	}
+	if n, _ := queue.base.client.HLen(ctx, queue.entriesKey()).Result(); n != 0 {
+		t.Errorf("expected no recorded entries, got %d", n)
+	}
+}
+
+func TestStreamQueue_ConsumersShareStream(t *testing.T) {
+	first, mr := setupTestStream(t)
+	second := newStreamConsumer(t, mr)
+	ctx := context.Background()
+
+	if first.Consumer() == second.Consumer() {
+		t.Fatalf("expected distinct consumer names, both are %s", first.Consumer())
+	}
+
+	a := job.NewJob("a", []byte(`{}`), job.PriorityNormal)
+	b := job.NewJob("b", []byte(`{}`), job.PriorityNormal)
+	first.Enqueue(ctx, a)
+	first.Enqueue(ctx, b)
+
+	got1, _ := first.Dequeue(ctx, allPriorities)
+	got2, _ := second.Dequeue(ctx, allPriorities)
+	if got1 == nil || got2 == nil || got1.ID == got2.ID {
+		t.Fatalf("expected each consumer to get a different job, got %v and %v", got1, got2)
+	}
+	if got, _ := second.Dequeue(ctx, allPriorities); got != nil {
+		t.Errorf("expected no more jobs, got %s", got.ID)
+	}
+}
+
+func TestStreamQueue_DequeueWakesOnEnqueue(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	queue.SetDequeueTimeout(2 * time.Second)
+	ctx := context.Background()
+
+	j := job.NewJob("late", []byte(`{}`), job.PriorityLow)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		queue.Enqueue(ctx, j)
+	}()
+
+	start := time.Now()
+	got, err := queue.Dequeue(ctx, allPriorities)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got == nil || got.ID != j.ID {
+		t.Fatalf("expected job %s, got %v", j.ID, got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Dequeue to return when the job arrived, took %v", elapsed)
+	}
+}
+
+func TestStreamQueue_RecreatesDeletedGroups(t *testing.T) {
+	queue, mr := setupTestStream(t)
+	ctx := context.Background()
+
+	mr.FlushAll()
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := queue.Dequeue(ctx, allPriorities)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got == nil || got.ID != j.ID {
+		t.Errorf("expected job %s once the groups were recreated, got %v", j.ID, got)
+	}
+}
+
+func TestStreamQueue_RejectsRoutingKeys(t *testing.T) {
+	queue, _ := setupTestStream(t)
+
+	j := job.NewJob("gpu_job", []byte(`{}`), job.PriorityNormal)
+	j.SetRoutingKey("gpu")
+	if err := queue.Enqueue(context.Background(), j); err == nil {
+		t.Error("expected routed job to be rejected")
+	}
+	if err := queue.Schedule(context.Background(), j, time.Now()); err == nil {
+		t.Error("expected routed job to be rejected when scheduled")
+	}
+}
+
+func TestStreamQueue_RejectsDependencies(t *testing.T) {
+	queue, _ := setupTestStream(t)
+
+	parent := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	j := job.NewJob("report", []byte(`{}`), job.PriorityNormal)
+	j.After(parent)
+	if err := queue.Enqueue(context.Background(), j); err == nil {
+		t.Error("expected job with dependencies to be rejected")
+	}
+	if err := queue.Schedule(context.Background(), j, time.Now()); err == nil {
+		t.Error("expected job with dependencies to be rejected when scheduled")
+	}
+	if _, ok := interface{}(queue).(interface {
+		EnqueueDAG(context.Context, []*job.Job) error
+	}); ok {
+		t.Error("expected stream queue not to accept DAG submissions")
+	}
+}
+
+func TestStreamQueue_CloseDeletesConsumer(t *testing.T) {
+	queue, mr := setupTestStream(t)
+	worker := newStreamConsumer(t, mr)
+	ctx := context.Background()
+
+	// One consumer finishes its job, the other still has one running when it closes
+	done := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	running := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, done)
+	queue.Enqueue(ctx, running)
+	queue.Dequeue(ctx, allPriorities)
+	queue.Complete(ctx, done.ID)
+	worker.Dequeue(ctx, allPriorities)
+
+	queue.Close()
+	worker.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	streamKey := queue.streamKey(job.PriorityNormal)
+	consumers, err := client.XInfoConsumers(ctx, streamKey, StreamGroup).Result()
+	if err != nil {
+		t.Fatalf("failed to list consumers: %v", err)
+	}
+	if len(consumers) != 1 || consumers[0].Name != worker.Consumer() {
+		t.Errorf("expected only the consumer with a pending job to be kept, got %+v", consumers)
+	}
+	if consumers, _ := client.XInfoConsumers(ctx, queue.streamKey(job.PriorityHigh), StreamGroup).Result(); len(consumers) != 0 {
+		t.Errorf("expected no consumers left on the high priority stream, got %+v", consumers)
+	}
+}
+
+func TestStreamQueue_CorruptedJobIsDeadLettered(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	queue.base.client.Set(ctx, queue.base.jobKey(j.ID), "not json", 0)
+
+	got, err := queue.Dequeue(ctx, allPriorities)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != nil {
+		t.Fatalf("expected corrupted job to be skipped, got %s", got.ID)
+	}
+
+	stats, _ := queue.Stats(ctx)
+	if stats.Dead != 1 || stats.Processing != 0 || stats.TotalReady() != 0 {
+		t.Errorf("expected the job in the dead letter queue only, got %+v", stats)
+	}
+}
+
+func TestStreamQueue_Quota(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	ctx := context.Background()
+	queue.SetQuota(Quota{MaxQueuedJobs: 1})
+
+	if err := queue.Enqueue(ctx, job.NewJob("first", []byte(`{}`), job.PriorityNormal)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err := queue.Enqueue(ctx, job.NewJob("second", []byte(`{}`), job.PriorityNormal))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if n, _ := queue.base.client.XLen(ctx, queue.streamKey(job.PriorityNormal)).Result(); n != 1 {
+		t.Errorf("expected only the first job in the stream, got %d entries", n)
+	}
+}
+
+func TestStreamQueue_HeartbeatAndReap(t *testing.T) {
+	worker, mr := setupTestStream(t)
+	scheduler := newStreamConsumer(t, mr)
+	ctx := context.Background()
+	worker.SetLeaseDuration(time.Minute)
+	scheduler.SetLeaseDuration(time.Minute)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	worker.Enqueue(ctx, j)
+	worker.Dequeue(ctx, allPriorities)
+
+	if err := worker.Heartbeat(ctx, j.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reaped, err := scheduler.ReapExpiredLeases(ctx); err != nil || reaped != 0 {
+		t.Fatalf("expected live entry to be left alone, got %d (err: %v)", reaped, err)
+	}
+
+	// The worker stops heartbeating
+	mr.SetTime(time.Now().Add(2 * time.Minute))
+	reaped, err := scheduler.ReapExpiredLeases(ctx)
+	if err != nil || reaped != 1 {
+		t.Fatalf("expected 1 job reaped, got %d (err: %v)", reaped, err)
+	}
+
+	got, _ := worker.GetJob(ctx, j.ID)
+	if got.Attempts != 1 || got.Status != job.StatusPending {
+		t.Errorf("expected job scheduled for retry after 1 attempt, got %s attempt %d", got.Status, got.Attempts)
+	}
+	if stats, _ := worker.Stats(ctx); stats.Scheduled != 1 || stats.Processing != 0 {
+		t.Errorf("expected the job in the scheduled set only, got %+v", stats)
+	}
+	if err := worker.Heartbeat(ctx, j.ID); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost after reaping, got %v", err)
+	}
+}
+
+func TestStreamQueue_RequeuedJobsAreNotReaped(t *testing.T) {
+	queue, mr := setupTestStream(t)
+	ctx := context.Background()
+	queue.SetLeaseDuration(time.Minute)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	dequeued, _ := queue.Dequeue(ctx, allPriorities)
+	if err := queue.Requeue(ctx, dequeued); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	mr.SetTime(time.Now().Add(2 * time.Minute))
+	if reaped, err := queue.ReapExpiredLeases(ctx); err != nil || reaped != 0 {
+		t.Fatalf("expected requeued job not to be reaped, got %d (err: %v)", reaped, err)
+	}
+
+	got, err := queue.Dequeue(ctx, allPriorities)
+	if err != nil || got == nil || got.ID != j.ID || got.Attempts != 0 {
+		t.Fatalf("expected requeued job without an attempt counted, got %v (err: %v)", got, err)
+	}
+	if err := queue.Heartbeat(ctx, j.ID); err != nil {
+		t.Errorf("expected the job to be leased again, got %v", err)
+	}
+}
+
+func TestStreamQueue_MoveScheduledToReady(t *testing.T) {
+	queue, mr := setupTestStream(t)
+	other := newStreamConsumer(t, mr)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityHigh)
+	if err := queue.Schedule(ctx, j, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to schedule job: %v", err)
+	}
+	// Stale entry whose job data is gone
+	queue.base.client.ZAdd(ctx, queue.base.getScheduledSetKey(), redis.Z{Score: 0, Member: "missing"})
+
+	if moved, err := queue.MoveScheduledToReady(ctx); err != nil || moved != 1 {
+		t.Fatalf("expected 1 job moved, got %d (err: %v)", moved, err)
+	}
+	if moved, err := other.MoveScheduledToReady(ctx); err != nil || moved != 0 {
+		t.Fatalf("expected the job to be moved only once, got %d (err: %v)", moved, err)
+	}
+	if stats, _ := queue.Stats(ctx); stats.Scheduled != 0 || stats.Ready[job.PriorityHigh] != 1 {
+		t.Errorf("expected one ready job and an empty scheduled set, got %+v", stats)
+	}
+
+	got, err := queue.Dequeue(ctx, allPriorities)
+	if err != nil || got == nil || got.ID != j.ID {
+		t.Fatalf("expected the scheduled job, got %v (err: %v)", got, err)
+	}
+	if got.ScheduledFor != nil {
+		t.Errorf("expected ScheduledFor to be cleared, got %v", got.ScheduledFor)
+	}
+	if err := queue.Complete(ctx, j.ID); err != nil {
+		t.Errorf("expected the moved job's entry to be recorded, got %v", err)
+	}
+}
+
+func TestStreamQueue_CancelRequeued(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	dequeued, _ := queue.Dequeue(ctx, allPriorities)
+	queue.Requeue(ctx, dequeued)
+
+	if err := queue.Cancel(ctx, j.ID); err != nil {
+		t.Fatalf("expected requeued job to be cancellable, got %v", err)
+	}
+	if got, _ := queue.Dequeue(ctx, allPriorities); got != nil {
+		t.Errorf("expected no job after cancelling, got %s", got.ID)
+	}
+	if stats, _ := queue.Stats(ctx); stats.TotalReady() != 0 || stats.Processing != 0 {
+		t.Errorf("expected empty streams, got %+v", stats)
+	}
+}
+
+func TestStreamQueue_LateCompleteKeepsRetry(t *testing.T) {
+	worker, mr := setupTestStream(t)
+	scheduler := newStreamConsumer(t, mr)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	worker.Enqueue(ctx, j)
+	worker.Dequeue(ctx, allPriorities)
+
+	// Reaped and moved back to the stream while the worker was partitioned
+	mr.SetTime(time.Now().Add(2 * DefaultLeaseDuration))
+	scheduler.ReapExpiredLeases(ctx)
+	scheduler.base.client.ZAdd(ctx, scheduler.base.getScheduledSetKey(), redis.Z{Score: 0, Member: j.ID})
+	if moved, err := scheduler.MoveScheduledToReady(ctx); err != nil || moved != 1 {
+		t.Fatalf("expected the retry to be moved to its stream, got %d (err: %v)", moved, err)
+	}
+
+	// The old worker's acknowledgement must not delete the retry's entry
+	if _, err := worker.ack(ctx, j.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, _ := scheduler.Dequeue(ctx, allPriorities)
+	if got == nil || got.ID != j.ID {
+		t.Errorf("expected the retry to be delivered, got %v", got)
+	}
+}
+
+func TestNewRedisBroker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	url := "redis://" + mr.Addr()
+
+	for backend, want := range map[string]string{"": "*queue.RedisQueue", BackendList: "*queue.RedisQueue", BackendStreams: "*queue.StreamQueue"} {
+		b, err := NewRedisBroker(url, backend)
+		if err != nil {
+			t.Fatalf("NewRedisBroker(%q) error = %v", backend, err)
+		}
+		if got := fmt.Sprintf("%T", b); got != want {
+			t.Errorf("NewRedisBroker(%q) = %s, want %s", backend, got, want)
+		}
+		b.Close()
+	}
+
+	if _, err := NewRedisBroker(url, "kafka"); err == nil {
+		t.Error("expected unknown backend to be rejected")
+	}
+}
+
+func TestStreamQueue_PutBack(t *testing.T) {
+	queue, _ := setupTestStream(t)
+	ctx := context.Background()
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	streamKey := queue.streamKey(job.PriorityNormal)
+	streams, err := queue.base.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    StreamGroup,
+		Consumer: queue.consumer,
+		Streams:  []string{streamKey, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	entryID := streams[0].Messages[0].ID
+
+	if err := queue.putBack(ctx, streamKey, entryID, j.ID); err != nil {
+		t.Fatalf("failed to put back: %v", err)
+	}
+	// Putting back an entry it no longer owns does nothing
+	if err := queue.putBack(ctx, streamKey, entryID, j.ID); err != nil {
+		t.Fatalf("failed to put back again: %v", err)
+	}
+
+	entries, _ := queue.base.client.XRange(ctx, streamKey, "-", "+").Result()
+	if len(entries) != 1 || entries[0].ID == entryID || entries[0].Values["job"] != j.ID {
+		t.Fatalf("expected the job re-added once under a new entry, got %+v", entries)
+	}
+	if _, newEntryID, ok, _ := queue.entry(ctx, j.ID); !ok || newEntryID != entries[0].ID {
+		t.Errorf("expected the job's entry to be %s, got %s", entries[0].ID, newEntryID)
+	}
+	if pending, _ := queue.base.client.XPending(ctx, streamKey, StreamGroup).Result(); pending.Count != 0 {
+		t.Errorf("expected no pending entries, got %d", pending.Count)
+	}
+	if got, _ := queue.Dequeue(ctx, allPriorities); got == nil || got.ID != j.ID {
+		t.Errorf("expected the job to be dequeued again, got %v", got)
+	}
+}
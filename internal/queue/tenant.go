@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrQuotaExceeded is returned by Enqueue when the namespace is over one of its quotas
+// The wrapped message says which limit was hit.
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// Quota limits how much work a namespace (tenant) can enqueue
+// Zero values disable the corresponding limit.
+type Quota struct {
+	// MaxQueuedJobs caps the namespace's unfinished jobs: queued, scheduled for retry or running
+	MaxQueuedJobs int64
+	// EnqueueRate caps how many jobs the namespace can enqueue per RateWindow
+	EnqueueRate int64
+	// RateWindow is the fixed window EnqueueRate is counted over (default: 1 second, at least 1ms)
+	RateWindow time.Duration
+}
+
+// enabled reports whether any limit is set
+func (qt Quota) enabled() bool {
+	return qt.MaxQueuedJobs > 0 || qt.EnqueueRate > 0
+}
+
+// enqueueScript enqueues a job unless the namespace is over a quota
+// KEYS: job key, ready queue (or scheduled set), outstanding set, rate window counter,
+// stream entries hash (stream queues only)
+// ARGV: job data, job ID, max queued jobs, enqueue rate, rate window (ms),
+// scheduled time score ("" for ready jobs), "stream" when the ready queue is a stream
+// Returns 0 when enqueued, 1 when over MaxQueuedJobs and 2 when over EnqueueRate.
+var enqueueScript = redis.NewScript(`
+local maxQueued = tonumber(ARGV[3])
+if maxQueued > 0 and redis.call("SCARD", KEYS[3]) >= maxQueued then
+	return 1
+end
+
+local rate = tonumber(ARGV[4])
+if rate > 0 then
+	if tonumber(redis.call("GET", KEYS[4]) or "0") >= rate then
+		return 2
+	end
+	if redis.call("INCR", KEYS[4]) == 1 then
+		redis.call("PEXPIRE", KEYS[4], ARGV[5])
+	end
+end
+
+redis.call("SET", KEYS[1], ARGV[1])
+if ARGV[6] ~= "" then
+	redis.call("ZADD", KEYS[2], ARGV[6], ARGV[2])
+elseif ARGV[7] == "stream" then
+	local entry = redis.call("XADD", KEYS[2], "*", "job", ARGV[2])
+	redis.call("HSET", KEYS[5], ARGV[2], KEYS[2] .. " " .. entry)
+else
+	redis.call("LPUSH", KEYS[2], ARGV[2])
+end
+redis.call("SADD", KEYS[3], ARGV[2])
+return 0
+`)
+
+// SetNamespace moves the queue into a namespace's isolated keyspace
+//
+// All keys the queue uses are prefixed with "<namespace>:" instead of "bananas:",
+// so several products can share one Redis without seeing each other's jobs. The
+// default namespace is "bananas". Call it before the queue is used (and before
+// EnableEventStream); producers, workers and schedulers of one product must use
+// the same namespace.
+func (q *RedisQueue) SetNamespace(ns string) error {
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	q.useNamespace(ns)
+	return nil
+}
+
+// Namespace returns the namespace the queue's keys live in
+func (q *RedisQueue) Namespace() string {
+	return q.namespace
+}
+
+// SetQuota limits the jobs this queue's namespace can enqueue
+//
+// Limits are enforced atomically in Redis at Enqueue, so they hold across every
+// producer sharing the namespace as long as each of them sets the same quota.
+// Enqueue returns an error wrapping ErrQuotaExceeded when a limit is hit.
+func (q *RedisQueue) SetQuota(quota Quota) {
+	if quota.RateWindow < time.Millisecond {
+		quota.RateWindow = time.Second
+	}
+	q.quota = quota
+}
+
+// Quota returns the namespace's enqueue limits
+func (q *RedisQueue) Quota() Quota {
+	return q.quota
+}
+
+// QueuedJobs returns how many unfinished jobs the namespace holds, as counted by MaxQueuedJobs
+func (q *RedisQueue) QueuedJobs(ctx context.Context) (int64, error) {
+	n, err := q.client.SCard(ctx, q.outstandingKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count queued jobs: %w", err)
+	}
+	return n, nil
+}
+
+// rateWindowKey returns the counter key for the current enqueue rate window
+func (q *RedisQueue) rateWindowKey(now time.Time) string {
+	window := now.UnixMilli() / q.quota.RateWindow.Milliseconds()
+	return q.keyPrefix + "quota:rate:" + strconv.FormatInt(window, 10)
+}
+
+// enqueueWithQuota stores and pushes (or schedules) a job if the namespace is within its quota
+func (q *RedisQueue) enqueueWithQuota(ctx context.Context, j *job.Job, jobData []byte, queueKey string, at *time.Time) error {
+	var score string
+	if at != nil {
+		score = strconv.FormatInt(at.Unix(), 10)
+	}
+
+	keys := []string{q.jobKey(j.ID), queueKey, q.outstandingKey, q.rateWindowKey(time.Now())}
+	return q.runEnqueueScript(ctx, keys, jobData, j.ID, score, "list")
+}
+
+// runEnqueueScript runs enqueueScript with the namespace's quota
+// mode is "stream" when the ready queue is a stream (see StreamQueue), "list" otherwise.
+func (q *RedisQueue) runEnqueueScript(ctx context.Context, keys []string, jobData []byte, jobID, score, mode string) error {
+	result, err := enqueueScript.Run(ctx, q.client, keys,
+		jobData, jobID, q.quota.MaxQueuedJobs, q.quota.EnqueueRate, q.quota.RateWindow.Milliseconds(), score, mode).Int()
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	switch result {
+	case 1:
+		metrics.Default().RecordTenantRejected(q.namespace)
+		return fmt.Errorf("%w: namespace %s has %d unfinished jobs", ErrQuotaExceeded, q.namespace, q.quota.MaxQueuedJobs)
+	case 2:
+		metrics.Default().RecordTenantRejected(q.namespace)
+		return fmt.Errorf("%w: namespace %s enqueued %d jobs in %v", ErrQuotaExceeded, q.namespace, q.quota.EnqueueRate, q.quota.RateWindow)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+)
+
+func TestSetNamespace_IsolatesKeyspaces(t *testing.T) {
+	acme, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer acme.Close()
+
+	other, err := NewRedisQueue("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer other.Close()
+
+	if err := acme.SetNamespace("acme"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if acme.Namespace() != "acme" || other.Namespace() != "bananas" {
+		t.Fatalf("expected namespaces acme and bananas, got %s and %s", acme.Namespace(), other.Namespace())
+	}
+
+	ctx := context.Background()
+	j := job.NewJob("send_email", []byte(`{}`), job.PriorityHigh)
+	if err := acme.Enqueue(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !mr.Exists("acme:job:" + j.ID) {
+		t.Error("expected job data under the acme namespace")
+	}
+	if n, _ := acme.client.LLen(ctx, "acme:queue:high").Result(); n != 1 {
+		t.Errorf("expected 1 job in acme high queue, got %d", n)
+	}
+	if n, _ := acme.client.LLen(ctx, "bananas:queue:high").Result(); n != 0 {
+		t.Errorf("expected default namespace queue to be empty, got %d", n)
+	}
+	if _, err := other.GetJob(ctx, j.ID); err == nil {
+		t.Error("expected job to be invisible from another namespace")
+	}
+}
+
+func TestSetNamespace_Invalid(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	if err := queue.SetNamespace("acme:prod"); err == nil {
+		t.Fatal("expected error for namespace containing ':'")
+	}
+	if queue.Namespace() != "bananas" {
+		t.Errorf("expected namespace unchanged, got %s", queue.Namespace())
+	}
+}
+
+func TestQuota_MaxQueuedJobs(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetNamespace("acme")
+	queue.SetQuota(Quota{MaxQueuedJobs: 2})
+	metrics.ResetMetrics()
+
+	first := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	for _, j := range []*job.Job{first, job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)} {
+		if err := queue.Enqueue(ctx, j); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	rejected := job.NewJob("send_email", []byte(`{}`), job.PriorityNormal)
+	err := queue.Enqueue(ctx, rejected)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if mr.Exists("acme:job:" + rejected.ID) {
+		t.Error("expected rejected job not to be stored")
+	}
+
+	// Running jobs still count; finishing one frees a slot
+	dequeued, err := queue.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	if err != nil || dequeued == nil {
+		t.Fatalf("expected a job, got %v (%v)", dequeued, err)
+	}
+	if err := queue.Enqueue(ctx, rejected); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected running job to count against the quota, got %v", err)
+	}
+	if err := queue.Complete(ctx, dequeued.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := queue.Enqueue(ctx, rejected); err != nil {
+		t.Fatalf("expected enqueue after completion, got %v", err)
+	}
+
+	if n, _ := queue.QueuedJobs(ctx); n != 2 {
+		t.Errorf("expected 2 queued jobs, got %d", n)
+	}
+
+	tenant := metrics.GetMetrics().Tenants["acme"]
+	if tenant.Enqueued != 3 || tenant.Rejected != 2 || tenant.Completed != 1 {
+		t.Errorf("expected 3 enqueued, 2 rejected, 1 completed, got %+v", tenant)
+	}
+}
+
+func TestQuota_EnqueueRate(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.SetQuota(Quota{EnqueueRate: 3, RateWindow: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		if err := queue.Enqueue(ctx, job.NewJob("send_email", []byte(`{}`), job.PriorityLow)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := queue.Enqueue(ctx, job.NewJob("send_email", []byte(`{}`), job.PriorityLow)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	// The window counter expires with the window
+	key := queue.rateWindowKey(time.Now())
+	if ttl := mr.TTL(key); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected window counter to expire within the window, got %v", ttl)
+	}
+	mr.Del(key)
+	if err := queue.Enqueue(ctx, job.NewJob("send_email", []byte(`{}`), job.PriorityLow)); err != nil {
+		t.Fatalf("expected enqueue in a new window, got %v", err)
+	}
+}
+
+func TestQueuedJobs_DeadLetteredJobsAreReleased(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer queue.Close()
+
+	ctx := context.Background()
+	j := job.NewJob("send_email", []byte(`{}`), job.PriorityHigh)
+	queue.Enqueue(ctx, j)
+
+	dequeued, _ := queue.Dequeue(ctx, []job.JobPriority{job.PriorityHigh})
+	if err := queue.DeadLetter(ctx, dequeued, "bad payload"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n, _ := queue.QueuedJobs(ctx); n != 0 {
+		t.Errorf("expected dead-lettered job to be released, got %d queued", n)
+	}
+}
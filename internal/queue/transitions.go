@@ -0,0 +1,332 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/redis/go-redis/v9"
+)
+
+// Job state transitions run as Lua scripts that first claim the job: they remove
+// it from the place its current state keeps it (the processing queue, a stream's
+// pending entries, the scheduled set, the waiting set or a ready queue) and only apply the
+// transition if that succeeded. Concurrent workers, reapers and schedulers
+// therefore can't both move the same job, so jobs are neither duplicated nor lost.
+//
+// Transitions of running jobs also only apply if the job's stored data is still
+// what they were computed from (see updateRunning), so they never overwrite a
+// concurrent change with stale data.
+
+// maxUpdateAttempts bounds how often updateRunning rereads a job whose data keeps changing
+const maxUpdateAttempts = 5
+
+// errJobChanged is returned by transition when the job's stored data changed after it was read
+var errJobChanged = errors.New("job data changed")
+
+// claimLua removes a running job's processing record, reporting whether it was there
+// KEYS[1]: processing queue (list and lease mode) or stream (stream mode)
+// KEYS[2]: stream entries hash (stream mode) or leases (lease mode)
+// ARGV[1]: "list", "lease" or "stream"
+// ARGV[2]: job ID
+// ARGV[3]: lease deadline in unix milliseconds (lease mode)
+// ARGV[3], ARGV[4], ARGV[5]: consumer group, consumer and entry ID (stream mode)
+// In lease mode the job is only claimed if its lease expired by the deadline, and
+// in stream mode the entry is only acknowledged if the consumer still owns it.
+// Scripts using it take their own keys from KEYS[3] and arguments from ARGV[6].
+const claimLua = `
+local function claim()
+	if ARGV[1] == "list" then
+		return redis.call("LREM", KEYS[1], 1, ARGV[2]) == 1
+	end
+	if ARGV[1] == "lease" then
+		local expiry = redis.call("ZSCORE", KEYS[2], ARGV[2])
+		if not expiry or tonumber(expiry) > tonumber(ARGV[3]) then
+			return false
+		end
+		redis.call("ZREM", KEYS[2], ARGV[2])
+		return redis.call("LREM", KEYS[1], 1, ARGV[2]) == 1
+	end
+	local pending = redis.call("XPENDING", KEYS[1], ARGV[3], ARGV[5], ARGV[5], 1)
+	if #pending == 0 or pending[1][2] ~= ARGV[4] then
+		return false
+	end
+	redis.call("XACK", KEYS[1], ARGV[3], ARGV[5])
+	redis.call("XDEL", KEYS[1], ARGV[5])
+	redis.call("HDEL", KEYS[2], ARGV[2])
+	return true
+end
+`
+
+// unchangedLua checks that the job data in KEYS[3] is still what the caller read
+// (the last argument), before claim() changes anything
+const unchangedLua = `
+local function unchanged()
+	return redis.call("GET", KEYS[3]) == ARGV[#ARGV]
+end
+`
+
+// setJobLua stores job data, with a TTL in milliseconds unless it is 0
+const setJobLua = `
+local function setJob(key, data, ttl)
+	if tonumber(ttl) > 0 then
+		redis.call("SET", key, data, "PX", ttl)
+	else
+		redis.call("SET", key, data)
+	end
+end
+`
+
+// finishScript stores a finished (completed or expired) job
+// With a completion record TTL, the job is also recorded as the one that completed
+// an idempotency key, unless another job already did.
+// KEYS: claim keys, job, leases, checkpoint, outstanding set, completion record
+// ARGV: claim args, job data, TTL (ms), completion record TTL (ms, 0 for none), job data read
+var finishScript = redis.NewScript(claimLua + unchangedLua + setJobLua + `
+if not unchanged() then
+	return -1
+end
+if not claim() then
+	return 0
+end
+setJob(KEYS[3], ARGV[6], ARGV[7])
+redis.call("ZREM", KEYS[4], ARGV[2])
+redis.call("DEL", KEYS[5])
+redis.call("SREM", KEYS[6], ARGV[2])
+if tonumber(ARGV[8]) > 0 then
+	redis.call("SET", KEYS[7], ARGV[2], "NX", "PX", ARGV[8])
+end
+return 1
+`)
+
+// retryScript schedules a failed job's next attempt
+// The checkpoint is kept so the next attempt can resume from it.
+// KEYS: claim keys, job, scheduled set, leases
+// ARGV: claim args, job data, retry time (unix seconds), job data read
+var retryScript = redis.NewScript(claimLua + unchangedLua + `
+if not unchanged() then
+	return -1
+end
+if not claim() then
+	return 0
+end
+redis.call("SET", KEYS[3], ARGV[6])
+redis.call("ZADD", KEYS[4], ARGV[7], ARGV[2])
+redis.call("ZREM", KEYS[5], ARGV[2])
+return 1
+`)
+
+// deadLetterScript moves a failed job to the dead letter queue
+// KEYS: claim keys, job, dead letter queue, leases, outstanding set, checkpoint, expiring blobs
+// ARGV: claim args, job data, TTL (ms), offloaded payload ref (may be empty), payload expiry (unix seconds), job data read
+var deadLetterScript = redis.NewScript(claimLua + unchangedLua + setJobLua + `
+if not unchanged() then
+	return -1
+end
+if not claim() then
+	return 0
+end
+setJob(KEYS[3], ARGV[6], ARGV[7])
+if tonumber(ARGV[7]) > 0 then
+	redis.call("PEXPIRE", KEYS[7], ARGV[7])
+end
+if ARGV[8] ~= "" then
+	redis.call("ZADD", KEYS[8], ARGV[9], ARGV[8])
+end
+redis.call("LPUSH", KEYS[4], ARGV[2])
+redis.call("ZREM", KEYS[5], ARGV[2])
+redis.call("SREM", KEYS[6], ARGV[2])
+return 1
+`)
+
+// requeueScript returns a running job to the end of its ready queue that Dequeue pops from
+// KEYS: claim keys, job, ready queue, leases
+// ARGV: claim args, job data
+var requeueScript = redis.NewScript(claimLua + `
+if not claim() then
+	return 0
+end
+redis.call("SET", KEYS[3], ARGV[6])
+redis.call("RPUSH", KEYS[4], ARGV[2])
+redis.call("ZREM", KEYS[5], ARGV[2])
+return 1
+`)
+
+// promoteScript moves due jobs from the scheduled set to their ready queues
+// Jobs another caller already removed from the scheduled set (moved or cancelled) are skipped.
+// KEYS: scheduled set, then job and ready queue per job
+// ARGV: job ID and job data per job
+// Returns the IDs of the jobs it moved.
+var promoteScript = redis.NewScript(`
+local moved = {}
+for i = 1, #ARGV, 2 do
+	local id = ARGV[i]
+	if redis.call("ZREM", KEYS[1], id) == 1 then
+		redis.call("SET", KEYS[i + 1], ARGV[i + 1])
+		redis.call("LPUSH", KEYS[i + 2], id)
+		moved[#moved + 1] = id
+	end
+end
+return moved
+`)
+
+// cancelScript removes a job that hasn't started from the scheduled set, the set of
+// jobs waiting for dependencies or one of its ready queues and stores it as cancelled
+// KEYS: job, scheduled set, checkpoint, outstanding set, waiting set, dependencies, then candidate ready queues
+// ARGV: job ID, job data, TTL (ms)
+// Returns 0 if the job wasn't waiting (it is running or finished).
+var cancelScript = redis.NewScript(setJobLua + `
+local removed = redis.call("ZREM", KEYS[2], ARGV[1])
+if removed == 0 then
+	removed = redis.call("SREM", KEYS[5], ARGV[1])
+end
+for i = 7, #KEYS do
+	if removed > 0 then
+		break
+	end
+	removed = redis.call("LREM", KEYS[i], 1, ARGV[1])
+end
+if removed == 0 then
+	return 0
+end
+setJob(KEYS[1], ARGV[2], ARGV[3])
+redis.call("DEL", KEYS[3])
+redis.call("DEL", KEYS[6])
+redis.call("SREM", KEYS[4], ARGV[1])
+return 1
+`)
+
+// releaseScript resolves one of a waiting job's dependencies and moves the job to its
+// ready queue once none are left
+// Only the caller that removes the job from the waiting set releases it.
+// KEYS: dependencies, waiting set, job, ready queue, outstanding set
+// ARGV: job ID, finished parent ID, job data
+// Returns 1 if the job was released.
+var releaseScript = redis.NewScript(`
+redis.call("SREM", KEYS[1], ARGV[2])
+if redis.call("SCARD", KEYS[1]) > 0 then
+	return 0
+end
+if redis.call("SREM", KEYS[2], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("SET", KEYS[3], ARGV[3])
+redis.call("LPUSH", KEYS[4], ARGV[1])
+redis.call("SADD", KEYS[5], ARGV[1])
+return 1
+`)
+
+// cancelWaitingScript cancels a job waiting for dependencies
+// KEYS: waiting set, job, dependencies
+// ARGV: job ID, job data, TTL (ms)
+// Returns 0 if the job was no longer waiting.
+var cancelWaitingScript = redis.NewScript(setJobLua + `
+if redis.call("SREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+setJob(KEYS[2], ARGV[2], ARGV[3])
+redis.call("DEL", KEYS[3])
+return 1
+`)
+
+// runningClaim identifies the record a running job must still hold for a transition to apply
+type runningClaim struct {
+	keys []string
+	args []interface{}
+}
+
+// processingClaim claims a job through the processing queue
+func (q *RedisQueue) processingClaim(jobID string) runningClaim {
+	return runningClaim{
+		keys: []string{q.processingQueueKey(), q.processingQueueKey()},
+		args: []interface{}{"list", jobID, "", "", ""},
+	}
+}
+
+// expiredLeaseClaim claims a job through the processing queue, but only while its
+// lease is still expired at deadline (unix ms), so a late heartbeat keeps the job
+func (q *RedisQueue) expiredLeaseClaim(jobID string, deadline int64) runningClaim {
+	return runningClaim{
+		keys: []string{q.processingQueueKey(), q.leasesKey},
+		args: []interface{}{"lease", jobID, deadline, "", ""},
+	}
+}
+
+// streamEntryClaim claims a job through the stream entry a consumer owns
+func streamEntryClaim(streamKey, entriesKey, jobID, consumer, entryID string) runningClaim {
+	return runningClaim{
+		keys: []string{streamKey, entriesKey},
+		args: []interface{}{"stream", jobID, StreamGroup, consumer, entryID},
+	}
+}
+
+// transition runs a claiming script, returning ErrLeaseLost if the job wasn't running
+func (q *RedisQueue) transition(ctx context.Context, script *redis.Script, c runningClaim, jobID string, keys []string, args ...interface{}) error {
+	allKeys := append(append([]string{}, c.keys...), keys...)
+	allArgs := append(append([]interface{}{}, c.args...), args...)
+	applied, err := script.Run(ctx, q.client, allKeys, allArgs...).Int()
+	if err != nil {
+		return err
+	}
+	switch applied {
+	case 0:
+		return fmt.Errorf("%w: job %s is not running", ErrLeaseLost, jobID)
+	case -1:
+		return errJobChanged
+	}
+	return nil
+}
+
+// storedTransition is the claiming script that stores a running job's updated data
+type storedTransition struct {
+	script *redis.Script
+	keys   []string      // after the claim keys, starting with the job
+	args   []interface{} // after the job data
+}
+
+// updateRunning applies update to a copy of a running job's stored data and runs
+// the transition it returns with the result
+//
+// The script only applies if the stored data is still what was read; otherwise the
+// job is read again and update reapplied. Returns the updated job once stored.
+func (q *RedisQueue) updateRunning(ctx context.Context, c runningClaim, jobID string, update func(j *job.Job) storedTransition) (*job.Job, error) {
+	for attempt := 1; ; attempt++ {
+		stored, err := q.client.Get(ctx, q.jobKey(jobID)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job data: %w", err)
+		}
+
+		var j job.Job
+		if err := json.Unmarshal([]byte(stored), &j); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+		}
+		t := update(&j)
+
+		// The payload is kept as stored (encrypted or offloaded)
+		data, err := json.Marshal(&j)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal updated job: %w", err)
+		}
+
+		args := append(append([]interface{}{data}, t.args...), stored)
+		err = q.transition(ctx, t.script, c, jobID, t.keys, args...)
+		if errors.Is(err, errJobChanged) && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &j, nil
+	}
+}
+
+// copyState copies the fields a transition changes from a stored job to the caller's
+func copyState(dst, src *job.Job) {
+	dst.Status = src.Status
+	dst.Attempts = src.Attempts
+	dst.Error = src.Error
+	dst.ScheduledFor = src.ScheduledFor
+	dst.UpdatedAt = src.UpdatedAt
+}
@@ -0,0 +1,389 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/muaviaUsmani/bananas/internal/events"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// newQueueOn opens another queue on the same Redis, like another worker or scheduler process
+func newQueueOn(t *testing.T, mr *miniredis.Miniredis) *RedisQueue {
+	q, err := NewRedisQueue("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+// dequeueNormal dequeues a job enqueued with normal priority
+func dequeueNormal(t *testing.T, q *RedisQueue) *job.Job {
+	t.Helper()
+	j, err := q.Dequeue(context.Background(), []job.JobPriority{job.PriorityNormal})
+	if err != nil || j == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+	return j
+}
+
+func TestComplete_NotRunning(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	if err := queue.Enqueue(ctx, j); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	// A job that was never dequeued can't be completed
+	if err := queue.Complete(ctx, j.ID); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	stored, _ := queue.GetJob(ctx, j.ID)
+	if stored.Status != job.StatusPending {
+		t.Errorf("expected job to stay pending, got %s", stored.Status)
+	}
+	if n, _ := mr.List(queue.queueKey(job.PriorityNormal)); len(n) != 1 {
+		t.Errorf("expected job to stay in its ready queue, got %v", n)
+	}
+}
+
+func TestComplete_Twice(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	dequeueNormal(t, queue)
+
+	var successes int
+	queue.Hooks().OnSuccess(func(ctx context.Context, e events.Event) { successes++ })
+
+	if err := queue.Complete(ctx, j.ID); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+	if err := queue.Complete(ctx, j.ID); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost on second Complete, got %v", err)
+	}
+	if successes != 1 {
+		t.Errorf("expected 1 success event, got %d", successes)
+	}
+}
+
+func TestFail_AfterReapIsRejected(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 5
+	queue.Enqueue(ctx, j)
+	dequeued := dequeueNormal(t, queue)
+
+	// The lease expires and the reaper retries the job
+	mr.ZAdd(queue.leasesKey, 0, j.ID)
+	if reaped, err := queue.ReapExpiredLeases(ctx); err != nil || reaped != 1 {
+		t.Fatalf("expected 1 reaped job, got %d (%v)", reaped, err)
+	}
+
+	// The original worker's late result must not count a second attempt
+	if err := queue.Fail(ctx, dequeued, "late"); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	stored, _ := queue.GetJob(ctx, j.ID)
+	if stored.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", stored.Attempts)
+	}
+	if members, _ := mr.ZMembers(queue.getScheduledSetKey()); len(members) != 1 {
+		t.Errorf("expected the job scheduled once, got %v", members)
+	}
+}
+
+func TestMoveScheduledToReady_ConcurrentSchedulers(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	const jobs = 50
+	past := time.Now().Add(-time.Minute)
+	for i := 0; i < jobs; i++ {
+		if err := queue.Schedule(ctx, job.NewJob("test", []byte(`{}`), job.PriorityNormal), past); err != nil {
+			t.Fatalf("failed to schedule: %v", err)
+		}
+	}
+
+	schedulers := make([]*RedisQueue, 5)
+	for i := range schedulers {
+		schedulers[i] = newQueueOn(t, mr)
+	}
+
+	var wg sync.WaitGroup
+	moved := make([]int, len(schedulers))
+	for i, s := range schedulers {
+		wg.Add(1)
+		go func(i int, s *RedisQueue) {
+			defer wg.Done()
+			n, err := s.MoveScheduledToReady(ctx)
+			if err != nil {
+				t.Errorf("scheduler %d: %v", i, err)
+			}
+			moved[i] = n
+		}(i, s)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range moved {
+		total += n
+	}
+	if total != jobs {
+		t.Errorf("expected %d jobs moved in total, got %d", jobs, total)
+	}
+
+	ready, _ := mr.List(queue.queueKey(job.PriorityNormal))
+	seen := make(map[string]bool, len(ready))
+	for _, id := range ready {
+		if seen[id] {
+			t.Errorf("job %s was pushed twice", id)
+		}
+		seen[id] = true
+	}
+	if len(ready) != jobs {
+		t.Errorf("expected %d ready jobs, got %d", jobs, len(ready))
+	}
+}
+
+func TestComplete_RacesReaper(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	reaper := newQueueOn(t, mr)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+		j.MaxRetries = 5
+		queue.Enqueue(ctx, j)
+		dequeueNormal(t, queue)
+		mr.ZAdd(queue.leasesKey, 0, j.ID)
+
+		var completeErr, reapErr error
+		var reaped int
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			completeErr = queue.Complete(ctx, j.ID)
+		}()
+		go func() {
+			defer wg.Done()
+			reaped, reapErr = reaper.ReapExpiredLeases(ctx)
+		}()
+		wg.Wait()
+		if reapErr != nil {
+			t.Fatalf("reaper failed: %v", reapErr)
+		}
+
+		// Exactly one of them moves the job
+		stored, _ := queue.GetJob(ctx, j.ID)
+		scheduled := len(zMembers(mr, queue.getScheduledSetKey())) > 0
+		switch {
+		case completeErr == nil:
+			if stored.Status != job.StatusCompleted || scheduled || reaped != 0 {
+				t.Fatalf("complete won, but job is %s (scheduled: %v, reaped: %d)", stored.Status, scheduled, reaped)
+			}
+		case errors.Is(completeErr, ErrLeaseLost):
+			if stored.Status != job.StatusPending || !scheduled || stored.Attempts != 1 {
+				t.Fatalf("reaper won, but job is %s (scheduled: %v, attempts: %d)", stored.Status, scheduled, stored.Attempts)
+			}
+		default:
+			t.Fatalf("complete failed: %v", completeErr)
+		}
+		if processing, _ := mr.List(queue.processingQueueKey()); len(processing) != 0 {
+			t.Fatalf("expected empty processing queue, got %v", processing)
+		}
+		mr.Del(queue.getScheduledSetKey())
+	}
+}
+
+func TestFail_ConcurrentWorkers(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	other := newQueueOn(t, mr)
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 5
+	queue.Enqueue(ctx, j)
+	dequeued := dequeueNormal(t, queue)
+	copied := *dequeued
+
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); errs[0] = queue.Fail(ctx, dequeued, "first") }()
+	go func() { defer wg.Done(); errs[1] = other.Fail(ctx, &copied, "second") }()
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			failed++
+		case !errors.Is(err, ErrLeaseLost):
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected exactly one Fail to apply, got %d", failed)
+	}
+	stored, _ := queue.GetJob(ctx, j.ID)
+	if stored.Attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", stored.Attempts)
+	}
+}
+
+func TestCancel_RacesDequeue(t *testing.T) {
+	queue, mr := setupTestRedis(t)
+	defer queue.Close()
+	worker := newQueueOn(t, mr)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+		queue.Enqueue(ctx, j)
+
+		var cancelErr error
+		var dequeued *job.Job
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cancelErr = queue.Cancel(ctx, j.ID)
+		}()
+		go func() {
+			defer wg.Done()
+			dequeued, _ = worker.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+		}()
+		wg.Wait()
+
+		// Exactly one of them takes the job
+		cancelled := cancelErr == nil
+		started := dequeued != nil
+		if cancelled == started {
+			t.Fatalf("expected either cancel or dequeue to win, got cancel error %v and dequeued %v", cancelErr, dequeued)
+		}
+		if started {
+			if !errors.Is(cancelErr, ErrNotCancellable) {
+				t.Fatalf("expected ErrNotCancellable, got %v", cancelErr)
+			}
+			worker.Complete(ctx, dequeued.ID)
+		}
+	}
+}
+
+func TestStreamQueue_CompleteAfterReapIsRejected(t *testing.T) {
+	worker, mr := setupTestStream(t)
+	reaper := newStreamConsumer(t, mr)
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 5
+	worker.Enqueue(ctx, j)
+	if _, err := worker.Dequeue(ctx, []job.JobPriority{job.PriorityNormal}); err != nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+
+	// The reaper takes over the idle entry and retries the job
+	reaper.SetLeaseDuration(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if reaped, err := reaper.ReapExpiredLeases(ctx); err != nil || reaped != 1 {
+		t.Fatalf("expected 1 reaped job, got %d (%v)", reaped, err)
+	}
+
+	if err := worker.Complete(ctx, j.ID); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	stored, _ := worker.GetJob(ctx, j.ID)
+	if stored.Status != job.StatusPending || stored.Attempts != 1 {
+		t.Errorf("expected the reaped retry to stand, got %s with %d attempts", stored.Status, stored.Attempts)
+	}
+}
+
+// zMembers returns a sorted set's members, or nil if it doesn't exist
+func zMembers(mr *miniredis.Miniredis, key string) []string {
+	members, err := mr.ZMembers(key)
+	if err != nil {
+		return nil
+	}
+	return members
+}
+
+func TestUpdateRunning_RereadsChangedJob(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	queue.Enqueue(ctx, j)
+	dequeueNormal(t, queue)
+
+	// Another writer changes the job after the first read
+	calls := 0
+	updated, err := queue.updateRunning(ctx, queue.processingClaim(j.ID), j.ID, func(stored *job.Job) storedTransition {
+		calls++
+		if calls == 1 {
+			concurrent := *stored
+			concurrent.Description = "changed concurrently"
+			data, _ := json.Marshal(&concurrent)
+			queue.client.Set(ctx, queue.jobKey(j.ID), data, 0)
+		}
+		stored.UpdateStatus(job.StatusCompleted)
+		return queue.finishTransition(stored, "")
+	})
+	if err != nil {
+		t.Fatalf("updateRunning failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the update to be applied again after the change, got %d calls", calls)
+	}
+
+	// The stored job has both the change and the transition
+	stored, _ := queue.GetJob(ctx, j.ID)
+	if stored.Description != "changed concurrently" || stored.Status != job.StatusCompleted {
+		t.Errorf("expected completed job keeping the concurrent change, got %s %q", stored.Status, stored.Description)
+	}
+	if updated.Description != "changed concurrently" {
+		t.Errorf("expected the returned job to include the change, got %q", updated.Description)
+	}
+}
+
+func TestFail_LeaseLostLeavesJobUnchanged(t *testing.T) {
+	queue, _ := setupTestRedis(t)
+	defer queue.Close()
+	ctx := context.Background()
+
+	j := job.NewJob("test", []byte(`{}`), job.PriorityNormal)
+	j.MaxRetries = 3
+	queue.Enqueue(ctx, j)
+	dequeued := dequeueNormal(t, queue)
+	status := dequeued.Status
+	queue.Complete(ctx, j.ID)
+
+	if err := queue.Fail(ctx, dequeued, "late"); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if dequeued.Attempts != 0 || dequeued.Error != "" || dequeued.Status != status {
+		t.Errorf("expected the caller's job to be unchanged, got %s attempt %d %q",
+			dequeued.Status, dequeued.Attempts, dequeued.Error)
+	}
+}
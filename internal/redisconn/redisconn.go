@@ -0,0 +1,203 @@
+// Package redisconn opens Redis clients for standalone, Sentinel and Cluster deployments
+// from a single URL, and picks the key prefix each deployment needs.
+package redisconn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/redis/go-redis/v9"
+)
+
+// Mode is the kind of Redis deployment a URL points at
+type Mode string
+
+const (
+	// ModeStandalone is a single Redis server (redis://, rediss://, unix://)
+	ModeStandalone Mode = "standalone"
+	// ModeSentinel is a Sentinel-managed master with automatic failover
+	// (redis+sentinel://, rediss+sentinel://)
+	ModeSentinel Mode = "sentinel"
+	// ModeCluster is a Redis Cluster (redis+cluster://, rediss+cluster://)
+	ModeCluster Mode = "cluster"
+)
+
+const (
+	sentinelSuffix = "+sentinel"
+	clusterSuffix  = "+cluster"
+)
+
+// Tuning overrides connection pool, retry and timeout settings after a URL is parsed
+// Zero fields keep the URL's (or go-redis's default) value.
+type Tuning struct {
+	PoolSize        int
+	MinIdleConns    int
+	ConnMaxIdleTime time.Duration
+	PoolTimeout     time.Duration
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	DialTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	// ContextTimeoutEnabled makes commands respect context deadlines
+	ContextTimeoutEnabled bool
+}
+
+// ParseMode returns the deployment mode selected by a URL's scheme
+func ParseMode(redisURL string) (Mode, error) {
+	scheme, _, ok := strings.Cut(redisURL, "://")
+	if !ok {
+		return "", fmt.Errorf("invalid Redis URL %q: missing scheme", redisURL)
+	}
+	switch scheme {
+	case "redis", "rediss", "unix":
+		return ModeStandalone, nil
+	case "redis" + sentinelSuffix, "rediss" + sentinelSuffix:
+		return ModeSentinel, nil
+	case "redis" + clusterSuffix, "rediss" + clusterSuffix:
+		return ModeCluster, nil
+	default:
+		return "", fmt.Errorf("invalid Redis URL scheme %q", scheme)
+	}
+}
+
+// NewClient parses a Redis URL and returns a client for the deployment it describes
+//
+//	redis://[user:password@]host:port[/db]
+//	redis+sentinel://[sentinel-user:sentinel-password@]host:port[/db]?master_name=mymaster[&addr=host:port...]
+//	redis+cluster://[user:password@]host:port[?addr=host:port...]
+//
+// The rediss variants enable TLS. Extra seed nodes or sentinels are given as addr
+// query parameters, and the master's password for Sentinel as the password parameter.
+// The URL is only parsed; nothing connects until the first command.
+func NewClient(redisURL string, tuning *Tuning) (redis.UniversalClient, error) {
+	mode, err := ParseMode(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case ModeSentinel:
+		opts, err := redis.ParseFailoverURL(strings.Replace(redisURL, sentinelSuffix, "", 1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Redis Sentinel URL: %w", err)
+		}
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("failed to parse Redis Sentinel URL: master_name is required")
+		}
+		tuning.applyFailover(opts)
+		return redis.NewFailoverClient(opts), nil
+	case ModeCluster:
+		opts, err := redis.ParseClusterURL(strings.Replace(redisURL, clusterSuffix, "", 1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Redis Cluster URL: %w", err)
+		}
+		tuning.applyCluster(opts)
+		return redis.NewClusterClient(opts), nil
+	default:
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		}
+		tuning.applySimple(opts)
+		return redis.NewClient(opts), nil
+	}
+}
+
+// Connect is NewClient followed by a PING, closing the client if Redis is unreachable
+func Connect(ctx context.Context, redisURL string, tuning *Tuning) (redis.UniversalClient, error) {
+	client, err := NewClient(redisURL, tuning)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return client, nil
+}
+
+// IsCluster reports whether a client talks to a Redis Cluster
+func IsCluster(client redis.UniversalClient) bool {
+	_, ok := client.(*redis.ClusterClient)
+	return ok
+}
+
+// KeyPrefix returns the key prefix a namespace uses on a client's deployment
+// On Cluster the namespace is hash-tagged ("{bananas}:") so all of its keys hash to
+// one slot and multi-key commands, transactions and Lua scripts keep working.
+// Standalone and Sentinel deployments keep the untagged prefix ("bananas:") and
+// therefore their existing data.
+func KeyPrefix(client redis.UniversalClient, ns string) string {
+	if IsCluster(client) {
+		return namespace.HashTaggedPrefix(ns)
+	}
+	return namespace.Prefix(ns)
+}
+
+func (t *Tuning) applySimple(o *redis.Options) {
+	if t == nil {
+		return
+	}
+	setInt(&o.PoolSize, t.PoolSize)
+	setInt(&o.MinIdleConns, t.MinIdleConns)
+	setInt(&o.MaxRetries, t.MaxRetries)
+	setDuration(&o.ConnMaxIdleTime, t.ConnMaxIdleTime)
+	setDuration(&o.PoolTimeout, t.PoolTimeout)
+	setDuration(&o.MinRetryBackoff, t.MinRetryBackoff)
+	setDuration(&o.MaxRetryBackoff, t.MaxRetryBackoff)
+	setDuration(&o.DialTimeout, t.DialTimeout)
+	setDuration(&o.ReadTimeout, t.ReadTimeout)
+	setDuration(&o.WriteTimeout, t.WriteTimeout)
+	o.ContextTimeoutEnabled = o.ContextTimeoutEnabled || t.ContextTimeoutEnabled
+}
+
+func (t *Tuning) applyFailover(o *redis.FailoverOptions) {
+	if t == nil {
+		return
+	}
+	setInt(&o.PoolSize, t.PoolSize)
+	setInt(&o.MinIdleConns, t.MinIdleConns)
+	setInt(&o.MaxRetries, t.MaxRetries)
+	setDuration(&o.ConnMaxIdleTime, t.ConnMaxIdleTime)
+	setDuration(&o.PoolTimeout, t.PoolTimeout)
+	setDuration(&o.MinRetryBackoff, t.MinRetryBackoff)
+	setDuration(&o.MaxRetryBackoff, t.MaxRetryBackoff)
+	setDuration(&o.DialTimeout, t.DialTimeout)
+	setDuration(&o.ReadTimeout, t.ReadTimeout)
+	setDuration(&o.WriteTimeout, t.WriteTimeout)
+	o.ContextTimeoutEnabled = o.ContextTimeoutEnabled || t.ContextTimeoutEnabled
+}
+
+func (t *Tuning) applyCluster(o *redis.ClusterOptions) {
+	if t == nil {
+		return
+	}
+	setInt(&o.PoolSize, t.PoolSize)
+	setInt(&o.MinIdleConns, t.MinIdleConns)
+	setInt(&o.MaxRetries, t.MaxRetries)
+	setDuration(&o.ConnMaxIdleTime, t.ConnMaxIdleTime)
+	setDuration(&o.PoolTimeout, t.PoolTimeout)
+	setDuration(&o.MinRetryBackoff, t.MinRetryBackoff)
+	setDuration(&o.MaxRetryBackoff, t.MaxRetryBackoff)
+	setDuration(&o.DialTimeout, t.DialTimeout)
+	setDuration(&o.ReadTimeout, t.ReadTimeout)
+	setDuration(&o.WriteTimeout, t.WriteTimeout)
+	o.ContextTimeoutEnabled = o.ContextTimeoutEnabled || t.ContextTimeoutEnabled
+}
+
+func setInt(dst *int, v int) {
+	if v != 0 {
+		*dst = v
+	}
+}
+
+func setDuration(dst *time.Duration, v time.Duration) {
+	if v != 0 {
+		*dst = v
+	}
+}
@@ -0,0 +1,147 @@
+package redisconn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]Mode{
+		"redis://localhost:6379":                                 ModeStandalone,
+		"rediss://localhost:6379/1":                              ModeStandalone,
+		"unix:///tmp/redis.sock":                                 ModeStandalone,
+		"redis+sentinel://localhost:26379?master_name=mymaster":  ModeSentinel,
+		"rediss+sentinel://localhost:26379?master_name=mymaster": ModeSentinel,
+		"redis+cluster://localhost:7000":                         ModeCluster,
+		"rediss+cluster://localhost:7000":                        ModeCluster,
+	}
+	for url, want := range tests {
+		got, err := ParseMode(url)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", url, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected mode %s, got %s", url, want, got)
+		}
+	}
+
+	for _, url := range []string{"localhost:6379", "http://localhost", "redis+replica://localhost"} {
+		if _, err := ParseMode(url); err == nil {
+			t.Errorf("%s: expected error", url)
+		}
+	}
+}
+
+func TestNewClient_Standalone(t *testing.T) {
+	client, err := NewClient("redis://localhost:6379/2", &Tuning{PoolSize: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	c, ok := client.(*redis.Client)
+	if !ok {
+		t.Fatalf("expected *redis.Client, got %T", client)
+	}
+	if c.Options().DB != 2 || c.Options().PoolSize != 7 {
+		t.Errorf("expected db 2 and pool size 7, got %d and %d", c.Options().DB, c.Options().PoolSize)
+	}
+	if IsCluster(client) {
+		t.Error("expected standalone client not to be a cluster client")
+	}
+}
+
+func TestNewClient_Sentinel(t *testing.T) {
+	client, err := NewClient("redis+sentinel://localhost:26379/1?master_name=mymaster&addr=localhost:26380", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	// A failover client is a *redis.Client whose connections go to the current master
+	if _, ok := client.(*redis.Client); !ok {
+		t.Fatalf("expected *redis.Client, got %T", client)
+	}
+	if IsCluster(client) {
+		t.Error("expected sentinel client not to be a cluster client")
+	}
+
+	if _, err := NewClient("redis+sentinel://localhost:26379", nil); err == nil {
+		t.Error("expected error without master_name")
+	}
+}
+
+func TestNewClient_Cluster(t *testing.T) {
+	client, err := NewClient("redis+cluster://localhost:7000?addr=localhost:7001", &Tuning{
+		PoolSize:    9,
+		ReadTimeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	c, ok := client.(*redis.ClusterClient)
+	if !ok {
+		t.Fatalf("expected *redis.ClusterClient, got %T", client)
+	}
+	opts := c.Options()
+	if len(opts.Addrs) != 2 {
+		t.Errorf("expected 2 seed addresses, got %v", opts.Addrs)
+	}
+	if opts.PoolSize != 9 || opts.ReadTimeout != 2*time.Second {
+		t.Errorf("expected tuning to apply, got pool %d read timeout %s", opts.PoolSize, opts.ReadTimeout)
+	}
+	if !IsCluster(client) {
+		t.Error("expected cluster client")
+	}
+}
+
+func TestNewClient_InvalidURL(t *testing.T) {
+	for _, url := range []string{"invalid://url", "redis+cluster://localhost:7000?bogus=1"} {
+		if _, err := NewClient(url, nil); err == nil {
+			t.Errorf("%s: expected error", url)
+		}
+	}
+}
+
+func TestConnect(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	for _, url := range []string{"redis://" + mr.Addr(), "redis+cluster://" + mr.Addr()} {
+		client, err := Connect(context.Background(), url, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to connect: %v", url, err)
+		}
+		if err := client.Set(context.Background(), "k", "v", 0).Err(); err != nil {
+			t.Errorf("%s: failed to write: %v", url, err)
+		}
+		client.Close()
+	}
+
+	if _, err := Connect(context.Background(), "redis://localhost:9999", nil); err == nil {
+		t.Error("expected connection error")
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	standalone := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer standalone.Close()
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"localhost:7000"}})
+	defer cluster.Close()
+
+	if got := KeyPrefix(standalone, "acme"); got != "acme:" {
+		t.Errorf("expected 'acme:', got %q", got)
+	}
+	if got := KeyPrefix(cluster, "acme"); got != "{acme}:" {
+		t.Errorf("expected '{acme}:', got %q", got)
+	}
+	if got := KeyPrefix(cluster, ""); got != "{bananas}:" {
+		t.Errorf("expected '{bananas}:', got %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+package result
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// PostgresResultSchema creates the table PostgresBackend uses
+// NewPostgresBackend applies it (it is idempotent).
+const PostgresResultSchema = `
+CREATE TABLE IF NOT EXISTS bananas_results (
+	job_id       TEXT PRIMARY KEY,
+	status       TEXT NOT NULL,
+	result       BYTEA,
+	error        TEXT NOT NULL DEFAULT '',
+	completed_at TIMESTAMPTZ NOT NULL,
+	duration_ms  BIGINT NOT NULL,
+	expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS bananas_results_expires ON bananas_results (expires_at);
+`
+
+// postgresResultChannel is the LISTEN/NOTIFY channel announcing stored results (payload: job ID)
+const postgresResultChannel = "bananas_results"
+
+// PostgresBackend implements the Backend interface using PostgreSQL
+type PostgresBackend struct {
+	pool       *pgxpool.Pool
+	successTTL time.Duration
+	failureTTL time.Duration
+}
+
+// NewPostgresBackend creates a PostgreSQL-backed result backend, creating its table if needed
+// The pool can be shared with a queue.PostgresQueue (see PostgresQueue.Pool).
+func NewPostgresBackend(ctx context.Context, pool *pgxpool.Pool, successTTL, failureTTL time.Duration) (*PostgresBackend, error) {
+	if _, err := pool.Exec(ctx, PostgresResultSchema); err != nil {
+		return nil, fmt.Errorf("failed to create result schema: %w", err)
+	}
+	return &PostgresBackend{
+		pool:       pool,
+		successTTL: successTTL,
+		failureTTL: failureTTL,
+	}, nil
+}
+
+// StoreResult stores a job result and notifies waiters
+func (p *PostgresBackend) StoreResult(ctx context.Context, result *job.JobResult) error {
+	ttl := p.successTTL
+	if result.IsFailed() {
+		ttl = p.failureTTL
+	}
+
+	var data []byte
+	if result.IsSuccess() {
+		data = result.Result
+	}
+	var errMsg string
+	if result.IsFailed() {
+		errMsg = result.Error
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(`
+		INSERT INTO bananas_results (job_id, status, result, error, completed_at, duration_ms, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (job_id) DO UPDATE SET
+			status = EXCLUDED.status, result = EXCLUDED.result, error = EXCLUDED.error,
+			completed_at = EXCLUDED.completed_at, duration_ms = EXCLUDED.duration_ms,
+			expires_at = EXCLUDED.expires_at`,
+		result.JobID, string(result.Status), data, errMsg, result.CompletedAt,
+		result.Duration.Milliseconds(), time.Now().Add(ttl))
+	batch.Queue("SELECT pg_notify($1, $2)", postgresResultChannel, result.JobID)
+	// Expired results are deleted lazily as new ones arrive
+	batch.Queue("DELETE FROM bananas_results WHERE expires_at <= now()")
+
+	if err := p.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store result: %w", err)
+	}
+	return nil
+}
+
+// GetResult retrieves a job result
+// Returns nil if the result doesn't exist or has expired.
+func (p *PostgresBackend) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	var (
+		status     string
+		data       []byte
+		durationMs int64
+	)
+	result := &job.JobResult{JobID: jobID}
+	err := p.pool.QueryRow(ctx, `
+		SELECT status, result, error, completed_at, duration_ms FROM bananas_results
+		WHERE job_id = $1 AND expires_at > now()`, jobID).
+		Scan(&status, &data, &result.Error, &result.CompletedAt, &durationMs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result: %w", err)
+	}
+
+	result.Status = job.JobStatus(status)
+	result.Duration = time.Duration(durationMs) * time.Millisecond
+	if len(data) > 0 {
+		result.Result = data
+	}
+	return result, nil
+}
+
+// WaitForResult blocks until a result is available or timeout is reached
+// Uses LISTEN/NOTIFY for efficient waiting
+func (p *PostgresBackend) WaitForResult(ctx context.Context, jobID string, timeout time.Duration) (*job.JobResult, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pooled, err := p.pool.Acquire(waitCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for result: %w", err)
+	}
+	conn := pooled.Conn()
+	defer func() {
+		// A connection that fails to UNLISTEN is closed, so it doesn't return to the pool
+		if _, err := conn.Exec(context.Background(), "UNLISTEN "+postgresResultChannel); err != nil {
+			conn.Close(context.Background())
+		}
+		pooled.Release()
+	}()
+
+	// Listen before checking, so a result stored in between isn't missed
+	if _, err := conn.Exec(waitCtx, "LISTEN "+postgresResultChannel); err != nil {
+		return nil, fmt.Errorf("failed to wait for result: %w", err)
+	}
+	if result, err := p.GetResult(ctx, jobID); err != nil || result != nil {
+		return result, err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(waitCtx)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				// Timeout or context cancelled: one final check
+				return p.GetResult(ctx, jobID)
+			}
+			return nil, fmt.Errorf("failed to wait for result: %w", err)
+		}
+		if notification.Payload == jobID {
+			return p.GetResult(ctx, jobID)
+		}
+	}
+}
+
+// DeleteResult removes a result
+func (p *PostgresBackend) DeleteResult(ctx context.Context, jobID string) error {
+	if _, err := p.pool.Exec(ctx, "DELETE FROM bananas_results WHERE job_id = $1", jobID); err != nil {
+		return fmt.Errorf("failed to delete result: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op: the pool belongs to the caller
+func (p *PostgresBackend) Close() error {
+	return nil
+}
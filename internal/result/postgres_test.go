@@ -0,0 +1,98 @@
+package result
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// newTestPostgresBackend connects to the database in BANANAS_TEST_POSTGRES_URL and
+// empties the results table, or skips the test when it isn't set
+func newTestPostgresBackend(t *testing.T) *PostgresBackend {
+	t.Helper()
+	url := os.Getenv("BANANAS_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("BANANAS_TEST_POSTGRES_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	backend, err := NewPostgresBackend(ctx, pool, time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "TRUNCATE bananas_results"); err != nil {
+		t.Fatalf("failed to empty results table: %v", err)
+	}
+	return backend
+}
+
+func TestPostgresBackend_StoreAndGet(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestPostgresBackend(t)
+
+	stored := &job.JobResult{
+		JobID:       "job-1",
+		Status:      job.StatusCompleted,
+		Result:      json.RawMessage(`{"total":42}`),
+		CompletedAt: time.Now().Truncate(time.Millisecond),
+		Duration:    1500 * time.Millisecond,
+	}
+	if err := backend.StoreResult(ctx, stored); err != nil {
+		t.Fatalf("StoreResult failed: %v", err)
+	}
+
+	got, err := backend.GetResult(ctx, "job-1")
+	if err != nil || got == nil {
+		t.Fatalf("expected result, got %v, %v", got, err)
+	}
+	if got.Status != job.StatusCompleted || string(got.Result) != `{"total":42}` || got.Duration != stored.Duration {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if missing, err := backend.GetResult(ctx, "missing"); missing != nil || err != nil {
+		t.Errorf("expected nil, nil for missing result, got %v, %v", missing, err)
+	}
+
+	backend.DeleteResult(ctx, "job-1")
+	if deleted, _ := backend.GetResult(ctx, "job-1"); deleted != nil {
+		t.Error("expected result to be deleted")
+	}
+}
+
+func TestPostgresBackend_WaitForResult(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestPostgresBackend(t)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		backend.StoreResult(ctx, &job.JobResult{
+			JobID:       "job-2",
+			Status:      job.StatusFailed,
+			Error:       "boom",
+			CompletedAt: time.Now(),
+		})
+	}()
+
+	got, err := backend.WaitForResult(ctx, "job-2", 5*time.Second)
+	if err != nil || got == nil {
+		t.Fatalf("expected result, got %v, %v", got, err)
+	}
+	if got.Status != job.StatusFailed || got.Error != "boom" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if timedOut, err := backend.WaitForResult(ctx, "never", 100*time.Millisecond); timedOut != nil || err != nil {
+		t.Errorf("expected nil, nil on timeout, got %v, %v", timedOut, err)
+	}
+}
@@ -8,29 +8,88 @@ import (
 	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
 	"github.com/redis/go-redis/v9"
 )
 
 // RedisBackend implements the Backend interface using Redis
 type RedisBackend struct {
-	client     *redis.Client
+	client     redis.UniversalClient
 	successTTL time.Duration
 	failureTTL time.Duration
+	encryptor  *serialization.Encryptor
+	prefix     string
 }
 
 // NewRedisBackend creates a new Redis-backed result backend
-func NewRedisBackend(client *redis.Client, successTTL, failureTTL time.Duration) *RedisBackend {
+func NewRedisBackend(client redis.UniversalClient, successTTL, failureTTL time.Duration) *RedisBackend {
 	return &RedisBackend{
 		client:     client,
 		successTTL: successTTL,
 		failureTTL: failureTTL,
+		prefix:     redisconn.KeyPrefix(client, namespace.Default),
 	}
 }
 
+// SetNamespace stores results in a namespace's keyspace
+// It must match the namespace of the queue whose jobs produce the results.
+func (r *RedisBackend) SetNamespace(ns string) error {
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	r.prefix = redisconn.KeyPrefix(r.client, ns)
+	return nil
+}
+
+// resultKey returns the key holding a job's result
+func (r *RedisBackend) resultKey(jobID string) string {
+	return r.prefix + "result:" + jobID
+}
+
+// notifyChannel returns the pub/sub channel announcing a job's result
+func (r *RedisBackend) notifyChannel(jobID string) string {
+	return r.prefix + "result:notify:" + jobID
+}
+
+// SetEncryptor enables encryption of result data and error messages at rest
+// Results stored before encryption was enabled remain readable
+func (r *RedisBackend) SetEncryptor(enc *serialization.Encryptor) {
+	r.encryptor = enc
+}
+
+// seal encrypts a result field if encryption is enabled
+func (r *RedisBackend) seal(value []byte) (string, error) {
+	if r.encryptor == nil {
+		return string(value), nil
+	}
+	encrypted, err := r.encryptor.Encrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt result: %w", err)
+	}
+	return string(encrypted), nil
+}
+
+// open decrypts a result field if it was stored encrypted
+func (r *RedisBackend) open(value string) (string, error) {
+	if !serialization.IsEncrypted([]byte(value)) {
+		return value, nil
+	}
+	if r.encryptor == nil {
+		return "", fmt.Errorf("result is encrypted but no encryptor is configured")
+	}
+	decrypted, err := r.encryptor.Decrypt([]byte(value))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt result: %w", err)
+	}
+	return string(decrypted), nil
+}
+
 // StoreResult stores a job result in Redis
 func (r *RedisBackend) StoreResult(ctx context.Context, result *job.JobResult) error {
-	key := fmt.Sprintf("bananas:result:%s", result.JobID)
-	notifyChannel := fmt.Sprintf("bananas:result:notify:%s", result.JobID)
+	key := r.resultKey(result.JobID)
+	notifyChannel := r.notifyChannel(result.JobID)
 
 	// Prepare data to store
 	data := map[string]interface{}{
@@ -40,11 +99,19 @@ func (r *RedisBackend) StoreResult(ctx context.Context, result *job.JobResult) e
 	}
 
 	if result.IsSuccess() && len(result.Result) > 0 {
-		data["result"] = string(result.Result)
+		sealed, err := r.seal(result.Result)
+		if err != nil {
+			return err
+		}
+		data["result"] = sealed
 	}
 
 	if result.IsFailed() && result.Error != "" {
-		data["error"] = result.Error
+		sealed, err := r.seal([]byte(result.Error))
+		if err != nil {
+			return err
+		}
+		data["error"] = sealed
 	}
 
 	// Determine TTL based on status
@@ -69,7 +136,7 @@ func (r *RedisBackend) StoreResult(ctx context.Context, result *job.JobResult) e
 
 // GetResult retrieves a job result from Redis
 func (r *RedisBackend) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
-	key := fmt.Sprintf("bananas:result:%s", jobID)
+	key := r.resultKey(jobID)
 
 	// Get all fields
 	data, err := r.client.HGetAll(ctx, key).Result()
@@ -110,12 +177,20 @@ func (r *RedisBackend) GetResult(ctx context.Context, jobID string) (*job.JobRes
 
 	// Parse result data
 	if resultData, exists := data["result"]; exists {
-		result.Result = json.RawMessage(resultData)
+		opened, err := r.open(resultData)
+		if err != nil {
+			return nil, err
+		}
+		result.Result = json.RawMessage(opened)
 	}
 
 	// Parse error
 	if errorMsg, exists := data["error"]; exists {
-		result.Error = errorMsg
+		opened, err := r.open(errorMsg)
+		if err != nil {
+			return nil, err
+		}
+		result.Error = opened
 	}
 
 	return result, nil
@@ -124,7 +199,7 @@ func (r *RedisBackend) GetResult(ctx context.Context, jobID string) (*job.JobRes
 // WaitForResult blocks until a result is available or timeout is reached
 // Uses Redis pub/sub for efficient waiting
 func (r *RedisBackend) WaitForResult(ctx context.Context, jobID string, timeout time.Duration) (*job.JobResult, error) {
-	notifyChannel := fmt.Sprintf("bananas:result:notify:%s", jobID)
+	notifyChannel := r.notifyChannel(jobID)
 
 	// First check if result already exists
 	result, err := r.GetResult(ctx, jobID)
@@ -172,7 +247,7 @@ func (r *RedisBackend) WaitForResult(ctx context.Context, jobID string, timeout
 
 // DeleteResult removes a result from Redis
 func (r *RedisBackend) DeleteResult(ctx context.Context, jobID string) error {
-	key := fmt.Sprintf("bananas:result:%s", jobID)
+	key := r.resultKey(jobID)
 
 	err := r.client.Del(ctx, key).Err()
 	if err != nil {
@@ -1,12 +1,15 @@
 package result
 
 import (
+	"bytes"
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -374,3 +377,97 @@ func TestRedisBackend_TTL(t *testing.T) {
 		}
 	})
 }
+
+func TestRedisBackend_Encryption(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+
+	keys, err := serialization.NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{3}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	enc := serialization.NewEncryptor(keys)
+
+	backend := NewRedisBackend(client, time.Hour, 24*time.Hour)
+	backend.SetEncryptor(enc)
+	ctx := context.Background()
+
+	success := &job.JobResult{
+		JobID:       "job-ok",
+		Status:      job.StatusCompleted,
+		Result:      []byte(`{"account":"DE89370400440532013000"}`),
+		CompletedAt: time.Now(),
+	}
+	failure := &job.JobResult{
+		JobID:       "job-failed",
+		Status:      job.StatusFailed,
+		Error:       "invalid card 4111111111111111",
+		CompletedAt: time.Now(),
+	}
+	for _, r := range []*job.JobResult{success, failure} {
+		if err := backend.StoreResult(ctx, r); err != nil {
+			t.Fatalf("StoreResult() error = %v", err)
+		}
+	}
+
+	if raw := mr.HGet("bananas:result:job-ok", "result"); strings.Contains(raw, "DE89") {
+		t.Error("result stored in plain text")
+	}
+	if raw := mr.HGet("bananas:result:job-failed", "error"); strings.Contains(raw, "4111") {
+		t.Error("error stored in plain text")
+	}
+
+	got, err := backend.GetResult(ctx, "job-ok")
+	if err != nil {
+		t.Fatalf("GetResult() error = %v", err)
+	}
+	if string(got.Result) != string(success.Result) {
+		t.Errorf("Result = %s, want %s", got.Result, success.Result)
+	}
+
+	got, err = backend.GetResult(ctx, "job-failed")
+	if err != nil {
+		t.Fatalf("GetResult() error = %v", err)
+	}
+	if got.Error != failure.Error {
+		t.Errorf("Error = %q, want %q", got.Error, failure.Error)
+	}
+
+	// A backend without the encryptor cannot read encrypted results
+	plainBackend := NewRedisBackend(client, time.Hour, 24*time.Hour)
+	if _, err := plainBackend.GetResult(ctx, "job-ok"); err == nil {
+		t.Error("expected error reading encrypted result without encryptor")
+	}
+}
+
+func TestRedisBackend_Namespace(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+
+	acme := NewRedisBackend(client, time.Hour, 24*time.Hour)
+	if err := acme.SetNamespace("acme"); err != nil {
+		t.Fatalf("SetNamespace() error = %v", err)
+	}
+	other := NewRedisBackend(client, time.Hour, 24*time.Hour)
+	ctx := context.Background()
+
+	result := &job.JobResult{
+		JobID:       "job123",
+		Status:      job.StatusCompleted,
+		Result:      []byte(`{"count":42}`),
+		CompletedAt: time.Now(),
+	}
+	if err := acme.StoreResult(ctx, result); err != nil {
+		t.Fatalf("StoreResult() error = %v", err)
+	}
+
+	if !mr.Exists("acme:result:job123") {
+		t.Error("expected result stored under the acme namespace")
+	}
+	if retrieved, err := other.GetResult(ctx, "job123"); err != nil || retrieved != nil {
+		t.Errorf("expected result invisible from the default namespace, got %v (%v)", retrieved, err)
+	}
+	if retrieved, err := acme.GetResult(ctx, "job123"); err != nil || retrieved == nil {
+		t.Errorf("expected result in the acme namespace, got %v (%v)", retrieved, err)
+	}
+}
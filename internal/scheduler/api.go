@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// APIHandler serves CRUD endpoints for the schedules in a Store
+//
+//	GET    /schedules       list schedules
+//	GET    /schedules/{id}  get a schedule
+//	PUT    /schedules/{id}  create or replace a schedule (JSON body)
+//	DELETE /schedules/{id}  delete a schedule
+//
+// Schedulers watching the store (Registry.Watch) pick up changes on their own.
+type APIHandler struct {
+	store *Store
+}
+
+// NewAPIHandler creates a schedule API handler backed by a store
+func NewAPIHandler(store *Store) *APIHandler {
+	return &APIHandler{store: store}
+}
+
+// Register mounts the schedule endpoints on a mux
+func (h *APIHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /schedules", h.list)
+	mux.HandleFunc("GET /schedules/{id}", h.get)
+	mux.HandleFunc("PUT /schedules/{id}", h.save)
+	mux.HandleFunc("DELETE /schedules/{id}", h.delete)
+}
+
+func (h *APIHandler) list(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.store.List(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (h *APIHandler) get(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.store.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+func (h *APIHandler) save(w http.ResponseWriter, r *http.Request) {
+	var schedule Schedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The path names the schedule; a body ID, if any, must agree
+	id := r.PathValue("id")
+	if schedule.ID != "" && schedule.ID != id {
+		http.Error(w, "schedule ID does not match the path", http.StatusBadRequest)
+		return
+	}
+	schedule.ID = id
+
+	if err := h.store.Save(r.Context(), &schedule); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &schedule)
+}
+
+func (h *APIHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps store errors to HTTP status codes
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrScheduleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidSchedule):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
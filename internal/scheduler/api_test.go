@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIHandler(t *testing.T) {
+	store, _ := setupStore(t)
+	mux := http.NewServeMux()
+	NewAPIHandler(store).Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := do("PUT", "/schedules/nightly", `{"cron":"0 3 * * *","job":"cleanup","enabled":true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 saving a schedule, got %d", resp.StatusCode)
+	}
+
+	resp = do("GET", "/schedules/nightly", "")
+	var schedule Schedule
+	json.NewDecoder(resp.Body).Decode(&schedule)
+	if resp.StatusCode != http.StatusOK || schedule.ID != "nightly" || schedule.Job != "cleanup" {
+		t.Errorf("Expected the saved schedule, got %d %+v", resp.StatusCode, schedule)
+	}
+
+	resp = do("GET", "/schedules", "")
+	var schedules []Schedule
+	json.NewDecoder(resp.Body).Decode(&schedules)
+	if len(schedules) != 1 {
+		t.Errorf("Expected 1 schedule, got %d", len(schedules))
+	}
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"PUT", "/schedules/nightly", `{"cron":"never","job":"cleanup"}`, http.StatusBadRequest},
+		{"PUT", "/schedules/nightly", `{"id":"other","cron":"0 3 * * *","job":"cleanup"}`, http.StatusBadRequest},
+		{"PUT", "/schedules/nightly", `not json`, http.StatusBadRequest},
+		{"DELETE", "/schedules/nightly", "", http.StatusNoContent},
+		{"GET", "/schedules/nightly", "", http.StatusNotFound},
+		{"DELETE", "/schedules/nightly", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if resp := do(tt.method, tt.path, tt.body); resp.StatusCode != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.method, tt.path, tt.body, tt.want, resp.StatusCode)
+		}
+	}
+}
@@ -7,33 +7,40 @@ import (
 
 	"github.com/muaviaUsmani/bananas/internal/job"
 	"github.com/muaviaUsmani/bananas/internal/logger"
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
 	"github.com/redis/go-redis/v9"
 )
 
-// Queue defines the interface for enqueueing jobs
-type Queue interface {
-	Enqueue(ctx context.Context, j *job.Job) error
-}
+// Queue is the broker side the scheduler enqueues cron jobs through (any queue.Broker)
+type Queue = queue.Producer
 
 // CronScheduler manages periodic task execution
 type CronScheduler struct {
 	registry *Registry
 	queue    Queue
-	client   *redis.Client
+	client   redis.UniversalClient
 	interval time.Duration
 	lockTTL  time.Duration
+	prefix   string
 	log      logger.Logger
+
+	misfireThreshold time.Duration
 }
 
 // NewCronScheduler creates a new cron scheduler
-func NewCronScheduler(registry *Registry, queue Queue, client *redis.Client, interval time.Duration) *CronScheduler {
+func NewCronScheduler(registry *Registry, queue Queue, client redis.UniversalClient, interval time.Duration) *CronScheduler {
 	return &CronScheduler{
 		registry: registry,
 		queue:    queue,
 		client:   client,
 		interval: interval,
 		lockTTL:  60 * time.Second, // Default: 60s lock TTL
+		prefix:   redisconn.KeyPrefix(client, namespace.Default),
 		log:      logger.Default().WithComponent(logger.ComponentScheduler),
+
+		misfireThreshold: DefaultMisfireThreshold,
 	}
 }
 
@@ -42,6 +49,32 @@ func (cs *CronScheduler) SetLockTTL(ttl time.Duration) {
 	cs.lockTTL = ttl
 }
 
+// SetMisfireThreshold sets how late a cron run may start before the schedule's
+// misfire policy applies to it (default: DefaultMisfireThreshold)
+func (cs *CronScheduler) SetMisfireThreshold(threshold time.Duration) {
+	cs.misfireThreshold = threshold
+}
+
+// SetNamespace keeps schedule state and locks in a namespace's keyspace
+// It must match the namespace of the queue the scheduler enqueues into.
+func (cs *CronScheduler) SetNamespace(ns string) error {
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	cs.prefix = redisconn.KeyPrefix(cs.client, ns)
+	return nil
+}
+
+// stateKey returns the hash holding a schedule's state
+func (cs *CronScheduler) stateKey(scheduleID string) string {
+	return cs.prefix + "schedules:" + scheduleID
+}
+
+// lockKey returns the distributed lock key of a schedule
+func (cs *CronScheduler) lockKey(scheduleID string) string {
+	return cs.prefix + "schedule_lock:" + scheduleID
+}
+
 // Start begins the cron scheduler loop
 func (cs *CronScheduler) Start(ctx context.Context) {
 	cs.log.Info("Cron scheduler started",
@@ -74,7 +107,7 @@ func (cs *CronScheduler) tick(ctx context.Context) {
 
 		// Check if schedule is due
 		if cs.isDue(ctx, schedule, now) {
-			cs.executeSchedule(ctx, schedule, now)
+			cs.runDue(ctx, schedule, now)
 		}
 	}
 }
@@ -90,8 +123,13 @@ func (cs *CronScheduler) isDue(ctx context.Context, schedule *Schedule, now time
 		return false
 	}
 
+	from, immediate := cs.cursor(schedule, state)
+	if immediate {
+		return true
+	}
+
 	// Calculate next run time
-	nextRun, err := cs.registry.NextRun(schedule, state.LastRun)
+	nextRun, err := cs.registry.NextRun(schedule, from)
 	if err != nil {
 		cs.log.Error("Failed to calculate next run",
 			"schedule_id", schedule.ID,
@@ -101,12 +139,48 @@ func (cs *CronScheduler) isDue(ctx context.Context, schedule *Schedule, now time
 
 	// Due if next run time is in the past or equal to now
 	// Use 1-second buffer to account for tick timing
-	return now.After(nextRun.Add(-1*time.Second)) || now.Equal(nextRun)
+	return !nextRun.IsZero() && (now.After(nextRun.Add(-1*time.Second)) || now.Equal(nextRun))
 }
 
-// executeSchedule attempts to execute a schedule
-func (cs *CronScheduler) executeSchedule(ctx context.Context, schedule *Schedule, now time.Time) {
-	lockKey := fmt.Sprintf("bananas:schedule_lock:%s", schedule.ID)
+// runDue enqueues the runs of a due schedule according to its misfire policy
+//
+// The state is read again under the lock, so runs another instance has just
+// handled are not enqueued twice.
+func (cs *CronScheduler) runDue(ctx context.Context, schedule *Schedule, now time.Time) {
+	lock := cs.lock(ctx, schedule)
+	if lock == nil {
+		return
+	}
+	defer cs.unlock(ctx, schedule, lock)
+
+	state, err := cs.getState(ctx, schedule.ID)
+	if err != nil {
+		cs.log.Error("Failed to get schedule state",
+			"schedule_id", schedule.ID,
+			"error", err)
+		return
+	}
+
+	plan, err := cs.planRuns(schedule, state, now)
+	if err != nil {
+		cs.log.Error("Failed to calculate due runs",
+			"schedule_id", schedule.ID,
+			"error", err)
+		return
+	}
+
+	if plan.missed > 0 || (len(plan.runs) == 0 && !plan.lastSlot.IsZero()) {
+		cs.recordMissed(ctx, schedule, plan)
+	}
+	for _, slot := range plan.runs {
+		cs.enqueueRun(ctx, schedule, slot, now)
+	}
+}
+
+// lock acquires a schedule's distributed lock, returning nil if another instance
+// holds it or it can't be acquired
+func (cs *CronScheduler) lock(ctx context.Context, schedule *Schedule) *DistributedLock {
+	lockKey := cs.lockKey(schedule.ID)
 
 	// Try to acquire distributed lock
 	lock, err := AcquireLock(ctx, cs.client, lockKey, cs.lockTTL)
@@ -114,42 +188,43 @@ func (cs *CronScheduler) executeSchedule(ctx context.Context, schedule *Schedule
 		cs.log.Error("Failed to acquire schedule lock",
 			"schedule_id", schedule.ID,
 			"error", err)
-		return
+		return nil
 	}
 
 	if lock == nil {
 		// Another instance is already running this schedule
 		cs.log.Debug("Schedule already locked by another instance",
 			"schedule_id", schedule.ID)
-		return
+		return nil
 	}
+	return lock
+}
 
-	defer func() {
-		if err := lock.Release(ctx); err != nil {
-			cs.log.Error("Failed to release schedule lock",
-				"schedule_id", schedule.ID,
-				"error", err)
-		}
-	}()
+// unlock releases a schedule's distributed lock
+func (cs *CronScheduler) unlock(ctx context.Context, schedule *Schedule, lock *DistributedLock) {
+	if err := lock.Release(ctx); err != nil {
+		cs.log.Error("Failed to release schedule lock",
+			"schedule_id", schedule.ID,
+			"error", err)
+	}
+}
 
+// enqueueRun enqueues the job of the run due at slot and updates the schedule state
+func (cs *CronScheduler) enqueueRun(ctx context.Context, schedule *Schedule, slot, now time.Time) {
 	// Create and enqueue job
 	description := schedule.Description
 	if description == "" {
 		description = fmt.Sprintf("Scheduled job: %s (schedule: %s)", schedule.Job, schedule.ID)
 	}
 
-	j := &job.Job{
-		Name:        schedule.Job,
-		Payload:     schedule.Payload,
-		Priority:    schedule.Priority,
-		Description: description,
-	}
-
 	// Default to normal priority if not specified
-	if j.Priority == "" {
-		j.Priority = job.PriorityNormal
+	priority := schedule.Priority
+	if priority == "" {
+		priority = job.PriorityNormal
 	}
 
+	j := job.NewJob(schedule.Job, schedule.Payload, priority, description)
+
 	if err := cs.queue.Enqueue(ctx, j); err != nil {
 		cs.log.Error("Failed to enqueue scheduled job",
 			"schedule_id", schedule.ID,
@@ -160,6 +235,7 @@ func (cs *CronScheduler) executeSchedule(ctx context.Context, schedule *Schedule
 		cs.updateState(ctx, schedule.ID, &ScheduleState{
 			ID:        schedule.ID,
 			LastRun:   now,
+			LastSlot:  slot,
 			LastError: err.Error(),
 		})
 		return
@@ -170,6 +246,7 @@ func (cs *CronScheduler) executeSchedule(ctx context.Context, schedule *Schedule
 		"job_name", schedule.Job,
 		"job_id", j.ID,
 		"priority", schedule.Priority,
+		"scheduled_for", slot.Format(time.RFC3339),
 		"description", schedule.Description)
 
 	// Calculate next run time
@@ -188,6 +265,7 @@ func (cs *CronScheduler) executeSchedule(ctx context.Context, schedule *Schedule
 		LastRun:     now,
 		NextRun:     nextRun,
 		LastSuccess: now,
+		LastSlot:    slot,
 		RunCount:    runCount,
 		LastError:   "", // Clear error on success
 	})
@@ -198,9 +276,30 @@ func (cs *CronScheduler) executeSchedule(ctx context.Context, schedule *Schedule
 		"run_count", runCount)
 }
 
+// recordMissed records the runs a plan skips, and how far it got, in the schedule state
+func (cs *CronScheduler) recordMissed(ctx context.Context, schedule *Schedule, plan runPlan) {
+	key := cs.stateKey(schedule.ID)
+	pipe := cs.client.TxPipeline()
+	if plan.missed > 0 {
+		cs.log.Warn("Missed scheduled runs",
+			"schedule_id", schedule.ID,
+			"missed", plan.missed,
+			"last_missed", plan.lastMissed.Format(time.RFC3339),
+			"misfire", schedule.misfirePolicy())
+		pipe.HIncrBy(ctx, key, "missed_runs", int64(plan.missed))
+		pipe.HSet(ctx, key, "last_missed", plan.lastMissed.Format(time.RFC3339))
+	}
+	pipe.HSet(ctx, key, "last_slot", plan.lastSlot.Format(time.RFC3339))
+	if _, err := pipe.Exec(ctx); err != nil {
+		cs.log.Error("Failed to record missed runs",
+			"schedule_id", schedule.ID,
+			"error", err)
+	}
+}
+
 // getState retrieves the current state of a schedule from Redis
 func (cs *CronScheduler) getState(ctx context.Context, scheduleID string) (*ScheduleState, error) {
-	key := fmt.Sprintf("bananas:schedules:%s", scheduleID)
+	key := cs.stateKey(scheduleID)
 
 	result, err := cs.client.HGetAll(ctx, key).Result()
 	if err != nil {
@@ -239,6 +338,20 @@ func (cs *CronScheduler) getState(ctx context.Context, scheduleID string) (*Sche
 		}
 	}
 
+	if lastSlot, exists := result["last_slot"]; exists && lastSlot != "" {
+		parsed, err := time.Parse(time.RFC3339, lastSlot)
+		if err == nil {
+			state.LastSlot = parsed
+		}
+	}
+
+	if lastMissed, exists := result["last_missed"]; exists && lastMissed != "" {
+		parsed, err := time.Parse(time.RFC3339, lastMissed)
+		if err == nil {
+			state.LastMissed = parsed
+		}
+	}
+
 	if lastError, exists := result["last_error"]; exists {
 		state.LastError = lastError
 	}
@@ -249,12 +362,18 @@ func (cs *CronScheduler) getState(ctx context.Context, scheduleID string) (*Sche
 		state.RunCount = count
 	}
 
+	if missedRuns, exists := result["missed_runs"]; exists && missedRuns != "" {
+		var count int64
+		fmt.Sscanf(missedRuns, "%d", &count)
+		state.MissedRuns = count
+	}
+
 	return state, nil
 }
 
 // updateState updates the schedule state in Redis
 func (cs *CronScheduler) updateState(ctx context.Context, scheduleID string, state *ScheduleState) error {
-	key := fmt.Sprintf("bananas:schedules:%s", scheduleID)
+	key := cs.stateKey(scheduleID)
 
 	fields := map[string]interface{}{
 		"last_run": state.LastRun.Format(time.RFC3339),
@@ -268,6 +387,10 @@ func (cs *CronScheduler) updateState(ctx context.Context, scheduleID string, sta
 		fields["last_success"] = state.LastSuccess.Format(time.RFC3339)
 	}
 
+	if !state.LastSlot.IsZero() {
+		fields["last_slot"] = state.LastSlot.Format(time.RFC3339)
+	}
+
 	if state.LastError != "" {
 		fields["last_error"] = state.LastError
 	} else {
@@ -280,7 +403,7 @@ func (cs *CronScheduler) updateState(ctx context.Context, scheduleID string, sta
 
 // incrementRunCount increments and returns the run count
 func (cs *CronScheduler) incrementRunCount(ctx context.Context, scheduleID string) int64 {
-	key := fmt.Sprintf("bananas:schedules:%s", scheduleID)
+	key := cs.stateKey(scheduleID)
 	count, err := cs.client.HIncrBy(ctx, key, "run_count", 1).Result()
 	if err != nil {
 		cs.log.Error("Failed to increment run count",
@@ -25,6 +25,11 @@ func (mq *mockQueue) Enqueue(ctx context.Context, j *job.Job) error {
 	return nil
 }
 
+func (mq *mockQueue) Schedule(ctx context.Context, j *job.Job, at time.Time) error {
+	j.ScheduledFor = &at
+	return mq.Enqueue(ctx, j)
+}
+
 func setupCronScheduler(t *testing.T) (*CronScheduler, *Registry, *mockQueue, *redis.Client, *miniredis.Miniredis) {
 	mr, err := miniredis.Run()
 	if err != nil {
@@ -64,7 +69,7 @@ func TestNewCronScheduler(t *testing.T) {
 	}
 }
 
-func TestCronScheduler_ExecuteSchedule(t *testing.T) {
+func TestCronScheduler_RunDue(t *testing.T) {
 	scheduler, registry, q, _, mr := setupCronScheduler(t)
 	defer mr.Close()
 
@@ -82,9 +87,8 @@ func TestCronScheduler_ExecuteSchedule(t *testing.T) {
 
 	registry.MustRegister(schedule)
 
-	// Execute the schedule
-	now := time.Now()
-	scheduler.executeSchedule(ctx, schedule, now)
+	// Run the schedule for the first time
+	scheduler.runDue(ctx, schedule, at(9, 0))
 
 	// Check job was enqueued
 	if len(q.enqueued) != 1 {
@@ -115,20 +119,20 @@ func TestCronScheduler_ExecuteSchedule(t *testing.T) {
 		t.Fatalf("Failed to get state: %v", err)
 	}
 
-	if state.LastRun.IsZero() {
-		t.Error("LastRun was not updated")
+	if !state.LastRun.Equal(at(9, 0)) {
+		t.Errorf("LastRun mismatch: got %v, want %v", state.LastRun, at(9, 0))
 	}
 
-	if state.LastSuccess.IsZero() {
-		t.Error("LastSuccess was not updated")
+	if !state.LastSuccess.Equal(at(9, 0)) {
+		t.Errorf("LastSuccess mismatch: got %v, want %v", state.LastSuccess, at(9, 0))
 	}
 
 	if state.RunCount != 1 {
 		t.Errorf("RunCount mismatch: got %d, want 1", state.RunCount)
 	}
 
-	if state.NextRun.IsZero() {
-		t.Error("NextRun was not calculated")
+	if !state.NextRun.Equal(at(9, 1)) {
+		t.Errorf("NextRun mismatch: got %v, want %v", state.NextRun, at(9, 1))
 	}
 }
 
@@ -148,8 +152,7 @@ func TestCronScheduler_DefaultPriority(t *testing.T) {
 
 	registry.MustRegister(schedule)
 
-	// Execute
-	scheduler.executeSchedule(ctx, schedule, time.Now())
+	scheduler.runDue(ctx, schedule, at(9, 0))
 
 	// Should default to normal priority
 	if len(q.enqueued) != 1 {
@@ -179,8 +182,7 @@ func TestCronScheduler_EnqueueError(t *testing.T) {
 
 	registry.MustRegister(schedule)
 
-	// Execute
-	scheduler.executeSchedule(ctx, schedule, time.Now())
+	scheduler.runDue(ctx, schedule, at(9, 0))
 
 	// Job should not be enqueued
 	if len(q.enqueued) != 0 {
@@ -233,16 +235,16 @@ func TestCronScheduler_DistributedLocking(t *testing.T) {
 
 	registry.MustRegister(schedule)
 
-	// Execute on both schedulers simultaneously
+	// Run on both schedulers simultaneously
 	done := make(chan bool, 2)
 
 	go func() {
-		scheduler1.executeSchedule(ctx, schedule, time.Now())
+		scheduler1.runDue(ctx, schedule, at(9, 0))
 		done <- true
 	}()
 
 	go func() {
-		scheduler2.executeSchedule(ctx, schedule, time.Now())
+		scheduler2.runDue(ctx, schedule, at(9, 0))
 		done <- true
 	}()
 
@@ -274,8 +276,7 @@ func TestCronScheduler_IsDue_NeverRun(t *testing.T) {
 	registry.MustRegister(schedule)
 
 	// First check should be due (never run before)
-	now := time.Now()
-	isDue := scheduler.isDue(ctx, schedule, now)
+	isDue := scheduler.isDue(ctx, schedule, at(9, 0))
 
 	if !isDue {
 		t.Error("Expected schedule to be due on first check")
@@ -298,11 +299,11 @@ func TestCronScheduler_IsDue_RecentlyRun(t *testing.T) {
 	registry.MustRegister(schedule)
 
 	// Set last run to 30 minutes ago
-	lastRun := time.Now().Add(-30 * time.Minute)
+	now := at(9, 45)
+	lastRun := now.Add(-30 * time.Minute)
 	client.HSet(ctx, "bananas:schedules:test_schedule", "last_run", lastRun.Format(time.RFC3339))
 
 	// Should not be due yet
-	now := time.Now()
 	isDue := scheduler.isDue(ctx, schedule, now)
 
 	if isDue {
@@ -326,11 +327,11 @@ func TestCronScheduler_IsDue_PastDue(t *testing.T) {
 	registry.MustRegister(schedule)
 
 	// Set last run to 2 hours ago
-	lastRun := time.Now().Add(-2 * time.Hour)
+	now := at(9, 45)
+	lastRun := now.Add(-2 * time.Hour)
 	client.HSet(ctx, "bananas:schedules:test_schedule", "last_run", lastRun.Format(time.RFC3339))
 
 	// Should be due now
-	now := time.Now()
 	isDue := scheduler.isDue(ctx, schedule, now)
 
 	if !isDue {
@@ -435,7 +436,7 @@ func TestCronScheduler_StateUpdate_ClearsError(t *testing.T) {
 	// First, set an error in state
 	scheduler.updateState(ctx, "test_schedule", &ScheduleState{
 		ID:        "test_schedule",
-		LastRun:   time.Now(),
+		LastRun:   at(9, 0),
 		LastError: "previous error",
 	})
 
@@ -445,8 +446,8 @@ func TestCronScheduler_StateUpdate_ClearsError(t *testing.T) {
 		t.Error("Expected error to be set")
 	}
 
-	// Now execute successfully
-	scheduler.executeSchedule(ctx, schedule, time.Now())
+	// Now run successfully
+	scheduler.runDue(ctx, schedule, at(9, 1))
 
 	// Error should be cleared
 	state, err := scheduler.GetState(ctx, "test_schedule")
@@ -474,9 +475,9 @@ func TestCronScheduler_RunCount_Increment(t *testing.T) {
 
 	registry.MustRegister(schedule)
 
-	// Execute multiple times
+	// Run once a minute
 	for i := 1; i <= 5; i++ {
-		scheduler.executeSchedule(ctx, schedule, time.Now())
+		scheduler.runDue(ctx, schedule, at(9, i))
 
 		state, err := scheduler.GetState(ctx, "test_schedule")
 		if err != nil {
@@ -516,3 +517,93 @@ func TestCronScheduler_Start_Stop(t *testing.T) {
 		t.Error("Scheduler did not stop within timeout")
 	}
 }
+
+func TestCronScheduler_Namespaces(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	registry := NewRegistry()
+	schedule := &Schedule{
+		ID:      "test_schedule",
+		Cron:    "* * * * *",
+		Job:     "test_job",
+		Enabled: true,
+	}
+	registry.MustRegister(schedule)
+
+	acmeQueue := &mockQueue{enqueued: make([]*job.Job, 0)}
+	otherQueue := &mockQueue{enqueued: make([]*job.Job, 0)}
+	acme := NewCronScheduler(registry, acmeQueue, client, 100*time.Millisecond)
+	other := NewCronScheduler(registry, otherQueue, client, 100*time.Millisecond)
+	if err := acme.SetNamespace("acme"); err != nil {
+		t.Fatalf("SetNamespace() error = %v", err)
+	}
+	if err := other.SetNamespace("acme:other"); err == nil {
+		t.Error("Expected error for namespace containing ':'")
+	}
+
+	// Schedules with the same ID in different namespaces neither share state nor a lock
+	ctx := context.Background()
+	mr.Set("bananas:schedule_lock:test_schedule", "held-by-another-scheduler")
+	acme.runDue(ctx, schedule, at(9, 0))
+	other.runDue(ctx, schedule, at(9, 0))
+
+	if len(acmeQueue.enqueued) != 1 {
+		t.Errorf("Expected acme scheduler to enqueue 1 job, got %d", len(acmeQueue.enqueued))
+	}
+	if len(otherQueue.enqueued) != 0 {
+		t.Errorf("Expected default namespace lock to be held, got %d jobs", len(otherQueue.enqueued))
+	}
+	if !mr.Exists("acme:schedules:test_schedule") {
+		t.Error("Expected schedule state under the acme namespace")
+	}
+	if mr.Exists("bananas:schedules:test_schedule") {
+		t.Error("Expected no schedule state in the default namespace")
+	}
+}
+
+func TestCronScheduler_ClusterUsesHashTaggedKeys(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	// miniredis answers CLUSTER SLOTS as a single node owning every slot
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: []string{mr.Addr()},
+	})
+	defer client.Close()
+
+	registry := NewRegistry()
+	schedule := &Schedule{
+		ID:      "test_schedule",
+		Cron:    "* * * * *",
+		Job:     "test_job",
+		Enabled: true,
+	}
+	registry.MustRegister(schedule)
+
+	q := &mockQueue{enqueued: make([]*job.Job, 0)}
+	scheduler := NewCronScheduler(registry, q, client, 100*time.Millisecond)
+	if err := scheduler.SetNamespace("acme"); err != nil {
+		t.Fatalf("SetNamespace() error = %v", err)
+	}
+
+	// The lock is taken and released (a Lua script) in the namespace's slot
+	scheduler.runDue(context.Background(), schedule, at(9, 0))
+
+	if len(q.enqueued) != 1 {
+		t.Errorf("Expected 1 job to be enqueued, got %d", len(q.enqueued))
+	}
+	if !mr.Exists("{acme}:schedules:test_schedule") {
+		t.Errorf("Expected schedule state under the {acme} hash tag, got keys %v", mr.Keys())
+	}
+}
@@ -12,7 +12,7 @@ import (
 // DistributedLock provides Redis-based distributed locking
 // Ensures only one scheduler instance can execute a given schedule at a time
 type DistributedLock struct {
-	client *redis.Client
+	client redis.UniversalClient
 	key    string
 	token  string
 	ttl    time.Duration
@@ -20,7 +20,7 @@ type DistributedLock struct {
 
 // AcquireLock attempts to acquire a distributed lock
 // Returns lock if successful, nil if already locked by another instance
-func AcquireLock(ctx context.Context, client *redis.Client, key string, ttl time.Duration) (*DistributedLock, error) {
+func AcquireLock(ctx context.Context, client redis.UniversalClient, key string, ttl time.Duration) (*DistributedLock, error) {
 	token := uuid.New().String()
 
 	// SETNX: Set if not exists
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// DefaultMisfireThreshold is how late a cron run may start before it counts as missed
+const DefaultMisfireThreshold = time.Minute
+
+// runPlan is what a tick does with the cron times that came due since a schedule
+// was last handled
+type runPlan struct {
+	// runs are the cron times to enqueue a job for, oldest first
+	runs []time.Time
+	// missed counts the cron times skipped by the misfire policy
+	missed int
+	// lastMissed is the latest skipped cron time
+	lastMissed time.Time
+	// lastSlot is the latest time handled by this plan
+	lastSlot time.Time
+}
+
+// miss records a skipped cron time; cron times are skipped oldest first
+func (p *runPlan) miss(slot time.Time) {
+	p.missed++
+	p.lastMissed = slot
+}
+
+// cursor returns the time after which a schedule's next cron time is due
+// immediate is true for a schedule that has never run and has no StartAt: it is
+// due once, at once.
+func (cs *CronScheduler) cursor(schedule *Schedule, state *ScheduleState) (from time.Time, immediate bool) {
+	from = state.LastRun
+	if state.LastSlot.After(from) {
+		from = state.LastSlot
+	}
+	if !from.IsZero() {
+		return from, false
+	}
+	if schedule.StartAt.IsZero() {
+		return time.Time{}, true
+	}
+	// Cron times fall on whole minutes; this makes StartAt itself eligible
+	return schedule.StartAt.Add(-time.Second), false
+}
+
+// planRuns walks the cron times due at now since the schedule was last handled
+// and applies the schedule's misfire policy to them
+//
+// A cron time is missed if it is more than the misfire threshold before now.
+// MisfireFireOnce runs the latest once, MisfireSkip runs only those on time and
+// MisfireFireAll runs the latest MaxCatchUp. Only the runs are kept; skipped cron
+// times are counted. With a catch-up window the walk starts at the window, so runs
+// older than it are skipped without a NextRun call (or a count) for each.
+func (cs *CronScheduler) planRuns(schedule *Schedule, state *ScheduleState, now time.Time) (runPlan, error) {
+	from, immediate := cs.cursor(schedule, state)
+	if immediate {
+		return runPlan{runs: []time.Time{now}, lastSlot: now}, nil
+	}
+
+	var plan runPlan
+	if schedule.CatchUpWindow > 0 {
+		// Cron times fall on whole minutes; this makes the window start itself eligible
+		if start := now.Add(-schedule.CatchUpWindow).Add(-time.Second); start.After(from) {
+			from = start
+			plan.lastSlot = start
+		}
+	}
+
+	policy := schedule.misfirePolicy()
+	for {
+		next, err := cs.registry.NextRun(schedule, from)
+		if err != nil {
+			return runPlan{}, err
+		}
+		// Use 1-second buffer to account for tick timing
+		if next.IsZero() || !next.Before(now.Add(time.Second)) {
+			return plan, nil
+		}
+		plan.lastSlot = next
+		from = next
+
+		switch policy {
+		case MisfireSkip:
+			if now.Sub(next) > cs.misfireThreshold {
+				plan.miss(next)
+			} else {
+				plan.runs = append(plan.runs, next)
+			}
+		case MisfireFireAll:
+			plan.runs = append(plan.runs, next)
+			if len(plan.runs) > schedule.maxCatchUp() {
+				plan.miss(plan.runs[0])
+				plan.runs = plan.runs[1:]
+			}
+		default:
+			if len(plan.runs) > 0 {
+				plan.miss(plan.runs[0])
+			}
+			plan.runs = append(plan.runs[:0], next)
+		}
+	}
+}
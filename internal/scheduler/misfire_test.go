@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// hourly returns an hourly schedule with the given misfire handling
+func hourly(misfire MisfirePolicy) *Schedule {
+	return &Schedule{
+		ID:      "hourly",
+		Cron:    "0 * * * *",
+		Job:     "report",
+		Enabled: true,
+		Misfire: misfire,
+	}
+}
+
+// at returns a time on 2025-01-15 in UTC
+func at(hour, min int) time.Time {
+	return time.Date(2025, 1, 15, hour, min, 0, 0, time.UTC)
+}
+
+// seedState stores a schedule's last run as if an earlier scheduler had run it
+func seedState(t *testing.T, cs *CronScheduler, lastRun time.Time) {
+	t.Helper()
+	err := cs.updateState(context.Background(), "hourly", &ScheduleState{ID: "hourly", LastRun: lastRun, LastSlot: lastRun})
+	if err != nil {
+		t.Fatalf("Failed to seed state: %v", err)
+	}
+}
+
+func TestPlanRuns_Policies(t *testing.T) {
+	scheduler, _, _, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+
+	// Down from 09:00 to 14:10: the 10:00 to 14:00 runs were missed
+	now := at(14, 10)
+	state := &ScheduleState{ID: "hourly", LastRun: at(9, 0)}
+
+	tests := []struct {
+		name         string
+		schedule     *Schedule
+		wantRuns     []time.Time
+		wantMissed   int
+		wantLastSlot time.Time
+	}{
+		{"fire once by default", hourly(""), []time.Time{at(14, 0)}, 4, at(14, 0)},
+		{"skip", hourly(MisfireSkip), nil, 5, at(14, 0)},
+		{"fire all", hourly(MisfireFireAll), []time.Time{at(10, 0), at(11, 0), at(12, 0), at(13, 0), at(14, 0)}, 0, at(14, 0)},
+		{"fire all up to max", &Schedule{ID: "hourly", Cron: "0 * * * *", Job: "report", Misfire: MisfireFireAll, MaxCatchUp: 2}, []time.Time{at(13, 0), at(14, 0)}, 3, at(14, 0)},
+		// Runs before the window aren't walked or counted
+		{"window", &Schedule{ID: "hourly", Cron: "0 * * * *", Job: "report", Misfire: MisfireFireAll, CatchUpWindow: 150 * time.Minute}, []time.Time{at(12, 0), at(13, 0), at(14, 0)}, 0, at(14, 0)},
+		{"window start is eligible", &Schedule{ID: "hourly", Cron: "0 * * * *", Job: "report", Misfire: MisfireFireAll, CatchUpWindow: 130 * time.Minute}, []time.Time{at(12, 0), at(13, 0), at(14, 0)}, 0, at(14, 0)},
+		{"nothing in window", &Schedule{ID: "hourly", Cron: "0 * * * *", Job: "report", CatchUpWindow: 5 * time.Minute}, nil, 0, at(14, 4).Add(59 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := scheduler.planRuns(tt.schedule, state, now)
+			if err != nil {
+				t.Fatalf("Failed to plan runs: %v", err)
+			}
+
+			if len(plan.runs) != len(tt.wantRuns) {
+				t.Fatalf("Expected runs %v, got %v", tt.wantRuns, plan.runs)
+			}
+			for i, run := range plan.runs {
+				if !run.Equal(tt.wantRuns[i]) {
+					t.Errorf("Expected runs %v, got %v", tt.wantRuns, plan.runs)
+				}
+			}
+			if plan.missed != tt.wantMissed {
+				t.Errorf("Expected %d missed runs, got %d", tt.wantMissed, plan.missed)
+			}
+			if !plan.lastSlot.Equal(tt.wantLastSlot) {
+				t.Errorf("Expected last slot %v, got %v", tt.wantLastSlot, plan.lastSlot)
+			}
+		})
+	}
+}
+
+func TestPlanRuns_SkipKeepsOnTimeRuns(t *testing.T) {
+	scheduler, _, _, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+
+	schedule := hourly(MisfireSkip)
+	state := &ScheduleState{ID: "hourly", LastRun: at(13, 0)}
+
+	plan, _ := scheduler.planRuns(schedule, state, at(14, 0).Add(20*time.Second))
+	if len(plan.runs) != 1 || plan.missed != 0 {
+		t.Errorf("Expected the 14:00 run within the threshold, got runs %v and missed %v", plan.runs, plan.missed)
+	}
+}
+
+func TestRunDue_RecordsMissedRuns(t *testing.T) {
+	scheduler, registry, q, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	schedule := hourly(MisfireSkip)
+	registry.MustRegister(schedule)
+	seedState(t, scheduler, at(9, 0))
+
+	scheduler.runDue(ctx, schedule, at(14, 10))
+
+	if len(q.enqueued) != 0 {
+		t.Errorf("Expected no jobs for skipped runs, got %d", len(q.enqueued))
+	}
+	state, err := scheduler.GetState(ctx, "hourly")
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if state.MissedRuns != 5 {
+		t.Errorf("Expected 5 missed runs, got %d", state.MissedRuns)
+	}
+	if !state.LastMissed.Equal(at(14, 0)) || !state.LastSlot.Equal(at(14, 0)) {
+		t.Errorf("Expected last missed and last slot 14:00, got %v and %v", state.LastMissed, state.LastSlot)
+	}
+
+	// The missed runs are handled: the next run is 15:00
+	scheduler.runDue(ctx, schedule, at(14, 30))
+	scheduler.runDue(ctx, schedule, at(15, 0))
+	if len(q.enqueued) != 1 {
+		t.Errorf("Expected only the 15:00 run, got %d jobs", len(q.enqueued))
+	}
+}
+
+func TestRunDue_FireAllEnqueuesEachRun(t *testing.T) {
+	scheduler, registry, q, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	schedule := hourly(MisfireFireAll)
+	registry.MustRegister(schedule)
+	seedState(t, scheduler, at(11, 0))
+
+	scheduler.runDue(ctx, schedule, at(14, 10))
+	scheduler.runDue(ctx, schedule, at(14, 10))
+
+	if len(q.enqueued) != 3 {
+		t.Fatalf("Expected the 12:00, 13:00 and 14:00 runs once each, got %d jobs", len(q.enqueued))
+	}
+	ids := make(map[string]bool)
+	for _, j := range q.enqueued {
+		ids[j.ID] = true
+	}
+	if len(ids) != 3 || ids[""] {
+		t.Errorf("Expected distinct job IDs, got %v", ids)
+	}
+
+	state, _ := scheduler.GetState(ctx, "hourly")
+	if state.RunCount != 3 || state.MissedRuns != 0 {
+		t.Errorf("Expected 3 runs and none missed, got %d and %d", state.RunCount, state.MissedRuns)
+	}
+}
+
+func TestRunDue_StartAt(t *testing.T) {
+	scheduler, registry, q, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	schedule := hourly("")
+	schedule.StartAt = at(15, 0)
+	registry.MustRegister(schedule)
+
+	if scheduler.isDue(ctx, schedule, at(14, 10)) {
+		t.Error("Expected a schedule not to be due before its start time")
+	}
+	if !scheduler.isDue(ctx, schedule, at(15, 0)) {
+		t.Error("Expected a schedule to be due at its start time")
+	}
+
+	scheduler.runDue(ctx, schedule, at(15, 0))
+	if len(q.enqueued) != 1 {
+		t.Fatalf("Expected the first run at the start time, got %d jobs", len(q.enqueued))
+	}
+	if state, _ := scheduler.GetState(ctx, "hourly"); state.MissedRuns != 0 {
+		t.Errorf("Expected no missed runs before the start time, got %d", state.MissedRuns)
+	}
+}
+
+func TestRunDue_CatchUpWindowSkipsLongOutage(t *testing.T) {
+	scheduler, registry, q, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	// Every minute, down for 30 days: only the last 5 minutes are walked
+	schedule := &Schedule{ID: "hourly", Cron: "* * * * *", Job: "report", Enabled: true, CatchUpWindow: 5 * time.Minute}
+	registry.MustRegister(schedule)
+	seedState(t, scheduler, at(14, 0).AddDate(0, 0, -30))
+
+	scheduler.runDue(ctx, schedule, at(14, 0))
+
+	if len(q.enqueued) != 1 {
+		t.Fatalf("Expected one run for the outage, got %d jobs", len(q.enqueued))
+	}
+	state, _ := scheduler.GetState(ctx, "hourly")
+	if state.MissedRuns != 5 {
+		t.Errorf("Expected the 5 runs inside the window before 14:00 to be missed, got %d", state.MissedRuns)
+	}
+	if !state.LastSlot.Equal(at(14, 0)) {
+		t.Errorf("Expected last slot 14:00, got %v", state.LastSlot)
+	}
+}
+
+func TestRunDue_NothingInWindowAdvancesState(t *testing.T) {
+	scheduler, registry, q, _, mr := setupCronScheduler(t)
+	defer mr.Close()
+	ctx := context.Background()
+
+	schedule := hourly("")
+	schedule.CatchUpWindow = 5 * time.Minute
+	registry.MustRegister(schedule)
+	seedState(t, scheduler, at(9, 0))
+
+	scheduler.runDue(ctx, schedule, at(14, 10))
+
+	if len(q.enqueued) != 0 {
+		t.Errorf("Expected no runs outside the window, got %d jobs", len(q.enqueued))
+	}
+	if scheduler.isDue(ctx, schedule, at(14, 20)) {
+		t.Error("Expected the schedule not to be due again before 15:00")
+	}
+	if !scheduler.isDue(ctx, schedule, at(15, 0)) {
+		t.Error("Expected the schedule to be due at 15:00")
+	}
+}
@@ -1,12 +1,14 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"sync"
 	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/logger"
 	"github.com/robfig/cron/v3"
 )
 
@@ -20,12 +22,15 @@ type Registry struct {
 	mu        sync.RWMutex
 	schedules map[string]*Schedule
 	parser    cron.Parser
+	// stored holds the IDs of schedules loaded from a Store
+	stored map[string]bool
 }
 
 // NewRegistry creates a new schedule registry
 func NewRegistry() *Registry {
 	return &Registry{
 		schedules: make(map[string]*Schedule),
+		stored:    make(map[string]bool),
 		parser:    cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow),
 	}
 }
@@ -89,6 +94,85 @@ func (r *Registry) Count() int {
 	return len(r.schedules)
 }
 
+// Load replaces the schedules previously loaded from a store with the ones it holds now
+//
+// Schedules registered in code are kept and win over stored schedules with the same
+// ID. Stored schedules that are invalid here (e.g. an unknown timezone) are skipped
+// with an error log. Returns the version of the store that was loaded (see Watch).
+func (r *Registry) Load(ctx context.Context, store *Store) (int64, error) {
+	// Read the version first: a change made meanwhile is picked up by the next load
+	version, err := store.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	schedules, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	log := logger.Default().WithComponent(logger.ComponentScheduler)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id := range r.stored {
+		delete(r.schedules, id)
+	}
+	r.stored = make(map[string]bool, len(schedules))
+
+	for _, schedule := range schedules {
+		if _, exists := r.schedules[schedule.ID]; exists {
+			log.Error("Stored schedule conflicts with a registered schedule, skipping",
+				"schedule_id", schedule.ID)
+			continue
+		}
+		if err := r.validate(schedule); err != nil {
+			log.Error("Invalid stored schedule, skipping",
+				"schedule_id", schedule.ID,
+				"error", err)
+			continue
+		}
+		loaded := *schedule
+		if loaded.Timezone == "" {
+			loaded.Timezone = "UTC"
+		}
+		r.schedules[loaded.ID] = &loaded
+		r.stored[loaded.ID] = true
+	}
+	return version, nil
+}
+
+// Watch reloads the store's schedules every interval if they changed since the
+// version loaded (as returned by Load; pass -1 to load them first)
+// It blocks until ctx is cancelled, so every scheduler instance picks up schedules
+// saved or deleted at runtime without restarting.
+func (r *Registry) Watch(ctx context.Context, store *Store, interval time.Duration, loaded int64) {
+	log := logger.Default().WithComponent(logger.ComponentScheduler)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		version, err := store.Version(ctx)
+		if err != nil {
+			log.Error("Failed to check stored schedules", "error", err)
+		} else if version != loaded {
+			if version, err = r.Load(ctx, store); err != nil {
+				log.Error("Failed to load stored schedules", "error", err)
+			} else {
+				loaded = version
+				log.Info("Stored schedules loaded", "schedules", r.Count())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // NextRun calculates the next run time for a schedule
 func (r *Registry) NextRun(schedule *Schedule, after time.Time) (time.Time, error) {
 	cronSchedule, err := r.parser.Parse(schedule.Cron)
@@ -153,5 +237,18 @@ func (r *Registry) validate(schedule *Schedule) error {
 		}
 	}
 
+	// Validate misfire handling
+	switch schedule.Misfire {
+	case "", MisfireFireOnce, MisfireSkip, MisfireFireAll:
+	default:
+		return fmt.Errorf("invalid misfire policy %q", schedule.Misfire)
+	}
+	if schedule.MaxCatchUp < 0 {
+		return fmt.Errorf("max catch-up cannot be negative")
+	}
+	if schedule.CatchUpWindow < 0 {
+		return fmt.Errorf("catch-up window cannot be negative")
+	}
+
 	return nil
 }
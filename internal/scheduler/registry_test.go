@@ -448,3 +448,25 @@ func TestRegister_ValidPriorities(t *testing.T) {
 		}
 	}
 }
+
+func TestRegister_InvalidMisfire(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *Schedule
+	}{
+		{"unknown policy", &Schedule{Misfire: "fire_twice"}},
+		{"negative max catch-up", &Schedule{Misfire: MisfireFireAll, MaxCatchUp: -1}},
+		{"negative window", &Schedule{CatchUpWindow: -time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.schedule.ID = "test_schedule"
+			tt.schedule.Cron = "0 * * * *"
+			tt.schedule.Job = "test_job"
+			if err := NewRegistry().Register(tt.schedule); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -9,7 +9,7 @@ import (
 // Schedule represents a periodic task schedule
 type Schedule struct {
 	// ID is a unique identifier for the schedule
-	ID string
+	ID string `json:"id"`
 
 	// Cron expression (standard 5-field: minute hour day month weekday)
 	// Examples:
@@ -17,26 +17,74 @@ type Schedule struct {
 	//   "*/15 * * * *"  - Every 15 minutes
 	//   "0 9 * * 1"     - Every Monday at 9:00 AM
 	//   "0 0 1 * *"     - First day of every month at midnight
-	Cron string
+	Cron string `json:"cron"`
 
 	// Job name (must be registered with worker)
-	Job string
+	Job string `json:"job"`
 
 	// Payload is the job payload (JSON bytes)
-	Payload []byte
+	Payload []byte `json:"payload,omitempty"`
 
 	// Priority for the enqueued job
-	Priority job.JobPriority
+	Priority job.JobPriority `json:"priority,omitempty"`
 
 	// Timezone for cron evaluation (default: UTC)
 	// Must be a valid IANA timezone (e.g., "America/New_York", "UTC")
-	Timezone string
+	Timezone string `json:"timezone,omitempty"`
 
 	// Enabled flag (allows disabling without removing)
-	Enabled bool
+	Enabled bool `json:"enabled"`
 
 	// Description for logging/monitoring
-	Description string
+	Description string `json:"description,omitempty"`
+
+	// Misfire decides what happens to runs missed while no scheduler was running
+	// (default: MisfireFireOnce)
+	Misfire MisfirePolicy `json:"misfire,omitempty"`
+
+	// MaxCatchUp limits how many missed runs MisfireFireAll enqueues; the most
+	// recent ones are kept (default: DefaultMaxCatchUp)
+	MaxCatchUp int `json:"max_catch_up,omitempty"`
+
+	// CatchUpWindow is how far back missed runs are caught up; older runs are
+	// skipped whatever the policy and aren't counted in MissedRuns (0: no limit)
+	CatchUpWindow time.Duration `json:"catch_up_window,omitempty"`
+
+	// StartAt is when the schedule starts: its first run is the first cron time at
+	// or after StartAt. If zero, a schedule that has never run runs immediately.
+	StartAt time.Time `json:"start_at,omitempty"`
+}
+
+// MisfirePolicy decides what happens to cron runs that were missed, e.g. because
+// no scheduler was running at the time
+type MisfirePolicy string
+
+const (
+	// MisfireFireOnce runs once for all missed runs (the default)
+	MisfireFireOnce MisfirePolicy = "fire_once"
+	// MisfireSkip skips missed runs and waits for the next cron time
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireFireAll runs every missed run, up to MaxCatchUp
+	MisfireFireAll MisfirePolicy = "fire_all"
+)
+
+// DefaultMaxCatchUp is how many missed runs MisfireFireAll enqueues when MaxCatchUp is 0
+const DefaultMaxCatchUp = 10
+
+// misfirePolicy returns the schedule's misfire policy, defaulting to MisfireFireOnce
+func (s *Schedule) misfirePolicy() MisfirePolicy {
+	if s.Misfire == "" {
+		return MisfireFireOnce
+	}
+	return s.Misfire
+}
+
+// maxCatchUp returns how many missed runs MisfireFireAll enqueues
+func (s *Schedule) maxCatchUp() int {
+	if s.MaxCatchUp <= 0 {
+		return DefaultMaxCatchUp
+	}
+	return s.MaxCatchUp
 }
 
 // ScheduleState represents the runtime state of a schedule
@@ -47,4 +95,10 @@ type ScheduleState struct {
 	RunCount    int64
 	LastError   string
 	LastSuccess time.Time
+	// LastSlot is the time up to which the scheduler has handled cron times, run or missed
+	LastSlot time.Time
+	// MissedRuns counts the cron times skipped by the misfire policy within the catch-up window
+	MissedRuns int64
+	// LastMissed is the latest cron time that was skipped
+	LastMissed time.Time
 }
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/muaviaUsmani/bananas/internal/namespace"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrScheduleNotFound is returned for a schedule ID that isn't stored
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrInvalidSchedule is wrapped by the error Store.Save returns for an invalid schedule
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// Store persists schedules in Redis so they can be changed at runtime
+//
+// Schedules are kept as JSON in one hash (bananas:schedule_defs in the default
+// namespace) with a version counter bumped on every change, which registries
+// poll to pick up changes (see Registry.Watch).
+type Store struct {
+	client    redis.UniversalClient
+	prefix    string
+	validator *Registry
+}
+
+// NewStore creates a schedule store
+func NewStore(client redis.UniversalClient) *Store {
+	return &Store{
+		client:    client,
+		prefix:    redisconn.KeyPrefix(client, namespace.Default),
+		validator: NewRegistry(),
+	}
+}
+
+// SetNamespace stores schedules in a namespace's keyspace
+// It must match the namespace of the schedulers running them.
+func (s *Store) SetNamespace(ns string) error {
+	if err := namespace.Validate(ns); err != nil {
+		return err
+	}
+	s.prefix = redisconn.KeyPrefix(s.client, ns)
+	return nil
+}
+
+// definitionsKey returns the hash holding the stored schedules
+func (s *Store) definitionsKey() string {
+	return s.prefix + "schedule_defs"
+}
+
+// versionKey returns the counter bumped on every change to the stored schedules
+func (s *Store) versionKey() string {
+	return s.prefix + "schedule_defs:version"
+}
+
+// Save creates or replaces a schedule
+// The schedule is validated like Registry.Register; the timezone defaults to UTC.
+func (s *Store) Save(ctx context.Context, schedule *Schedule) error {
+	if err := s.validator.validate(schedule); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	stored := *schedule
+	if stored.Timezone == "" {
+		stored.Timezone = "UTC"
+	}
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.definitionsKey(), stored.ID, data)
+	pipe.Incr(ctx, s.versionKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+// Get returns a stored schedule
+func (s *Store) Get(ctx context.Context, id string) (*Schedule, error) {
+	data, err := s.client.HGet(ctx, s.definitionsKey(), id).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	var schedule Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule %s: %w", id, err)
+	}
+	return &schedule, nil
+}
+
+// List returns the stored schedules, sorted by ID
+func (s *Store) List(ctx context.Context) ([]*Schedule, error) {
+	result, err := s.client.HGetAll(ctx, s.definitionsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	schedules := make([]*Schedule, 0, len(result))
+	for id, data := range result {
+		var schedule Schedule
+		if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schedule %s: %w", id, err)
+		}
+		schedules = append(schedules, &schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+// Delete removes a stored schedule and its runtime state
+func (s *Store) Delete(ctx context.Context, id string) error {
+	pipe := s.client.TxPipeline()
+	deleted := pipe.HDel(ctx, s.definitionsKey(), id)
+	pipe.Del(ctx, s.prefix+"schedules:"+id) // CronScheduler state
+	pipe.Incr(ctx, s.versionKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	if deleted.Val() == 0 {
+		return fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	return nil
+}
+
+// Version returns a counter that changes whenever a schedule is saved or deleted
+func (s *Store) Version(ctx context.Context) (int64, error) {
+	version, err := s.client.Get(ctx, s.versionKey()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schedule version: %w", err)
+	}
+	return version, nil
+}
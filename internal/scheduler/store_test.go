@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewStore(client), mr
+}
+
+func TestStore_CRUD(t *testing.T) {
+	store, _ := setupStore(t)
+	ctx := context.Background()
+
+	schedule := &Schedule{
+		ID:            "nightly",
+		Cron:          "0 3 * * *",
+		Job:           "cleanup",
+		Payload:       []byte(`{"days":30}`),
+		Enabled:       true,
+		Misfire:       MisfireSkip,
+		CatchUpWindow: time.Hour,
+	}
+	if err := store.Save(ctx, schedule); err != nil {
+		t.Fatalf("Failed to save schedule: %v", err)
+	}
+	if err := store.Save(ctx, &Schedule{ID: "hourly", Cron: "0 * * * *", Job: "report"}); err != nil {
+		t.Fatalf("Failed to save schedule: %v", err)
+	}
+
+	got, err := store.Get(ctx, "nightly")
+	if err != nil {
+		t.Fatalf("Failed to get schedule: %v", err)
+	}
+	if got.Cron != "0 3 * * *" || string(got.Payload) != `{"days":30}` || got.Misfire != MisfireSkip || got.CatchUpWindow != time.Hour {
+		t.Errorf("Schedule did not round-trip: %+v", got)
+	}
+	if got.Timezone != "UTC" {
+		t.Errorf("Expected default timezone UTC, got %s", got.Timezone)
+	}
+	if schedule.Timezone != "" {
+		t.Errorf("Expected the caller's schedule to be unchanged, got timezone %s", schedule.Timezone)
+	}
+
+	schedules, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list schedules: %v", err)
+	}
+	if len(schedules) != 2 || schedules[0].ID != "hourly" || schedules[1].ID != "nightly" {
+		t.Errorf("Expected schedules sorted by ID, got %v", schedules)
+	}
+
+	if err := store.Delete(ctx, "nightly"); err != nil {
+		t.Fatalf("Failed to delete schedule: %v", err)
+	}
+	if _, err := store.Get(ctx, "nightly"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, "nightly"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected ErrScheduleNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestStore_RejectsInvalidSchedule(t *testing.T) {
+	store, mr := setupStore(t)
+
+	err := store.Save(context.Background(), &Schedule{ID: "bad", Cron: "every hour", Job: "report"})
+	if !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("Expected ErrInvalidSchedule, got %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("Expected nothing stored, got %v", keys)
+	}
+}
+
+func TestStore_VersionChangesOnWrite(t *testing.T) {
+	store, _ := setupStore(t)
+	ctx := context.Background()
+
+	v0, _ := store.Version(ctx)
+	store.Save(ctx, &Schedule{ID: "hourly", Cron: "0 * * * *", Job: "report"})
+	v1, _ := store.Version(ctx)
+	store.Delete(ctx, "hourly")
+	v2, _ := store.Version(ctx)
+
+	if v0 == v1 || v1 == v2 {
+		t.Errorf("Expected the version to change on every write, got %d, %d, %d", v0, v1, v2)
+	}
+}
+
+func TestRegistry_Load(t *testing.T) {
+	store, _ := setupStore(t)
+	ctx := context.Background()
+
+	registry := NewRegistry()
+	registry.MustRegister(&Schedule{ID: "static", Cron: "0 * * * *", Job: "static_job"})
+
+	store.Save(ctx, &Schedule{ID: "static", Cron: "* * * * *", Job: "stored_job"})
+	store.Save(ctx, &Schedule{ID: "dynamic", Cron: "*/5 * * * *", Job: "report"})
+	loaded, err := registry.Load(ctx, store)
+	if err != nil {
+		t.Fatalf("Failed to load schedules: %v", err)
+	}
+	if version, _ := store.Version(ctx); loaded != version {
+		t.Errorf("Expected loaded version %d, got %d", version, loaded)
+	}
+
+	if registry.Count() != 2 {
+		t.Errorf("Expected 2 schedules, got %d", registry.Count())
+	}
+	if s, _ := registry.Get("static"); s.Job != "static_job" {
+		t.Errorf("Expected the registered schedule to win, got job %s", s.Job)
+	}
+
+	// Updates replace the loaded schedule; deletes remove it
+	store.Save(ctx, &Schedule{ID: "dynamic", Cron: "*/10 * * * *", Job: "report"})
+	registry.Load(ctx, store)
+	if s, _ := registry.Get("dynamic"); s.Cron != "*/10 * * * *" {
+		t.Errorf("Expected the updated cron, got %s", s.Cron)
+	}
+	store.Delete(ctx, "dynamic")
+	registry.Load(ctx, store)
+	if _, ok := registry.Get("dynamic"); ok {
+		t.Error("Expected the deleted schedule to be removed")
+	}
+	if _, ok := registry.Get("static"); !ok {
+		t.Error("Expected the registered schedule to be kept")
+	}
+}
+
+func TestRegistry_Watch(t *testing.T) {
+	store, _ := setupStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := NewRegistry()
+	go registry.Watch(ctx, store, 10*time.Millisecond, -1)
+
+	store.Save(ctx, &Schedule{ID: "dynamic", Cron: "*/5 * * * *", Job: "report", Enabled: true})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for registry.Count() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the saved schedule to be picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
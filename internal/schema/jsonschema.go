@@ -0,0 +1,388 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+)
+
+// JSONSchema validates JSON payloads against a JSON Schema document
+//
+// The commonly used validation keywords are supported: type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum and
+// exclusiveMaximum. Other keywords ($ref, oneOf, format, ...) are ignored.
+type JSONSchema struct {
+	root *jsonNode
+}
+
+// jsonNode is one compiled (sub)schema
+type jsonNode struct {
+	types                []string
+	enum                 []interface{}
+	constValue           interface{}
+	hasConst             bool
+	properties           map[string]*jsonNode
+	required             []string
+	additionalProperties *jsonNode
+	noAdditional         bool
+	items                *jsonNode
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	exclusiveMin         *float64
+	exclusiveMax         *float64
+}
+
+// jsonSchemaDoc is the subset of a JSON Schema document that is compiled
+type jsonSchemaDoc struct {
+	Type                 json.RawMessage            `json:"type"`
+	Enum                 []interface{}              `json:"enum"`
+	Const                json.RawMessage            `json:"const"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              *string                    `json:"pattern"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	ExclusiveMinimum     *float64                   `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64                   `json:"exclusiveMaximum"`
+}
+
+var jsonTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// NewJSONSchema compiles a JSON Schema document
+//
+// Example:
+//
+//	s, err := schema.NewJSONSchema([]byte(`{
+//	    "type": "object",
+//	    "required": ["to"],
+//	    "properties": {"to": {"type": "string", "minLength": 3}}
+//	}`))
+func NewJSONSchema(doc []byte) (*JSONSchema, error) {
+	root, err := compileJSONNode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return &JSONSchema{root: root}, nil
+}
+
+// MustJSONSchema is like NewJSONSchema but panics on an invalid document
+func MustJSONSchema(doc string) *JSONSchema {
+	s, err := NewJSONSchema([]byte(doc))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func compileJSONNode(raw json.RawMessage) (*jsonNode, error) {
+	var doc jsonSchemaDoc
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	n := &jsonNode{
+		enum:         doc.Enum,
+		required:     doc.Required,
+		minItems:     doc.MinItems,
+		maxItems:     doc.MaxItems,
+		minLength:    doc.MinLength,
+		maxLength:    doc.MaxLength,
+		minimum:      doc.Minimum,
+		maximum:      doc.Maximum,
+		exclusiveMin: doc.ExclusiveMinimum,
+		exclusiveMax: doc.ExclusiveMaximum,
+	}
+
+	if len(doc.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(doc.Type, &single); err == nil {
+			n.types = []string{single}
+		} else if err := json.Unmarshal(doc.Type, &n.types); err != nil {
+			return nil, fmt.Errorf("type must be a string or an array of strings")
+		}
+		for _, t := range n.types {
+			if !jsonTypes[t] {
+				return nil, fmt.Errorf("unknown type %q", t)
+			}
+		}
+	}
+
+	if len(doc.Const) > 0 {
+		if err := decodeJSON(doc.Const, &n.constValue); err != nil {
+			return nil, err
+		}
+		n.hasConst = true
+	}
+
+	if len(n.enum) > 0 {
+		// Re-decode with UseNumber so enum values compare equal to payload values
+		var enum []interface{}
+		data, _ := json.Marshal(doc.Enum)
+		if err := decodeJSON(data, &enum); err != nil {
+			return nil, err
+		}
+		n.enum = enum
+	}
+
+	if len(doc.Properties) > 0 {
+		n.properties = make(map[string]*jsonNode, len(doc.Properties))
+		for name, sub := range doc.Properties {
+			child, err := compileJSONNode(sub)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %w", name, err)
+			}
+			n.properties[name] = child
+		}
+	}
+
+	if len(doc.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(doc.AdditionalProperties, &allowed); err == nil {
+			n.noAdditional = !allowed
+		} else {
+			child, err := compileJSONNode(doc.AdditionalProperties)
+			if err != nil {
+				return nil, fmt.Errorf("additionalProperties: %w", err)
+			}
+			n.additionalProperties = child
+		}
+	}
+
+	if len(doc.Items) > 0 {
+		child, err := compileJSONNode(doc.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		n.items = child
+	}
+
+	if doc.Pattern != nil {
+		re, err := regexp.Compile(*doc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		n.pattern = re
+	}
+
+	return n, nil
+}
+
+// Validate checks a JSON payload (with or without the JSON format prefix)
+func (s *JSONSchema) Validate(payload []byte) error {
+	format, data, err := serialization.NewJSONSerializer().DetectFormat(payload)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	if format != serialization.FormatJSON {
+		return invalid("expected a JSON payload")
+	}
+
+	var v interface{}
+	if err := decodeJSON(data, &v); err != nil {
+		return invalid("malformed JSON: %v", err)
+	}
+
+	return s.root.validate("$", v)
+}
+
+func (n *jsonNode) validate(path string, v interface{}) error {
+	if len(n.types) > 0 && !n.matchesType(v) {
+		return invalid("%s: expected %s, got %s", path, strings.Join(n.types, " or "), jsonTypeOf(v))
+	}
+
+	if n.hasConst && !jsonEqual(v, n.constValue) {
+		return invalid("%s: must be %v", path, n.constValue)
+	}
+
+	if len(n.enum) > 0 {
+		found := false
+		for _, e := range n.enum {
+			if jsonEqual(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return invalid("%s: must be one of %v", path, n.enum)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return n.validateObject(path, val)
+	case []interface{}:
+		return n.validateArray(path, val)
+	case string:
+		return n.validateString(path, val)
+	case json.Number:
+		return n.validateNumber(path, val)
+	}
+	return nil
+}
+
+func (n *jsonNode) validateObject(path string, obj map[string]interface{}) error {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			return invalid("%s: missing required property %q", path, name)
+		}
+	}
+
+	// Sorted for deterministic error messages
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child, known := n.properties[name]
+		switch {
+		case known:
+		case n.additionalProperties != nil:
+			child = n.additionalProperties
+		case n.noAdditional:
+			return invalid("%s: unexpected property %q", path, name)
+		default:
+			continue
+		}
+		if err := child.validate(path+"."+name, obj[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *jsonNode) validateArray(path string, arr []interface{}) error {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		return invalid("%s: must have at least %d items", path, *n.minItems)
+	}
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		return invalid("%s: must have at most %d items", path, *n.maxItems)
+	}
+	if n.items != nil {
+		for i, item := range arr {
+			if err := n.items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (n *jsonNode) validateString(path, s string) error {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		return invalid("%s: must be at least %d characters", path, *n.minLength)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		return invalid("%s: must be at most %d characters", path, *n.maxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		return invalid("%s: must match pattern %s", path, n.pattern)
+	}
+	return nil
+}
+
+func (n *jsonNode) validateNumber(path string, num json.Number) error {
+	f, err := num.Float64()
+	if err != nil {
+		return invalid("%s: invalid number %s", path, num)
+	}
+	if n.minimum != nil && f < *n.minimum {
+		return invalid("%s: must be >= %v", path, *n.minimum)
+	}
+	if n.maximum != nil && f > *n.maximum {
+		return invalid("%s: must be <= %v", path, *n.maximum)
+	}
+	if n.exclusiveMin != nil && f <= *n.exclusiveMin {
+		return invalid("%s: must be > %v", path, *n.exclusiveMin)
+	}
+	if n.exclusiveMax != nil && f >= *n.exclusiveMax {
+		return invalid("%s: must be < %v", path, *n.exclusiveMax)
+	}
+	return nil
+}
+
+func (n *jsonNode) matchesType(v interface{}) bool {
+	actual := jsonTypeOf(v)
+	for _, t := range n.types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf returns the JSON Schema type name of a decoded value
+func jsonTypeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+	ea, _ := json.Marshal(a)
+	eb, _ := json.Marshal(b)
+	return bytes.Equal(ea, eb)
+}
+
+// decodeJSON decodes preserving number precision
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const emailSchema = `{
+	"type": "object",
+	"required": ["to", "subject"],
+	"additionalProperties": false,
+	"properties": {
+		"to": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"subject": {"type": "string", "minLength": 1, "maxLength": 20},
+		"priority": {"type": "integer", "minimum": 1, "maximum": 5},
+		"kind": {"enum": ["welcome", "reset"]},
+		"cc": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"meta": {"type": "object", "additionalProperties": {"type": ["string", "null"]}}
+	}
+}`
+
+func TestJSONSchema_Valid(t *testing.T) {
+	s := MustJSONSchema(emailSchema)
+
+	payloads := []string{
+		`{"to": "a@b.c", "subject": "hi"}`,
+		`{"to": "a@b.c", "subject": "hi", "priority": 3, "kind": "reset"}`,
+		`{"to": "a@b.c", "subject": "hi", "cc": ["x@y.z"], "meta": {"k": "v", "n": null}}`,
+	}
+
+	for _, p := range payloads {
+		if err := s.Validate([]byte(p)); err != nil {
+			t.Errorf("expected %s to be valid, got %v", p, err)
+		}
+	}
+}
+
+func TestJSONSchema_FormatPrefixedPayload(t *testing.T) {
+	s := MustJSONSchema(emailSchema)
+
+	payload := append([]byte{0x00}, []byte(`{"to": "a@b.c", "subject": "hi"}`)...)
+	if err := s.Validate(payload); err != nil {
+		t.Errorf("expected prefixed JSON payload to be valid, got %v", err)
+	}
+}
+
+func TestJSONSchema_Invalid(t *testing.T) {
+	s := MustJSONSchema(emailSchema)
+
+	cases := []struct {
+		payload string
+		message string
+	}{
+		{`[]`, "$: expected object, got array"},
+		{`{"to": "a@b.c"}`, `$: missing required property "subject"`},
+		{`{"to": 7, "subject": "hi"}`, "$.to: expected string, got integer"},
+		{`{"to": "nope", "subject": "hi"}`, "$.to: must match pattern"},
+		{`{"to": "a@b.c", "subject": ""}`, "$.subject: must be at least 1 characters"},
+		{`{"to": "a@b.c", "subject": "hi", "priority": 2.5}`, "$.priority: expected integer, got number"},
+		{`{"to": "a@b.c", "subject": "hi", "priority": 9}`, "$.priority: must be <= 5"},
+		{`{"to": "a@b.c", "subject": "hi", "kind": "spam"}`, "$.kind: must be one of"},
+		{`{"to": "a@b.c", "subject": "hi", "cc": ["a", "b", "c"]}`, "$.cc: must have at most 2 items"},
+		{`{"to": "a@b.c", "subject": "hi", "cc": [1]}`, "$.cc[0]: expected string"},
+		{`{"to": "a@b.c", "subject": "hi", "meta": {"k": 1}}`, "$.meta.k: expected string or null"},
+		{`{"to": "a@b.c", "subject": "hi", "extra": true}`, `$: unexpected property "extra"`},
+		{`{"to": "a@b.c",`, "malformed JSON"},
+	}
+
+	for _, tc := range cases {
+		err := s.Validate([]byte(tc.payload))
+		if !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("expected ErrInvalidPayload for %s, got %v", tc.payload, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("expected error for %s to contain %q, got %q", tc.payload, tc.message, err)
+		}
+	}
+}
+
+func TestJSONSchema_RejectsProtobufPayload(t *testing.T) {
+	s := MustJSONSchema(`{"type": "object"}`)
+
+	if err := s.Validate([]byte{0x01, 0x0a, 0x01, 'x'}); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("expected ErrInvalidPayload for protobuf payload, got %v", err)
+	}
+}
+
+func TestNewJSONSchema_InvalidDocuments(t *testing.T) {
+	docs := []string{
+		`not json`,
+		`{"type": "float"}`,
+		`{"type": 5}`,
+		`{"pattern": "("}`,
+		`{"properties": {"a": {"type": "nope"}}}`,
+	}
+
+	for _, doc := range docs {
+		if _, err := NewJSONSchema([]byte(doc)); err == nil {
+			t.Errorf("expected error compiling %s", doc)
+		}
+	}
+}
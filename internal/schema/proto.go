@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtoSchema requires payloads to decode as a specific protobuf message type
+//
+// Protobuf payloads must decode without unknown fields (fields from another message
+// type), JSON payloads must match the message's JSON mapping.
+type ProtoSchema struct {
+	msgType protoreflect.MessageType
+}
+
+// NewProtoSchema creates a schema for the type of the given message
+//
+// Example:
+//
+//	registry.RegisterSchema("send_email", schema.NewProtoSchema(&tasks.EmailTask{}))
+func NewProtoSchema(msg proto.Message) *ProtoSchema {
+	return &ProtoSchema{msgType: msg.ProtoReflect().Type()}
+}
+
+// Validate decodes the payload into a new message of the schema's type
+func (s *ProtoSchema) Validate(payload []byte) error {
+	name := s.msgType.Descriptor().FullName()
+
+	format, data, err := serialization.NewProtobufSerializer().DetectFormat(payload)
+	if err != nil {
+		return invalid("%v", err)
+	}
+
+	msg := s.msgType.New().Interface()
+	switch format {
+	case serialization.FormatProtobuf:
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return invalid("not a %s message: %v", name, err)
+		}
+		if len(msg.ProtoReflect().GetUnknown()) > 0 {
+			return invalid("not a %s message: contains unknown fields", name)
+		}
+	default:
+		if err := protojson.Unmarshal(data, msg); err != nil {
+			return invalid("not a %s message: %v", name, err)
+		}
+	}
+
+	if err := proto.CheckInitialized(msg); err != nil {
+		return invalid("%s: %v", name, err)
+	}
+	return nil
+}
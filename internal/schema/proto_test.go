@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
+)
+
+func TestProtoSchema_ValidProtobufPayload(t *testing.T) {
+	s := NewProtoSchema(&tasks.EmailTask{})
+
+	payload, err := serialization.NewProtobufSerializer().Marshal(&tasks.EmailTask{To: "user@example.com", Subject: "hi"})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if err := s.Validate(payload); err != nil {
+		t.Errorf("expected valid payload, got %v", err)
+	}
+}
+
+func TestProtoSchema_ValidJSONPayload(t *testing.T) {
+	s := NewProtoSchema(&tasks.EmailTask{})
+
+	if err := s.Validate([]byte(`{"to": "user@example.com", "bodyText": "hello"}`)); err != nil {
+		t.Errorf("expected valid JSON payload, got %v", err)
+	}
+}
+
+func TestProtoSchema_WrongMessageType(t *testing.T) {
+	s := NewProtoSchema(&tasks.EmailTask{})
+
+	payload, err := serialization.NewProtobufSerializer().Marshal(&tasks.WebhookTask{Url: "https://example.com", TimeoutSeconds: 30})
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if err := s.Validate(payload); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("expected ErrInvalidPayload for wrong message type, got %v", err)
+	}
+}
+
+func TestProtoSchema_InvalidPayloads(t *testing.T) {
+	s := NewProtoSchema(&tasks.EmailTask{})
+
+	cases := map[string][]byte{
+		"unknown JSON field": []byte(`{"recipient": "user@example.com"}`),
+		"wrong JSON type":    []byte(`{"to": 42}`),
+		"garbage protobuf":   {0x01, 0xff, 0xff, 0xff},
+		"empty":              {},
+	}
+
+	for name, payload := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Validate(payload); !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("expected ErrInvalidPayload, got %v", err)
+			}
+		})
+	}
+}
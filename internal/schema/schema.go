@@ -0,0 +1,64 @@
+// Package schema validates job payloads against a schema registered per job name.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidPayload is returned when a payload does not match its job's schema
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// Schema validates a serialized job payload
+type Schema interface {
+	// Validate returns an error wrapping ErrInvalidPayload if the payload does not match
+	Validate(payload []byte) error
+}
+
+// Registry maps job names to payload schemas
+// Jobs without a registered schema are not validated
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[string]Schema
+}
+
+// NewRegistry creates an empty schema registry
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: make(map[string]Schema),
+	}
+}
+
+// Register sets the payload schema for a job name, replacing any existing one
+func (r *Registry) Register(name string, s Schema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[name] = s
+}
+
+// Get retrieves the schema for a job name
+func (r *Registry) Get(name string) (Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, exists := r.schemas[name]
+	return s, exists
+}
+
+// Validate checks a payload against the schema registered for the job name
+// Returns nil if no schema is registered
+func (r *Registry) Validate(name string, payload []byte) error {
+	s, exists := r.Get(name)
+	if !exists {
+		return nil
+	}
+	if err := s.Validate(payload); err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	return nil
+}
+
+// invalid builds an ErrInvalidPayload error
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPayload, fmt.Sprintf(format, args...))
+}
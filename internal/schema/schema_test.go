@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+type rejectAll struct{}
+
+func (rejectAll) Validate(payload []byte) error {
+	return invalid("rejected")
+}
+
+func TestRegistry_ValidateWithoutSchema(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.Validate("anything", []byte("not even json")); err != nil {
+		t.Errorf("expected no error for job without schema, got %v", err)
+	}
+}
+
+func TestRegistry_ValidateWithSchema(t *testing.T) {
+	r := NewRegistry()
+	r.Register("strict_job", rejectAll{})
+
+	err := r.Validate("strict_job", []byte(`{}`))
+	if !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected ErrInvalidPayload, got %v", err)
+	}
+	if err.Error() != "job strict_job: invalid payload: rejected" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestRegistry_RegisterReplaces(t *testing.T) {
+	r := NewRegistry()
+	r.Register("job", rejectAll{})
+	r.Register("job", MustJSONSchema(`{"type": "object"}`))
+
+	if err := r.Validate("job", []byte(`{}`)); err != nil {
+		t.Errorf("expected replaced schema to accept payload, got %v", err)
+	}
+	if _, ok := r.Get("job"); !ok {
+		t.Error("expected schema to be registered")
+	}
+}
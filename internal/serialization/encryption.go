@@ -0,0 +1,164 @@
+package serialization
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// FormatEncrypted marks an AES-GCM encrypted envelope wrapping a serialized payload
+	FormatEncrypted PayloadFormat = 0x02
+
+	// envelopeVersion is the current encrypted envelope layout version
+	envelopeVersion byte = 0x01
+
+	// maxKeyIDLength bounds key IDs so their length fits in a single header byte
+	maxKeyIDLength = 255
+)
+
+var (
+	// ErrPayloadEncrypted is returned when an encrypted payload is unmarshaled without decrypting it first
+	ErrPayloadEncrypted = errors.New("payload is encrypted")
+
+	// ErrDecryptFailed is returned when an encrypted envelope cannot be decrypted
+	ErrDecryptFailed = errors.New("failed to decrypt payload")
+
+	// ErrUnknownKey is returned when an envelope references a key the provider doesn't have
+	ErrUnknownKey = errors.New("unknown encryption key")
+)
+
+// Encryptor encrypts payloads at rest using AES-GCM
+//
+// Envelope layout:
+//
+//	[0x02 format][0x01 version][key ID length][key ID][12-byte nonce][ciphertext + GCM tag]
+//
+// The header (format, version and key ID) is authenticated as additional data, so a
+// ciphertext cannot be re-labelled with a different key ID. New payloads are always
+// encrypted with the provider's active key; any key known to the provider can decrypt,
+// which allows keys to be rotated without re-encrypting existing data.
+type Encryptor struct {
+	keys KeyProvider
+}
+
+// NewEncryptor creates an encryptor backed by the given key provider
+func NewEncryptor(keys KeyProvider) *Encryptor {
+	return &Encryptor{keys: keys}
+}
+
+// IsEncrypted returns true if the data is an encrypted envelope
+func IsEncrypted(data []byte) bool {
+	return len(data) > 1 && PayloadFormat(data[0]) == FormatEncrypted && data[1] == envelopeVersion
+}
+
+// Encrypt wraps plaintext in an encrypted envelope using the active key
+// Data that is already encrypted is returned unchanged
+func (e *Encryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	if IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	keyID, key, err := e.keys.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active encryption key: %w", err)
+	}
+	if len(keyID) == 0 || len(keyID) > maxKeyIDLength {
+		return nil, fmt.Errorf("invalid key ID length %d (must be 1-%d)", len(keyID), maxKeyIDLength)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 3+len(keyID))
+	header = append(header, byte(FormatEncrypted), envelopeVersion, byte(len(keyID)))
+	header = append(header, keyID...)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, header), nil
+}
+
+// Decrypt unwraps an encrypted envelope
+// Data that is not encrypted is returned unchanged, so legacy plaintext payloads keep working
+func (e *Encryptor) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	keyID, header, body, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := e.keys.Key(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptFailed, err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: envelope too short", ErrDecryptFailed)
+	}
+
+	nonce, ciphertext := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+// EnvelopeKeyID returns the ID of the key an envelope was encrypted with
+// Useful for finding data that still uses a retired key during rotation
+func EnvelopeKeyID(data []byte) (string, error) {
+	if !IsEncrypted(data) {
+		return "", fmt.Errorf("%w: data is not encrypted", ErrDecryptFailed)
+	}
+	keyID, _, _, err := parseEnvelope(data)
+	return keyID, err
+}
+
+// parseEnvelope splits an envelope into key ID, authenticated header and nonce+ciphertext
+func parseEnvelope(data []byte) (string, []byte, []byte, error) {
+	if len(data) < 3 {
+		return "", nil, nil, fmt.Errorf("%w: envelope too short", ErrDecryptFailed)
+	}
+
+	keyIDLen := int(data[2])
+	headerLen := 3 + keyIDLen
+	if keyIDLen == 0 || len(data) < headerLen {
+		return "", nil, nil, fmt.Errorf("%w: malformed envelope header", ErrDecryptFailed)
+	}
+
+	return string(data[3:headerLen]), data[:headerLen], data[headerLen:], nil
+}
+
+// newGCM creates an AES-GCM AEAD for a 16, 24 or 32 byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES-GCM cipher: %w", err)
+	}
+	return gcm, nil
+}
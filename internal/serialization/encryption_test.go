@@ -0,0 +1,142 @@
+package serialization
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func newTestEncryptor(t *testing.T, activeID string, keys map[string][]byte) *Encryptor {
+	t.Helper()
+	provider, err := NewStaticKeyProvider(activeID, keys)
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	return NewEncryptor(provider)
+}
+
+func TestEncryptor_RoundTrip(t *testing.T) {
+	enc := newTestEncryptor(t, "k1", map[string][]byte{"k1": testKey(1)})
+
+	plaintext := []byte(`{"ssn":"123-45-6789"}`)
+	sealed, err := enc.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	if !IsEncrypted(sealed) {
+		t.Error("expected sealed data to be detected as encrypted")
+	}
+	if bytes.Contains(sealed, []byte("123-45-6789")) {
+		t.Error("ciphertext contains plaintext")
+	}
+
+	keyID, err := EnvelopeKeyID(sealed)
+	if err != nil || keyID != "k1" {
+		t.Errorf("expected key ID k1, got %q (%v)", keyID, err)
+	}
+
+	opened, err := enc.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("expected %s, got %s", plaintext, opened)
+	}
+}
+
+func TestEncryptor_UniqueNonces(t *testing.T) {
+	enc := newTestEncryptor(t, "k1", map[string][]byte{"k1": testKey(1)})
+
+	a, _ := enc.Encrypt([]byte("same"))
+	b, _ := enc.Encrypt([]byte("same"))
+	if bytes.Equal(a, b) {
+		t.Error("expected different ciphertexts for repeated encryption")
+	}
+}
+
+func TestEncryptor_PassThrough(t *testing.T) {
+	enc := newTestEncryptor(t, "k1", map[string][]byte{"k1": testKey(1)})
+
+	// Plain payloads are returned unchanged by Decrypt (legacy data)
+	plain := []byte{byte(FormatJSON), '{', '}'}
+	opened, err := enc.Decrypt(plain)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Errorf("expected plain payload to pass through, got %v (%v)", opened, err)
+	}
+
+	// Encrypting twice does not double-wrap
+	sealed, _ := enc.Encrypt([]byte("data"))
+	again, err := enc.Encrypt(sealed)
+	if err != nil || !bytes.Equal(again, sealed) {
+		t.Error("expected already-encrypted payload to be returned unchanged")
+	}
+}
+
+func TestEncryptor_KeyRotation(t *testing.T) {
+	oldEnc := newTestEncryptor(t, "2024-01", map[string][]byte{"2024-01": testKey(1)})
+	sealedOld, _ := oldEnc.Encrypt([]byte("old data"))
+
+	// New active key, old key retained for decryption
+	rotated := newTestEncryptor(t, "2024-06", map[string][]byte{
+		"2024-01": testKey(1),
+		"2024-06": testKey(2),
+	})
+
+	opened, err := rotated.Decrypt(sealedOld)
+	if err != nil || string(opened) != "old data" {
+		t.Fatalf("expected old data to decrypt after rotation, got %q (%v)", opened, err)
+	}
+
+	sealedNew, _ := rotated.Encrypt([]byte("new data"))
+	if keyID, _ := EnvelopeKeyID(sealedNew); keyID != "2024-06" {
+		t.Errorf("expected new payloads to use active key 2024-06, got %s", keyID)
+	}
+
+	// Once the old key is retired, old data can no longer be read
+	retired := newTestEncryptor(t, "2024-06", map[string][]byte{"2024-06": testKey(2)})
+	if _, err := retired.Decrypt(sealedOld); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed for retired key, got %v", err)
+	}
+}
+
+func TestEncryptor_TamperDetection(t *testing.T) {
+	enc := newTestEncryptor(t, "k1", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	sealed, _ := enc.Encrypt([]byte("payload"))
+
+	// Flip a ciphertext bit
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 0x01
+	if _, err := enc.Decrypt(tampered); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed for tampered ciphertext, got %v", err)
+	}
+
+	// Relabel the key ID (header is authenticated)
+	relabeled := append([]byte(nil), sealed...)
+	relabeled[4] = '2' // "k1" -> "k2"
+	if _, err := enc.Decrypt(relabeled); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed for relabeled key ID, got %v", err)
+	}
+
+	// Truncated envelope
+	if _, err := enc.Decrypt(sealed[:6]); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed for truncated envelope, got %v", err)
+	}
+}
+
+func TestSerializer_RejectsEncryptedPayload(t *testing.T) {
+	enc := newTestEncryptor(t, "k1", map[string][]byte{"k1": testKey(1)})
+	s := NewJSONSerializer()
+
+	data, _ := s.Marshal(map[string]string{"a": "b"})
+	sealed, _ := enc.Encrypt(data)
+
+	var v map[string]string
+	if err := s.Unmarshal(sealed, &v); !errors.Is(err, ErrPayloadEncrypted) {
+		t.Errorf("expected ErrPayloadEncrypted, got %v", err)
+	}
+}
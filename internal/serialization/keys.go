@@ -0,0 +1,202 @@
+package serialization
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyProvider supplies encryption keys by ID
+type KeyProvider interface {
+	// ActiveKey returns the ID and key used to encrypt new payloads
+	ActiveKey() (string, []byte, error)
+
+	// Key returns the key with the given ID
+	// Returns ErrUnknownKey if the provider doesn't have it
+	Key(id string) ([]byte, error)
+}
+
+// Key provider kinds accepted by NewKeyProvider
+const (
+	// KeyProviderEnv reads keys from ENCRYPTION_KEYS and ENCRYPTION_ACTIVE_KEY
+	KeyProviderEnv = "env"
+	// KeyProviderFile reads keys from a JSON key file
+	KeyProviderFile = "file"
+)
+
+// NewKeyProvider creates the key provider of the given kind
+// path is only used by the file provider
+func NewKeyProvider(kind, path string) (KeyProvider, error) {
+	switch strings.ToLower(kind) {
+	case KeyProviderEnv, "":
+		return NewEnvKeyProvider()
+	case KeyProviderFile:
+		return NewFileKeyProvider(path)
+	default:
+		return nil, fmt.Errorf("unknown key provider: %s (must be one of: env, file)", kind)
+	}
+}
+
+// StaticKeyProvider holds a fixed set of keys in memory
+// Several keys can be known at once; only the active key is used for encryption
+type StaticKeyProvider struct {
+	mu       sync.RWMutex
+	activeID string
+	keys     map[string][]byte
+}
+
+// NewStaticKeyProvider creates a provider with the given keys and active key ID
+// Keys must be 16, 24 or 32 bytes (AES-128, AES-192 or AES-256)
+func NewStaticKeyProvider(activeID string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	p := &StaticKeyProvider{}
+	if err := p.set(activeID, keys); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ActiveKey returns the ID and key used for encryption
+func (p *StaticKeyProvider) ActiveKey() (string, []byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.activeID, p.keys[p.activeID], nil
+}
+
+// Key returns the key with the given ID
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// set validates and replaces the provider's key set
+func (p *StaticKeyProvider) set(activeID string, keys map[string][]byte) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one encryption key is required")
+	}
+	for id, key := range keys {
+		if id == "" || len(id) > maxKeyIDLength {
+			return fmt.Errorf("invalid key ID %q (must be 1-%d characters)", id, maxKeyIDLength)
+		}
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return fmt.Errorf("key %q has invalid length %d (must be 16, 24 or 32 bytes)", id, len(key))
+		}
+	}
+	if _, ok := keys[activeID]; !ok {
+		return fmt.Errorf("active key %q is not in the key set", activeID)
+	}
+
+	copied := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		copied[id] = append([]byte(nil), key...)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.activeID = activeID
+	p.keys = copied
+	return nil
+}
+
+// NewEnvKeyProvider loads keys from environment variables:
+//
+//	ENCRYPTION_KEYS=2024-06:<base64 key>,2024-01:<base64 key>
+//	ENCRYPTION_ACTIVE_KEY=2024-06   (optional, defaults to the first listed key)
+func NewEnvKeyProvider() (*StaticKeyProvider, error) {
+	spec := os.Getenv("ENCRYPTION_KEYS")
+	if spec == "" {
+		return nil, fmt.Errorf("ENCRYPTION_KEYS is not set")
+	}
+
+	keys := make(map[string][]byte)
+	firstID := ""
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid ENCRYPTION_KEYS entry %q (expected id:base64key)", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 for key %q: %w", id, err)
+		}
+		if firstID == "" {
+			firstID = id
+		}
+		keys[id] = key
+	}
+
+	activeID := os.Getenv("ENCRYPTION_ACTIVE_KEY")
+	if activeID == "" {
+		activeID = firstID
+	}
+
+	return NewStaticKeyProvider(activeID, keys)
+}
+
+// keyFile is the on-disk format read by FileKeyProvider
+type keyFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"` // key ID -> base64 key
+}
+
+// FileKeyProvider loads keys from a JSON file:
+//
+//	{"active": "2024-06", "keys": {"2024-06": "<base64>", "2024-01": "<base64>"}}
+//
+// Call Reload after rotating keys in the file to pick them up without a restart.
+type FileKeyProvider struct {
+	*StaticKeyProvider
+	path string
+}
+
+// NewFileKeyProvider creates a provider from a JSON key file
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("key file path cannot be empty")
+	}
+
+	p := &FileKeyProvider{StaticKeyProvider: &StaticKeyProvider{}, path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload re-reads the key file
+// The previous key set is kept if the file is invalid
+func (p *FileKeyProvider) Reload() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(kf.Keys))
+	for id, encoded := range kf.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid base64 for key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return p.set(kf.Active, keys)
+}
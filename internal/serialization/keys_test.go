@@ -0,0 +1,131 @@
+package serialization
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStaticKeyProvider_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		activeID string
+		keys     map[string][]byte
+	}{
+		{"no keys", "k1", map[string][]byte{}},
+		{"short key", "k1", map[string][]byte{"k1": []byte("short")}},
+		{"missing active", "k2", map[string][]byte{"k1": testKey(1)}},
+		{"empty id", "", map[string][]byte{"": testKey(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewStaticKeyProvider(tt.activeID, tt.keys); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestStaticKeyProvider_UnknownKey(t *testing.T) {
+	p, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": testKey(1)})
+	if _, err := p.Key("missing"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestNewEnvKeyProvider(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	t.Setenv("ENCRYPTION_KEYS", "2024-06:"+k2+", 2024-01:"+k1)
+	t.Setenv("ENCRYPTION_ACTIVE_KEY", "")
+
+	p, err := NewEnvKeyProvider()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	id, _, _ := p.ActiveKey()
+	if id != "2024-06" {
+		t.Errorf("expected first listed key to be active, got %s", id)
+	}
+	if _, err := p.Key("2024-01"); err != nil {
+		t.Errorf("expected older key to be available for decryption: %v", err)
+	}
+
+	t.Setenv("ENCRYPTION_ACTIVE_KEY", "2024-01")
+	p, _ = NewEnvKeyProvider()
+	if id, _, _ := p.ActiveKey(); id != "2024-01" {
+		t.Errorf("expected ENCRYPTION_ACTIVE_KEY to select active key, got %s", id)
+	}
+}
+
+func TestNewEnvKeyProvider_Invalid(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEYS", "")
+	if _, err := NewEnvKeyProvider(); err == nil {
+		t.Error("expected error when ENCRYPTION_KEYS is unset")
+	}
+
+	t.Setenv("ENCRYPTION_KEYS", "no-separator")
+	if _, err := NewEnvKeyProvider(); err == nil {
+		t.Error("expected error for malformed entry")
+	}
+
+	t.Setenv("ENCRYPTION_KEYS", "k1:not-base64!!")
+	if _, err := NewEnvKeyProvider(); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
+
+func TestFileKeyProvider_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	writeKeys := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write key file: %v", err)
+		}
+	}
+
+	writeKeys(`{"active":"k1","keys":{"k1":"` + k1 + `"}}`)
+	p, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	enc := NewEncryptor(p)
+	sealed, _ := enc.Encrypt([]byte("secret"))
+
+	// Rotate: add k2 and make it active
+	writeKeys(`{"active":"k2","keys":{"k1":"` + k1 + `","k2":"` + k2 + `"}}`)
+	if err := p.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if id, _, _ := p.ActiveKey(); id != "k2" {
+		t.Errorf("expected active key k2 after reload, got %s", id)
+	}
+	if opened, err := enc.Decrypt(sealed); err != nil || string(opened) != "secret" {
+		t.Errorf("expected data sealed with k1 to decrypt after rotation, got %q (%v)", opened, err)
+	}
+
+	// Invalid file keeps the previous key set
+	writeKeys(`{"active":"k3","keys":{}}`)
+	if err := p.Reload(); err == nil {
+		t.Error("expected reload error for invalid key file")
+	}
+	if id, _, _ := p.ActiveKey(); id != "k2" {
+		t.Errorf("expected previous key set to be kept, got active %s", id)
+	}
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	if _, err := NewKeyProvider("vault", ""); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+	if _, err := NewKeyProvider(KeyProviderFile, ""); err == nil {
+		t.Error("expected error for file provider without path")
+	}
+}
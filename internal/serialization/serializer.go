@@ -158,6 +158,10 @@ func (s *Serializer) DetectFormat(data []byte) (PayloadFormat, []byte, error) {
 		}
 		return format, data[1:], nil
 
+	case FormatEncrypted:
+		// Encrypted envelopes must be decrypted (see Encryptor) before unmarshaling
+		return format, data, fmt.Errorf("%w: decrypt it before unmarshaling", ErrPayloadEncrypted)
+
 	default:
 		// Assume legacy JSON without format prefix
 		// JSON typically starts with '{' (0x7B) or '[' (0x5B)
@@ -0,0 +1,93 @@
+// Package storage provides blob stores used to keep large job payloads out of Redis.
+//
+// Jobs whose payload exceeds a configured threshold have the payload written to a
+// BlobStore and replaced by a reference (the claim-check pattern). Workers resolve
+// the reference lazily when the payload is first unmarshaled.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrBlobNotFound is returned when a blob does not exist in the store
+	ErrBlobNotFound = errors.New("blob not found")
+
+	// ErrInvalidKey is returned when a blob key is malformed
+	ErrInvalidKey = errors.New("invalid blob key")
+
+	// blobKeyPattern restricts keys to a safe character set for filesystems and URLs
+	blobKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_.\-/]+$`)
+)
+
+// BlobStore stores opaque binary objects by key
+type BlobStore interface {
+	// Put stores data under key, overwriting any existing blob
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get retrieves the blob stored under key
+	// Returns ErrBlobNotFound if the blob doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete removes the blob stored under key
+	// Does not error if the blob doesn't exist
+	Delete(ctx context.Context, key string) error
+}
+
+// Store types accepted by NewBlobStore
+const (
+	// TypeNone disables payload offloading
+	TypeNone = ""
+	// TypeFile stores blobs on the local filesystem
+	TypeFile = "file"
+	// TypeS3 stores blobs in an S3-compatible object store
+	TypeS3 = "s3"
+)
+
+// Options configures NewBlobStore
+type Options struct {
+	// Type selects the implementation (file, s3, or empty to disable)
+	Type string
+	// Path is the root directory for the file store
+	Path string
+	// S3 configures the S3 store
+	S3 S3Config
+}
+
+// NewBlobStore creates the blob store described by opts
+// Returns nil and no error when opts.Type is empty (offloading disabled)
+func NewBlobStore(opts Options) (BlobStore, error) {
+	switch strings.ToLower(opts.Type) {
+	case TypeNone:
+		return nil, nil
+	case TypeFile:
+		return NewFileStore(opts.Path)
+	case TypeS3:
+		return NewS3Store(opts.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob store type: %s (must be one of: file, s3)", opts.Type)
+	}
+}
+
+// ValidateKey checks that a key is safe to use with every store implementation
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: key cannot be empty", ErrInvalidKey)
+	}
+	if !blobKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q contains unsupported characters", ErrInvalidKey, key)
+	}
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("%w: %q must be relative", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q contains an empty or relative path segment", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
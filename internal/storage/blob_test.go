@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"payloads/abc", "payloads/2f1c-44aa.bin", "a", "nested/dir/key_1"}
+	for _, key := range valid {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("expected %q to be valid, got %v", key, err)
+		}
+	}
+
+	invalid := []string{"", "/abs", "../escape", "a/../b", "a//b", "with space", "colon:key", "a/./b"}
+	for _, key := range invalid {
+		err := ValidateKey(key)
+		if err == nil {
+			t.Errorf("expected %q to be invalid", key)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+}
+
+func TestNewBlobStore(t *testing.T) {
+	store, err := NewBlobStore(Options{})
+	if err != nil || store != nil {
+		t.Fatalf("expected nil store for empty type, got %v, %v", store, err)
+	}
+
+	store, err = NewBlobStore(Options{Type: TypeFile, Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := store.(*FileStore); !ok {
+		t.Errorf("expected *FileStore, got %T", store)
+	}
+
+	store, err = NewBlobStore(Options{Type: TypeS3, S3: S3Config{Endpoint: "http://localhost:9000", Bucket: "payloads"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := store.(*S3Store); !ok {
+		t.Errorf("expected *S3Store, got %T", store)
+	}
+
+	if _, err := NewBlobStore(Options{Type: "ftp"}); err == nil {
+		t.Error("expected error for unknown store type")
+	}
+}
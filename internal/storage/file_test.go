@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore_PutGetDelete(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("x"), 4096)
+	if err := store.Put(ctx, "payloads/job-1", data); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	got, err := store.Get(ctx, "payloads/job-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("blob content mismatch")
+	}
+
+	// Overwrite
+	if err := store.Put(ctx, "payloads/job-1", []byte("new")); err != nil {
+		t.Fatalf("Put (overwrite) failed: %v", err)
+	}
+	got, _ = store.Get(ctx, "payloads/job-1")
+	if string(got) != "new" {
+		t.Errorf("expected overwritten content, got %q", got)
+	}
+
+	if err := store.Delete(ctx, "payloads/job-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "payloads/job-1"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound after delete, got %v", err)
+	}
+
+	// Deleting a missing blob is not an error
+	if err := store.Delete(ctx, "payloads/job-1"); err != nil {
+		t.Errorf("expected no error deleting missing blob, got %v", err)
+	}
+}
+
+func TestFileStore_RejectsTraversal(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileStore(filepath.Join(root, "blobs"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	err = store.Put(context.Background(), "../outside", []byte("data"))
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected ErrInvalidKey, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(root, "outside")); !os.IsNotExist(statErr) {
+		t.Error("blob written outside store root")
+	}
+}
+
+func TestNewFileStore_EmptyPath(t *testing.T) {
+	if _, err := NewFileStore(""); err == nil {
+		t.Error("expected error for empty path")
+	}
+}
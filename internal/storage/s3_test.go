@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible object store
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	lastAuth string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastAuth = r.Header.Get("Authorization")
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if sha256Hex(body) != r.Header.Get("X-Amz-Content-Sha256") {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = body
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3Store_PutGetDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "payloads",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		Prefix:          "bananas",
+	})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte{0x01, 0x02}, 1024)
+	if err := store.Put(ctx, "payloads/job-1", data); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, ok := fake.objects["/payloads/bananas/payloads/job-1"]; !ok {
+		t.Errorf("expected path-style object key, got %v", fake.objects)
+	}
+	if !strings.HasPrefix(fake.lastAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		t.Errorf("expected SigV4 authorization header, got %q", fake.lastAuth)
+	}
+	if !strings.Contains(fake.lastAuth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("unexpected signed headers: %q", fake.lastAuth)
+	}
+
+	got, err := store.Get(ctx, "payloads/job-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("blob content mismatch")
+	}
+
+	if err := store.Delete(ctx, "payloads/job-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "payloads/job-1"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound after delete, got %v", err)
+	}
+}
+
+func TestS3Store_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "payloads"})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	err = store.Put(context.Background(), "payloads/job-1", []byte("data"))
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected AccessDenied error, got %v", err)
+	}
+}
+
+func TestS3Store_SignatureIsDeterministic(t *testing.T) {
+	store, _ := NewS3Store(S3Config{
+		Endpoint:        "http://localhost:9000",
+		Bucket:          "payloads",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	})
+	store.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	sign := func() string {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:9000/payloads/a", nil)
+		store.sign(req, nil)
+		return req.Header.Get("Authorization")
+	}
+
+	first := sign()
+	if first != sign() {
+		t.Error("expected identical signatures for identical requests")
+	}
+	if !strings.Contains(first, "Credential=AKIDEXAMPLE/20240102/us-east-1/s3/aws4_request") {
+		t.Errorf("unexpected credential scope: %q", first)
+	}
+}
+
+func TestDeriveSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := deriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signing key = %s, want %s", got, want)
+	}
+}
+
+func TestNewS3Store_Validation(t *testing.T) {
+	if _, err := NewS3Store(S3Config{Bucket: "b"}); err == nil {
+		t.Error("expected error for missing endpoint")
+	}
+	if _, err := NewS3Store(S3Config{Endpoint: "http://localhost:9000"}); err == nil {
+		t.Error("expected error for missing bucket")
+	}
+	if _, err := NewS3Store(S3Config{Endpoint: "not a url", Bucket: "b"}); err == nil {
+		t.Error("expected error for invalid endpoint")
+	}
+}
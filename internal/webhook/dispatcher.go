@@ -0,0 +1,495 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/redis/go-redis/v9"
+)
+
+// Event names sent in the HeaderEvent header
+const (
+	EventJobCompleted = "job.completed"
+	EventJobFailed    = "job.failed"
+	EventJobExpired   = "job.expired"
+)
+
+// maxResponseLog caps how much of a receiver's response body is kept in the delivery log
+const maxResponseLog = 1024
+
+// claimScript leases a due delivery: it moves the delivery's score to ARGV[3]
+// only if it is still due (score <= ARGV[2]), returning 1 on success
+var claimScript = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not score or tonumber(score) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// Delivery is a pending or finished webhook delivery
+type Delivery struct {
+	ID          string    `json:"id"`
+	JobID       string    `json:"job_id"`
+	Event       string    `json:"event"`
+	URL         string    `json:"url"`
+	SecretRef   string    `json:"secret_ref,omitempty"`
+	Body        []byte    `json:"body"` // Encrypted when Options.Encryptor is set
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// Attempt is one entry in a job's delivery log
+type Attempt struct {
+	DeliveryID string        `json:"delivery_id"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Response   string        `json:"response,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	At         time.Time     `json:"at"`
+}
+
+// Options configures a Dispatcher
+type Options struct {
+	// MaxAttempts before a delivery is moved to the webhook dead letter queue (default 8)
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry, doubled per attempt (default 10s)
+	BaseBackoff time.Duration
+	// MaxBackoff caps the retry delay (default 1h)
+	MaxBackoff time.Duration
+	// Timeout is the per-request HTTP timeout (default 10s)
+	Timeout time.Duration
+	// PollInterval is how often Run looks for due deliveries (default 1s)
+	PollInterval time.Duration
+	// BatchSize is the maximum number of deliveries attempted per poll (default 20)
+	BatchSize int
+	// LogTTL is how long a job's delivery log is kept (default 7 days)
+	LogTTL time.Duration
+	// Secrets resolves Job.CallbackSecretRef (default EnvSecrets)
+	Secrets SecretProvider
+	// HTTPClient overrides the HTTP client (Timeout is ignored when set)
+	HTTPClient *http.Client
+	// Namespace is the keyspace deliveries are stored in (default "bananas")
+	Namespace string
+	// Encryptor encrypts delivery bodies at rest, including in the dead letter
+	// queue; they are decrypted only to be sent (default: stored in plaintext)
+	Encryptor *serialization.Encryptor
+}
+
+// Dispatcher delivers job results to callback URLs
+//
+// Notify only records a delivery in Redis, so it never blocks job execution on a
+// slow receiver. Run (in any number of processes) performs the HTTP requests,
+// retrying with exponential backoff; deliveries that exhaust MaxAttempts are moved
+// to a dead letter queue, from which they can be redriven.
+type Dispatcher struct {
+	client redis.UniversalClient
+	opts   Options
+	http   *http.Client
+	now    func() time.Time
+
+	pendingKey string
+	deadKey    string
+	keyPrefix  string
+}
+
+// NewDispatcher creates a webhook dispatcher storing its state in Redis
+func NewDispatcher(client redis.UniversalClient, opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 10 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Hour
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	if opts.LogTTL <= 0 {
+		opts.LogTTL = 7 * 24 * time.Hour
+	}
+	if opts.Secrets == nil {
+		opts.Secrets = EnvSecrets{}
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: opts.Timeout}
+	}
+
+	prefix := redisconn.KeyPrefix(client, opts.Namespace) + "webhook:"
+	return &Dispatcher{
+		client:     client,
+		opts:       opts,
+		http:       httpClient,
+		now:        time.Now,
+		pendingKey: prefix + "pending",
+		deadKey:    prefix + "dead",
+		keyPrefix:  prefix,
+	}
+}
+
+// deliveryKey returns the Redis key holding a delivery
+func (d *Dispatcher) deliveryKey(id string) string {
+	return d.keyPrefix + "delivery:" + id
+}
+
+// logKey returns the Redis key of a job's delivery log
+func (d *Dispatcher) logKey(jobID string) string {
+	return d.keyPrefix + "log:" + jobID
+}
+
+// Notify schedules delivery of a job's result to its callback URL
+// It is a no-op for jobs without a callback URL.
+func (d *Dispatcher) Notify(ctx context.Context, j *job.Job, result *job.JobResult) error {
+	if !j.HasCallback() {
+		return nil
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job result: %w", err)
+	}
+	if d.opts.Encryptor != nil {
+		if body, err = d.opts.Encryptor.Encrypt(body); err != nil {
+			return fmt.Errorf("failed to encrypt job result: %w", err)
+		}
+	}
+
+	event := EventJobCompleted
+	switch result.Status {
+	case job.StatusFailed:
+		event = EventJobFailed
+	case job.StatusExpired:
+		event = EventJobExpired
+	}
+
+	delivery := &Delivery{
+		ID:        uuid.New().String(),
+		JobID:     j.ID,
+		Event:     event,
+		URL:       j.CallbackURL,
+		SecretRef: j.CallbackSecretRef,
+		Body:      body,
+		CreatedAt: d.now(),
+	}
+	return d.schedule(ctx, delivery, d.now())
+}
+
+// schedule stores a delivery and adds it to the pending set at the given time
+func (d *Dispatcher) schedule(ctx context.Context, delivery *Delivery, at time.Time) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	pipe := d.client.Pipeline()
+	pipe.Set(ctx, d.deliveryKey(delivery.ID), data, 0)
+	pipe.ZAdd(ctx, d.pendingKey, redis.Z{Score: float64(at.UnixMilli()), Member: delivery.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to schedule webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// Run delivers due webhooks until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.ProcessDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Webhook dispatcher error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue attempts every delivery whose next attempt time has arrived (up to BatchSize)
+// Returns the number of deliveries attempted.
+func (d *Dispatcher) ProcessDue(ctx context.Context) (int, error) {
+	ids, err := d.client.ZRangeByScore(ctx, d.pendingKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(d.now().UnixMilli(), 10),
+		Count: int64(d.opts.BatchSize),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+
+	attempted := 0
+	for _, id := range ids {
+		// Claim the delivery by pushing its next attempt past the request timeout;
+		// if this process dies mid-request, another dispatcher retries it then
+		claimed, err := claimScript.Run(ctx, d.client, []string{d.pendingKey}, id,
+			d.now().UnixMilli(), d.now().Add(2*d.opts.Timeout).UnixMilli()).Int()
+		if err != nil {
+			return attempted, fmt.Errorf("failed to claim webhook delivery: %w", err)
+		}
+		if claimed == 0 {
+			continue // Another dispatcher took it
+		}
+
+		delivery, err := d.GetDelivery(ctx, id)
+		if err != nil {
+			log.Printf("Dropping webhook delivery %s: %v", id, err)
+			d.client.ZRem(ctx, d.pendingKey, id)
+			continue
+		}
+
+		attempted++
+		if err := d.attempt(ctx, delivery); err != nil {
+			return attempted, err
+		}
+	}
+	return attempted, nil
+}
+
+// attempt sends one delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) error {
+	delivery.Attempts++
+	start := d.now()
+	status, response, sendErr := d.send(ctx, delivery)
+
+	entry := Attempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		StatusCode: status,
+		Response:   response,
+		Duration:   d.now().Sub(start),
+		At:         start,
+	}
+	if sendErr != nil {
+		entry.Error = sendErr.Error()
+	}
+	d.appendLog(ctx, delivery.JobID, entry)
+
+	if sendErr == nil {
+		delivery.DeliveredAt = d.now()
+		delivery.LastError = ""
+		data, err := json.Marshal(delivery)
+		if err != nil {
+			return fmt.Errorf("failed to marshal delivery: %w", err)
+		}
+		// Delivered - keep the record only as long as the delivery log
+		pipe := d.client.Pipeline()
+		pipe.ZRem(ctx, d.pendingKey, delivery.ID)
+		pipe.Set(ctx, d.deliveryKey(delivery.ID), data, d.opts.LogTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to record webhook delivery: %w", err)
+		}
+		return nil
+	}
+
+	delivery.LastError = sendErr.Error()
+	if delivery.Attempts >= d.opts.MaxAttempts {
+		log.Printf("Webhook delivery %s for job %s moved to dead letter queue after %d attempts: %v",
+			delivery.ID, delivery.JobID, delivery.Attempts, sendErr)
+		return d.deadLetter(ctx, delivery)
+	}
+
+	return d.schedule(ctx, delivery, d.now().Add(d.backoff(delivery.Attempts)))
+}
+
+// send performs the HTTP request; any non-2xx response is an error
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, string, error) {
+	body, err := d.openBody(delivery)
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Bananas-Webhook/1.0")
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+
+	if delivery.SecretRef != "" {
+		secret, err := d.opts.Secrets.Secret(delivery.SecretRef)
+		if err != nil {
+			return 0, "", err
+		}
+		req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	}
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseLog))
+	io.Copy(io.Discard, resp.Body) // Drain so the connection can be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(response), fmt.Errorf("receiver returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(response), nil
+}
+
+// openBody returns a delivery's request body, decrypting it if it was stored encrypted
+func (d *Dispatcher) openBody(delivery *Delivery) ([]byte, error) {
+	if !serialization.IsEncrypted(delivery.Body) {
+		return delivery.Body, nil
+	}
+	if d.opts.Encryptor == nil {
+		return nil, fmt.Errorf("delivery body is encrypted but no encryptor is configured")
+	}
+	body, err := d.opts.Encryptor.Decrypt(delivery.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt delivery body: %w", err)
+	}
+	return body, nil
+}
+
+// backoff returns the delay before the next attempt: BaseBackoff * 2^(attempts-1), capped
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.opts.MaxBackoff {
+			return d.opts.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// deadLetter moves a delivery to the webhook dead letter queue
+func (d *Dispatcher) deadLetter(ctx context.Context, delivery *Delivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	pipe := d.client.Pipeline()
+	pipe.ZRem(ctx, d.pendingKey, delivery.ID)
+	pipe.Set(ctx, d.deliveryKey(delivery.ID), data, 0)
+	pipe.LPush(ctx, d.deadKey, delivery.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to move webhook delivery to dead letter queue: %w", err)
+	}
+	return nil
+}
+
+// appendLog records an attempt in the job's delivery log (best-effort)
+func (d *Dispatcher) appendLog(ctx context.Context, jobID string, entry Attempt) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	key := d.logKey(jobID)
+	pipe := d.client.Pipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -100, -1)
+	pipe.Expire(ctx, key, d.opts.LogTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to write webhook delivery log for job %s: %v", jobID, err)
+	}
+}
+
+// GetDelivery retrieves a delivery by ID
+func (d *Dispatcher) GetDelivery(ctx context.Context, id string) (*Delivery, error) {
+	data, err := d.client.Get(ctx, d.deliveryKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("webhook delivery %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	var delivery Delivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// DeliveryLog returns every recorded attempt to deliver a job's webhooks, oldest first
+func (d *Dispatcher) DeliveryLog(ctx context.Context, jobID string) ([]Attempt, error) {
+	entries, err := d.client.LRange(ctx, d.logKey(jobID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery log: %w", err)
+	}
+
+	attempts := make([]Attempt, 0, len(entries))
+	for _, entry := range entries {
+		var a Attempt
+		if err := json.Unmarshal([]byte(entry), &a); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal delivery log entry: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, nil
+}
+
+// DeadDeliveries returns up to limit deliveries from the dead letter queue, newest first
+func (d *Dispatcher) DeadDeliveries(ctx context.Context, limit int64) ([]*Delivery, error) {
+	ids, err := d.client.LRange(ctx, d.deadKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]*Delivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := d.GetDelivery(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// Redrive moves a dead delivery back to pending with a fresh attempt budget
+func (d *Dispatcher) Redrive(ctx context.Context, id string) error {
+	removed, err := d.client.LRem(ctx, d.deadKey, 1, id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove webhook delivery from dead letter queue: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("webhook delivery %s is not in the dead letter queue", id)
+	}
+
+	delivery, err := d.GetDelivery(ctx, id)
+	if err != nil {
+		return err
+	}
+	delivery.Attempts = 0
+	return d.schedule(ctx, delivery, d.now())
+}
@@ -0,0 +1,371 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
+	"github.com/redis/go-redis/v9"
+)
+
+// receiver is an httptest server that records webhook requests
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	status   int
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	r := &receiver{status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		status := r.status
+		r.mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// setupDispatcher creates a dispatcher on miniredis with a controllable clock
+func setupDispatcher(t *testing.T, opts Options) (*Dispatcher, *time.Time) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	d := NewDispatcher(client, opts)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func callbackJob(t *testing.T, url, secretRef string) *job.Job {
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	if err := j.SetCallback(url, secretRef); err != nil {
+		t.Fatalf("failed to set callback: %v", err)
+	}
+	return j
+}
+
+func TestDispatcher_DeliversResult(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusOK)
+	d, _ := setupDispatcher(t, Options{Secrets: StaticSecrets{"partner-a": []byte("s3cret")}})
+
+	j := callbackJob(t, recv.URL, "partner-a")
+	res := &job.JobResult{JobID: j.ID, Status: job.StatusCompleted, Result: json.RawMessage(`{"count":3}`)}
+	if err := d.Notify(ctx, j, res); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+
+	// Notify doesn't send anything by itself
+	if recv.count() != 0 {
+		t.Fatalf("expected no request before ProcessDue, got %d", recv.count())
+	}
+
+	n, err := d.ProcessDue(ctx)
+	if err != nil {
+		t.Fatalf("ProcessDue failed: %v", err)
+	}
+	if n != 1 || recv.count() != 1 {
+		t.Fatalf("expected 1 delivery, got %d attempted and %d received", n, recv.count())
+	}
+
+	req, body := recv.requests[0], recv.bodies[0]
+	if req.Header.Get(HeaderEvent) != EventJobCompleted {
+		t.Errorf("expected event %s, got %s", EventJobCompleted, req.Header.Get(HeaderEvent))
+	}
+	if err := Verify([]byte("s3cret"), req.Header.Get(HeaderSignature), req.Header.Get(HeaderTimestamp), body, time.Minute); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	var received job.JobResult
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	if received.JobID != j.ID || string(received.Result) != `{"count":3}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	// Nothing left to deliver
+	if n, _ := d.ProcessDue(ctx); n != 0 {
+		t.Errorf("expected no further deliveries, got %d", n)
+	}
+
+	log, err := d.DeliveryLog(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("failed to get delivery log: %v", err)
+	}
+	if len(log) != 1 || log[0].StatusCode != http.StatusOK || log[0].Error != "" {
+		t.Errorf("unexpected delivery log: %+v", log)
+	}
+
+	delivery, err := d.GetDelivery(ctx, log[0].DeliveryID)
+	if err != nil {
+		t.Fatalf("failed to get delivery: %v", err)
+	}
+	if delivery.DeliveredAt.IsZero() {
+		t.Error("expected delivery to be marked delivered")
+	}
+}
+
+func TestDispatcher_DeliversProtobufResult(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusOK)
+	d, _ := setupDispatcher(t, Options{})
+
+	data, err := job.EncodeValue(&tasks.EmailTask{To: "user@example.com"})
+	if err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+	j := callbackJob(t, recv.URL, "")
+	res := &job.JobResult{JobID: j.ID, Status: job.StatusCompleted, Result: data}
+	if err := d.Notify(ctx, j, res); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+
+	if n, err := d.ProcessDue(ctx); err != nil || n != 1 || recv.count() != 1 {
+		t.Fatalf("expected 1 delivery, got %d (err %v) and %d received", n, err, recv.count())
+	}
+
+	var received job.JobResult
+	if err := json.Unmarshal(recv.bodies[0], &received); err != nil {
+		t.Fatalf("failed to unmarshal body: %v", err)
+	}
+	var task tasks.EmailTask
+	if err := received.UnmarshalResult(&task); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if task.To != "user@example.com" {
+		t.Errorf("unexpected result: %+v", &task)
+	}
+}
+
+func TestDispatcher_EncryptsBodyAtRest(t *testing.T) {
+	ctx := context.Background()
+	keys, err := serialization.NewStaticKeyProvider("k1", map[string][]byte{"k1": make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	recv := newReceiver(t, http.StatusInternalServerError)
+	d, _ := setupDispatcher(t, Options{
+		MaxAttempts: 1,
+		Secrets:     StaticSecrets{"partner-a": []byte("s3cret")},
+		Encryptor:   serialization.NewEncryptor(keys),
+	})
+
+	j := callbackJob(t, recv.URL, "partner-a")
+	res := &job.JobResult{JobID: j.ID, Status: job.StatusCompleted, Result: json.RawMessage(`{"card":"4242"}`)}
+	if err := d.Notify(ctx, j, res); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	d.ProcessDue(ctx)
+
+	// The receiver gets the plaintext result, signed as sent
+	req, body := recv.requests[0], recv.bodies[0]
+	if !strings.Contains(string(body), `"card":"4242"`) {
+		t.Errorf("expected the plaintext result to be sent, got %s", body)
+	}
+	if err := Verify([]byte("s3cret"), req.Header.Get(HeaderSignature), req.Header.Get(HeaderTimestamp), body, time.Minute); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	// The dead-lettered delivery only holds the sealed body
+	dead, err := d.DeadDeliveries(ctx, 10)
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("expected 1 dead delivery, got %d, %v", len(dead), err)
+	}
+	if !serialization.IsEncrypted(dead[0].Body) || strings.Contains(string(dead[0].Body), "4242") {
+		t.Errorf("expected the stored body to be encrypted, got %q", dead[0].Body)
+	}
+}
+
+func TestDispatcher_FailedJobEvent(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusNoContent)
+	d, _ := setupDispatcher(t, Options{})
+
+	j := callbackJob(t, recv.URL, "")
+	d.Notify(ctx, j, &job.JobResult{JobID: j.ID, Status: job.StatusFailed, Error: "boom"})
+	d.ProcessDue(ctx)
+
+	if recv.count() != 1 {
+		t.Fatalf("expected 1 request, got %d", recv.count())
+	}
+	if got := recv.requests[0].Header.Get(HeaderEvent); got != EventJobFailed {
+		t.Errorf("expected event %s, got %s", EventJobFailed, got)
+	}
+	if recv.requests[0].Header.Get(HeaderSignature) != "" {
+		t.Error("expected no signature without a secret reference")
+	}
+}
+
+func TestDispatcher_NotifyWithoutCallbackIsNoop(t *testing.T) {
+	ctx := context.Background()
+	d, _ := setupDispatcher(t, Options{})
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	if err := d.Notify(ctx, j, &job.JobResult{JobID: j.ID, Status: job.StatusCompleted}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n, _ := d.client.ZCard(ctx, d.pendingKey).Result(); n != 0 {
+		t.Errorf("expected nothing pending, got %d", n)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusInternalServerError)
+	d, now := setupDispatcher(t, Options{BaseBackoff: 10 * time.Second, MaxAttempts: 5})
+
+	j := callbackJob(t, recv.URL, "")
+	d.Notify(ctx, j, &job.JobResult{JobID: j.ID, Status: job.StatusCompleted})
+
+	d.ProcessDue(ctx)
+	if recv.count() != 1 {
+		t.Fatalf("expected 1 attempt, got %d", recv.count())
+	}
+
+	// Not due again until the backoff has passed
+	*now = now.Add(9 * time.Second)
+	d.ProcessDue(ctx)
+	if recv.count() != 1 {
+		t.Fatalf("expected retry to wait for backoff, got %d attempts", recv.count())
+	}
+
+	recv.setStatus(http.StatusOK)
+	*now = now.Add(2 * time.Second)
+	d.ProcessDue(ctx)
+	if recv.count() != 2 {
+		t.Fatalf("expected retry after backoff, got %d attempts", recv.count())
+	}
+	if recv.requests[0].Header.Get(HeaderDelivery) != recv.requests[1].Header.Get(HeaderDelivery) {
+		t.Error("expected retries to reuse the delivery ID")
+	}
+
+	log, _ := d.DeliveryLog(ctx, j.ID)
+	if len(log) != 2 || log[0].StatusCode != http.StatusInternalServerError || log[0].Error == "" || log[1].StatusCode != http.StatusOK {
+		t.Errorf("unexpected delivery log: %+v", log)
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d, _ := setupDispatcher(t, Options{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := d.backoff(i + 1); got != want {
+			t.Errorf("attempt %d: expected backoff %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestDispatcher_DeadLetterAndRedrive(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusBadGateway)
+	d, now := setupDispatcher(t, Options{MaxAttempts: 3, BaseBackoff: time.Second})
+
+	j := callbackJob(t, recv.URL, "")
+	d.Notify(ctx, j, &job.JobResult{JobID: j.ID, Status: job.StatusCompleted})
+
+	for i := 0; i < 5; i++ {
+		d.ProcessDue(ctx)
+		*now = now.Add(time.Minute)
+	}
+	if recv.count() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", recv.count())
+	}
+
+	dead, err := d.DeadDeliveries(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list dead deliveries: %v", err)
+	}
+	if len(dead) != 1 || dead[0].JobID != j.ID || dead[0].Attempts != 3 || dead[0].LastError == "" {
+		t.Fatalf("unexpected dead deliveries: %+v", dead)
+	}
+
+	recv.setStatus(http.StatusOK)
+	if err := d.Redrive(ctx, dead[0].ID); err != nil {
+		t.Fatalf("failed to redrive: %v", err)
+	}
+	d.ProcessDue(ctx)
+	if recv.count() != 4 {
+		t.Fatalf("expected redriven delivery to be sent, got %d attempts", recv.count())
+	}
+
+	if dead, _ := d.DeadDeliveries(ctx, 10); len(dead) != 0 {
+		t.Errorf("expected empty dead letter queue, got %d", len(dead))
+	}
+	if err := d.Redrive(ctx, dead[0].ID); err == nil {
+		t.Error("expected error redriving a delivery that isn't dead")
+	}
+}
+
+func TestDispatcher_UnknownSecretIsRetried(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusOK)
+	d, _ := setupDispatcher(t, Options{Secrets: StaticSecrets{}})
+
+	j := callbackJob(t, recv.URL, "missing")
+	d.Notify(ctx, j, &job.JobResult{JobID: j.ID, Status: job.StatusCompleted})
+	d.ProcessDue(ctx)
+
+	if recv.count() != 0 {
+		t.Errorf("expected unsigned request not to be sent, got %d", recv.count())
+	}
+	log, _ := d.DeliveryLog(ctx, j.ID)
+	if len(log) != 1 || log[0].Error == "" {
+		t.Errorf("expected failed attempt in log, got %+v", log)
+	}
+	if n, _ := d.client.ZCard(ctx, d.pendingKey).Result(); n != 1 {
+		t.Errorf("expected delivery to stay pending, got %d", n)
+	}
+}
+
+func TestDispatcher_ClaimedDeliveryNotSentTwice(t *testing.T) {
+	ctx := context.Background()
+	recv := newReceiver(t, http.StatusInternalServerError)
+	d, _ := setupDispatcher(t, Options{BaseBackoff: time.Hour})
+
+	j := callbackJob(t, recv.URL, "")
+	d.Notify(ctx, j, &job.JobResult{JobID: j.ID, Status: job.StatusCompleted})
+
+	// A second dispatcher sharing the same Redis and clock finds nothing due
+	d.ProcessDue(ctx)
+	other := NewDispatcher(d.client, Options{})
+	other.now = d.now
+	if n, _ := other.ProcessDue(ctx); n != 0 {
+		t.Errorf("expected claimed delivery to be skipped, got %d", n)
+	}
+	if recv.count() != 1 {
+		t.Errorf("expected 1 request, got %d", recv.count())
+	}
+}
@@ -0,0 +1,95 @@
+// Package webhook delivers job results to callback URLs over HTTP.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on every webhook request
+const (
+	// HeaderSignature carries "sha256=<hex HMAC>" of "<timestamp>.<body>" when a secret is configured
+	HeaderSignature = "X-Bananas-Signature"
+	// HeaderTimestamp is the Unix time the request was signed
+	HeaderTimestamp = "X-Bananas-Timestamp"
+	// HeaderDelivery is the delivery ID; it is the same across retries, so receivers can deduplicate
+	HeaderDelivery = "X-Bananas-Delivery"
+	// HeaderEvent is job.completed, job.failed or job.expired
+	HeaderEvent = "X-Bananas-Event"
+)
+
+var (
+	// ErrInvalidSignature is returned by Verify when the signature doesn't match
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrUnknownSecret is returned when a secret reference can't be resolved
+	ErrUnknownSecret = errors.New("unknown webhook secret")
+)
+
+// Sign computes the signature header value for a request body
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received webhook's signature and rejects timestamps older than tolerance
+// Receivers call it with the raw request body and the signature and timestamp headers.
+func Verify(secret []byte, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(ts, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+		}
+	}
+
+	expected := Sign(secret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SecretProvider resolves callback secret references to signing secrets
+type SecretProvider interface {
+	Secret(ref string) ([]byte, error)
+}
+
+// StaticSecrets is a SecretProvider backed by a map
+type StaticSecrets map[string][]byte
+
+// Secret returns the secret for ref
+func (s StaticSecrets) Secret(ref string) ([]byte, error) {
+	secret, ok := s[ref]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSecret, ref)
+	}
+	return secret, nil
+}
+
+// EnvSecrets resolves secret references from environment variables:
+// reference "partner-a" reads WEBHOOK_SECRET_PARTNER_A
+type EnvSecrets struct{}
+
+// Secret returns the secret for ref
+func (EnvSecrets) Secret(ref string) ([]byte, error) {
+	name := "WEBHOOK_SECRET_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(ref))
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("%w: %s (%s not set)", ErrUnknownSecret, ref, name)
+	}
+	return []byte(value), nil
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"job_id":"abc"}`)
+	ts := time.Now().Unix()
+
+	sig := Sign(secret, ts, body)
+	if err := Verify(secret, sig, strconv.FormatInt(ts, 10), body, 5*time.Minute); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	if err := Verify(secret, sig, strconv.FormatInt(ts, 10), []byte(`{"job_id":"xyz"}`), 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for tampered body, got %v", err)
+	}
+	if err := Verify([]byte("other"), sig, strconv.FormatInt(ts, 10), body, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for wrong secret, got %v", err)
+	}
+	if err := Verify(secret, sig, strconv.FormatInt(ts+1, 10), body, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for different timestamp, got %v", err)
+	}
+}
+
+func TestVerify_RejectsStaleTimestamp(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{}`)
+	ts := time.Now().Add(-time.Hour).Unix()
+	sig := Sign(secret, ts, body)
+
+	if err := Verify(secret, sig, strconv.FormatInt(ts, 10), body, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected stale timestamp to be rejected, got %v", err)
+	}
+	if err := Verify(secret, sig, strconv.FormatInt(ts, 10), body, 0); err != nil {
+		t.Errorf("expected zero tolerance to skip the age check, got %v", err)
+	}
+	if err := Verify(secret, sig, "not-a-number", body, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected malformed timestamp to be rejected, got %v", err)
+	}
+}
+
+func TestEnvSecrets(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRET_PARTNER_A", "abc123")
+
+	secret, err := EnvSecrets{}.Secret("partner-a")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(secret) != "abc123" {
+		t.Errorf("expected secret 'abc123', got %q", secret)
+	}
+
+	if _, err := (EnvSecrets{}).Secret("partner-b"); !errors.Is(err, ErrUnknownSecret) {
+		t.Errorf("expected ErrUnknownSecret, got %v", err)
+	}
+}
+
+func TestStaticSecrets(t *testing.T) {
+	secrets := StaticSecrets{"partner-a": []byte("abc123")}
+
+	if secret, err := secrets.Secret("partner-a"); err != nil || string(secret) != "abc123" {
+		t.Errorf("expected secret 'abc123', got %q (%v)", secret, err)
+	}
+	if _, err := secrets.Secret("missing"); !errors.Is(err, ErrUnknownSecret) {
+		t.Errorf("expected ErrUnknownSecret, got %v", err)
+	}
+}
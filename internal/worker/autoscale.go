@@ -0,0 +1,237 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/config"
+	"github.com/muaviaUsmani/bananas/internal/logger"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+)
+
+// QueueMetricsRefresher is implemented by queues that can record their current depths
+// in metrics (see queue.RedisQueue.RefreshQueueMetrics)
+type QueueMetricsRefresher interface {
+	RefreshQueueMetrics(ctx context.Context)
+}
+
+// ReplicaPublisher counts a worker deployment's replicas and publishes how many it
+// should have, for an external orchestrator (see fleet.Registry.ReplicaGroup)
+type ReplicaPublisher interface {
+	Replicas(ctx context.Context) (int, error)
+	PublishDesiredReplicas(ctx context.Context, replicas int) error
+}
+
+// autoscaleSignals is one sample of the inputs to a scaling decision
+type autoscaleSignals struct {
+	depth       int64         // jobs waiting in the pool's priority queues
+	wait        time.Duration // average queue wait of jobs started since the last sample
+	utilization float64       // percentage of busy workers
+	cpuPercent  float64
+	memoryBytes uint64
+}
+
+// Autoscaler grows and shrinks a pool's worker goroutines between a minimum and maximum
+//
+// Every interval it reads queue depth, queue wait and worker utilization from the
+// metrics collector. The pool grows by half when the backlog exceeds BacklogPerWorker
+// jobs per worker, jobs wait longer than TargetWait, or utilization reaches
+// TargetUtilization. It shrinks by a quarter when the queues are empty and utilization
+// is below half the target. Above the CPU or memory ceiling it never grows and sheds
+// workers instead. Cooldowns separate consecutive steps.
+//
+// With a ReplicaPublisher, it also publishes how many worker processes the deployment
+// needs, for an orchestrator to scale replicas once pools reach their maximum.
+type Autoscaler struct {
+	pool      *Pool
+	cfg       config.AutoscaleConfig
+	collector *metrics.Collector
+	refresher QueueMetricsRefresher
+	resources ResourceSampler
+	replicas  ReplicaPublisher
+
+	lastScale       time.Time
+	lastWaitTotal   time.Duration
+	lastWaitSamples int64
+}
+
+// NewAutoscaler creates an autoscaler for a pool
+// The pool is resized into the configured range when Run starts.
+func NewAutoscaler(pool *Pool, cfg *config.AutoscaleConfig) *Autoscaler {
+	refresher, _ := pool.queue.(QueueMetricsRefresher)
+	return &Autoscaler{
+		pool:      pool,
+		cfg:       *cfg,
+		collector: metrics.Default(),
+		refresher: refresher,
+		resources: newRuntimeSampler(),
+	}
+}
+
+// SetResourceSampler replaces the sampler used for the CPU and memory ceiling
+func (a *Autoscaler) SetResourceSampler(s ResourceSampler) {
+	a.resources = s
+}
+
+// SetReplicaPublisher publishes the desired replica count after every evaluation
+func (a *Autoscaler) SetReplicaPublisher(p ReplicaPublisher) {
+	a.replicas = p
+}
+
+// Run evaluates the pool every interval until ctx is cancelled or the pool stops
+func (a *Autoscaler) Run(ctx context.Context) {
+	a.pool.Resize(a.cfg.Clamp(a.pool.Concurrency()))
+
+	// Only jobs started from now on count towards the first wait sample
+	m := a.collector.GetMetrics()
+	a.lastWaitTotal, a.lastWaitSamples = m.TotalWaitTime, m.WaitSamples
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.pool.stopChan:
+			return
+		case <-ticker.C:
+			a.evaluate(ctx, time.Now())
+		}
+	}
+}
+
+// evaluate samples the signals, resizes the pool if needed and returns its new size
+func (a *Autoscaler) evaluate(ctx context.Context, now time.Time) int {
+	if a.refresher != nil {
+		a.refresher.RefreshQueueMetrics(ctx)
+	}
+	s := a.sample()
+	current := a.pool.Concurrency()
+
+	overCeiling := (a.cfg.MaxCPUPercent > 0 && s.cpuPercent >= a.cfg.MaxCPUPercent) ||
+		(a.cfg.MaxMemoryBytes > 0 && s.memoryBytes >= a.cfg.MaxMemoryBytes)
+	pressure := a.pressure(current, s)
+	idle := s.depth == 0 && s.utilization < a.cfg.TargetUtilization/2
+
+	want, reason := current, pressure
+	switch {
+	case overCeiling:
+		want, reason = current-shrinkStep(current), "resource ceiling"
+	case pressure != "":
+		want = current + growStep(current)
+	case idle:
+		want, reason = current-shrinkStep(current), "idle"
+	}
+
+	next := a.cfg.Clamp(want)
+	sinceLast := now.Sub(a.lastScale)
+	switch {
+	case next > current && sinceLast >= a.cfg.ScaleUpCooldown:
+	case next < current && sinceLast >= a.cfg.ScaleDownCooldown:
+	default:
+		next = current
+	}
+
+	if next != current {
+		a.pool.Resize(next)
+		a.lastScale = now
+		logger.Info("Autoscaled worker pool",
+			"from", current,
+			"to", next,
+			"reason", reason,
+			"queue_depth", s.depth,
+			"queue_wait", s.wait,
+			"utilization", s.utilization,
+			"cpu_percent", s.cpuPercent,
+			"memory_bytes", s.memoryBytes)
+	}
+
+	if a.replicas != nil {
+		// Demand the pool can't meet within its own limits is what extra replicas are for
+		demand, capacity := want, a.cfg.MaxConcurrency
+		if overCeiling {
+			capacity = current
+			if pressure != "" {
+				demand = current + growStep(current)
+			}
+		}
+		a.publishReplicas(ctx, demand, capacity)
+	}
+	return next
+}
+
+// pressure returns why the pool should grow, or "" if it shouldn't
+func (a *Autoscaler) pressure(current int, s autoscaleSignals) string {
+	switch {
+	case s.depth > int64(current*a.cfg.BacklogPerWorker):
+		return "queue backlog"
+	case a.cfg.TargetWait > 0 && s.wait > a.cfg.TargetWait:
+		return "queue wait"
+	case s.utilization >= a.cfg.TargetUtilization:
+		return "utilization"
+	}
+	return ""
+}
+
+// sample reads the current signals from the metrics collector and resource sampler
+func (a *Autoscaler) sample() autoscaleSignals {
+	m := a.collector.GetMetrics()
+
+	var s autoscaleSignals
+	for _, priority := range a.pool.workerConfig.Priorities {
+		s.depth += m.QueueDepths[priority]
+	}
+	if samples := m.WaitSamples - a.lastWaitSamples; samples > 0 {
+		s.wait = (m.TotalWaitTime - a.lastWaitTotal) / time.Duration(samples)
+	}
+	a.lastWaitTotal, a.lastWaitSamples = m.TotalWaitTime, m.WaitSamples
+	s.utilization = m.WorkerUtilization
+
+	if a.resources != nil {
+		s.cpuPercent, s.memoryBytes = a.resources.Sample()
+	}
+	return s
+}
+
+// publishReplicas publishes how many replicas the deployment needs for demand
+// worker goroutines per replica when each replica can run at most capacity,
+// assuming every replica sees about the same load
+// This is a best-effort operation - failures are logged but don't affect scaling
+func (a *Autoscaler) publishReplicas(ctx context.Context, demand, capacity int) {
+	replicas, err := a.replicas.Replicas(ctx)
+	if err != nil {
+		logger.Warn("Failed to count worker replicas", "error", err)
+		return
+	}
+	if replicas < 1 {
+		replicas = 1
+	}
+	if demand < a.cfg.MinConcurrency {
+		demand = a.cfg.MinConcurrency
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	desired := (replicas*demand + capacity - 1) / capacity
+	if err := a.replicas.PublishDesiredReplicas(ctx, desired); err != nil {
+		logger.Warn("Failed to publish desired replicas", "error", err)
+	}
+}
+
+// growStep is how many workers a scale-up adds
+func growStep(current int) int {
+	if step := current / 2; step > 1 {
+		return step
+	}
+	return 1
+}
+
+// shrinkStep is how many workers a scale-down removes
+func shrinkStep(current int) int {
+	if step := current / 4; step > 1 {
+		return step
+	}
+	return 1
+}
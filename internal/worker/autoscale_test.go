@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/config"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/metrics"
+)
+
+// fakeSampler reports fixed resource usage
+type fakeSampler struct {
+	cpu    float64
+	memory uint64
+}
+
+func (s *fakeSampler) Sample() (float64, uint64) {
+	return s.cpu, s.memory
+}
+
+// fakeReplicas records published replica counts
+type fakeReplicas struct {
+	running   int
+	published []int
+}
+
+func (r *fakeReplicas) Replicas(ctx context.Context) (int, error) {
+	return r.running, nil
+}
+
+func (r *fakeReplicas) PublishDesiredReplicas(ctx context.Context, replicas int) error {
+	r.published = append(r.published, replicas)
+	return nil
+}
+
+func newTestAutoscaler(t *testing.T, concurrency int, cfg *config.AutoscaleConfig) (*Autoscaler, *metrics.Collector, *fakeSampler) {
+	t.Helper()
+	pool := NewPool(NewExecutor(NewRegistry(), &mockQueue{}, concurrency), &mockQueueReader{}, concurrency, time.Second)
+	collector := metrics.NewCollector()
+	sampler := &fakeSampler{}
+
+	a := NewAutoscaler(pool, cfg)
+	a.collector = collector
+	a.SetResourceSampler(sampler)
+	return a, collector, sampler
+}
+
+func TestAutoscaler_ScalesUpOnBacklog(t *testing.T) {
+	a, collector, _ := newTestAutoscaler(t, 4, config.DefaultAutoscaleConfig(2, 20))
+	collector.RecordQueueDepth(job.PriorityNormal, 100) // > 4 workers * 10 jobs
+
+	if got := a.evaluate(context.Background(), time.Now()); got != 6 {
+		t.Errorf("expected pool to grow by half to 6, got %d", got)
+	}
+	if a.pool.Concurrency() != 6 {
+		t.Errorf("expected pool resized to 6, got %d", a.pool.Concurrency())
+	}
+}
+
+func TestAutoscaler_ScalesUpOnWaitTime(t *testing.T) {
+	a, collector, _ := newTestAutoscaler(t, 4, config.DefaultAutoscaleConfig(2, 20))
+	collector.RecordWorkerActivity(2, 4)
+	collector.RecordJobWait(10 * time.Second)
+	collector.RecordJobWait(20 * time.Second)
+
+	if got := a.evaluate(context.Background(), time.Now()); got != 6 {
+		t.Errorf("expected pool to grow on queue wait, got %d", got)
+	}
+
+	// Only waits recorded since the last evaluation count
+	collector.RecordJobWait(time.Second)
+	if got := a.evaluate(context.Background(), time.Now().Add(time.Hour)); got != 6 {
+		t.Errorf("expected pool to hold at 6, got %d", got)
+	}
+}
+
+func TestAutoscaler_ScalesDownWhenIdle(t *testing.T) {
+	a, collector, _ := newTestAutoscaler(t, 8, config.DefaultAutoscaleConfig(2, 20))
+	collector.RecordWorkerActivity(0, 8)
+
+	if got := a.evaluate(context.Background(), time.Now()); got != 6 {
+		t.Errorf("expected pool to shrink by a quarter to 6, got %d", got)
+	}
+}
+
+func TestAutoscaler_RespectsBoundsAndCooldowns(t *testing.T) {
+	cfg := config.DefaultAutoscaleConfig(2, 5)
+	a, collector, _ := newTestAutoscaler(t, 4, cfg)
+	collector.RecordQueueDepth(job.PriorityHigh, 1000)
+
+	now := time.Now()
+	if got := a.evaluate(context.Background(), now); got != 5 {
+		t.Errorf("expected pool capped at max 5, got %d", got)
+	}
+
+	// Idle right after scaling up: the scale-down cooldown holds the pool
+	collector.RecordQueueDepth(job.PriorityHigh, 0)
+	collector.RecordWorkerActivity(0, 5)
+	if got := a.evaluate(context.Background(), now.Add(time.Minute)); got != 5 {
+		t.Errorf("expected scale-down cooldown to hold the pool at 5, got %d", got)
+	}
+	if got := a.evaluate(context.Background(), now.Add(cfg.ScaleDownCooldown)); got != 4 {
+		t.Errorf("expected pool to shrink after the cooldown, got %d", got)
+	}
+
+	// Never below the minimum
+	later := now.Add(cfg.ScaleDownCooldown)
+	for i := 0; i < 5; i++ {
+		later = later.Add(cfg.ScaleDownCooldown)
+		a.evaluate(context.Background(), later)
+	}
+	if a.pool.Concurrency() != 2 {
+		t.Errorf("expected pool floored at min 2, got %d", a.pool.Concurrency())
+	}
+}
+
+func TestAutoscaler_ResourceCeiling(t *testing.T) {
+	cfg := config.DefaultAutoscaleConfig(2, 20)
+	cfg.MaxMemoryBytes = 1 << 30
+	a, collector, sampler := newTestAutoscaler(t, 8, cfg)
+	collector.RecordQueueDepth(job.PriorityNormal, 1000)
+
+	sampler.cpu = 95
+	if got := a.evaluate(context.Background(), time.Now()); got != 6 {
+		t.Errorf("expected pool to shed workers above the CPU ceiling, got %d", got)
+	}
+
+	sampler.cpu = 10
+	sampler.memory = 2 << 30
+	if got := a.evaluate(context.Background(), time.Now().Add(time.Hour)); got != 5 {
+		t.Errorf("expected pool to shed workers above the memory ceiling, got %d", got)
+	}
+}
+
+func TestAutoscaler_PublishesDesiredReplicas(t *testing.T) {
+	cfg := config.DefaultAutoscaleConfig(1, 10)
+	a, collector, sampler := newTestAutoscaler(t, 10, cfg)
+	replicas := &fakeReplicas{running: 3}
+	a.SetReplicaPublisher(replicas)
+
+	// Saturated at the maximum: 3 replicas * 15 wanted workers / 10 per replica
+	collector.RecordQueueDepth(job.PriorityNormal, 1000)
+	a.evaluate(context.Background(), time.Now())
+
+	// At the CPU ceiling the pool sheds workers, and a replica's capacity is
+	// what it runs now, so the same demand still needs 5 replicas
+	sampler.cpu = 99
+	a.evaluate(context.Background(), time.Now().Add(time.Hour))
+
+	// Idle: the minimum fits in fewer replicas
+	sampler.cpu = 0
+	collector.RecordQueueDepth(job.PriorityNormal, 0)
+	a.evaluate(context.Background(), time.Now().Add(2*time.Hour))
+
+	want := []int{5, 5, 2}
+	if len(replicas.published) != len(want) {
+		t.Fatalf("expected published %v, got %v", want, replicas.published)
+	}
+	for i := range want {
+		if replicas.published[i] != want[i] {
+			t.Errorf("expected published %v, got %v", want, replicas.published)
+			break
+		}
+	}
+}
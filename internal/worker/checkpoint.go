@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// CheckpointStore persists handler checkpoints between attempts (see queue.RedisQueue)
+// Executors use it automatically when their Queue implements it.
+type CheckpointStore interface {
+	SaveCheckpoint(ctx context.Context, jobID string, data []byte) error
+	LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error)
+}
+
+// checkpointKey is the context key for the executor's checkpoint slot
+type checkpointKey struct{}
+
+// checkpointSlot connects a running job's handler to the checkpoint store
+type checkpointSlot struct {
+	jobID string
+	store CheckpointStore
+}
+
+// withCheckpoints returns a context in which handlers can use SaveCheckpoint and LoadCheckpoint
+func withCheckpoints(ctx context.Context, jobID string, store CheckpointStore) context.Context {
+	if store == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, checkpointKey{}, &checkpointSlot{jobID: jobID, store: store})
+}
+
+// SaveCheckpoint records how far the running job has got, so a retry can resume
+// from there instead of starting over
+//
+// v is encoded like a result (protobuf messages or JSON); each call replaces the
+// previous checkpoint. It is a no-op outside a handler invoked by an Executor whose
+// queue stores checkpoints.
+func SaveCheckpoint(ctx context.Context, v interface{}) error {
+	slot, ok := ctx.Value(checkpointKey{}).(*checkpointSlot)
+	if !ok {
+		return nil
+	}
+
+	data, err := job.EncodeValue(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	return slot.store.SaveCheckpoint(ctx, slot.jobID, data)
+}
+
+// LoadCheckpoint decodes the running job's last checkpoint into dest
+// Returns false if there is none, e.g. on the first attempt.
+func LoadCheckpoint(ctx context.Context, dest interface{}) (bool, error) {
+	slot, ok := ctx.Value(checkpointKey{}).(*checkpointSlot)
+	if !ok {
+		return false, nil
+	}
+
+	data, err := slot.store.LoadCheckpoint(ctx, slot.jobID)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := job.DecodeValue(data, dest); err != nil {
+		return false, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// leasingQueue is a mockQueue that also stores checkpoints and records heartbeats
+type leasingQueue struct {
+	*mockQueue
+	lease time.Duration
+
+	mu          sync.Mutex
+	checkpoints map[string][]byte
+	heartbeats  int
+}
+
+func newLeasingQueue(lease time.Duration) *leasingQueue {
+	return &leasingQueue{
+		mockQueue:   &mockQueue{},
+		lease:       lease,
+		checkpoints: make(map[string][]byte),
+	}
+}
+
+func (q *leasingQueue) SaveCheckpoint(ctx context.Context, jobID string, data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.checkpoints[jobID] = data
+	return nil
+}
+
+func (q *leasingQueue) LoadCheckpoint(ctx context.Context, jobID string) ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.checkpoints[jobID], nil
+}
+
+func (q *leasingQueue) Heartbeat(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.heartbeats++
+	return nil
+}
+
+func (q *leasingQueue) LeaseDuration() time.Duration {
+	return q.lease
+}
+
+func (q *leasingQueue) heartbeatCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.heartbeats
+}
+
+type etlCheckpoint struct {
+	Offset int `json:"offset"`
+}
+
+func TestCheckpoint_NoopWithoutStore(t *testing.T) {
+	ctx := context.Background()
+	if err := SaveCheckpoint(ctx, etlCheckpoint{Offset: 1}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	var cp etlCheckpoint
+	found, err := LoadCheckpoint(ctx, &cp)
+	if found || err != nil {
+		t.Errorf("expected no checkpoint, got %v (%v)", found, err)
+	}
+}
+
+func TestExecuteJob_ResumesFromCheckpoint(t *testing.T) {
+	var starts []int
+	registry := NewRegistry()
+	registry.Register("etl", func(ctx context.Context, j *job.Job) error {
+		var cp etlCheckpoint
+		if _, err := LoadCheckpoint(ctx, &cp); err != nil {
+			return err
+		}
+		starts = append(starts, cp.Offset)
+
+		for offset := cp.Offset; offset < 30; offset += 10 {
+			if j.Attempts == 0 && offset == 20 {
+				return errors.New("connection reset")
+			}
+			if err := SaveCheckpoint(ctx, etlCheckpoint{Offset: offset + 10}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	queue := newLeasingQueue(time.Minute)
+	executor := NewExecutor(registry, queue, 1)
+	j := job.NewJob("etl", []byte(`{}`), job.PriorityNormal)
+
+	if err := executor.ExecuteJob(context.Background(), j); err == nil {
+		t.Fatal("expected first attempt to fail")
+	}
+	j.Attempts = 1 // as recorded by the queue's Fail
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+
+	if len(starts) != 2 || starts[0] != 0 || starts[1] != 20 {
+		t.Errorf("expected attempts to start at offsets [0 20], got %v", starts)
+	}
+}
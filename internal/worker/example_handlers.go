@@ -21,6 +21,18 @@ func HandleCountItems(ctx context.Context, j *job.Job) error {
 	return nil
 }
 
+// CountItemsResult is the result of the typed count_items handler
+type CountItemsResult struct {
+	Count int `json:"count"`
+}
+
+// CountItems counts items in a JSON array payload
+// Register it with RegisterTyped - the payload is decoded and the result stored automatically
+func CountItems(ctx context.Context, items []string) (CountItemsResult, error) {
+	log.Printf("Counted %d items", len(items))
+	return CountItemsResult{Count: len(items)}, nil
+}
+
 // HandleSendEmail simulates sending an email
 func HandleSendEmail(ctx context.Context, j *job.Job) error {
 	var email struct {
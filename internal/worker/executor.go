@@ -2,19 +2,34 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/events"
 	"github.com/muaviaUsmani/bananas/internal/job"
 	"github.com/muaviaUsmani/bananas/internal/metrics"
+	"github.com/muaviaUsmani/bananas/internal/queue"
 	"github.com/muaviaUsmani/bananas/internal/result"
+	"github.com/muaviaUsmani/bananas/internal/schema"
 )
 
-// Queue interface defines the methods needed for job queue operations
-type Queue interface {
-	Complete(ctx context.Context, jobID string) error
-	Fail(ctx context.Context, j *job.Job, errMsg string) error
+// Queue is the broker side the executor settles jobs through (any queue.Broker)
+type Queue = queue.Consumer
+
+// ErrJobExpired is returned by ExecuteJob for jobs discarded because their deadline passed
+var ErrJobExpired = errors.New("job deadline passed before it started")
+
+// ErrWorkerShutdown is the cancellation cause of jobs still running when a pool's
+// shutdown grace period ends; such jobs are requeued without counting an attempt
+var ErrWorkerShutdown = errors.New("worker shutting down")
+
+// WebhookNotifier schedules delivery of a job's result to its callback URL
+// Implementations must not block on the HTTP request (see webhook.Dispatcher)
+type WebhookNotifier interface {
+	Notify(ctx context.Context, j *job.Job, result *job.JobResult) error
 }
 
 // Executor manages job execution with concurrency control
@@ -23,15 +38,33 @@ type Executor struct {
 	queue         Queue
 	resultBackend result.Backend
 	concurrency   int
+	middleware    []Middleware
+	hooks         *events.Registry
+	webhooks      WebhookNotifier
+	progress      ProgressReporter
+	checkpoints   CheckpointStore
+	heartbeats    Heartbeater
+	completions   CompletionStore
 }
 
 // NewExecutor creates a new job executor with Redis queue integration
+// Checkpoints, lease heartbeats and idempotency keys are enabled if the queue supports them.
 func NewExecutor(registry *Registry, queue Queue, concurrency int) *Executor {
-	return &Executor{
+	e := &Executor{
 		registry:    registry,
 		queue:       queue,
 		concurrency: concurrency,
 	}
+	if store, ok := queue.(CheckpointStore); ok {
+		e.checkpoints = store
+	}
+	if hb, ok := queue.(Heartbeater); ok {
+		e.heartbeats = hb
+	}
+	if store, ok := queue.(CompletionStore); ok {
+		e.completions = store
+	}
+	return e
 }
 
 // SetResultBackend sets the result backend for storing job results
@@ -40,17 +73,98 @@ func (e *Executor) SetResultBackend(backend result.Backend) {
 	e.resultBackend = backend
 }
 
+// SetWebhookNotifier enables result callbacks for jobs with a CallbackURL
+// Callbacks are sent when a job completes or fails permanently, not on retries
+func (e *Executor) SetWebhookNotifier(notifier WebhookNotifier) {
+	e.webhooks = notifier
+}
+
+// SetProgressReporter enables ReportProgress for handlers run by this executor
+func (e *Executor) SetProgressReporter(reporter ProgressReporter) {
+	e.progress = reporter
+}
+
+// SetHooks sets the lifecycle hook registry that receives start events
+// Share the queue's registry (RedisQueue.Hooks) so all transitions go to the same hooks and sinks
+func (e *Executor) SetHooks(hooks *events.Registry) {
+	e.hooks = hooks
+}
+
+// Use adds middleware applied around every handler this executor runs
+// Executor middleware wraps the registry's global and per-job-name middleware
+func (e *Executor) Use(middleware ...Middleware) {
+	e.middleware = append(e.middleware, middleware...)
+}
+
 // ExecuteJob executes a single job using the registered handler and updates Redis queue
 func (e *Executor) ExecuteJob(ctx context.Context, j *job.Job) error {
-	// Look up handler
-	handler, exists := e.registry.Get(j.Name)
+	// Jobs past their deadline are discarded without running
+	if j.IsExpired(time.Now()) {
+		return e.expire(ctx, j)
+	}
+
+	// Look up handler (wrapped with registry middleware)
+	handler, exists := e.registry.Handler(j.Name)
 	if !exists {
 		err := fmt.Errorf("no handler registered for job: %s", j.Name)
 		// Mark as failed in queue (will go to dead letter queue after max retries)
-		if queueErr := e.queue.Fail(ctx, j, err.Error()); queueErr != nil {
+		queueErr := e.queue.Fail(ctx, j, err.Error())
+		if queueErr != nil {
 			log.Printf("Failed to mark job %s as failed in queue: %v", j.ID, queueErr)
 		}
+		if !lostLease(queueErr) {
+			e.finishIfFinal(ctx, j, e.newResult(j.ID, job.StatusFailed, nil, err.Error(), 0), nil)
+		}
+		return err
+	}
+
+	// Validate payload - retrying a job with an invalid payload can never succeed,
+	// but one that couldn't be read (e.g. the blob store was down) is retried
+	if err := e.registry.ValidatePayload(ctx, j); err != nil && !errors.Is(err, schema.ErrInvalidPayload) {
+		log.Printf("Job %s payload unavailable: %v", j.ID, err)
+		metrics.Default().RecordJobFailed(j.Priority, 0)
+
+		res := e.newResult(j.ID, job.StatusFailed, nil, err.Error(), 0)
+		e.storeResult(ctx, res)
+
+		queueErr := e.queue.Fail(ctx, j, err.Error())
+		if queueErr != nil {
+			log.Printf("Failed to update job %s in queue after failure: %v", j.ID, queueErr)
+		}
+		if !lostLease(queueErr) {
+			e.finishIfFinal(ctx, j, res, nil)
+		}
 		return err
+	} else if err != nil {
+		errMsg := fmt.Sprintf("payload validation failed: %v", err)
+		log.Printf("Job %s rejected: %s", j.ID, errMsg)
+
+		// Record job failure in metrics
+		metrics.Default().RecordJobFailed(j.Priority, 0)
+
+		// Store result if backend is configured
+		res := e.newResult(j.ID, job.StatusFailed, nil, errMsg, 0)
+		e.storeResult(ctx, res)
+
+		queueErr := e.queue.DeadLetter(ctx, j, errMsg)
+		if queueErr != nil {
+			log.Printf("Failed to move job %s to dead letter queue: %v", j.ID, queueErr)
+		}
+		if !lostLease(queueErr) {
+			e.finish(ctx, j, res, nil)
+		}
+		return fmt.Errorf("payload validation failed: %w", err)
+	}
+
+	// Work another attempt or job already completed isn't done again
+	var idempotencyKey string
+	if e.completions != nil {
+		idempotencyKey = e.registry.IdempotencyKeyFor(j)
+	}
+	if idempotencyKey != "" {
+		if done, err := e.completeDuplicate(ctx, j, idempotencyKey); done {
+			return err
+		}
 	}
 
 	// Update status to Processing (already done by queue.Dequeue, but update locally)
@@ -59,30 +173,56 @@ func (e *Executor) ExecuteJob(ctx context.Context, j *job.Job) error {
 
 	// Record job started in metrics
 	metrics.Default().RecordJobStarted(j.Priority)
+	e.hooks.Emit(ctx, events.TypeStart, j)
 
-	// Execute handler with context
+	// Execute handler with context (handlers report results via SetResult,
+	// progress via ReportProgress and checkpoints via SaveCheckpoint)
+	handler = Chain(handler, e.middleware...)
+	handlerCtx, result := withResultSlot(ctx)
+	handlerCtx, prog := withProgress(handlerCtx, j.ID, e.progress)
+	handlerCtx = withCheckpoints(handlerCtx, j.ID, e.checkpoints)
+	stopHeartbeat := startHeartbeat(ctx, j.ID, e.heartbeats)
 	startTime := time.Now()
-	err := handler(ctx, j)
+	err := handler(handlerCtx, j)
 	duration := time.Since(startTime)
+	stopHeartbeat()
+
+	// Bookkeeping must still reach Redis when the job's context was cancelled or timed out
+	jobCtx := ctx
+	ctx = context.WithoutCancel(ctx)
 
 	// Update job based on result
 	if err != nil {
+		// Jobs interrupted by a worker shutdown go back to their queue as if never started
+		if errors.Is(context.Cause(jobCtx), ErrWorkerShutdown) {
+			log.Printf("Job %s interrupted by worker shutdown, requeueing", j.ID)
+			if queueErr := e.queue.Requeue(ctx, j); queueErr != nil {
+				log.Printf("Failed to requeue job %s: %v", j.ID, queueErr)
+			}
+			return fmt.Errorf("job requeued: %w", ErrWorkerShutdown)
+		}
+
 		// Check if error was due to context cancellation
-		if ctx.Err() != nil {
-			log.Printf("Job %s cancelled: %v", j.ID, ctx.Err())
-			errMsg := fmt.Sprintf("context cancelled: %v", ctx.Err())
+		if jobCtx.Err() != nil {
+			log.Printf("Job %s cancelled: %v", j.ID, jobCtx.Err())
+			errMsg := fmt.Sprintf("context cancelled: %v", jobCtx.Err())
 
 			// Record job failure in metrics
 			metrics.Default().RecordJobFailed(j.Priority, duration)
 
 			// Store result if backend is configured
-			e.storeResult(ctx, j.ID, job.StatusFailed, nil, errMsg, duration)
+			res := e.newResult(j.ID, job.StatusFailed, nil, errMsg, duration)
+			e.storeResult(ctx, res)
 
 			// Mark as failed in queue (will trigger exponential backoff retry)
-			if queueErr := e.queue.Fail(ctx, j, errMsg); queueErr != nil {
+			queueErr := e.queue.Fail(ctx, j, errMsg)
+			if queueErr != nil {
 				log.Printf("Failed to update job %s in queue after cancellation: %v", j.ID, queueErr)
 			}
-			return fmt.Errorf("job cancelled: %w", ctx.Err())
+			if !lostLease(queueErr) {
+				e.finishIfFinal(ctx, j, res, prog)
+			}
+			return fmt.Errorf("job cancelled: %w", jobCtx.Err())
 		}
 
 		// Handler returned an error
@@ -92,12 +232,36 @@ func (e *Executor) ExecuteJob(ctx context.Context, j *job.Job) error {
 		metrics.Default().RecordJobFailed(j.Priority, duration)
 
 		// Store result if backend is configured
-		e.storeResult(ctx, j.ID, job.StatusFailed, nil, err.Error(), duration)
+		// Handlers may report a result with the error (e.g. the failing HTTP response)
+		res := e.newResult(j.ID, job.StatusFailed, result.data, err.Error(), duration)
+		e.storeResult(ctx, res)
+
+		// Permanent errors can't be fixed by retrying - skip straight to the dead letter queue
+		if bananaserrors.IsPermanent(err) {
+			queueErr := e.queue.DeadLetter(ctx, j, err.Error())
+			if queueErr != nil {
+				log.Printf("Failed to move job %s to dead letter queue: %v", j.ID, queueErr)
+			}
+			if !lostLease(queueErr) {
+				e.finish(ctx, j, res, prog)
+			}
+			return err
+		}
 
-		// Mark as failed in queue (will trigger exponential backoff retry)
-		if queueErr := e.queue.Fail(ctx, j, err.Error()); queueErr != nil {
+		// Mark as failed in queue (will trigger exponential backoff retry,
+		// or a retry after the delay the handler asked for)
+		var queueErr error
+		if delay, ok := bananaserrors.RetryDelay(err); ok {
+			queueErr = e.queue.FailAfter(ctx, j, err.Error(), delay)
+		} else {
+			queueErr = e.queue.Fail(ctx, j, err.Error())
+		}
+		if queueErr != nil {
 			log.Printf("Failed to update job %s in queue after failure: %v", j.ID, queueErr)
 		}
+		if !lostLease(queueErr) {
+			e.finishIfFinal(ctx, j, res, prog)
+		}
 		return err
 	}
 
@@ -108,24 +272,47 @@ func (e *Executor) ExecuteJob(ctx context.Context, j *job.Job) error {
 	metrics.Default().RecordJobCompleted(j.Priority, duration)
 
 	// Store result if backend is configured
-	e.storeResult(ctx, j.ID, job.StatusCompleted, nil, "", duration)
+	res := e.newResult(j.ID, job.StatusCompleted, result.data, "", duration)
+	e.storeResult(ctx, res)
 
-	if err := e.queue.Complete(ctx, j.ID); err != nil {
-		log.Printf("Failed to mark job %s as completed in queue: %v", j.ID, err)
-		return fmt.Errorf("job succeeded but failed to update queue: %w", err)
+	// The idempotency key is recorded by the same call that acknowledges the job,
+	// so later duplicates are recognized exactly when the job counts as completed
+	var queueErr error
+	if idempotencyKey != "" {
+		queueErr = e.completions.CompleteIdempotent(ctx, j.ID, idempotencyKey)
+	} else {
+		queueErr = e.queue.Complete(ctx, j.ID)
+	}
+	if queueErr != nil {
+		log.Printf("Failed to mark job %s as completed in queue: %v", j.ID, queueErr)
+		return fmt.Errorf("job succeeded but failed to update queue: %w", queueErr)
 	}
+	e.finish(ctx, j, res, prog)
 
 	return nil
 }
 
-// storeResult stores the job result in the backend if configured
-// This is a best-effort operation - failures are logged but don't fail the job
-func (e *Executor) storeResult(ctx context.Context, jobID string, status job.JobStatus, resultData []byte, errorMsg string, duration time.Duration) {
-	if e.resultBackend == nil {
-		return // Result backend not configured
+// expire discards a job whose deadline has passed
+func (e *Executor) expire(ctx context.Context, j *job.Job) error {
+	reason := fmt.Sprintf("deadline %s passed before the job started", j.Deadline.Format(time.RFC3339))
+	log.Printf("Job %s expired: %s", j.ID, reason)
+
+	res := e.newResult(j.ID, job.StatusExpired, nil, reason, 0)
+	e.storeResult(ctx, res)
+
+	err := e.queue.Expire(ctx, j, reason)
+	if err != nil {
+		log.Printf("Failed to mark job %s as expired in queue: %v", j.ID, err)
 	}
+	if !lostLease(err) {
+		e.finish(ctx, j, res, nil)
+	}
+	return ErrJobExpired
+}
 
-	result := &job.JobResult{
+// newResult builds the result record for a finished job attempt
+func (e *Executor) newResult(jobID string, status job.JobStatus, resultData []byte, errorMsg string, duration time.Duration) *job.JobResult {
+	return &job.JobResult{
 		JobID:       jobID,
 		Status:      status,
 		Result:      resultData,
@@ -133,9 +320,50 @@ func (e *Executor) storeResult(ctx context.Context, jobID string, status job.Job
 		CompletedAt: time.Now(),
 		Duration:    duration,
 	}
+}
+
+// storeResult stores the job result in the backend if configured
+// This is a best-effort operation - failures are logged but don't fail the job
+func (e *Executor) storeResult(ctx context.Context, result *job.JobResult) {
+	if e.resultBackend == nil {
+		return // Result backend not configured
+	}
 
 	if err := e.resultBackend.StoreResult(ctx, result); err != nil {
-		log.Printf("Failed to store result for job %s: %v", jobID, err)
+		log.Printf("Failed to store result for job %s: %v", result.JobID, err)
 	}
 }
 
+// finish runs the side effects of a job reaching its final state: the result
+// callback and, if the job reported progress, the final progress update
+func (e *Executor) finish(ctx context.Context, j *job.Job, result *job.JobResult, prog *progressSlot) {
+	e.notify(ctx, j, result)
+	prog.done(ctx, result.Status)
+}
+
+// lostLease reports whether a queue transition failed because the job is no longer
+// this worker's (its lease expired and it was reaped or redelivered). Its result
+// callback and final progress then belong to the attempt that holds it now.
+func lostLease(queueErr error) bool {
+	return errors.Is(queueErr, queue.ErrLeaseLost)
+}
+
+// finishIfFinal calls finish only once the queue has given up on the job
+// (Fail marks the job failed when it moves it to the dead letter queue)
+func (e *Executor) finishIfFinal(ctx context.Context, j *job.Job, result *job.JobResult, prog *progressSlot) {
+	if j.Status == job.StatusFailed {
+		e.finish(ctx, j, result, prog)
+	}
+}
+
+// notify schedules the job's result callback if configured
+// This is a best-effort operation - failures are logged but don't fail the job
+func (e *Executor) notify(ctx context.Context, j *job.Job, result *job.JobResult) {
+	if e.webhooks == nil || !j.HasCallback() {
+		return
+	}
+
+	if err := e.webhooks.Notify(ctx, j, result); err != nil {
+		log.Printf("Failed to schedule result callback for job %s: %v", j.ID, err)
+	}
+}
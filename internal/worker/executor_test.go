@@ -4,20 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/events"
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/schema"
+	"github.com/muaviaUsmani/bananas/internal/storage"
 )
 
 // mockQueue is a mock implementation of the Queue interface for testing
 type mockQueue struct {
-	completeCalled bool
-	failCalled     bool
-	lastError      string
-	lastJobID      string
-	completeErr    error
-	failErr        error
+	completeCalled   bool
+	failCalled       bool
+	deadLetterCalled bool
+	expireCalled     bool
+	lastRetryDelay   time.Duration
+	lastError        string
+	lastJobID        string
+	completeErr      error
+	failErr          error
+	deadLetterErr    error
+	requeueCount     atomic.Int64 // Requeue may be called by the pool and executor concurrently
+}
+
+func (m *mockQueue) Dequeue(ctx context.Context, priorities []job.JobPriority) (*job.Job, error) {
+	return nil, nil
 }
 
 func (m *mockQueue) Complete(ctx context.Context, jobID string) error {
@@ -33,6 +50,30 @@ func (m *mockQueue) Fail(ctx context.Context, j *job.Job, errMsg string) error {
 	return m.failErr
 }
 
+func (m *mockQueue) FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	m.lastRetryDelay = delay
+	return m.Fail(ctx, j, errMsg)
+}
+
+func (m *mockQueue) Requeue(ctx context.Context, j *job.Job) error {
+	m.requeueCount.Add(1)
+	return nil
+}
+
+func (m *mockQueue) Expire(ctx context.Context, j *job.Job, reason string) error {
+	m.expireCalled = true
+	m.lastError = reason
+	m.lastJobID = j.ID
+	return nil
+}
+
+func (m *mockQueue) DeadLetter(ctx context.Context, j *job.Job, errMsg string) error {
+	m.deadLetterCalled = true
+	m.lastError = errMsg
+	m.lastJobID = j.ID
+	return m.deadLetterErr
+}
+
 func TestNewExecutor(t *testing.T) {
 	registry := NewRegistry()
 	queue := &mockQueue{}
@@ -180,3 +221,330 @@ func TestExecuteJob_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestExecuteJob_ShutdownRequeuesWithoutAttempt(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("slow_job", func(ctx context.Context, j *job.Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	mockQ := &mockQueue{}
+	backend := &mockResultBackend{}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetResultBackend(backend)
+	j := job.NewJob("slow_job", []byte("{}"), job.PriorityNormal)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(50*time.Millisecond, func() { cancel(ErrWorkerShutdown) })
+
+	err := executor.ExecuteJob(ctx, j)
+	if !errors.Is(err, ErrWorkerShutdown) {
+		t.Fatalf("expected ErrWorkerShutdown, got %v", err)
+	}
+	if mockQ.requeueCount.Load() != 1 {
+		t.Errorf("expected job to be requeued once, got %d", mockQ.requeueCount.Load())
+	}
+	if mockQ.failCalled {
+		t.Error("expected no failed attempt to be recorded")
+	}
+	if backend.results[j.ID] != nil {
+		t.Error("expected no result to be stored for a requeued job")
+	}
+}
+
+func TestExecuteJob_InvalidPayloadGoesToDeadLetter(t *testing.T) {
+	registry := NewRegistry()
+
+	handlerCalled := false
+	registry.Register("send_email", func(ctx context.Context, j *job.Job) error {
+		handlerCalled = true
+		return nil
+	})
+	registry.RegisterSchema("send_email", schema.MustJSONSchema(`{
+		"type": "object",
+		"required": ["to"],
+		"properties": {"to": {"type": "string"}}
+	}`))
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("send_email", []byte(`{"to": 42}`), job.PriorityNormal)
+
+	err := executor.ExecuteJob(context.Background(), j)
+
+	if !errors.Is(err, schema.ErrInvalidPayload) {
+		t.Fatalf("expected ErrInvalidPayload, got %v", err)
+	}
+	if handlerCalled {
+		t.Error("expected handler not to be called for invalid payload")
+	}
+	if !mockQ.deadLetterCalled {
+		t.Error("expected DeadLetter to be called on queue")
+	}
+	if mockQ.failCalled {
+		t.Error("expected Fail not to be called (invalid payloads are not retried)")
+	}
+	if !strings.Contains(mockQ.lastError, "$.to: expected string") {
+		t.Errorf("expected validation error message, got '%s'", mockQ.lastError)
+	}
+}
+
+// unavailableBlobStore fails every read, like a blob store that is down
+type unavailableBlobStore struct {
+	storage.BlobStore
+}
+
+func (unavailableBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("blob store unavailable")
+}
+
+func TestExecuteJob_UnreadablePayloadIsRetried(t *testing.T) {
+	original := job.DefaultBlobStore
+	job.DefaultBlobStore = unavailableBlobStore{}
+	defer func() { job.DefaultBlobStore = original }()
+
+	registry := NewRegistry()
+	handlerCalled := false
+	registry.Register("send_email", func(ctx context.Context, j *job.Job) error {
+		handlerCalled = true
+		return nil
+	})
+	registry.RegisterSchema("send_email", schema.MustJSONSchema(`{"type": "object", "required": ["to"]}`))
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("send_email", nil, job.PriorityNormal)
+	j.PayloadRef = "payloads/" + j.ID
+
+	err := executor.ExecuteJob(context.Background(), j)
+
+	if err == nil || errors.Is(err, schema.ErrInvalidPayload) {
+		t.Fatalf("expected a payload read error, got %v", err)
+	}
+	if handlerCalled {
+		t.Error("expected handler not to be called when the payload can't be read")
+	}
+	if !mockQ.failCalled {
+		t.Error("expected Fail to be called so the job is retried")
+	}
+	if mockQ.deadLetterCalled {
+		t.Error("expected DeadLetter not to be called for an unreadable payload")
+	}
+	if !strings.Contains(mockQ.lastError, "blob store unavailable") {
+		t.Errorf("expected blob store error message, got '%s'", mockQ.lastError)
+	}
+}
+
+func TestExecuteJob_ValidPayloadRunsHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("send_email", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+	registry.RegisterSchema("send_email", schema.MustJSONSchema(`{"type": "object", "required": ["to"]}`))
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("send_email", []byte(`{"to": "user@example.com"}`), job.PriorityNormal)
+
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mockQ.completeCalled {
+		t.Error("expected Complete to be called on queue")
+	}
+}
+
+func TestExecuteJob_EmitsStartEvent(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+
+	hooks := events.NewRegistry()
+	var started []string
+	hooks.OnStart(func(ctx context.Context, e events.Event) {
+		started = append(started, e.JobID)
+	})
+
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.SetHooks(hooks)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(started) != 1 || started[0] != j.ID {
+		t.Errorf("expected one start event for job %s, got %v", j.ID, started)
+	}
+}
+
+// mockNotifier records webhook notifications
+type mockNotifier struct {
+	results []*job.JobResult
+}
+
+func (m *mockNotifier) Notify(ctx context.Context, j *job.Job, result *job.JobResult) error {
+	m.results = append(m.results, result)
+	return nil
+}
+
+func TestExecuteJob_NotifiesCallbackOnCompletion(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+
+	notifier := &mockNotifier{}
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.SetWebhookNotifier(notifier)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	if err := j.SetCallback("https://partner.example.com/hook", ""); err != nil {
+		t.Fatalf("failed to set callback: %v", err)
+	}
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(notifier.results) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.results))
+	}
+	if notifier.results[0].Status != job.StatusCompleted || notifier.results[0].JobID != j.ID {
+		t.Errorf("unexpected notification: %+v", notifier.results[0])
+	}
+}
+
+func TestExecuteJob_NotifiesCallbackOnlyOnFinalFailure(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		return errors.New("boom")
+	})
+
+	notifier := &mockNotifier{}
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetWebhookNotifier(notifier)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	j.SetCallback("https://partner.example.com/hook", "")
+
+	// Retryable failure: the mock queue leaves the job processing, as a retry would
+	executor.ExecuteJob(context.Background(), j)
+	if len(notifier.results) != 0 {
+		t.Fatalf("expected no notification for a retried job, got %d", len(notifier.results))
+	}
+
+	// Permanent failure goes to the dead letter queue and is final
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		return bananaserrors.Permanent(errors.New("bad input"))
+	})
+	executor.ExecuteJob(context.Background(), j)
+	if len(notifier.results) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.results))
+	}
+	if !notifier.results[0].IsFailed() || notifier.results[0].Error == "" {
+		t.Errorf("expected failed result with error, got %+v", notifier.results[0])
+	}
+}
+
+func TestExecuteJob_LostLeaseSkipsCallback(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("ok_job", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+	registry.Register("bad_job", func(ctx context.Context, j *job.Job) error {
+		return bananaserrors.Permanent(errors.New("bad input"))
+	})
+
+	// The job was reaped while its handler ran, so its transitions no longer apply
+	lost := fmt.Errorf("%w: job is not running", queue.ErrLeaseLost)
+	notifier := &mockNotifier{}
+	mockQ := &mockQueue{completeErr: lost, deadLetterErr: lost}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetWebhookNotifier(notifier)
+
+	for _, name := range []string{"ok_job", "bad_job"} {
+		j := job.NewJob(name, []byte(`{}`), job.PriorityNormal)
+		j.SetCallback("https://partner.example.com/hook", "")
+		executor.ExecuteJob(context.Background(), j)
+	}
+
+	if len(notifier.results) != 0 {
+		t.Errorf("expected no notification for jobs whose lease was lost, got %d", len(notifier.results))
+	}
+}
+
+func TestExecuteJob_NoCallbackNoNotification(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+
+	notifier := &mockNotifier{}
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.SetWebhookNotifier(notifier)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(notifier.results) != 0 {
+		t.Errorf("expected no notification, got %d", len(notifier.results))
+	}
+}
+
+func TestExecuteJob_ExpiredJobIsDiscarded(t *testing.T) {
+	registry := NewRegistry()
+	handlerCalled := false
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		handlerCalled = true
+		return nil
+	})
+
+	mockQ := &mockQueue{}
+	backend := &mockResultBackend{}
+	notifier := &mockNotifier{}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetResultBackend(backend)
+	executor.SetWebhookNotifier(notifier)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	j.SetCallback("https://partner.example.com/hook", "")
+	j.SetDeadline(time.Now().Add(-time.Minute))
+
+	err := executor.ExecuteJob(context.Background(), j)
+	if !errors.Is(err, ErrJobExpired) {
+		t.Fatalf("expected ErrJobExpired, got %v", err)
+	}
+	if handlerCalled {
+		t.Error("expected handler not to run for an expired job")
+	}
+	if !mockQ.expireCalled || mockQ.failCalled || mockQ.completeCalled {
+		t.Errorf("expected only Expire to be called, got %+v", mockQ)
+	}
+	if r := backend.results[j.ID]; r == nil || r.Status != job.StatusExpired {
+		t.Errorf("expected expired result, got %+v", r)
+	}
+	if len(notifier.results) != 1 || notifier.results[0].Status != job.StatusExpired {
+		t.Errorf("expected expired callback, got %+v", notifier.results)
+	}
+}
+
+func TestExecuteJob_DeadlineInFutureRuns(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+
+	j := job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)
+	j.SetDeadline(time.Now().Add(time.Minute))
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mockQ.completeCalled {
+		t.Error("expected job to complete")
+	}
+}
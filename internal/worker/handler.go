@@ -3,8 +3,10 @@ package worker
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/schema"
 )
 
 // HandlerFunc is a function that processes a job
@@ -12,13 +14,22 @@ type HandlerFunc func(context.Context, *job.Job) error
 
 // Registry manages job handlers by name
 type Registry struct {
-	handlers map[string]HandlerFunc
+	handlers      map[string]HandlerFunc
+	schemas       *schema.Registry
+	middleware    []Middleware
+	jobMiddleware map[string][]Middleware
+	timeouts      map[string]time.Duration
+	idempotency   map[string]IdempotencyKeyFunc
 }
 
 // NewRegistry creates a new handler registry
 func NewRegistry() *Registry {
 	return &Registry{
-		handlers: make(map[string]HandlerFunc),
+		handlers:      make(map[string]HandlerFunc),
+		schemas:       schema.NewRegistry(),
+		jobMiddleware: make(map[string][]Middleware),
+		timeouts:      make(map[string]time.Duration),
+		idempotency:   make(map[string]IdempotencyKeyFunc),
 	}
 }
 
@@ -33,14 +44,99 @@ func (r *Registry) Get(name string) (HandlerFunc, bool) {
 	return handler, exists
 }
 
+// Use adds middleware applied to every handler in the registry
+func (r *Registry) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// UseFor adds middleware applied only to the handler for a specific job name
+// It runs inside the registry's global middleware
+func (r *Registry) UseFor(name string, middleware ...Middleware) {
+	r.jobMiddleware[name] = append(r.jobMiddleware[name], middleware...)
+}
+
+// Handler retrieves a handler by job name wrapped with the registry's global and
+// per-job-name middleware
+func (r *Registry) Handler(name string) (HandlerFunc, bool) {
+	handler, exists := r.Get(name)
+	if !exists {
+		return nil, false
+	}
+
+	middleware := make([]Middleware, 0, len(r.middleware)+len(r.jobMiddleware[name]))
+	middleware = append(middleware, r.middleware...)
+	middleware = append(middleware, r.jobMiddleware[name]...)
+	return Chain(handler, middleware...), true
+}
+
+// SetTimeout sets the default timeout for jobs with a specific name
+// It overrides the worker's JOB_TIMEOUT, longer or shorter (TimeoutMiddleware can only
+// shorten it); a Timeout set on the job itself overrides both
+func (r *Registry) SetTimeout(name string, timeout time.Duration) {
+	r.timeouts[name] = timeout
+}
+
+// TimeoutFor returns the timeout for a job: its own Timeout, else the default set
+// with SetTimeout for its name, else fallback
+func (r *Registry) TimeoutFor(j *job.Job, fallback time.Duration) time.Duration {
+	if j.Timeout > 0 {
+		return j.Timeout
+	}
+	if timeout, ok := r.timeouts[j.Name]; ok && timeout > 0 {
+		return timeout
+	}
+	return fallback
+}
+
+// SetIdempotencyKey declares how to derive the idempotency key of jobs with a specific name
+// Once a job with a key completes, later jobs with the same name and key (including
+// redeliveries of the same job) complete with its stored result without running.
+func (r *Registry) SetIdempotencyKey(name string, fn IdempotencyKeyFunc) {
+	r.idempotency[name] = fn
+}
+
+// IdempotencyKeyFor returns a job's idempotency key scoped to its name, or "" if it has none
+func (r *Registry) IdempotencyKeyFor(j *job.Job) string {
+	fn, ok := r.idempotency[j.Name]
+	if !ok {
+		return ""
+	}
+	key := fn(j)
+	if key == "" {
+		return ""
+	}
+	return j.Name + ":" + key
+}
+
+// RegisterSchema sets the payload schema for a job name
+// Jobs with payloads that don't match are sent to the dead letter queue without running the handler
+func (r *Registry) RegisterSchema(name string, s schema.Schema) {
+	r.schemas.Register(name, s)
+}
+
+// ValidatePayload checks a job's payload against the schema registered for its name
+// Offloaded payloads are resolved and encrypted payloads decrypted before validation.
+// Payloads that don't match return an error wrapping schema.ErrInvalidPayload; any
+// other error (e.g. an unreachable blob store or a missing key) may go away on retry.
+func (r *Registry) ValidatePayload(ctx context.Context, j *job.Job) error {
+	if _, exists := r.schemas.Get(j.Name); !exists {
+		return nil
+	}
+	payload, err := j.PlainPayload(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read payload: %w", err)
+	}
+	return r.schemas.Validate(j.Name, payload)
+}
+
 // Count returns the number of registered handlers
 func (r *Registry) Count() int {
 	return len(r.handlers)
 }
 
-// Execute runs the appropriate handler for a job
+// Execute runs the appropriate handler for a job, wrapped with the registry's middleware
 func (r *Registry) Execute(ctx context.Context, j *job.Job) error {
-	handler, exists := r.Get(j.Name)
+	handler, exists := r.Handler(j.Name)
 	if !exists {
 		return fmt.Errorf("no handler registered for job: %s", j.Name)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/job"
 )
@@ -146,3 +147,23 @@ func TestRegistry_MultipleHandlers(t *testing.T) {
 	}
 }
 
+
+func TestRegistry_TimeoutFor(t *testing.T) {
+	registry := NewRegistry()
+	registry.SetTimeout("report", 2*time.Hour)
+
+	email := job.NewJob("email", []byte(`{}`), job.PriorityNormal)
+	if got := registry.TimeoutFor(email, 5*time.Minute); got != 5*time.Minute {
+		t.Errorf("expected worker default 5m, got %v", got)
+	}
+
+	report := job.NewJob("report", []byte(`{}`), job.PriorityNormal)
+	if got := registry.TimeoutFor(report, 5*time.Minute); got != 2*time.Hour {
+		t.Errorf("expected handler default 2h, got %v", got)
+	}
+
+	report.SetTimeout(10 * time.Minute)
+	if got := registry.TimeoutFor(report, 5*time.Minute); got != 10*time.Minute {
+		t.Errorf("expected job timeout 10m, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Heartbeater extends the lease on a running job (see queue.RedisQueue)
+// Executors send heartbeats automatically when their Queue implements it.
+type Heartbeater interface {
+	Heartbeat(ctx context.Context, jobID string) error
+	LeaseDuration() time.Duration
+}
+
+// startHeartbeat renews a job's lease every third of the lease duration until
+// the returned stop function is called, so long-running jobs aren't reaped
+func startHeartbeat(ctx context.Context, jobID string, hb Heartbeater) (stop func()) {
+	if hb == nil {
+		return func() {}
+	}
+
+	interval := hb.LeaseDuration() / 3
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Keep beating on errors - a transient failure shouldn't lose the lease
+				if err := hb.Heartbeat(ctx, jobID); err != nil && ctx.Err() == nil {
+					log.Printf("Failed to send heartbeat for job %s: %v", jobID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+func TestExecuteJob_SendsHeartbeats(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("long_job", func(ctx context.Context, j *job.Job) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	queue := newLeasingQueue(30 * time.Millisecond)
+	executor := NewExecutor(registry, queue, 1)
+
+	j := job.NewJob("long_job", []byte(`{}`), job.PriorityNormal)
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	beats := queue.heartbeatCount()
+	if beats < 3 {
+		t.Errorf("expected at least 3 heartbeats, got %d", beats)
+	}
+
+	// Heartbeats stop once the handler returns
+	time.Sleep(50 * time.Millisecond)
+	if after := queue.heartbeatCount(); after != beats {
+		t.Errorf("expected no heartbeats after the job finished, got %d more", after-beats)
+	}
+}
+
+func TestStartHeartbeat_NoopWithoutHeartbeater(t *testing.T) {
+	stop := startHeartbeat(context.Background(), "job-1", nil)
+	stop()
+}
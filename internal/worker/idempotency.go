@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// IdempotencyKeyFunc derives a job's idempotency key, typically from its payload
+// Jobs with the same name and key do the same work, e.g. charge the same payment.
+// Returning "" runs the job without the check.
+type IdempotencyKeyFunc func(j *job.Job) string
+
+// CompletionStore records which job completed each idempotency key (see queue.RedisQueue)
+// Executors use it automatically when their Queue implements it. CompleteIdempotent
+// completes a running job and records it for the key atomically, so a job is never
+// acknowledged without its record.
+type CompletionStore interface {
+	CompleteIdempotent(ctx context.Context, jobID, key string) error
+	CompletedBy(ctx context.Context, key string) (string, error)
+}
+
+// completeDuplicate completes a job without running it if another attempt or job
+// already completed its idempotency key, returning that job's stored result
+// Returns false if the key hasn't been completed, so the job should run.
+func (e *Executor) completeDuplicate(ctx context.Context, j *job.Job, key string) (bool, error) {
+	owner, err := e.completions.CompletedBy(ctx, key)
+	if err != nil {
+		// Running the job could repeat its side effects; retry it later instead
+		errMsg := fmt.Sprintf("idempotency check failed: %v", err)
+		log.Printf("Job %s: %s", j.ID, errMsg)
+		queueErr := e.queue.Fail(ctx, j, errMsg)
+		if queueErr != nil {
+			log.Printf("Failed to mark job %s as failed in queue: %v", j.ID, queueErr)
+		}
+		if !lostLease(queueErr) {
+			e.finishIfFinal(ctx, j, e.newResult(j.ID, job.StatusFailed, nil, errMsg, 0), nil)
+		}
+		return true, fmt.Errorf("idempotency check failed: %w", err)
+	}
+	if owner == "" {
+		return false, nil
+	}
+
+	log.Printf("Job %s skipped: idempotency key %s was already completed by job %s", j.ID, key, owner)
+	res := e.newResult(j.ID, job.StatusCompleted, nil, "", 0)
+	if e.resultBackend != nil {
+		stored, err := e.resultBackend.GetResult(ctx, owner)
+		if err != nil {
+			log.Printf("Failed to load result of job %s for duplicate job %s: %v", owner, j.ID, err)
+		} else if stored != nil {
+			res.Result = stored.Result
+		}
+	}
+	// A redelivered job's own result is already stored
+	if owner != j.ID {
+		e.storeResult(ctx, res)
+	}
+
+	if err := e.queue.Complete(ctx, j.ID); err != nil {
+		log.Printf("Failed to mark duplicate job %s as completed in queue: %v", j.ID, err)
+		return true, fmt.Errorf("duplicate job failed to update queue: %w", err)
+	}
+	e.finish(ctx, j, res, nil)
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/queue"
+)
+
+// paymentRegistry registers a charge handler keyed by the payment ID in its payload
+func paymentRegistry(charges *int) *Registry {
+	registry := NewRegistry()
+	registry.Register("charge", func(ctx context.Context, j *job.Job) error {
+		*charges++
+		SetResult(ctx, []byte(`{"receipt":"r-1"}`))
+		return nil
+	})
+	registry.SetIdempotencyKey("charge", func(j *job.Job) string {
+		var p struct {
+			PaymentID string `json:"payment_id"`
+		}
+		json.Unmarshal(j.Payload, &p)
+		return p.PaymentID
+	})
+	return registry
+}
+
+// dequeueCharge enqueues a charge for a payment and dequeues it
+func dequeueCharge(t *testing.T, b *queue.MemoryBroker, paymentID string) *job.Job {
+	t.Helper()
+	ctx := context.Background()
+	b.Enqueue(ctx, job.NewJob("charge", []byte(`{"payment_id":"`+paymentID+`"}`), job.PriorityNormal))
+	j, err := b.Dequeue(ctx, []job.JobPriority{job.PriorityNormal})
+	if err != nil || j == nil {
+		t.Fatalf("failed to dequeue: %v", err)
+	}
+	return j
+}
+
+func TestRegistry_IdempotencyKeyFor(t *testing.T) {
+	registry := NewRegistry()
+	registry.SetIdempotencyKey("charge", func(j *job.Job) string { return string(j.Payload) })
+
+	if key := registry.IdempotencyKeyFor(job.NewJob("charge", []byte("p-1"), job.PriorityNormal)); key != "charge:p-1" {
+		t.Errorf("expected key scoped to the job name, got %q", key)
+	}
+	if key := registry.IdempotencyKeyFor(job.NewJob("charge", nil, job.PriorityNormal)); key != "" {
+		t.Errorf("expected no key for an empty key, got %q", key)
+	}
+	if key := registry.IdempotencyKeyFor(job.NewJob("refund", []byte("p-1"), job.PriorityNormal)); key != "" {
+		t.Errorf("expected no key for a job name without a key function, got %q", key)
+	}
+}
+
+func TestExecuteJob_DuplicateReturnsStoredResult(t *testing.T) {
+	ctx := context.Background()
+	charges := 0
+	broker := queue.NewMemoryBroker()
+	backend := &mockResultBackend{}
+	executor := NewExecutor(paymentRegistry(&charges), broker, 1)
+	executor.SetResultBackend(backend)
+
+	first := dequeueCharge(t, broker, "p-1")
+	if err := executor.ExecuteJob(ctx, first); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// A second job for the same payment completes without charging again
+	second := dequeueCharge(t, broker, "p-1")
+	if err := executor.ExecuteJob(ctx, second); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if charges != 1 {
+		t.Errorf("expected 1 charge, got %d", charges)
+	}
+	stored, _ := broker.GetJob(ctx, second.ID)
+	if stored.Status != job.StatusCompleted {
+		t.Errorf("expected duplicate to be completed, got %s", stored.Status)
+	}
+	res := backend.results[second.ID]
+	if res == nil || string(res.Result) != `{"receipt":"r-1"}` {
+		t.Errorf("expected duplicate to get the first job's result, got %+v", res)
+	}
+
+	// Other payments still run
+	if err := executor.ExecuteJob(ctx, dequeueCharge(t, broker, "p-2")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if charges != 2 {
+		t.Errorf("expected 2 charges, got %d", charges)
+	}
+}
+
+// failedAckQueue is a memory broker whose first acknowledgement fails before
+// reaching the broker, like a dropped connection
+type failedAckQueue struct {
+	*queue.MemoryBroker
+	failed bool
+}
+
+func (q *failedAckQueue) CompleteIdempotent(ctx context.Context, jobID, key string) error {
+	if !q.failed {
+		q.failed = true
+		return errors.New("connection reset")
+	}
+	return q.MemoryBroker.CompleteIdempotent(ctx, jobID, key)
+}
+
+func TestExecuteJob_FailedAckDoesNotRecordCompletion(t *testing.T) {
+	ctx := context.Background()
+	charges := 0
+	broker := &failedAckQueue{MemoryBroker: queue.NewMemoryBroker()}
+	executor := NewExecutor(paymentRegistry(&charges), broker, 1)
+
+	j := dequeueCharge(t, broker.MemoryBroker, "p-1")
+	if err := executor.ExecuteJob(ctx, j); err == nil {
+		t.Fatal("expected the failed acknowledgement to be reported")
+	}
+
+	// The key isn't recorded for a job that wasn't acknowledged
+	if owner, _ := broker.CompletedBy(ctx, "charge:p-1"); owner != "" {
+		t.Errorf("expected no completion record, got %s", owner)
+	}
+
+	// The next attempt records and acknowledges together
+	if err := executor.ExecuteJob(ctx, j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if owner, _ := broker.CompletedBy(ctx, "charge:p-1"); owner != j.ID {
+		t.Errorf("expected completion recorded for %s, got %q", j.ID, owner)
+	}
+	stored, _ := broker.GetJob(ctx, j.ID)
+	if stored.Status != job.StatusCompleted {
+		t.Errorf("expected job to be completed, got %s", stored.Status)
+	}
+}
+
+func TestExecuteJob_IdempotencyKeyIgnoredWithoutStore(t *testing.T) {
+	charges := 0
+	executor := NewExecutor(paymentRegistry(&charges), &mockQueue{}, 1)
+
+	for i := 0; i < 2; i++ {
+		j := job.NewJob("charge", []byte(`{"payment_id":"p-1"}`), job.PriorityNormal)
+		if err := executor.ExecuteJob(context.Background(), j); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if charges != 2 {
+		t.Errorf("expected both jobs to run without a completion store, got %d charges", charges)
+	}
+}
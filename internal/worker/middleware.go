@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/logger"
+)
+
+// Middleware wraps a handler with cross-cutting behavior (logging, tracing, auth, ...)
+//
+// Middleware is applied around the handler in ExecuteJob in this order, outermost first:
+//  1. Executor middleware (Executor.Use)
+//  2. Registry global middleware (Registry.Use)
+//  3. Registry per-job-name middleware (Registry.UseFor)
+//
+// Within each group, middleware runs in the order it was added.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain wraps a handler with middleware; the first middleware is the outermost
+func Chain(handler HandlerFunc, middleware ...Middleware) HandlerFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// LoggingMiddleware logs the start and outcome of every handler invocation
+func LoggingMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, j *job.Job) error {
+			jobLogger := logger.Default().WithSource(logger.LogSourceJob)
+			jobLogger.InfoContext(ctx, "Handler started", "job_id", j.ID, "job_name", j.Name, "attempt", j.Attempts+1)
+
+			start := time.Now()
+			err := next(ctx, j)
+			if err != nil {
+				jobLogger.ErrorContext(ctx, "Handler failed", "job_id", j.ID, "job_name", j.Name, "duration", time.Since(start), "error", err)
+			} else {
+				jobLogger.InfoContext(ctx, "Handler finished", "job_id", j.ID, "job_name", j.Name, "duration", time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// TimingMiddleware reports how long each handler invocation took
+// observe is called after the handler returns, with the handler's error (nil on success)
+func TimingMiddleware(observe func(j *job.Job, duration time.Duration, err error)) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, j *job.Job) error {
+			start := time.Now()
+			err := next(ctx, j)
+			observe(j, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// RecoveryMiddleware turns a handler panic into an *errors.PanicError, so the job fails
+// (and is retried) like any other error instead of unwinding the worker
+func RecoveryMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, j *job.Job) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &bananaserrors.PanicError{
+						Value:      r,
+						Stacktrace: string(debug.Stack()),
+					}
+				}
+			}()
+			return next(ctx, j)
+		}
+	}
+}
+
+// TimeoutMiddleware limits the handlers it wraps to timeout
+//
+// It can only shorten the job's timeout: the pool's deadline for the job still
+// applies. Use Registry.SetTimeout to give jobs with a name a longer timeout than
+// the worker's JOB_TIMEOUT.
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, j *job.Job) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, j)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/job"
+)
+
+// recordingMiddleware appends its name to calls before and after the handler
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, j *job.Job) error {
+			*calls = append(*calls, name+":before")
+			err := next(ctx, j)
+			*calls = append(*calls, name+":after")
+			return err
+		}
+	}
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	var calls []string
+
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	registry.UseFor("test_job", recordingMiddleware("job", &calls))
+	registry.Use(recordingMiddleware("registry1", &calls), recordingMiddleware("registry2", &calls))
+	registry.UseFor("other_job", recordingMiddleware("other", &calls))
+
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.Use(recordingMiddleware("executor", &calls))
+
+	if err := executor.ExecuteJob(context.Background(), job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []string{
+		"executor:before", "registry1:before", "registry2:before", "job:before",
+		"handler",
+		"job:after", "registry2:after", "registry1:after", "executor:after",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected call order %v, got %v", expected, calls)
+	}
+}
+
+func TestRegistry_ExecuteAppliesMiddleware(t *testing.T) {
+	var calls []string
+
+	registry := NewRegistry()
+	registry.Register("test_job", func(ctx context.Context, j *job.Job) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	registry.Use(recordingMiddleware("mw", &calls))
+
+	if err := registry.Execute(context.Background(), job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(calls) != 3 || calls[0] != "mw:before" {
+		t.Errorf("expected middleware around handler, got %v", calls)
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := Chain(func(ctx context.Context, j *job.Job) error {
+		panic("boom")
+	}, RecoveryMiddleware())
+
+	err := handler(context.Background(), job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+
+	var panicErr *bananaserrors.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+	if panicErr.Value != "boom" {
+		t.Errorf("expected panic value 'boom', got %v", panicErr.Value)
+	}
+	if panicErr.Stacktrace == "" {
+		t.Error("expected stack trace to be captured")
+	}
+}
+
+func TestRecoveryMiddleware_FailsJobInExecutor(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("panicky", func(ctx context.Context, j *job.Job) error {
+		panic("boom")
+	})
+	registry.Use(RecoveryMiddleware())
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+
+	if err := executor.ExecuteJob(context.Background(), job.NewJob("panicky", []byte(`{}`), job.PriorityNormal)); err == nil {
+		t.Fatal("expected error from panicking handler")
+	}
+	if !mockQ.failCalled {
+		t.Error("expected Fail to be called on queue")
+	}
+}
+
+func TestTimingMiddleware(t *testing.T) {
+	var observed time.Duration
+	var observedErr error
+	failure := errors.New("failed")
+
+	handler := Chain(func(ctx context.Context, j *job.Job) error {
+		time.Sleep(10 * time.Millisecond)
+		return failure
+	}, TimingMiddleware(func(j *job.Job, d time.Duration, err error) {
+		observed = d
+		observedErr = err
+	}))
+
+	handler(context.Background(), job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+
+	if observed < 10*time.Millisecond {
+		t.Errorf("expected duration >= 10ms, got %v", observed)
+	}
+	if observedErr != failure {
+		t.Errorf("expected handler error to be observed, got %v", observedErr)
+	}
+}
+
+func TestLoggingMiddleware_PassesThrough(t *testing.T) {
+	failure := errors.New("failed")
+	handler := Chain(func(ctx context.Context, j *job.Job) error {
+		return failure
+	}, LoggingMiddleware())
+
+	if err := handler(context.Background(), job.NewJob("test_job", []byte(`{}`), job.PriorityNormal)); err != failure {
+		t.Errorf("expected handler error to be returned, got %v", err)
+	}
+}
+
+func TestTimeoutMiddleware_Shortens(t *testing.T) {
+	handler := Chain(func(ctx context.Context, j *job.Job) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}, TimeoutMiddleware(20*time.Millisecond))
+
+	err := handler(context.Background(), job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestTimeoutMiddleware_KeepsParentDeadline(t *testing.T) {
+	handler := Chain(func(ctx context.Context, j *job.Job) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}, TimeoutMiddleware(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := handler(ctx, job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the parent deadline to still apply, got %v", err)
+	}
+}
+
+func TestTimeoutMiddleware_PropagatesCancellation(t *testing.T) {
+	handler := Chain(func(ctx context.Context, j *job.Job) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}, TimeoutMiddleware(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := handler(ctx, job.NewJob("test_job", []byte(`{}`), job.PriorityNormal))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected parent cancellation to propagate, got %v", err)
+	}
+}
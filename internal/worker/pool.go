@@ -9,21 +9,44 @@ import (
 	"time"
 
 	"github.com/muaviaUsmani/bananas/internal/config"
+	"github.com/muaviaUsmani/bananas/internal/fleet"
 	"github.com/muaviaUsmani/bananas/internal/job"
 	"github.com/muaviaUsmani/bananas/internal/logger"
 	"github.com/muaviaUsmani/bananas/internal/metrics"
+	"github.com/muaviaUsmani/bananas/internal/queue"
 )
 
-// QueueReader defines the interface for dequeuing jobs from the queue
-type QueueReader interface {
-	Dequeue(ctx context.Context, priorities []job.JobPriority) (*job.Job, error)
-	Fail(ctx context.Context, j *job.Job, errMsg string) error
+// QueueReader is the broker side the pool dequeues jobs from (any queue.Broker)
+type QueueReader = queue.Consumer
+
+// RoutedQueueReader is implemented by queues with routing keys and per-job-name
+// queues (see queue.RedisQueue). Pools use it when their config asks for either.
+type RoutedQueueReader interface {
+	DequeueRoutes(ctx context.Context, routingKeys []string, priorities []job.JobPriority) (*job.Job, error)
+	DequeueJobNames(ctx context.Context, names []string, priorities []job.JobPriority) (*job.Job, error)
+	DedicateJobNames(ctx context.Context, names ...string) error
+}
+
+// mismatchBackoff is how long a worker waits after returning a job it doesn't
+// handle, so workers that do handle it get a chance to dequeue it
+const mismatchBackoff = 100 * time.Millisecond
+
+// DefaultShutdownGracePeriod is how long Stop lets in-flight jobs finish
+const DefaultShutdownGracePeriod = 30 * time.Second
+
+// stateReportInterval is how often a pool refreshes its entry in the worker registry
+const stateReportInterval = 15 * time.Second
+
+// StateReporter publishes a pool's lifecycle state (see fleet.Member)
+type StateReporter interface {
+	ReportState(ctx context.Context, state fleet.State, activeJobs int) error
 }
 
 // Pool manages a pool of workers that process jobs from the queue
 type Pool struct {
 	executor          *Executor
 	queue             QueueReader
+	routed            RoutedQueueReader // nil if the queue doesn't support routing
 	workerConfig      *config.WorkerConfig
 	jobTimeout        time.Duration
 	wg                sync.WaitGroup
@@ -31,6 +54,27 @@ type Pool struct {
 	activeWorkers     atomic.Int64
 	redisRetryBackoff time.Duration // Current backoff for Redis connection errors
 	maxRetryBackoff   time.Duration // Maximum backoff duration (default 30s)
+	shutdownGrace     time.Duration
+	// Worker goroutines; the count changes at runtime with Resize (see Autoscaler)
+	scaleMu      sync.Mutex
+	concurrency  atomic.Int64
+	started      bool
+	runCtx       context.Context
+	retire       []chan struct{} // one per running worker, closed to retire it
+	nextWorkerID int
+	// Running jobs, so shutdown can interrupt them
+	inFlightMu sync.Mutex
+	inFlight   map[string]*inFlightJob
+	// Optional worker registry reporting (see SetStateReporter)
+	reporter   StateReporter
+	state      atomic.Value // fleet.State
+	reportDone chan struct{}
+}
+
+// inFlightJob is a job being executed by one of the pool's workers
+type inFlightJob struct {
+	job    *job.Job
+	cancel context.CancelCauseFunc
 }
 
 // NewPool creates a new worker pool
@@ -51,61 +95,295 @@ func NewPool(executor *Executor, queue QueueReader, concurrency int, jobTimeout
 
 // NewPoolWithConfig creates a new worker pool with explicit configuration
 func NewPoolWithConfig(executor *Executor, queue QueueReader, workerConfig *config.WorkerConfig, jobTimeout time.Duration) *Pool {
-	return &Pool{
+	routed, _ := queue.(RoutedQueueReader)
+	p := &Pool{
 		executor:          executor,
 		queue:             queue,
+		routed:            routed,
 		workerConfig:      workerConfig,
 		jobTimeout:        jobTimeout,
 		redisRetryBackoff: time.Second,      // Initial backoff: 1 second
 		maxRetryBackoff:   30 * time.Second, // Max backoff: 30 seconds
+		shutdownGrace:     DefaultShutdownGracePeriod,
 		stopChan:          make(chan struct{}),
+		inFlight:          make(map[string]*inFlightJob),
+		reportDone:        make(chan struct{}),
+	}
+	p.concurrency.Store(int64(workerConfig.Concurrency))
+	return p
+}
+
+// SetShutdownGracePeriod sets how long Stop lets in-flight jobs finish before
+// interrupting them and returning them to their queues
+func (p *Pool) SetShutdownGracePeriod(d time.Duration) {
+	if d <= 0 {
+		d = DefaultShutdownGracePeriod
 	}
+	p.shutdownGrace = d
+}
+
+// SetStateReporter reports the pool's state (running, draining, stopped) and
+// number of running jobs to the worker registry. Must be called before Start.
+func (p *Pool) SetStateReporter(reporter StateReporter) {
+	p.reporter = reporter
 }
 
 // Start begins processing jobs from the queue with the configured concurrency
 func (p *Pool) Start(ctx context.Context) {
 	logger.Info("Starting worker pool",
 		"mode", p.workerConfig.Mode,
-		"workers", p.workerConfig.Concurrency,
+		"workers", p.Concurrency(),
 		"priorities", len(p.workerConfig.Priorities),
 		"scheduler_enabled", p.workerConfig.EnableScheduler)
 
 	// Log worker configuration details
 	logger.Info("Worker configuration", "config", p.workerConfig.String())
 
+	// Job-specialized workers get their job names' own queues, so they (and no
+	// other workers) dequeue exactly the jobs they handle
+	if p.dequeuesJobNames() {
+		p.dedicateJobNames(ctx)
+		go p.dedicateLoop(ctx)
+	}
+
 	// Start worker goroutines (unless scheduler-only mode)
 	if p.workerConfig.Mode != config.WorkerModeSchedulerOnly {
-		for i := 0; i < p.workerConfig.Concurrency; i++ {
-			p.wg.Add(1)
-			go p.worker(ctx, i+1)
+		p.scaleMu.Lock()
+		p.started = true
+		p.runCtx = ctx
+		for i := 0; i < p.Concurrency(); i++ {
+			p.addWorker()
 		}
+		p.scaleMu.Unlock()
+	}
+
+	p.setState(fleet.StateRunning)
+	if p.reporter != nil {
+		go p.reportStateLoop()
 	}
 
 	logger.Info("Worker pool started successfully")
 }
 
-// Stop gracefully shuts down the worker pool with a 30-second timeout
+// Stop shuts down the worker pool in two phases
+//
+// First the workers stop dequeuing and in-flight jobs get the shutdown grace period
+// to finish. Jobs still running near its end are interrupted (their context is
+// cancelled with ErrWorkerShutdown) and returned to the front of their queue without
+// counting an attempt. Jobs whose handlers ignore the interruption are left where
+// they are: their lease expires once the process exits and the broker's
+// ReapExpiredLeases retries them. Cancel the context passed to Start only after
+// Stop returns, otherwise running jobs are cancelled and failed instead.
 func (p *Pool) Stop() {
-	logger.Info("Stopping worker pool")
+	logger.Info("Stopping worker pool", "grace_period", p.shutdownGrace)
+	p.setState(fleet.StateDraining)
+	// Under scaleMu so Resize can't start workers while Stop waits for them
+	p.scaleMu.Lock()
 	close(p.stopChan)
+	p.scaleMu.Unlock()
 
-	// Wait for workers with timeout
 	done := make(chan struct{})
 	go func() {
 		p.wg.Wait()
 		close(done)
 	}()
 
+	// The last tenth of the grace period is left for interrupted handlers to return
+	interruptWait := p.shutdownGrace / 10
 	select {
 	case <-done:
 		logger.Info("Worker pool stopped gracefully")
-	case <-time.After(30 * time.Second):
-		logger.Warn("Worker pool shutdown timed out", "timeout", "30s")
+	case <-time.After(p.shutdownGrace - interruptWait):
+		interrupted := p.interruptInFlight()
+		logger.Warn("Shutdown grace period ending - interrupted running jobs", "jobs", interrupted)
+
+		select {
+		case <-done:
+			logger.Info("Worker pool stopped after requeueing interrupted jobs")
+		case <-time.After(interruptWait):
+			// Requeueing a job whose handler is still running would run it twice;
+			// its lease expires instead once the process exits
+			logger.Warn("Worker pool shutdown timed out - unfinished jobs are left to lease expiry",
+				"timeout", p.shutdownGrace,
+				"jobs", p.unfinishedJobs())
+		}
+	}
+
+	p.setState(fleet.StateStopped)
+	close(p.reportDone)
+}
+
+// Concurrency returns the current number of worker goroutines
+func (p *Pool) Concurrency() int {
+	return int(p.concurrency.Load())
+}
+
+// Resize changes the number of worker goroutines (at least 1) and returns the new count
+//
+// Retired workers finish the job they are running before exiting. Before Start it
+// only sets how many workers Start launches; once Stop has been called it does nothing.
+func (p *Pool) Resize(n int) int {
+	if n < 1 {
+		n = 1
+	}
+
+	p.scaleMu.Lock()
+	defer p.scaleMu.Unlock()
+
+	select {
+	case <-p.stopChan:
+		return p.Concurrency()
+	default:
+	}
+
+	if p.started {
+		for len(p.retire) < n {
+			p.addWorker()
+		}
+		for len(p.retire) > n {
+			last := len(p.retire) - 1
+			close(p.retire[last])
+			p.retire = p.retire[:last]
+		}
+	}
+
+	p.concurrency.Store(int64(n))
+	metrics.Default().RecordWorkerActivity(p.activeWorkers.Load(), int64(n))
+	return n
+}
+
+// addWorker starts a worker goroutine; the caller must hold scaleMu
+func (p *Pool) addWorker() {
+	p.nextWorkerID++
+	retire := make(chan struct{})
+	p.retire = append(p.retire, retire)
+
+	p.wg.Add(1)
+	go p.worker(p.runCtx, p.nextWorkerID, retire)
+}
+
+// interruptInFlight cancels the contexts of running jobs with ErrWorkerShutdown,
+// which makes the executor requeue them once their handlers return
+func (p *Pool) interruptInFlight() int {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+
+	for _, f := range p.inFlight {
+		f.cancel(ErrWorkerShutdown)
+	}
+	return len(p.inFlight)
+}
+
+// unfinishedJobs returns the IDs of jobs whose handlers are still running
+func (p *Pool) unfinishedJobs() []string {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+
+	ids := make([]string, 0, len(p.inFlight))
+	for id := range p.inFlight {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// trackJob records a running job until the returned function is called
+func (p *Pool) trackJob(j *job.Job, cancel context.CancelCauseFunc) func() {
+	p.inFlightMu.Lock()
+	p.inFlight[j.ID] = &inFlightJob{job: j, cancel: cancel}
+	p.inFlightMu.Unlock()
+
+	return func() {
+		p.inFlightMu.Lock()
+		delete(p.inFlight, j.ID)
+		p.inFlightMu.Unlock()
+	}
+}
+
+// setState records the pool's state and reports it to the worker registry
+func (p *Pool) setState(state fleet.State) {
+	p.state.Store(state)
+	p.reportState()
+}
+
+// reportState sends the pool's current state to the worker registry, if configured
+// This is a best-effort operation - failures are logged but don't affect the pool
+func (p *Pool) reportState() {
+	if p.reporter == nil {
+		return
+	}
+
+	state, _ := p.state.Load().(fleet.State)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.reporter.ReportState(ctx, state, int(p.activeWorkers.Load())); err != nil {
+		logger.Warn("Failed to report worker state", "state", state, "error", err)
+	}
+}
+
+// dedicateJobNames gives the pool's job names their own queues (or renews that)
+func (p *Pool) dedicateJobNames(ctx context.Context) {
+	if err := p.routed.DedicateJobNames(ctx, p.workerConfig.JobTypes...); err != nil {
+		logger.Error("Failed to dedicate job name queues", "job_types", p.workerConfig.JobTypes, "error", err)
+	}
+}
+
+// dedicateLoop renews the job names' dedication until the pool stops, after which
+// it lapses (queue.DedicationTTL) unless another pool for the names renews it
+func (p *Pool) dedicateLoop(ctx context.Context) {
+	ticker := time.NewTicker(queue.DedicationTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.dedicateJobNames(ctx)
+		}
+	}
+}
+
+// reportStateLoop refreshes the pool's registry entry until the pool has stopped
+func (p *Pool) reportStateLoop() {
+	ticker := time.NewTicker(stateReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.reportDone:
+			return
+		case <-ticker.C:
+			p.reportState()
+		}
+	}
+}
+
+// dequeuesJobNames reports whether the pool's workers dequeue from per-job-name queues
+func (p *Pool) dequeuesJobNames() bool {
+	return p.routed != nil &&
+		p.workerConfig.Mode == config.WorkerModeJobSpecialized &&
+		len(p.workerConfig.JobTypes) > 0
+}
+
+// dequeue retrieves the next job for this pool: from its job names' queues in
+// job-specialized mode, from its routing keys' queues if any are configured,
+// otherwise from the shared priority queues
+func (p *Pool) dequeue(ctx context.Context) (*job.Job, error) {
+	switch {
+	case p.dequeuesJobNames():
+		return p.routed.DequeueJobNames(ctx, p.workerConfig.JobTypes, p.workerConfig.Priorities)
+	case p.routed != nil && len(p.workerConfig.RoutingKeys) > 0:
+		return p.routed.DequeueRoutes(ctx, p.workerConfig.RoutingKeys, p.workerConfig.Priorities)
+	default:
+		return p.queue.Dequeue(ctx, p.workerConfig.Priorities)
 	}
 }
 
 // worker is the main loop for each worker goroutine
-func (p *Pool) worker(ctx context.Context, workerID int) {
+// It runs until the pool stops, its context is cancelled or retire is closed (see Resize).
+func (p *Pool) worker(ctx context.Context, workerID int, retire <-chan struct{}) {
 	defer p.wg.Done()
 	defer func() {
 		if r := recover(); r != nil {
@@ -131,12 +409,15 @@ func (p *Pool) worker(ctx context.Context, workerID int) {
 		case <-p.stopChan:
 			logger.Info("Worker stopping", "worker_id", workerID)
 			return
+		case <-retire:
+			logger.Info("Worker retired - pool scaled down", "worker_id", workerID)
+			return
 		case <-workerCtx.Done():
 			logger.Info("Worker stopping due to context cancellation", "worker_id", workerID)
 			return
 		default:
 			// Try to dequeue a job (uses blocking operations internally)
-			j, err := p.queue.Dequeue(workerCtx, p.workerConfig.Priorities)
+			j, err := p.dequeue(workerCtx)
 			if err != nil {
 				// Check if context was cancelled
 				if workerCtx.Err() != nil {
@@ -188,20 +469,36 @@ func (p *Pool) worker(ctx context.Context, workerID int) {
 				continue
 			}
 
-			// Check if this worker should process this job (job-type filtering)
+			// Shutdown began while this worker was waiting for a job - give it back
+			select {
+			case <-p.stopChan:
+				if err := p.executor.queue.Requeue(context.WithoutCancel(workerCtx), j); err != nil {
+					logger.Error("Failed to requeue job on shutdown", "worker_id", workerID, "job_id", j.ID, "error", err)
+				}
+				logger.Info("Worker stopping", "worker_id", workerID)
+				return
+			default:
+			}
+
+			// Check if this worker should process this job (job-type and routing filtering)
 			if !p.workerConfig.ShouldProcessJob(j) {
-				// Job doesn't match our filters (wrong job type for job-specialized mode)
-				// Put it back in the queue by marking it as failed with zero attempts
-				// Actually, we should skip it - another worker will pick it up
-				// For now, we'll process it anyway since we already dequeued it
-				// TODO: Implement proper job re-enqueue mechanism
-				logger.Debug("Skipping job due to job-type filter",
+				// Dequeue already moved it to the processing queue - give it back, without
+				// counting an attempt, so a worker that handles it can pick it up
+				logger.Warn("Returning job this worker doesn't handle to its queue",
 					"worker_id", workerID,
 					"job_id", j.ID,
 					"job_name", j.Name,
-					"allowed_types", p.workerConfig.JobTypes)
-				// For now, just continue - in practice this shouldn't happen often
-				// because queue separation should prevent this
+					"allowed_types", p.workerConfig.JobTypes,
+					"routing_keys", p.workerConfig.RoutingKeys)
+				if err := p.executor.queue.Requeue(context.WithoutCancel(workerCtx), j); err != nil {
+					logger.Error("Failed to requeue job", "worker_id", workerID, "job_id", j.ID, "error", err)
+				}
+
+				select {
+				case <-p.stopChan:
+				case <-workerCtx.Done():
+				case <-time.After(mismatchBackoff):
+				}
 				continue
 			}
 
@@ -211,26 +508,33 @@ func (p *Pool) worker(ctx context.Context, workerID int) {
 	}
 }
 
-// executeWithTimeout executes a job with the configured timeout
+// executeWithTimeout executes a job with its timeout (see Registry.TimeoutFor)
 func (p *Pool) executeWithTimeout(ctx context.Context, workerID int, j *job.Job) {
 	// Mark worker as active
 	active := p.activeWorkers.Add(1)
 	defer func() {
 		active = p.activeWorkers.Add(-1)
 		// Update metrics with current worker utilization
-		metrics.Default().RecordWorkerActivity(active, int64(p.workerConfig.Concurrency))
+		metrics.Default().RecordWorkerActivity(active, int64(p.Concurrency()))
 	}()
 
-	// Update metrics with current worker utilization
-	metrics.Default().RecordWorkerActivity(active, int64(p.workerConfig.Concurrency))
+	// Update metrics with current worker utilization and how long the job waited
+	metrics.Default().RecordWorkerActivity(active, int64(p.Concurrency()))
+	metrics.Default().RecordJobWait(time.Since(j.UpdatedAt))
 
 	// Add job_id to context
 	jobCtx := context.WithValue(ctx, "job_id", j.ID)
 
-	// Create context with timeout for job execution
-	jobCtx, cancel := context.WithTimeout(jobCtx, p.jobTimeout)
+	// Create context with timeout for job execution (the job's own timeout, else the
+	// handler's default, else the worker's)
+	jobCtx, cancel := context.WithTimeout(jobCtx, p.executor.registry.TimeoutFor(j, p.jobTimeout))
 	defer cancel()
 
+	// Tracked so shutdown can interrupt it (see Stop)
+	jobCtx, interrupt := context.WithCancelCause(jobCtx)
+	defer interrupt(nil)
+	defer p.trackJob(j, interrupt)()
+
 	// Use job-specific logger
 	jobLogger := logger.Default().WithSource(logger.LogSourceJob)
 
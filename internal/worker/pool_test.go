@@ -6,7 +6,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/muaviaUsmani/bananas/internal/config"
+	"github.com/muaviaUsmani/bananas/internal/fleet"
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/queue"
 )
 
 // mockQueueReader is a mock implementation for testing the pool
@@ -38,6 +43,20 @@ func (m *mockQueueReader) Fail(ctx context.Context, j *job.Job, errMsg string) e
 	return nil
 }
 
+func (m *mockQueueReader) FailAfter(ctx context.Context, j *job.Job, errMsg string, delay time.Duration) error {
+	return m.Fail(ctx, j, errMsg)
+}
+
+func (m *mockQueueReader) Complete(ctx context.Context, jobID string) error { return nil }
+
+func (m *mockQueueReader) DeadLetter(ctx context.Context, j *job.Job, errMsg string) error {
+	return nil
+}
+
+func (m *mockQueueReader) Expire(ctx context.Context, j *job.Job, reason string) error { return nil }
+
+func (m *mockQueueReader) Requeue(ctx context.Context, j *job.Job) error { return nil }
+
 func TestNewPool(t *testing.T) {
 	registry := NewRegistry()
 	mockQ := &mockQueue{}
@@ -238,6 +257,51 @@ func TestPool_RespectsJobTimeout(t *testing.T) {
 	}
 }
 
+func TestPool_UsesJobAndHandlerTimeouts(t *testing.T) {
+	registry := NewRegistry()
+
+	var mu sync.Mutex
+	deadlines := make(map[string]time.Duration)
+	record := func(ctx context.Context, j *job.Job) error {
+		deadline, _ := ctx.Deadline()
+		mu.Lock()
+		deadlines[j.ID] = time.Until(deadline)
+		mu.Unlock()
+		return nil
+	}
+	registry.Register("email", record)
+	registry.Register("report", record)
+	registry.SetTimeout("report", time.Hour)
+
+	email := job.NewJob("email", []byte("{}"), job.PriorityNormal)
+	report := job.NewJob("report", []byte("{}"), job.PriorityNormal)
+	quickReport := job.NewJob("report", []byte("{}"), job.PriorityNormal)
+	quickReport.SetTimeout(time.Second)
+
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	reader := &mockQueueReader{jobs: []*job.Job{email, report, quickReport}}
+	pool := NewPool(executor, reader, 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+	time.Sleep(200 * time.Millisecond)
+	pool.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := map[string]time.Duration{email.ID: time.Minute, report.ID: time.Hour, quickReport.ID: time.Second}
+	for id, want := range expected {
+		got, ok := deadlines[id]
+		if !ok {
+			t.Fatalf("job %s did not run", id)
+		}
+		if got > want || got < want-5*time.Second {
+			t.Errorf("job %s: expected timeout ~%v, got %v", id, want, got)
+		}
+	}
+}
+
 func TestPool_PanicRecovery(t *testing.T) {
 	registry := NewRegistry()
 
@@ -378,3 +442,255 @@ func TestPool_PriorityOrdering(t *testing.T) {
 	}
 }
 
+// mockStateReporter records the states a pool reports
+type mockStateReporter struct {
+	mu     sync.Mutex
+	states []fleet.State
+}
+
+func (m *mockStateReporter) ReportState(ctx context.Context, state fleet.State, activeJobs int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.states) == 0 || m.states[len(m.states)-1] != state {
+		m.states = append(m.states, state)
+	}
+	return nil
+}
+
+func TestPool_StopDrainsInFlightJobs(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("short_job", func(ctx context.Context, j *job.Job) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	reader := &mockQueueReader{jobs: []*job.Job{job.NewJob("short_job", []byte("{}"), job.PriorityNormal)}}
+
+	pool := NewPool(executor, reader, 1, 5*time.Second)
+	pool.SetShutdownGracePeriod(2 * time.Second)
+	reporter := &mockStateReporter{}
+	pool.SetStateReporter(reporter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	pool.Stop()
+
+	if !mockQ.completeCalled {
+		t.Error("expected in-flight job to finish during the grace period")
+	}
+	if mockQ.requeueCount.Load() != 0 || mockQ.failCalled {
+		t.Error("expected finished job not to be requeued or failed")
+	}
+
+	want := []fleet.State{fleet.StateRunning, fleet.StateDraining, fleet.StateStopped}
+	if len(reporter.states) != len(want) {
+		t.Fatalf("expected states %v, got %v", want, reporter.states)
+	}
+	for i := range want {
+		if reporter.states[i] != want[i] {
+			t.Errorf("expected states %v, got %v", want, reporter.states)
+		}
+	}
+}
+
+func TestPool_StopRequeuesUnfinishedJobs(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("long_job", func(ctx context.Context, j *job.Job) error {
+		select {
+		case <-time.After(10 * time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("long_job", []byte("{}"), job.PriorityNormal)
+	reader := &mockQueueReader{jobs: []*job.Job{j}}
+
+	pool := NewPool(executor, reader, 1, 30*time.Second)
+	pool.SetShutdownGracePeriod(300 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	pool.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected shutdown within the grace period, took %v", elapsed)
+	}
+
+	if mockQ.requeueCount.Load() == 0 {
+		t.Error("expected unfinished job to be requeued")
+	}
+	if mockQ.failCalled || j.Attempts != 0 {
+		t.Errorf("expected no attempt to be counted, got %d (fail called: %v)", j.Attempts, mockQ.failCalled)
+	}
+}
+
+func TestPool_StopLeavesStuckJobsToLeaseExpiry(t *testing.T) {
+	registry := NewRegistry()
+	release := make(chan struct{})
+	registry.Register("stuck_job", func(ctx context.Context, j *job.Job) error {
+		<-release // Ignores cancellation
+		return nil
+	})
+	defer close(release)
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	reader := &mockQueueReader{jobs: []*job.Job{job.NewJob("stuck_job", []byte("{}"), job.PriorityNormal)}}
+
+	pool := NewPool(executor, reader, 1, 30*time.Second)
+	pool.SetShutdownGracePeriod(300 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	pool.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected shutdown within the grace period, took %v", elapsed)
+	}
+
+	// The handler still holds the job, so requeueing it would run it twice
+	if mockQ.requeueCount.Load() != 0 {
+		t.Error("expected a job whose handler is still running not to be requeued")
+	}
+}
+
+func TestPool_RequeuesJobsItDoesNotHandle(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("send_email", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+	registry.Register("generate_report", func(ctx context.Context, j *job.Job) error {
+		t.Error("expected generate_report not to run on an email worker")
+		return nil
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	other := job.NewJob("generate_report", []byte("{}"), job.PriorityNormal)
+	reader := &mockQueueReader{jobs: []*job.Job{other}}
+
+	pool := NewPoolWithConfig(executor, reader, &config.WorkerConfig{
+		Mode:        config.WorkerModeJobSpecialized,
+		Concurrency: 1,
+		Priorities:  []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow},
+		JobTypes:    []string{"send_email"},
+	}, 5*time.Second)
+	pool.SetShutdownGracePeriod(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	pool.Stop()
+
+	if mockQ.requeueCount.Load() != 1 {
+		t.Errorf("expected mismatched job to be requeued once, got %d", mockQ.requeueCount.Load())
+	}
+	if mockQ.failCalled || mockQ.completeCalled || other.Attempts != 0 {
+		t.Error("expected mismatched job not to be run, failed or counted as an attempt")
+	}
+}
+
+func TestPool_MixedFleetWithJobSpecializedWorkers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	q, err := queue.NewRedisQueue("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.Close()
+
+	var mu sync.Mutex
+	ranOn := make(map[string]string) // job name -> pool
+	done := make(chan struct{}, 4)
+	newPool := func(name string, cfg *config.WorkerConfig) *Pool {
+		registry := NewRegistry()
+		for _, jobName := range []string{"send_email", "generate_report"} {
+			registry.Register(jobName, func(ctx context.Context, j *job.Job) error {
+				mu.Lock()
+				ranOn[jobName] = name
+				mu.Unlock()
+				done <- struct{}{}
+				return nil
+			})
+		}
+		pool := NewPoolWithConfig(NewExecutor(registry, q, cfg.Concurrency), q, cfg, 5*time.Second)
+		pool.SetShutdownGracePeriod(time.Second)
+		return pool
+	}
+
+	emailPool := newPool("email", &config.WorkerConfig{
+		Mode:        config.WorkerModeJobSpecialized,
+		Concurrency: 1,
+		Priorities:  []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow},
+		JobTypes:    []string{"send_email"},
+	})
+	defaultPool := newPool("default", &config.WorkerConfig{
+		Mode:        config.WorkerModeDefault,
+		Concurrency: 1,
+		Priorities:  []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The job-specialized pool dedicates its names' queues on Start
+	emailPool.Start(ctx)
+	defaultPool.Start(ctx)
+
+	q.Enqueue(ctx, job.NewJob("send_email", []byte("{}"), job.PriorityNormal))
+	q.Enqueue(ctx, job.NewJob("generate_report", []byte("{}"), job.PriorityNormal))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for jobs to be processed")
+		}
+	}
+	emailPool.Stop()
+	defaultPool.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if ranOn["send_email"] != "email" {
+		t.Errorf("expected send_email to run on the email pool, ran on %q", ranOn["send_email"])
+	}
+	if ranOn["generate_report"] != "default" {
+		t.Errorf("expected generate_report to run on the default pool, ran on %q", ranOn["generate_report"])
+	}
+}
+
+func TestPool_ResizeRunningPool(t *testing.T) {
+	registry := NewRegistry()
+	pool := NewPool(NewExecutor(registry, &mockQueue{}, 2), &mockQueueReader{}, 2, time.Second)
+	pool.SetShutdownGracePeriod(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+
+	if got := pool.Resize(5); got != 5 || len(pool.retire) != 5 {
+		t.Errorf("expected 5 workers, got %d (%d running)", got, len(pool.retire))
+	}
+	if got := pool.Resize(0); got != 1 || len(pool.retire) != 1 {
+		t.Errorf("expected at least 1 worker, got %d (%d running)", got, len(pool.retire))
+	}
+
+	pool.Stop()
+	if got := pool.Resize(3); got != 1 {
+		t.Errorf("expected resize after Stop to do nothing, got %d", got)
+	}
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/progress"
+)
+
+// ProgressReporter persists and publishes progress updates (see progress.RedisStore)
+type ProgressReporter interface {
+	Report(ctx context.Context, u progress.Update) error
+}
+
+// progressKey is the context key for the executor's progress slot
+type progressKey struct{}
+
+// progressSlot connects a running job's handler to the progress reporter
+type progressSlot struct {
+	jobID    string
+	reporter ProgressReporter
+
+	mu       sync.Mutex
+	reported bool
+	percent  float64
+}
+
+// withProgress returns a context in which handlers can report progress with ReportProgress
+// The slot is nil (and ReportProgress a no-op) when no reporter is configured.
+func withProgress(ctx context.Context, jobID string, reporter ProgressReporter) (context.Context, *progressSlot) {
+	if reporter == nil {
+		return ctx, nil
+	}
+	slot := &progressSlot{jobID: jobID, reporter: reporter}
+	return context.WithValue(ctx, progressKey{}, slot), slot
+}
+
+// ReportProgress records the running job's progress, for clients watching it
+// (client.WatchProgress, or the API's Server-Sent Events stream)
+//
+// percent is 0-100; message and meta are optional. Each call writes to Redis, so
+// handlers in tight loops should report on a coarser step (e.g. every 1%).
+// It is a no-op outside a handler invoked by an Executor with a progress reporter.
+func ReportProgress(ctx context.Context, percent float64, message string, meta map[string]interface{}) error {
+	slot, ok := ctx.Value(progressKey{}).(*progressSlot)
+	if !ok {
+		return nil
+	}
+
+	if err := slot.reporter.Report(ctx, progress.Update{
+		JobID:     slot.jobID,
+		Percent:   percent,
+		Message:   message,
+		Meta:      meta,
+		Timestamp: time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	slot.mu.Lock()
+	slot.reported = true
+	slot.percent = percent
+	slot.mu.Unlock()
+	return nil
+}
+
+// done publishes the final update for a job that reported progress, so watchers stop
+// This is a best-effort operation - failures are logged but don't fail the job
+func (s *progressSlot) done(ctx context.Context, status job.JobStatus) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	reported, percent := s.reported, s.percent
+	s.mu.Unlock()
+	if !reported {
+		return
+	}
+
+	if status == job.StatusCompleted {
+		percent = 100
+	}
+	if err := s.reporter.Report(ctx, progress.Update{
+		JobID:     s.jobID,
+		Percent:   percent,
+		Done:      true,
+		Status:    status,
+		Timestamp: time.Now(),
+	}); err != nil {
+		log.Printf("Failed to report final progress for job %s: %v", s.jobID, err)
+	}
+}
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/progress"
+)
+
+// mockProgressReporter records progress updates
+type mockProgressReporter struct {
+	mu      sync.Mutex
+	updates []progress.Update
+}
+
+func (m *mockProgressReporter) Report(ctx context.Context, u progress.Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates = append(m.updates, u)
+	return nil
+}
+
+func TestReportProgress_NoopWithoutReporter(t *testing.T) {
+	if err := ReportProgress(context.Background(), 50, "halfway", nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestExecuteJob_ReportsProgress(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("import", func(ctx context.Context, j *job.Job) error {
+		ReportProgress(ctx, 25, "reading", map[string]interface{}{"rows": 100})
+		ReportProgress(ctx, 75, "writing", nil)
+		return nil
+	})
+
+	reporter := &mockProgressReporter{}
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.SetProgressReporter(reporter)
+
+	j := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(reporter.updates) != 3 {
+		t.Fatalf("expected 3 updates, got %d", len(reporter.updates))
+	}
+	first, last := reporter.updates[0], reporter.updates[2]
+	if first.JobID != j.ID || first.Percent != 25 || first.Message != "reading" || first.Meta["rows"] != 100 {
+		t.Errorf("unexpected first update: %+v", first)
+	}
+	if !last.Done || last.Status != job.StatusCompleted || last.Percent != 100 {
+		t.Errorf("expected final completed update, got %+v", last)
+	}
+}
+
+func TestExecuteJob_ProgressDoneOnlyWhenFinal(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("import", func(ctx context.Context, j *job.Job) error {
+		ReportProgress(ctx, 40, "", nil)
+		return errors.New("boom")
+	})
+
+	reporter := &mockProgressReporter{}
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.SetProgressReporter(reporter)
+
+	// A retried failure isn't final, so watchers keep watching
+	j := job.NewJob("import", []byte(`{}`), job.PriorityNormal)
+	executor.ExecuteJob(context.Background(), j)
+	if len(reporter.updates) != 1 || reporter.updates[0].Done {
+		t.Fatalf("expected only the reported update, got %+v", reporter.updates)
+	}
+
+	// A job that fails permanently ends the stream at its last progress
+	j.Status = job.StatusFailed
+	executor.finishIfFinal(context.Background(), j, executor.newResult(j.ID, job.StatusFailed, nil, "boom", 0), &progressSlot{
+		jobID: j.ID, reporter: reporter, reported: true, percent: 40,
+	})
+	last := reporter.updates[len(reporter.updates)-1]
+	if !last.Done || last.Status != job.StatusFailed || last.Percent != 40 {
+		t.Errorf("expected final failed update at 40%%, got %+v", last)
+	}
+}
+
+func TestExecuteJob_NoProgressNoDoneUpdate(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("quick", func(ctx context.Context, j *job.Job) error {
+		return nil
+	})
+
+	reporter := &mockProgressReporter{}
+	executor := NewExecutor(registry, &mockQueue{}, 1)
+	executor.SetProgressReporter(reporter)
+
+	executor.ExecuteJob(context.Background(), job.NewJob("quick", []byte(`{}`), job.PriorityNormal))
+	if len(reporter.updates) != 0 {
+		t.Errorf("expected no updates, got %d", len(reporter.updates))
+	}
+}
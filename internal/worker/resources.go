@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// ResourceSampler reports the process's resource usage, for the autoscaler's ceiling
+type ResourceSampler interface {
+	// Sample returns CPU usage since the previous sample, as a percentage of the
+	// cores Go may use (GOMAXPROCS), and the memory mapped by the Go runtime in bytes
+	Sample() (cpuPercent float64, memoryBytes uint64)
+}
+
+// memoryMetric is the runtime metric for all memory mapped by the Go runtime
+const memoryMetric = "/memory/classes/total:bytes"
+
+// runtimeSampler samples the current process
+// CPU usage is 0 on platforms without getrusage.
+type runtimeSampler struct {
+	mu      sync.Mutex
+	lastCPU time.Duration
+	lastAt  time.Time
+}
+
+// newRuntimeSampler creates a sampler for the current process
+func newRuntimeSampler() *runtimeSampler {
+	s := &runtimeSampler{lastAt: time.Now()}
+	s.lastCPU, _ = processCPUTime()
+	return s
+}
+
+// Sample implements ResourceSampler
+func (s *runtimeSampler) Sample() (float64, uint64) {
+	sample := []metrics.Sample{{Name: memoryMetric}}
+	metrics.Read(sample)
+	var memory uint64
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		memory = sample[0].Value.Uint64()
+	}
+
+	cpuTime, ok := processCPUTime()
+	if !ok {
+		return 0, memory
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(s.lastAt)
+	used := cpuTime - s.lastCPU
+	s.lastCPU, s.lastAt = cpuTime, now
+	if elapsed <= 0 {
+		return 0, memory
+	}
+
+	capacity := float64(elapsed) * float64(runtime.GOMAXPROCS(0))
+	return float64(used) / capacity * 100, memory
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	"google.golang.org/protobuf/proto"
+)
+
+// TypedHandlerFunc processes a job's decoded payload and returns a result
+type TypedHandlerFunc[T any, R any] func(ctx context.Context, payload T) (R, error)
+
+// RegisterTyped adds a handler that receives the job's payload decoded into T
+//
+// JSON and protobuf payloads are decoded automatically based on the format byte
+// (protobuf requires T to be a generated message pointer, e.g. *tasks.EmailTask).
+// Payloads that cannot be decoded fail permanently and go to the dead letter queue;
+// payloads that cannot be read (e.g. the blob store is down) are retried.
+// The returned result is encoded the same way and stored in the result backend.
+//
+// Example:
+//
+//	worker.RegisterTyped(registry, "resize_image", func(ctx context.Context, req ResizeRequest) (ResizeResult, error) {
+//	    return resize(ctx, req)
+//	})
+func RegisterTyped[T any, R any](r *Registry, name string, handler TypedHandlerFunc[T, R]) {
+	r.Register(name, func(ctx context.Context, j *job.Job) error {
+		payload, err := decodePayload[T](ctx, j)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", j.Name, err)
+		}
+
+		out, err := handler(ctx, payload)
+		if err != nil {
+			return err
+		}
+
+		data, err := job.EncodeValue(out)
+		if err != nil {
+			return bananaserrors.Permanent(fmt.Errorf("failed to encode result for job %s: %w", j.Name, err))
+		}
+		SetResult(ctx, data)
+		return nil
+	})
+}
+
+// decodePayload decodes a job's payload into a new T
+// Only decode errors are permanent; errors reading the payload are returned as-is
+func decodePayload[T any](ctx context.Context, j *job.Job) (T, error) {
+	var payload T
+	target := interface{}(&payload)
+
+	// Generated protobuf types are used as pointers; allocate the message to decode into
+	if _, ok := interface{}(payload).(proto.Message); ok {
+		payload = reflect.New(reflect.TypeOf(payload).Elem()).Interface().(T)
+		target = payload
+	}
+
+	plain, err := j.PlainPayload(ctx)
+	if err != nil {
+		return payload, fmt.Errorf("failed to read payload: %w", err)
+	}
+
+	if err := job.DecodeValue(plain, target); err != nil {
+		return payload, bananaserrors.Permanent(fmt.Errorf("failed to decode payload: %w", err))
+	}
+	return payload, nil
+}
+
+// resultKey is the context key for the executor's result slot
+type resultKey struct{}
+
+// resultSlot receives the result of a handler
+type resultSlot struct {
+	data []byte
+}
+
+// withResultSlot returns a context in which handlers can report a result with SetResult
+func withResultSlot(ctx context.Context) (context.Context, *resultSlot) {
+	slot := &resultSlot{}
+	return context.WithValue(ctx, resultKey{}, slot), slot
+}
+
+// SetResult records the result of the running job, to be stored in the result backend
+// when the handler succeeds. It is a no-op outside a handler invoked by the Executor.
+func SetResult(ctx context.Context, result []byte) {
+	if slot, ok := ctx.Value(resultKey{}).(*resultSlot); ok {
+		slot.data = result
+	}
+}
@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
+)
+
+// mockResultBackend records stored results for testing
+type mockResultBackend struct {
+	results map[string]*job.JobResult
+}
+
+func (m *mockResultBackend) StoreResult(ctx context.Context, r *job.JobResult) error {
+	if m.results == nil {
+		m.results = make(map[string]*job.JobResult)
+	}
+	m.results[r.JobID] = r
+	return nil
+}
+
+func (m *mockResultBackend) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	return m.results[jobID], nil
+}
+
+func (m *mockResultBackend) WaitForResult(ctx context.Context, jobID string, timeout time.Duration) (*job.JobResult, error) {
+	return m.results[jobID], nil
+}
+
+func (m *mockResultBackend) DeleteResult(ctx context.Context, jobID string) error {
+	delete(m.results, jobID)
+	return nil
+}
+
+func (m *mockResultBackend) Close() error {
+	return nil
+}
+
+func TestRegisterTyped_JSONPayloadAndResult(t *testing.T) {
+	registry := NewRegistry()
+	RegisterTyped(registry, "count_items", CountItems)
+
+	mockQ := &mockQueue{}
+	backend := &mockResultBackend{}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetResultBackend(backend)
+
+	payload, _ := json.Marshal([]string{"a", "b", "c"})
+	j := job.NewJob("count_items", payload, job.PriorityNormal)
+
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mockQ.completeCalled {
+		t.Error("expected Complete to be called on queue")
+	}
+
+	var result CountItemsResult
+	if err := backend.results[j.ID].UnmarshalResult(&result); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+	if result.Count != 3 {
+		t.Errorf("expected count 3, got %d", result.Count)
+	}
+}
+
+func TestRegisterTyped_ProtobufPayloadAndResult(t *testing.T) {
+	registry := NewRegistry()
+	RegisterTyped(registry, "send_email", func(ctx context.Context, email *tasks.EmailTask) (*tasks.EmailTask, error) {
+		return &tasks.EmailTask{To: email.To, Subject: "Re: " + email.Subject}, nil
+	})
+
+	mockQ := &mockQueue{}
+	backend := &mockResultBackend{}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetResultBackend(backend)
+
+	payload, err := job.EncodeValue(&tasks.EmailTask{To: "user@example.com", Subject: "hello"})
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+	j := job.NewJob("send_email", payload, job.PriorityNormal)
+
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var result tasks.EmailTask
+	if err := backend.results[j.ID].UnmarshalResult(&result); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+	if result.To != "user@example.com" || result.Subject != "Re: hello" {
+		t.Errorf("unexpected result: %v", &result)
+	}
+}
+
+func TestRegisterTyped_DecodeFailureIsPermanent(t *testing.T) {
+	registry := NewRegistry()
+
+	handlerCalled := false
+	RegisterTyped(registry, "count_items", func(ctx context.Context, items []string) (int, error) {
+		handlerCalled = true
+		return len(items), nil
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("count_items", []byte(`{"not": "a list"}`), job.PriorityNormal)
+
+	err := executor.ExecuteJob(context.Background(), j)
+
+	if !bananaserrors.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if handlerCalled {
+		t.Error("expected handler not to be called")
+	}
+	if !mockQ.deadLetterCalled {
+		t.Error("expected DeadLetter to be called on queue")
+	}
+	if mockQ.failCalled {
+		t.Error("expected Fail not to be called (decode failures are not retried)")
+	}
+}
+
+func TestRegisterTyped_UnreadablePayloadIsRetried(t *testing.T) {
+	original := job.DefaultBlobStore
+	job.DefaultBlobStore = unavailableBlobStore{}
+	defer func() { job.DefaultBlobStore = original }()
+
+	registry := NewRegistry()
+	RegisterTyped(registry, "count_items", func(ctx context.Context, items []string) (int, error) {
+		return len(items), nil
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("count_items", nil, job.PriorityNormal)
+	j.PayloadRef = "payloads/" + j.ID
+
+	err := executor.ExecuteJob(context.Background(), j)
+
+	if err == nil || bananaserrors.IsPermanent(err) {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+	if !mockQ.failCalled {
+		t.Error("expected Fail to be called so the job is retried")
+	}
+	if mockQ.deadLetterCalled {
+		t.Error("expected DeadLetter not to be called for an unreadable payload")
+	}
+}
+
+func TestRegisterTyped_HandlerErrorIsRetried(t *testing.T) {
+	registry := NewRegistry()
+	RegisterTyped(registry, "flaky", func(ctx context.Context, payload map[string]string) (struct{}, error) {
+		return struct{}{}, errors.New("temporary failure")
+	})
+
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+	j := job.NewJob("flaky", []byte(`{}`), job.PriorityNormal)
+
+	if err := executor.ExecuteJob(context.Background(), j); err == nil {
+		t.Fatal("expected error from handler")
+	}
+	if !mockQ.failCalled {
+		t.Error("expected Fail to be called on queue")
+	}
+	if mockQ.deadLetterCalled {
+		t.Error("expected DeadLetter not to be called for ordinary errors")
+	}
+}
+
+func TestSetResult_OutsideExecutor(t *testing.T) {
+	// Must not panic when no result slot is present
+	SetResult(context.Background(), []byte(`{}`))
+}
@@ -0,0 +1,224 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
+)
+
+// WebhookJobName is the job name the built-in webhook handler is registered under
+const WebhookJobName = "webhook"
+
+// WebhookHandlerOptions configures the built-in webhook handler
+type WebhookHandlerOptions struct {
+	// DefaultTimeout applies when the task has no timeout_seconds (default 30s)
+	DefaultTimeout time.Duration
+	// MaxResponseBytes caps how much of the response body is read and stored (default 64KB)
+	MaxResponseBytes int64
+	// MaxRetryAfter caps the retry delay a Retry-After header asks for (default 1h)
+	MaxRetryAfter time.Duration
+	// AllowInsecureTLS lets tasks with verify_ssl=false skip certificate verification.
+	// Without it, certificates are always verified.
+	AllowInsecureTLS bool
+	// Transport overrides the HTTP transport (e.g. for proxies or tests)
+	Transport http.RoundTripper
+}
+
+// WebhookResponse is the result stored for a webhook job
+type WebhookResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	// Truncated is true if the body was longer than MaxResponseBytes
+	Truncated  bool  `json:"truncated,omitempty"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// RegisterWebhookHandler registers the built-in handler for tasks.WebhookTask jobs
+//
+// Submit jobs named "webhook" with a WebhookTask payload (protobuf or JSON). The
+// response is stored in the result backend as a WebhookResponse. Status codes map
+// to outcomes as follows:
+//   - 2xx: success
+//   - 429 and 5xx: retried; a Retry-After header sets the retry delay, up to
+//     MaxRetryAfter (negative or unparsable values are ignored)
+//   - other statuses: permanent failure (dead letter queue)
+//
+// Network errors and timeouts are retried. The task's max_retries, when set, caps the
+// number of attempts below the job's MaxRetries.
+func RegisterWebhookHandler(r *Registry, opts WebhookHandlerOptions) {
+	r.Register(WebhookJobName, NewWebhookHandler(opts))
+}
+
+// NewWebhookHandler returns the webhook handler, for registering under another name
+func NewWebhookHandler(opts WebhookHandlerOptions) HandlerFunc {
+	if opts.DefaultTimeout <= 0 {
+		opts.DefaultTimeout = 30 * time.Second
+	}
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = 64 << 10
+	}
+	if opts.MaxRetryAfter <= 0 {
+		opts.MaxRetryAfter = time.Hour
+	}
+
+	transport := opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client := &http.Client{Transport: transport}
+
+	// Only built when allowed, so verify_ssl=false can't weaken TLS by default
+	insecureClient := client
+	if opts.AllowInsecureTLS {
+		if t, ok := transport.(*http.Transport); ok {
+			insecure := t.Clone()
+			if insecure.TLSClientConfig == nil {
+				insecure.TLSClientConfig = &tls.Config{}
+			}
+			insecure.TLSClientConfig.InsecureSkipVerify = true
+			insecureClient = &http.Client{Transport: insecure}
+		}
+	}
+
+	return func(ctx context.Context, j *job.Job) error {
+		task, err := decodePayload[*tasks.WebhookTask](ctx, j)
+		if err != nil {
+			return fmt.Errorf("webhook task: %w", err)
+		}
+
+		c := client
+		if !task.GetVerifySsl() {
+			c = insecureClient
+		}
+
+		resp, err := callWebhook(ctx, c, task, opts)
+		if resp != nil {
+			if data, encErr := job.EncodeValue(resp); encErr == nil {
+				SetResult(ctx, data)
+			}
+		}
+		if err != nil && task.GetMaxRetries() > 0 && j.Attempts+1 >= int(task.GetMaxRetries()) {
+			// The task allows no more attempts
+			return bananaserrors.Permanent(err)
+		}
+		return err
+	}
+}
+
+// callWebhook performs the task's request and classifies the outcome
+func callWebhook(ctx context.Context, client *http.Client, task *tasks.WebhookTask, opts WebhookHandlerOptions) (*WebhookResponse, error) {
+	method := strings.ToUpper(task.GetMethod())
+	if method == "" {
+		method = http.MethodPost
+	}
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch:
+	default:
+		return nil, bananaserrors.Permanent(fmt.Errorf("unsupported webhook method %q", task.GetMethod()))
+	}
+
+	timeout := opts.DefaultTimeout
+	if task.GetTimeoutSeconds() > 0 {
+		timeout = time.Duration(task.GetTimeoutSeconds()) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var body io.Reader
+	if len(task.GetPayload()) > 0 {
+		body = bytes.NewReader(task.GetPayload())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, task.GetUrl(), body)
+	if err != nil {
+		return nil, bananaserrors.Permanent(fmt.Errorf("invalid webhook request: %w", err))
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, bananaserrors.Permanent(fmt.Errorf("invalid webhook URL %q: scheme must be http or https", task.GetUrl()))
+	}
+	for k, v := range task.GetHeaders() {
+		req.Header.Set(k, v)
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	httpResp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(httpResp.Body, opts.MaxResponseBytes+1))
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("failed to read webhook response: %w", err)
+	}
+
+	resp := &WebhookResponse{
+		StatusCode: httpResp.StatusCode,
+		Headers:    make(map[string]string, len(httpResp.Header)),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	for k := range httpResp.Header {
+		resp.Headers[k] = httpResp.Header.Get(k)
+	}
+	if int64(len(respBody)) > opts.MaxResponseBytes {
+		respBody = respBody[:opts.MaxResponseBytes]
+		resp.Truncated = true
+	}
+	resp.Body = string(respBody)
+
+	return resp, classifyWebhookStatus(httpResp, opts.MaxRetryAfter)
+}
+
+// classifyWebhookStatus maps a response status to nil, a retryable or a permanent error
+// Retry-After delays are capped at maxRetryAfter.
+func classifyWebhookStatus(resp *http.Response, maxRetryAfter time.Duration) error {
+	code := resp.StatusCode
+	switch {
+	case code >= 200 && code < 300:
+		return nil
+	case code == http.StatusTooManyRequests || code >= 500:
+		err := fmt.Errorf("webhook returned status %d", code)
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return bananaserrors.RetryAfter(err, min(delay, maxRetryAfter))
+		}
+		return err
+	default:
+		return bananaserrors.Permanent(fmt.Errorf("webhook returned status %d", code))
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+// Negative delays (including dates in the past) and unparsable values are rejected.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := at.Sub(now)
+		if delay < 0 {
+			return 0, false
+		}
+		return delay, true
+	}
+	return 0, false
+}
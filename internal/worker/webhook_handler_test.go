@@ -0,0 +1,252 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bananaserrors "github.com/muaviaUsmani/bananas/internal/errors"
+	"github.com/muaviaUsmani/bananas/internal/job"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
+)
+
+// runWebhookJob executes a webhook job through an executor and returns the queue and stored result
+func runWebhookJob(t *testing.T, opts WebhookHandlerOptions, task *tasks.WebhookTask) (*mockQueue, *job.JobResult, error) {
+	t.Helper()
+
+	registry := NewRegistry()
+	RegisterWebhookHandler(registry, opts)
+
+	mockQ := &mockQueue{}
+	backend := &mockResultBackend{}
+	executor := NewExecutor(registry, mockQ, 1)
+	executor.SetResultBackend(backend)
+
+	j, err := job.NewJobWithProto(WebhookJobName, task, job.PriorityNormal)
+	if err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	err = executor.ExecuteJob(context.Background(), j)
+	return mockQ, backend.results[j.ID], err
+}
+
+func decodeWebhookResponse(t *testing.T, r *job.JobResult) WebhookResponse {
+	t.Helper()
+	if r == nil {
+		t.Fatal("expected result to be stored")
+	}
+	var resp WebhookResponse
+	if err := job.DecodeValue(r.Result, &resp); err != nil {
+		t.Fatalf("failed to decode webhook response: %v", err)
+	}
+	return resp
+}
+
+func TestWebhookHandler_Success(t *testing.T) {
+	var gotMethod, gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotHeader, gotBody = r.Method, r.Header.Get("X-Api-Key"), string(body)
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	mockQ, res, err := runWebhookJob(t, WebhookHandlerOptions{}, &tasks.WebhookTask{
+		Url:     server.URL,
+		Method:  "put",
+		Headers: map[string]string{"X-Api-Key": "k"},
+		Payload: []byte(`{"event":"signup"}`),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mockQ.completeCalled {
+		t.Error("expected job to complete")
+	}
+	if gotMethod != http.MethodPut || gotHeader != "k" || gotBody != `{"event":"signup"}` {
+		t.Errorf("unexpected request: %s %q %q", gotMethod, gotHeader, gotBody)
+	}
+
+	resp := decodeWebhookResponse(t, res)
+	if resp.StatusCode != http.StatusOK || resp.Body != `{"ok":true}` || resp.Headers["X-Request-Id"] != "req-1" {
+		t.Errorf("unexpected stored response: %+v", resp)
+	}
+}
+
+func TestWebhookHandler_JSONPayload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected default method POST, got %s", r.Method)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	registry := NewRegistry()
+	RegisterWebhookHandler(registry, WebhookHandlerOptions{})
+	mockQ := &mockQueue{}
+	executor := NewExecutor(registry, mockQ, 1)
+
+	payload, _ := json.Marshal(map[string]interface{}{"url": server.URL})
+	j := job.NewJob(WebhookJobName, payload, job.PriorityNormal)
+	if err := executor.ExecuteJob(context.Background(), j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !mockQ.completeCalled {
+		t.Error("expected job to complete")
+	}
+}
+
+func TestWebhookHandler_StatusClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		permanent  bool
+		delay      time.Duration
+	}{
+		{name: "server error is retried", status: http.StatusBadGateway},
+		{name: "rate limit with Retry-After uses the delay", status: http.StatusTooManyRequests, retryAfter: "120", delay: 2 * time.Minute},
+		{name: "unavailable with Retry-After uses the delay", status: http.StatusServiceUnavailable, retryAfter: "30", delay: 30 * time.Second},
+		{name: "Retry-After is capped", status: http.StatusTooManyRequests, retryAfter: "86400", delay: time.Hour},
+		{name: "negative Retry-After is ignored", status: http.StatusTooManyRequests, retryAfter: "-30"},
+		{name: "unparsable Retry-After is ignored", status: http.StatusTooManyRequests, retryAfter: "soon"},
+		{name: "client error is permanent", status: http.StatusBadRequest, permanent: true},
+		{name: "not found is permanent", status: http.StatusNotFound, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte("nope"))
+			}))
+			defer server.Close()
+
+			mockQ, res, err := runWebhookJob(t, WebhookHandlerOptions{}, &tasks.WebhookTask{Url: server.URL})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := bananaserrors.IsPermanent(err); got != tt.permanent {
+				t.Errorf("expected permanent=%v, got %v (%v)", tt.permanent, got, err)
+			}
+			if tt.permanent != mockQ.deadLetterCalled {
+				t.Errorf("expected deadLetterCalled=%v", tt.permanent)
+			}
+			if mockQ.lastRetryDelay != tt.delay {
+				t.Errorf("expected retry delay %v, got %v", tt.delay, mockQ.lastRetryDelay)
+			}
+
+			// The failing response is stored too
+			resp := decodeWebhookResponse(t, res)
+			if resp.StatusCode != tt.status || resp.Body != "nope" {
+				t.Errorf("unexpected stored response: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_CapsResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	_, res, err := runWebhookJob(t, WebhookHandlerOptions{MaxResponseBytes: 10}, &tasks.WebhookTask{Url: server.URL})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	resp := decodeWebhookResponse(t, res)
+	if len(resp.Body) != 10 || !resp.Truncated {
+		t.Errorf("expected truncated 10 byte body, got %d bytes (truncated=%v)", len(resp.Body), resp.Truncated)
+	}
+}
+
+func TestWebhookHandler_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	mockQ, _, err := runWebhookJob(t, WebhookHandlerOptions{DefaultTimeout: 50 * time.Millisecond}, &tasks.WebhookTask{Url: server.URL})
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if bananaserrors.IsPermanent(err) || !mockQ.failCalled {
+		t.Errorf("expected timeout to be retried, got %v", err)
+	}
+}
+
+func TestWebhookHandler_InvalidTask(t *testing.T) {
+	for _, task := range []*tasks.WebhookTask{
+		{Url: "ftp://example.com"},
+		{Url: "http://example.com", Method: "TRACE"},
+	} {
+		mockQ, _, err := runWebhookJob(t, WebhookHandlerOptions{}, task)
+		if !bananaserrors.IsPermanent(err) || !mockQ.deadLetterCalled {
+			t.Errorf("expected permanent failure for %v, got %v", task, err)
+		}
+	}
+}
+
+func TestWebhookHandler_TaskMaxRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	handler := NewWebhookHandler(WebhookHandlerOptions{})
+	j, _ := job.NewJobWithProto(WebhookJobName, &tasks.WebhookTask{Url: server.URL, MaxRetries: 2}, job.PriorityNormal)
+
+	if err := handler(context.Background(), j); err == nil || bananaserrors.IsPermanent(err) {
+		t.Fatalf("expected retryable error on first attempt, got %v", err)
+	}
+	j.Attempts = 1
+	if err := handler(context.Background(), j); !bananaserrors.IsPermanent(err) {
+		t.Fatalf("expected permanent error on last allowed attempt, got %v", err)
+	}
+}
+
+func TestWebhookHandler_VerifiesTLSUnlessAllowed(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	task := &tasks.WebhookTask{Url: server.URL, VerifySsl: false}
+
+	// verify_ssl=false is ignored unless the handler allows it
+	if _, _, err := runWebhookJob(t, WebhookHandlerOptions{}, task); err == nil {
+		t.Error("expected certificate error")
+	}
+	if _, _, err := runWebhookJob(t, WebhookHandlerOptions{AllowInsecureTLS: true}, task); err != nil {
+		t.Errorf("expected insecure request to succeed, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if d, ok := parseRetryAfter("90", now); !ok || d != 90*time.Second {
+		t.Errorf("expected 90s, got %v %v", d, ok)
+	}
+	if d, ok := parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); !ok || d != time.Minute {
+		t.Errorf("expected 1m, got %v %v", d, ok)
+	}
+	for _, v := range []string{"", "-5", "soon", now.Add(-time.Minute).Format(http.TimeFormat)} {
+		if _, ok := parseRetryAfter(v, now); ok {
+			t.Errorf("expected %q to be rejected", v)
+		}
+	}
+}
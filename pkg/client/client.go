@@ -6,68 +6,197 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/muaviaUsmani/bananas/internal/events"
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/progress"
 	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/redisconn"
 	"github.com/muaviaUsmani/bananas/internal/result"
-	"github.com/redis/go-redis/v9"
+	"github.com/muaviaUsmani/bananas/internal/scheduler"
+	"github.com/muaviaUsmani/bananas/internal/schema"
+	"github.com/muaviaUsmani/bananas/internal/serialization"
+	"github.com/muaviaUsmani/bananas/internal/storage"
 )
 
 // Client provides a simple API for submitting and managing jobs
 type Client struct {
-	queue         *queue.RedisQueue
+	queue         queue.Broker
 	resultBackend result.Backend
+	progress      *progress.RedisStore
+	schedules     *scheduler.Store
+	schemas       *schema.Registry
+	hooks         *events.Registry
 	ctx           context.Context
 }
 
 // NewClient creates a new job client connected to Redis
 // The result backend is enabled by default with standard TTLs (1h success, 24h failure)
 func NewClient(redisURL string) (*Client, error) {
+	return NewClientWithConfig(redisURL, 1*time.Hour, 24*time.Hour)
+}
+
+// NewClientWithConfig creates a new job client with custom result backend TTLs
+func NewClientWithConfig(redisURL string, successTTL, failureTTL time.Duration) (*Client, error) {
 	// Connect to Redis queue
 	q, err := queue.NewRedisQueue(redisURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
+	return newRedisClient(q, redisURL, successTTL, failureTTL)
+}
 
+// NewStreamClient creates a job client for deployments running the Redis Streams
+// queue (QUEUE_BACKEND=streams), with the standard result TTLs
+// Jobs with a routing key other than the default are rejected.
+func NewStreamClient(redisURL string) (*Client, error) {
+	q, err := queue.NewStreamQueue(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return newRedisClient(q, redisURL, 1*time.Hour, 24*time.Hour)
+}
+
+// newRedisClient creates a client for a Redis queue, with Redis results and progress
+func newRedisClient(q queue.Broker, redisURL string, successTTL, failureTTL time.Duration) (*Client, error) {
 	// Create Redis client for result backend
-	opts, err := redis.ParseURL(redisURL)
+	redisClient, err := redisconn.NewClient(redisURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		q.Close()
+		return nil, err
 	}
-	redisClient := redis.NewClient(opts)
 
-	// Create result backend with default TTLs
-	resultBackend := result.NewRedisBackend(redisClient, 1*time.Hour, 24*time.Hour)
+	// Create result backend with the given TTLs
+	resultBackend := result.NewRedisBackend(redisClient, successTTL, failureTTL)
 
 	return &Client{
 		queue:         q,
 		resultBackend: resultBackend,
+		progress:      progress.NewRedisStore(redisClient, 24*time.Hour),
+		schedules:     scheduler.NewStore(redisClient),
+		schemas:       schema.NewRegistry(),
 		ctx:           context.Background(),
 	}, nil
 }
 
-// NewClientWithConfig creates a new job client with custom result backend TTLs
-func NewClientWithConfig(redisURL string, successTTL, failureTTL time.Duration) (*Client, error) {
-	// Connect to Redis queue
-	q, err := queue.NewRedisQueue(redisURL)
+// NewClientWithBroker creates a job client that submits jobs through any queue.Broker,
+// such as an in-memory or Postgres broker
+// resultBackend may be nil, in which case GetResult and SubmitAndWait return an error.
+// Progress tracking and stored schedules are only available on Redis clients (NewClient).
+func NewClientWithBroker(broker queue.Broker, resultBackend result.Backend) *Client {
+	return &Client{
+		queue:         broker,
+		resultBackend: resultBackend,
+		schemas:       schema.NewRegistry(),
+		ctx:           context.Background(),
+	}
+}
+
+// NewPostgresClient creates a job client backed by PostgreSQL
+// Jobs and results are stored in the database; SubmitJobTx enqueues a job in the
+// same transaction as the caller's own writes. Results use the standard TTLs
+// (1h success, 24h failure). Progress tracking is not available.
+func NewPostgresClient(databaseURL string) (*Client, error) {
+	q, err := queue.NewPostgresQueue(databaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
-	// Create Redis client for result backend
-	opts, err := redis.ParseURL(redisURL)
+	resultBackend, err := result.NewPostgresBackend(context.Background(), q.Pool(), 1*time.Hour, 24*time.Hour)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		q.Close()
+		return nil, err
 	}
-	redisClient := redis.NewClient(opts)
 
-	// Create result backend with custom TTLs
-	resultBackend := result.NewRedisBackend(redisClient, successTTL, failureTTL)
+	return NewClientWithBroker(q, resultBackend), nil
+}
 
-	return &Client{
-		queue:         q,
-		resultBackend: resultBackend,
-		ctx:           context.Background(),
-	}, nil
+// EnablePayloadOffload stores payloads larger than threshold bytes in the blob store
+// instead of Redis. Workers must be configured with the same store to resolve them.
+// It also sets job.DefaultBlobStore so jobs fetched with GetJob can resolve their payloads.
+// Brokers that can't offload payloads keep them inline.
+func (c *Client) EnablePayloadOffload(store storage.BlobStore, threshold int) {
+	if q, ok := c.queue.(interface {
+		SetBlobStore(storage.BlobStore, int)
+	}); ok {
+		q.SetBlobStore(store, threshold)
+	}
+	job.DefaultBlobStore = store
+}
+
+// EnableEncryption encrypts submitted payloads at rest and decrypts stored results
+// Workers must be configured with a key provider that knows the same keys.
+// It also sets job.DefaultEncryptor so jobs fetched with GetJob can be unmarshaled.
+func (c *Client) EnableEncryption(enc *serialization.Encryptor) {
+	if q, ok := c.queue.(interface {
+		SetEncryptor(*serialization.Encryptor)
+	}); ok {
+		q.SetEncryptor(enc)
+	}
+	if rb, ok := c.resultBackend.(*result.RedisBackend); ok {
+		rb.SetEncryptor(enc)
+	}
+	job.DefaultEncryptor = enc
+}
+
+// SetNamespace submits jobs to, and reads results, progress and schedules from, a
+// namespace's isolated keyspace instead of the default "bananas" one
+// Workers and schedulers serving these jobs must use the same namespace.
+func (c *Client) SetNamespace(ns string) error {
+	q, ok := c.queue.(interface {
+		SetNamespace(string) error
+	})
+	if !ok {
+		return fmt.Errorf("broker does not support namespaces")
+	}
+	if err := q.SetNamespace(ns); err != nil {
+		return err
+	}
+	if rb, ok := c.resultBackend.(*result.RedisBackend); ok {
+		if err := rb.SetNamespace(ns); err != nil {
+			return err
+		}
+	}
+	if c.schedules != nil {
+		if err := c.schedules.SetNamespace(ns); err != nil {
+			return err
+		}
+	}
+	if c.progress == nil {
+		return nil
+	}
+	return c.progress.SetNamespace(ns)
+}
+
+// SetQuota limits the jobs the client's namespace can enqueue
+// Submissions over a limit fail with an error wrapping queue.ErrQuotaExceeded.
+// Brokers without quotas ignore it.
+func (c *Client) SetQuota(quota queue.Quota) {
+	if q, ok := c.queue.(interface {
+		SetQuota(queue.Quota)
+	}); ok {
+		q.SetQuota(quota)
+	}
+}
+
+// Hooks returns the lifecycle hook registry for jobs submitted by this client
+// Only enqueue events fire in the client process; other transitions happen in workers.
+// Hooks registered on a broker without lifecycle hooks never fire.
+func (c *Client) Hooks() *events.Registry {
+	if q, ok := c.queue.(interface {
+		Hooks() *events.Registry
+	}); ok {
+		return q.Hooks()
+	}
+	if c.hooks == nil {
+		c.hooks = events.NewRegistry()
+	}
+	return c.hooks
+}
+
+// RegisterSchema sets the payload schema for a job name
+// Payloads submitted for that job name are validated before they are enqueued.
+func (c *Client) RegisterSchema(name string, s schema.Schema) {
+	c.schemas.Register(name, s)
 }
 
 // SubmitJob creates and submits a new job with the given parameters.
@@ -75,21 +204,129 @@ func NewClientWithConfig(redisURL string, successTTL, failureTTL time.Duration)
 // Description is optional - if provided, the first value will be used.
 // Returns the job ID on success.
 func (c *Client) SubmitJob(name string, payload interface{}, priority job.JobPriority, description ...string) (string, error) {
-	// Marshal payload to JSON
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
+	return c.submit(c.ctx, name, payload, priority, withDescription(description))
+}
 
-	// Create new job
-	j := job.NewJob(name, payloadBytes, priority, description...)
+// Enqueue submits a job built with job.NewJob (or NewJobWithProto/NewJobWithJSON),
+// for options the Submit methods don't cover, such as a timeout or deadline.
+//
+// Example:
+//
+//	j := job.NewJob("generate_report", payload, job.PriorityNormal)
+//	j.SetTimeout(2 * time.Hour)              // per-attempt limit
+//	j.SetDeadline(time.Now().Add(time.Hour)) // discard if not started within an hour
+//	err := client.Enqueue(j)
+func (c *Client) Enqueue(j *job.Job) error {
+	if err := c.validateJob(c.ctx, j); err != nil {
+		return err
+	}
 
-	// Enqueue to Redis
 	if err := c.queue.Enqueue(c.ctx, j); err != nil {
-		return "", fmt.Errorf("failed to enqueue job: %w", err)
+		return fmt.Errorf("failed to enqueue job: %w", err)
 	}
+	return nil
+}
 
-	return j.ID, nil
+// SubmitDAG submits jobs that depend on each other. Each job waits (status waiting)
+// until every job it was made to run After has finished, then goes to its priority
+// queue. By default a job is cancelled when one of its parents fails permanently,
+// expires or is cancelled; use SetOnParentFailure(job.ContinueOnParentFailure) to
+// run it anyway.
+//
+// Cycles and dependencies on jobs that aren't part of the submission are rejected
+// (job.ErrDependencyCycle, job.ErrUnknownDependency) before anything is enqueued.
+// Requires a Redis client (NewClient).
+//
+// Example:
+//
+//	importA := job.NewJob("import", payloadA, job.PriorityNormal)
+//	importB := job.NewJob("import", payloadB, job.PriorityNormal)
+//	report := job.NewJob("generate_report", nil, job.PriorityNormal)
+//	report.After(importA, importB)
+//	err := client.SubmitDAG(importA, importB, report)
+func (c *Client) SubmitDAG(jobs ...*job.Job) error {
+	q, ok := c.queue.(interface {
+		EnqueueDAG(context.Context, []*job.Job) error
+	})
+	if !ok {
+		return fmt.Errorf("broker does not support job dependencies")
+	}
+
+	for _, j := range jobs {
+		if err := c.validateJob(c.ctx, j); err != nil {
+			return err
+		}
+	}
+
+	if err := q.EnqueueDAG(c.ctx, jobs); err != nil {
+		return fmt.Errorf("failed to submit DAG: %w", err)
+	}
+	return nil
+}
+
+// validateJob rejects jobs with invalid payloads before they reach a worker
+func (c *Client) validateJob(ctx context.Context, j *job.Job) error {
+	if _, exists := c.schemas.Get(j.Name); !exists {
+		return nil
+	}
+	payload, err := j.PlainPayload(ctx)
+	if err != nil {
+		return err
+	}
+	return c.schemas.Validate(j.Name, payload)
+}
+
+// SubmitJobTx creates a job and enqueues it inside the caller's database transaction
+// (a pgx.Tx), so it is only submitted if the transaction commits. Requires a client
+// created with NewPostgresClient.
+// Returns the job ID on success.
+func (c *Client) SubmitJobTx(ctx context.Context, tx queue.DBTX, name string, payload interface{}, priority job.JobPriority, description ...string) (string, error) {
+	q, ok := c.queue.(interface {
+		EnqueueTx(context.Context, queue.DBTX, *job.Job) error
+	})
+	if !ok {
+		return "", fmt.Errorf("broker does not support transactional enqueue")
+	}
+
+	return c.submit(ctx, name, payload, priority, withDescription(description),
+		enqueueWith(func(ctx context.Context, j *job.Job) error {
+			return q.EnqueueTx(ctx, tx, j)
+		}))
+}
+
+// SubmitJobWithCallback creates and submits a new job whose result is POSTed to
+// callbackURL when it completes or fails permanently.
+// secretRef optionally names the secret the worker signs the request with; it is
+// resolved on the worker (WEBHOOK_SECRET_<REF> by default), never sent with the job.
+// Returns the job ID on success.
+func (c *Client) SubmitJobWithCallback(name string, payload interface{}, priority job.JobPriority, callbackURL, secretRef string, description ...string) (string, error) {
+	return c.submit(c.ctx, name, payload, priority, withDescription(description),
+		configure(func(j *job.Job) error {
+			return j.SetCallback(callbackURL, secretRef)
+		}))
+}
+
+// SubmitJobWithRoute creates and submits a new job with a routing key, so only
+// workers configured for that key (WorkerConfig.RoutingKeys) process it.
+// Routing keys may contain letters, digits, dashes and underscores.
+// Returns the job ID on success.
+func (c *Client) SubmitJobWithRoute(name string, payload interface{}, priority job.JobPriority, routingKey string, description ...string) (string, error) {
+	return c.submit(c.ctx, name, payload, priority, withDescription(description),
+		configure(func(j *job.Job) error {
+			return j.SetRoutingKey(routingKey)
+		}))
+}
+
+// Submit creates and submits a new job with a typed payload.
+// Protobuf messages are serialized as protobuf, everything else as JSON, matching
+// what a handler registered with worker.RegisterTyped for the same type expects.
+// Returns the job ID on success.
+//
+// Example:
+//
+//	id, err := client.Submit(c, "send_email", &tasks.EmailTask{To: "user@example.com"}, job.PriorityNormal)
+func Submit[T any](c *Client, name string, payload T, priority job.JobPriority, description ...string) (string, error) {
+	return c.submit(c.ctx, name, payload, priority, withDescription(description), encodeWith(job.EncodeValue))
 }
 
 // SubmitJobScheduled creates and submits a new job scheduled for future execution.
@@ -97,38 +334,70 @@ func (c *Client) SubmitJob(name string, payload interface{}, priority job.JobPri
 // Description is optional - if provided, the first value will be used.
 // Returns the job ID on success.
 func (c *Client) SubmitJobScheduled(name string, payload interface{}, priority job.JobPriority, scheduledFor time.Time, description ...string) (string, error) {
-	// Marshal payload to JSON
-	payloadBytes, err := json.Marshal(payload)
+	// The job is added to the scheduled set instead of its queue
+	return c.submit(c.ctx, name, payload, priority, withDescription(description),
+		enqueueWith(func(ctx context.Context, j *job.Job) error {
+			return c.queue.Schedule(ctx, j, scheduledFor)
+		}))
+}
+
+// submission is how submit creates and enqueues a job (see submitOption)
+type submission struct {
+	encode      func(v interface{}) ([]byte, error)
+	description []string
+	configure   []func(j *job.Job) error
+	enqueue     func(ctx context.Context, j *job.Job) error
+}
+
+// submitOption changes how submit creates or enqueues a job
+type submitOption func(s *submission)
+
+// withDescription sets the job's description (the first value, if any)
+func withDescription(description []string) submitOption {
+	return func(s *submission) { s.description = description }
+}
+
+// encodeWith replaces JSON as the payload encoding
+func encodeWith(encode func(v interface{}) ([]byte, error)) submitOption {
+	return func(s *submission) { s.encode = encode }
+}
+
+// configure changes the job before it is enqueued
+func configure(fn func(j *job.Job) error) submitOption {
+	return func(s *submission) { s.configure = append(s.configure, fn) }
+}
+
+// enqueueWith replaces the broker's Enqueue
+func enqueueWith(enqueue func(ctx context.Context, j *job.Job) error) submitOption {
+	return func(s *submission) { s.enqueue = enqueue }
+}
+
+// submit encodes a payload, validates it, creates a job from it and enqueues it
+func (c *Client) submit(ctx context.Context, name string, payload interface{}, priority job.JobPriority, opts ...submitOption) (string, error) {
+	s := submission{encode: json.Marshal, enqueue: c.queue.Enqueue}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	payloadBytes, err := s.encode(payload)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	// Create new job
-	j := job.NewJob(name, payloadBytes, priority, description...)
-	j.ScheduledFor = &scheduledFor
-	j.Status = job.StatusScheduled
-
-	// For scheduled jobs, we need to fail it once to trigger the scheduling mechanism
-	// Or we can directly add to the scheduled set via Redis
-	// For now, we'll enqueue it and immediately "fail" it to schedule it
-	// A better approach would be to add a ScheduleJob method to the queue
-
-	// For simplicity in this implementation, we'll add it to scheduled set directly
-	// by using the internal queue method after enqueueing
-	if err := c.queue.Enqueue(c.ctx, j); err != nil {
-		return "", fmt.Errorf("failed to enqueue job: %w", err)
+	// Reject invalid payloads before they reach a worker
+	if err := c.schemas.Validate(name, payloadBytes); err != nil {
+		return "", err
 	}
 
-	// Dequeue it immediately
-	priorities := []job.JobPriority{job.PriorityHigh, job.PriorityNormal, job.PriorityLow}
-	dequeuedJob, err := c.queue.Dequeue(c.ctx, priorities)
-	if err != nil || dequeuedJob == nil || dequeuedJob.ID != j.ID {
-		return "", fmt.Errorf("failed to schedule job: %w", err)
+	j := job.NewJob(name, payloadBytes, priority, s.description...)
+	for _, fn := range s.configure {
+		if err := fn(j); err != nil {
+			return "", err
+		}
 	}
 
-	// Fail it with zero retries to put it in scheduled set
-	if err := c.queue.Fail(c.ctx, dequeuedJob, "scheduled for later execution"); err != nil {
-		return "", fmt.Errorf("failed to schedule job: %w", err)
+	if err := s.enqueue(ctx, j); err != nil {
+		return "", fmt.Errorf("failed to enqueue job: %w", err)
 	}
 
 	return j.ID, nil
@@ -144,10 +413,27 @@ func (c *Client) GetJob(jobID string) (*job.Job, error) {
 	return j, nil
 }
 
+// CancelJob cancels a job that hasn't started yet (ready, scheduled or waiting for dependencies)
+// Running and finished jobs return an error wrapping queue.ErrNotCancellable.
+func (c *Client) CancelJob(jobID string) error {
+	if err := c.queue.Cancel(c.ctx, jobID); err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the number of ready, scheduled, processing and dead jobs
+func (c *Client) Stats(ctx context.Context) (queue.Stats, error) {
+	return c.queue.Stats(ctx)
+}
+
 // GetResult retrieves the result of a completed job by its ID
 // Returns nil if the job hasn't completed yet or if the result has expired
 // Returns an error if retrieval fails
 func (c *Client) GetResult(ctx context.Context, jobID string) (*job.JobResult, error) {
+	if c.resultBackend == nil {
+		return nil, fmt.Errorf("result backend is not configured")
+	}
 	result, err := c.resultBackend.GetResult(ctx, jobID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get result: %w", err)
@@ -156,12 +442,86 @@ func (c *Client) GetResult(ctx context.Context, jobID string) (*job.JobResult, e
 	return result, nil
 }
 
+// GetProgress returns the latest progress reported by a job, or nil if it hasn't reported any
+func (c *Client) GetProgress(ctx context.Context, jobID string) (*progress.Update, error) {
+	if c.progress == nil {
+		return nil, fmt.Errorf("progress tracking is not available")
+	}
+	return c.progress.Get(ctx, jobID)
+}
+
+// WatchProgress streams a job's progress updates, starting with the latest one.
+// The channel is closed after the job's final update (Done set) or when ctx is cancelled.
+// Jobs that never call worker.ReportProgress send no updates, so bound ctx with a timeout.
+//
+// Example:
+//
+//	updates, err := client.WatchProgress(ctx, jobID)
+//	for u := range updates {
+//	    fmt.Printf("%.0f%% %s\n", u.Percent, u.Message)
+//	}
+func (c *Client) WatchProgress(ctx context.Context, jobID string) (<-chan progress.Update, error) {
+	if c.progress == nil {
+		return nil, fmt.Errorf("progress tracking is not available")
+	}
+	return c.progress.Watch(ctx, jobID)
+}
+
+// SaveSchedule creates or replaces a cron schedule stored in Redis
+// Schedulers pick up the change on their next poll without restarting. Invalid
+// schedules return an error wrapping scheduler.ErrInvalidSchedule.
+//
+// Example:
+//
+//	err := client.SaveSchedule(&scheduler.Schedule{
+//	    ID:      "nightly-cleanup",
+//	    Cron:    "0 3 * * *",
+//	    Job:     "cleanup",
+//	    Enabled: true,
+//	})
+func (c *Client) SaveSchedule(schedule *scheduler.Schedule) error {
+	if c.schedules == nil {
+		return fmt.Errorf("stored schedules are not available")
+	}
+	return c.schedules.Save(c.ctx, schedule)
+}
+
+// GetSchedule returns a stored cron schedule
+// Unknown IDs return an error wrapping scheduler.ErrScheduleNotFound.
+func (c *Client) GetSchedule(id string) (*scheduler.Schedule, error) {
+	if c.schedules == nil {
+		return nil, fmt.Errorf("stored schedules are not available")
+	}
+	return c.schedules.Get(c.ctx, id)
+}
+
+// ListSchedules returns the stored cron schedules, sorted by ID
+// Schedules registered in scheduler code are not included.
+func (c *Client) ListSchedules() ([]*scheduler.Schedule, error) {
+	if c.schedules == nil {
+		return nil, fmt.Errorf("stored schedules are not available")
+	}
+	return c.schedules.List(c.ctx)
+}
+
+// DeleteSchedule deletes a stored cron schedule and its run history
+func (c *Client) DeleteSchedule(id string) error {
+	if c.schedules == nil {
+		return fmt.Errorf("stored schedules are not available")
+	}
+	return c.schedules.Delete(c.ctx, id)
+}
+
 // SubmitAndWait submits a job and waits for its result
 // This is a convenience method for RPC-style task execution
 // Blocks until the job completes or the timeout is reached
 // Returns the result if the job completes within the timeout
 // Returns an error if the job fails, times out, or if submission fails
 func (c *Client) SubmitAndWait(ctx context.Context, name string, payload interface{}, priority job.JobPriority, timeout time.Duration) (*job.JobResult, error) {
+	if c.resultBackend == nil {
+		return nil, fmt.Errorf("result backend is not configured")
+	}
+
 	// Submit the job
 	jobID, err := c.SubmitJob(name, payload, priority)
 	if err != nil {
@@ -1,13 +1,22 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/muaviaUsmani/bananas/internal/job"
+	"github.com/muaviaUsmani/bananas/internal/progress"
+	"github.com/muaviaUsmani/bananas/internal/queue"
+	"github.com/muaviaUsmani/bananas/internal/scheduler"
+	"github.com/muaviaUsmani/bananas/internal/schema"
+	"github.com/muaviaUsmani/bananas/internal/storage"
+	tasks "github.com/muaviaUsmani/bananas/proto/gen"
 )
 
 func TestNewClient(t *testing.T) {
@@ -209,11 +218,19 @@ func TestSubmitJobScheduled(t *testing.T) {
 		t.Fatalf("failed to get scheduled job: %v", err)
 	}
 
-	// Job should have a scheduled time set (even if not exactly what we specified,
-	// since we're using the Fail mechanism which uses exponential backoff)
+	// Job should be scheduled for the requested time
 	if j.ScheduledFor == nil {
 		t.Fatal("expected scheduled time to be set")
 	}
+	if !j.ScheduledFor.Equal(scheduledTime) {
+		t.Errorf("expected scheduled time %v, got %v", scheduledTime, *j.ScheduledFor)
+	}
+	if j.Status != job.StatusScheduled {
+		t.Errorf("expected status %s, got %s", job.StatusScheduled, j.Status)
+	}
+	if j.Attempts != 0 {
+		t.Errorf("expected no attempts to be counted, got %d", j.Attempts)
+	}
 
 	// Scheduled time should be in the future
 	if j.ScheduledFor.Before(time.Now()) {
@@ -221,6 +238,114 @@ func TestSubmitJobScheduled(t *testing.T) {
 	}
 }
 
+func TestCancelJob(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	jobID, err := client.SubmitJobScheduled("report", map[string]string{}, job.PriorityNormal, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+
+	if err := client.CancelJob(jobID); err != nil {
+		t.Fatalf("expected no error cancelling job, got %v", err)
+	}
+	j, err := client.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if j.Status != job.StatusCancelled {
+		t.Errorf("expected status %s, got %s", job.StatusCancelled, j.Status)
+	}
+
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Scheduled != 0 {
+		t.Errorf("expected cancelled job to be unscheduled, got %d scheduled", stats.Scheduled)
+	}
+
+	if err := client.CancelJob(jobID); !errors.Is(err, queue.ErrNotCancellable) {
+		t.Errorf("expected ErrNotCancellable cancelling twice, got %v", err)
+	}
+	if err := client.CancelJob("missing"); !errors.Is(err, queue.ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound for unknown job, got %v", err)
+	}
+}
+
+func TestNewClientWithBroker(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	broker, err := queue.NewRedisQueue("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	client := NewClientWithBroker(broker, nil)
+	defer client.Close()
+
+	jobID, err := client.SubmitJob("send_email", map[string]string{"to": "a@example.com"}, job.PriorityHigh)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Ready[job.PriorityHigh] != 1 {
+		t.Errorf("expected 1 ready high priority job, got %d", stats.Ready[job.PriorityHigh])
+	}
+	if _, err := client.GetJob(jobID); err != nil {
+		t.Errorf("failed to get job: %v", err)
+	}
+
+	// Without a result backend, results are unavailable rather than panicking
+	if _, err := client.GetResult(context.Background(), jobID); err == nil {
+		t.Error("expected error getting result without a result backend")
+	}
+}
+
+func TestNewStreamClient(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewStreamClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	jobID, err := client.SubmitJob("send_email", map[string]string{"to": "a@example.com"}, job.PriorityLow)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !s.Exists("bananas:stream:low") {
+		t.Error("expected the low priority stream to exist")
+	}
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Ready[job.PriorityLow] != 1 {
+		t.Errorf("expected 1 ready low priority job, got %d", stats.Ready[job.PriorityLow])
+	}
+	if err := client.CancelJob(jobID); err != nil {
+		t.Errorf("failed to cancel job: %v", err)
+	}
+
+	// Streams have no routing
+	if _, err := client.SubmitJobWithRoute("train", nil, job.PriorityNormal, "gpu"); err == nil {
+		t.Error("expected routed job to be rejected")
+	}
+}
+
 func TestSubmitJob_ThreadSafety(t *testing.T) {
 	s := miniredis.RunT(t)
 	defer s.Close()
@@ -257,3 +382,341 @@ func TestSubmitJob_ThreadSafety(t *testing.T) {
 	}
 }
 
+
+func TestEnablePayloadOffload(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	original := job.DefaultBlobStore
+	defer func() { job.DefaultBlobStore = original }()
+
+	client.EnablePayloadOffload(store, 16)
+
+	payload := map[string]string{"document": strings.Repeat("x", 128)}
+	jobID, err := client.SubmitJob("big_job", payload, job.PriorityNormal)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	j, err := client.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if !j.HasPayloadRef() {
+		t.Fatal("expected payload to be offloaded")
+	}
+
+	var got map[string]string
+	if err := j.UnmarshalPayload(&got); err != nil {
+		t.Fatalf("failed to resolve payload: %v", err)
+	}
+	if got["document"] != payload["document"] {
+		t.Error("resolved payload does not match submitted payload")
+	}
+}
+
+func TestSubmitJob_RejectsInvalidPayload(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	client.RegisterSchema("send_email", schema.MustJSONSchema(`{"type": "object", "required": ["to"]}`))
+
+	_, err = client.SubmitJob("send_email", map[string]string{"subject": "hi"}, job.PriorityNormal)
+	if !errors.Is(err, schema.ErrInvalidPayload) {
+		t.Fatalf("expected ErrInvalidPayload, got %v", err)
+	}
+
+	// Nothing should have been enqueued
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("expected no Redis keys after rejected submit, got %v", keys)
+	}
+
+	if _, err := client.SubmitJob("send_email", map[string]string{"to": "user@example.com"}, job.PriorityNormal); err != nil {
+		t.Errorf("expected valid payload to be accepted, got %v", err)
+	}
+}
+
+func TestSubmit_TypedPayloads(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	type resizeRequest struct {
+		URL   string `json:"url"`
+		Width int    `json:"width"`
+	}
+
+	jsonID, err := Submit(client, "resize_image", resizeRequest{URL: "s3://img.png", Width: 200}, job.PriorityNormal)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	jsonJob, err := client.GetJob(jsonID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	var req resizeRequest
+	if err := jsonJob.UnmarshalPayload(&req); err != nil {
+		t.Fatalf("failed to unmarshal JSON payload: %v", err)
+	}
+	if req.Width != 200 {
+		t.Errorf("expected width 200, got %d", req.Width)
+	}
+
+	protoID, err := Submit(client, "send_email", &tasks.EmailTask{To: "user@example.com"}, job.PriorityHigh)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	protoJob, err := client.GetJob(protoID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if !protoJob.IsProtobufPayload() {
+		t.Fatal("expected protobuf payload")
+	}
+	var email tasks.EmailTask
+	if err := protoJob.UnmarshalPayloadProto(&email); err != nil {
+		t.Fatalf("failed to unmarshal protobuf payload: %v", err)
+	}
+	if email.To != "user@example.com" {
+		t.Errorf("expected to 'user@example.com', got '%s'", email.To)
+	}
+}
+
+
+func TestSubmitJobWithCallback(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	jobID, err := client.SubmitJobWithCallback("test_job", map[string]string{"foo": "bar"}, job.PriorityNormal,
+		"https://partner.example.com/hook", "partner-a")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	j, err := client.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if j.CallbackURL != "https://partner.example.com/hook" || j.CallbackSecretRef != "partner-a" {
+		t.Errorf("expected callback to be stored, got %q %q", j.CallbackURL, j.CallbackSecretRef)
+	}
+
+	if _, err := client.SubmitJobWithCallback("test_job", nil, job.PriorityNormal, "not a url", ""); err == nil {
+		t.Error("expected error for invalid callback URL")
+	}
+}
+
+func TestSubmitJobWithRoute(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	jobID, err := client.SubmitJobWithRoute("process_image", map[string]string{"url": "a.jpg"}, job.PriorityHigh, "gpu")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	j, err := client.GetJob(jobID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if j.RoutingKey != "gpu" {
+		t.Errorf("expected routing key 'gpu', got %q", j.RoutingKey)
+	}
+	if !s.Exists("bananas:route:gpu:queue:high") {
+		t.Error("expected job in the gpu route's high priority queue")
+	}
+
+	if _, err := client.SubmitJobWithRoute("process_image", nil, job.PriorityHigh, "gpu:fast"); err == nil {
+		t.Error("expected error for invalid routing key")
+	}
+}
+
+func TestWatchProgress(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updates, err := client.WatchProgress(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to watch progress: %v", err)
+	}
+
+	// Reported by a worker sharing the same Redis
+	client.progress.Report(ctx, progress.Update{JobID: "job-1", Percent: 60, Message: "halfway"})
+	client.progress.Report(ctx, progress.Update{JobID: "job-1", Percent: 100, Done: true, Status: job.StatusCompleted})
+
+	var got []progress.Update
+	for u := range updates {
+		got = append(got, u)
+	}
+	if len(got) != 2 || got[0].Percent != 60 || !got[1].Done {
+		t.Errorf("unexpected updates: %+v", got)
+	}
+
+	latest, err := client.GetProgress(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("failed to get progress: %v", err)
+	}
+	if latest == nil || !latest.Done {
+		t.Errorf("expected latest update to be done, got %+v", latest)
+	}
+}
+
+func TestEnqueue_PreservesTimeoutAndDeadline(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	j := job.NewJob("generate_report", []byte(`{}`), job.PriorityNormal)
+	j.SetTimeout(2 * time.Hour)
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	j.SetDeadline(deadline)
+
+	if err := client.Enqueue(j); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored, err := client.GetJob(j.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if stored.Timeout != 2*time.Hour || stored.Deadline == nil || !stored.Deadline.Equal(deadline) {
+		t.Errorf("expected timeout and deadline to be stored, got %v %v", stored.Timeout, stored.Deadline)
+	}
+
+	client.RegisterSchema("strict", schema.MustJSONSchema(`{"type": "object", "required": ["id"]}`))
+	if err := client.Enqueue(job.NewJob("strict", []byte(`{}`), job.PriorityNormal)); !errors.Is(err, schema.ErrInvalidPayload) {
+		t.Errorf("expected ErrInvalidPayload, got %v", err)
+	}
+}
+
+func TestSubmitDAG(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	importA := job.NewJob("import", []byte(`{"file":"a"}`), job.PriorityNormal)
+	importB := job.NewJob("import", []byte(`{"file":"b"}`), job.PriorityNormal)
+	report := job.NewJob("generate_report", []byte(`{}`), job.PriorityNormal)
+	report.After(importA, importB)
+
+	if err := client.SubmitDAG(importA, importB, report); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	j, err := client.GetJob(report.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if j.Status != job.StatusWaiting {
+		t.Errorf("expected status %s, got %s", job.StatusWaiting, j.Status)
+	}
+	stats, _ := client.Stats(context.Background())
+	if stats.Waiting != 1 || stats.Ready[job.PriorityNormal] != 2 {
+		t.Errorf("expected 1 waiting and 2 ready jobs, got %+v", stats)
+	}
+
+	// Cycles are rejected before anything is enqueued
+	a := job.NewJob("a", []byte(`{}`), job.PriorityNormal)
+	b := job.NewJob("b", []byte(`{}`), job.PriorityNormal)
+	a.After(b)
+	b.After(a)
+	if err := client.SubmitDAG(a, b); !errors.Is(err, job.ErrDependencyCycle) {
+		t.Errorf("expected ErrDependencyCycle, got %v", err)
+	}
+	if _, err := client.GetJob(a.ID); err == nil {
+		t.Error("expected no job stored for a rejected DAG")
+	}
+
+	// Brokers without dependency support reject DAGs
+	memory := NewClientWithBroker(queue.NewMemoryBroker(), nil)
+	if err := memory.SubmitDAG(job.NewJob("a", nil, job.PriorityNormal)); err == nil {
+		t.Error("expected error submitting a DAG to the memory broker")
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	s := miniredis.RunT(t)
+	defer s.Close()
+
+	client, err := NewClient("redis://" + s.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+	if err := client.SetNamespace("acme"); err != nil {
+		t.Fatalf("failed to set namespace: %v", err)
+	}
+
+	err = client.SaveSchedule(&scheduler.Schedule{ID: "nightly", Cron: "0 3 * * *", Job: "cleanup", Enabled: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !s.Exists("acme:schedule_defs") {
+		t.Error("expected the schedule in the client's namespace")
+	}
+	if err := client.SaveSchedule(&scheduler.Schedule{ID: "bad", Cron: "0 3 * * *"}); !errors.Is(err, scheduler.ErrInvalidSchedule) {
+		t.Errorf("expected ErrInvalidSchedule, got %v", err)
+	}
+
+	schedules, err := client.ListSchedules()
+	if err != nil || len(schedules) != 1 || schedules[0].ID != "nightly" {
+		t.Fatalf("expected the saved schedule, got %v (%v)", schedules, err)
+	}
+	if err := client.DeleteSchedule("nightly"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := client.GetSchedule("nightly"); !errors.Is(err, scheduler.ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, got %v", err)
+	}
+}
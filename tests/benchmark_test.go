@@ -677,6 +677,145 @@ func benchmarkConcurrentLoad(b *testing.B, numClients int) {
 	b.ReportMetric(float64(numClients), "clients")
 }
 
+// =============================================================================
+// BENCHMARK: List vs Streams Queue Backends
+// =============================================================================
+
+// setupBenchmarkBroker creates a Redis queue of the given backend (list or streams)
+func setupBenchmarkBroker(t testing.TB, backend string) (*miniredis.Miniredis, queue.RedisBroker) {
+	s := miniredis.RunT(t)
+
+	q, err := queue.NewRedisBroker("redis://"+s.Addr(), backend)
+	if err != nil {
+		t.Fatalf("Failed to create %s queue: %v", backend, err)
+	}
+
+	return s, q
+}
+
+// BenchmarkQueueBackend_Enqueue_List tests enqueue performance of the list queue
+func BenchmarkQueueBackend_Enqueue_List(b *testing.B) {
+	benchmarkBackendEnqueue(b, queue.BackendList)
+}
+
+// BenchmarkQueueBackend_Enqueue_Streams tests enqueue performance of the streams queue
+func BenchmarkQueueBackend_Enqueue_Streams(b *testing.B) {
+	benchmarkBackendEnqueue(b, queue.BackendStreams)
+}
+
+// benchmarkBackendEnqueue measures enqueue latency for a queue backend
+func benchmarkBackendEnqueue(b *testing.B, backend string) {
+	s, q := setupBenchmarkBroker(b, backend)
+	defer s.Close()
+	defer q.Close()
+
+	ctx := context.Background()
+	payload, _ := json.Marshal(map[string]string{"test": "data"})
+	latencies := make([]time.Duration, 0, b.N)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		j := job.NewJob("test_job", payload, job.PriorityNormal)
+
+		start := time.Now()
+		if err := q.Enqueue(ctx, j); err != nil {
+			b.Fatalf("Failed to enqueue: %v", err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+
+	b.StopTimer()
+
+	p50, p95, p99 := calculatePercentiles(latencies)
+	b.ReportMetric(float64(p50.Microseconds()), "p50-μs")
+	b.ReportMetric(float64(p95.Microseconds()), "p95-μs")
+	b.ReportMetric(float64(p99.Microseconds()), "p99-μs")
+}
+
+// BenchmarkQueueBackend_Lifecycle_List tests dequeue + complete of the list queue
+func BenchmarkQueueBackend_Lifecycle_List(b *testing.B) {
+	benchmarkBackendLifecycle(b, queue.BackendList, 1)
+}
+
+// BenchmarkQueueBackend_Lifecycle_Streams tests dequeue + complete of the streams queue
+func BenchmarkQueueBackend_Lifecycle_Streams(b *testing.B) {
+	benchmarkBackendLifecycle(b, queue.BackendStreams, 1)
+}
+
+// BenchmarkQueueBackend_Lifecycle_List_10Workers tests the list queue with 10 concurrent workers
+func BenchmarkQueueBackend_Lifecycle_List_10Workers(b *testing.B) {
+	benchmarkBackendLifecycle(b, queue.BackendList, 10)
+}
+
+// BenchmarkQueueBackend_Lifecycle_Streams_10Workers tests the streams queue with 10 concurrent workers
+func BenchmarkQueueBackend_Lifecycle_Streams_10Workers(b *testing.B) {
+	benchmarkBackendLifecycle(b, queue.BackendStreams, 10)
+}
+
+// benchmarkBackendLifecycle measures dequeue + complete of pre-enqueued jobs,
+// the per-job work a worker does against the queue
+func benchmarkBackendLifecycle(b *testing.B, backend string, numWorkers int) {
+	s, q := setupBenchmarkBroker(b, backend)
+	defer s.Close()
+	defer q.Close()
+
+	ctx := context.Background()
+	payload, _ := json.Marshal(map[string]string{"test": "data"})
+	// Only the queue holding jobs is read, so the list queue's blocking wait on
+	// empty higher priorities doesn't dominate the comparison
+	priorities := []job.JobPriority{job.PriorityNormal}
+
+	for i := 0; i < b.N; i++ {
+		if err := q.Enqueue(ctx, job.NewJob("test_job", payload, job.PriorityNormal)); err != nil {
+			b.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+
+	latencies := make([]time.Duration, 0, b.N)
+	var mu sync.Mutex
+	var remaining atomic.Int64
+	remaining.Store(int64(b.N))
+	var wg sync.WaitGroup
+
+	b.ResetTimer()
+	start := time.Now()
+
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for remaining.Add(-1) >= 0 {
+				startOp := time.Now()
+				j, err := q.Dequeue(ctx, priorities)
+				if err != nil || j == nil {
+					b.Errorf("Failed to dequeue: %v", err)
+					return
+				}
+				if err := q.Complete(ctx, j.ID); err != nil {
+					b.Errorf("Failed to complete: %v", err)
+					return
+				}
+				latency := time.Since(startOp)
+
+				mu.Lock()
+				latencies = append(latencies, latency)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	duration := time.Since(start)
+	b.StopTimer()
+
+	p50, p95, p99 := calculatePercentiles(latencies)
+	b.ReportMetric(float64(len(latencies))/duration.Seconds(), "jobs/sec")
+	b.ReportMetric(float64(p50.Microseconds()), "p50-μs")
+	b.ReportMetric(float64(p95.Microseconds()), "p95-μs")
+	b.ReportMetric(float64(p99.Microseconds()), "p99-μs")
+}
+
 // =============================================================================
 // HELPER: Generate Benchmark Report
 // =============================================================================
@@ -399,7 +399,10 @@ func clearAllQueues(t *testing.T, client *redis.Client) {
 		}
 	}
 
-	// Clear shared queues
+	// Clear shared queues (the default routing key uses the shared priority queues)
+	for _, priority := range priorities {
+		client.Del(ctx, "bananas:queue:"+string(priority))
+	}
 	client.Del(ctx, "bananas:queue:processing")
 	client.Del(ctx, "bananas:queue:dead")
 	client.Del(ctx, "bananas:queue:scheduled")